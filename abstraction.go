@@ -0,0 +1,170 @@
+package farkle
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// Buckets of opponent scores for solving an abstracted game, in which states
+// that differ only in the scores of opponents within the same bucket share a
+// value. This shrinks the number of states to solve by roughly the bucket
+// width per opponent, at the cost of some accuracy.
+//
+// Opponents who have not yet opened are in their own bucket, and the
+// remaining buckets evenly divide the scores up to the score to win. Scores
+// at or above the score to win are not bucketed, since the current player's
+// last turn depends on the exact score to beat. The current player's own
+// score and turn score are never bucketed.
+type ScoreBuckets struct {
+	numBuckets int
+	width      uint8
+}
+
+func NewScoreBuckets(numBuckets int) (ScoreBuckets, error) {
+	openScores := int(scoreToWin - openingScore)
+	if numBuckets < 2 || numBuckets > openScores+1 {
+		return ScoreBuckets{}, fmt.Errorf("number of score buckets must be between 2 and %d, got %d",
+			openScores+1, numBuckets)
+	}
+
+	n := numBuckets - 1 // Excluding the bucket for players who have not opened.
+	return ScoreBuckets{
+		numBuckets: numBuckets,
+		width:      uint8((openScores + n - 1) / n),
+	}, nil
+}
+
+func (b ScoreBuckets) NumBuckets() int {
+	return b.numBuckets
+}
+
+// The score that represents all opponent scores in the same bucket as score:
+// the lowest score in the bucket.
+func (b ScoreBuckets) Representative(score uint8) uint8 {
+	if score == 0 || score >= scoreToWin {
+		return score
+	} else if score < openingScore {
+		// Unreachable, since players must open with at least openingScore.
+		return 0
+	}
+
+	return score - (score-openingScore)%b.width
+}
+
+// The abstract state that represents the given state, in which each
+// opponent's score is replaced with the representative of its bucket.
+func (b ScoreBuckets) AbstractState(gs GameState) GameState {
+	for i := 1; i < int(gs.NumPlayers); i++ {
+		gs.PlayerScores[i] = b.Representative(gs.PlayerScores[i])
+	}
+	return gs
+}
+
+// Whether the given state represents itself in the abstracted game.
+func (b ScoreBuckets) IsAbstract(gs GameState) bool {
+	return b.AbstractState(gs) == gs
+}
+
+// Filter sorted game states (see IterGameStates) to the states of the
+// abstracted game, so that UpdateAll only solves the abstract states. This
+// requires the representatives of all states to be among the given states,
+// as they are for the full game (see SortedGameStates).
+func (b ScoreBuckets) AbstractGameStates(states iter.Seq2[uint64, GameState]) iter.Seq2[uint64, GameState] {
+	return func(yield func(uint64, GameState) bool) {
+		for depth, gs := range states {
+			if b.IsAbstract(gs) && !yield(depth, gs) {
+				return
+			}
+		}
+	}
+}
+
+// DB for an abstracted game, which stores the value of each state in the
+// underlying database under the ID of its abstract state. Every state can be
+// looked up, and gets the value of the state that represents it, so the
+// database can be used to play the real game.
+type BucketedDB struct {
+	DB
+	buckets ScoreBuckets
+}
+
+func NewBucketedDB(db DB, buckets ScoreBuckets) *BucketedDB {
+	return &BucketedDB{DB: db, buckets: buckets}
+}
+
+func (db *BucketedDB) Buckets() ScoreBuckets {
+	return db.buckets
+}
+
+func (db *BucketedDB) abstractID(gsID int) int {
+	gs := GameStateFromID(db.NumPlayers(), gsID)
+	return db.buckets.AbstractState(gs).ID()
+}
+
+func (db *BucketedDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	db.DB.Put(db.abstractID(gsID), pWin)
+}
+
+func (db *BucketedDB) Get(gsID int) [maxNumPlayers]float64 {
+	return db.DB.Get(db.abstractID(gsID))
+}
+
+func (db *BucketedDB) IsDecided(gsID int) bool {
+	decided, ok := db.DB.(decidedDB)
+	return ok && decided.IsDecided(db.abstractID(gsID))
+}
+
+func (db *BucketedDB) prefetch(gsIDs []int) {
+	p, ok := db.DB.(prefetchDB)
+	if !ok {
+		return
+	}
+
+	abstractIDs := make([]int, len(gsIDs))
+	for i, gsID := range gsIDs {
+		abstractIDs[i] = db.abstractID(gsID)
+	}
+	slices.Sort(abstractIDs)
+	p.prefetch(slices.Compact(abstractIDs))
+}
+
+// Map a position in a game with any number of players onto a game with at
+// most 4 players, which can be solved, e.g. to play games with 5 or more
+// players approximately. Scores are in the units of GameState, ordered by
+// turn starting with the current player.
+//
+// The current player and the next player are always kept, since the next
+// player's score decides whether this is the current player's last turn.
+// The remaining seats go to the highest-scoring other opponents, in turn
+// order. Returns the reduced state and, for each of its players, the index
+// in scores of the player they represent.
+func ReduceGameState(scores []uint8, scoreThisRound, numDiceToRoll uint8) (GameState, []int, error) {
+	if len(scores) == 0 {
+		return GameState{}, nil, fmt.Errorf("no players")
+	} else if numDiceToRoll < 1 || numDiceToRoll > MaxNumDice {
+		return GameState{}, nil, fmt.Errorf("invalid number of dice to roll: %d", numDiceToRoll)
+	}
+
+	seats := make([]int, len(scores))
+	for i := range seats {
+		seats[i] = i
+	}
+	if len(seats) > maxNumPlayers {
+		others := slices.Clone(seats[2:])
+		slices.SortStableFunc(others, func(a, b int) int {
+			return int(scores[b]) - int(scores[a])
+		})
+		others = others[:maxNumPlayers-2]
+		slices.Sort(others)
+		seats = append(seats[:2], others...)
+	}
+
+	gs := NewGameState(len(seats))
+	gs.ScoreThisRound = scoreThisRound
+	gs.NumDiceToRoll = numDiceToRoll
+	for i, seat := range seats {
+		gs.PlayerScores[i] = scores[seat]
+	}
+	return gs, seats, nil
+}
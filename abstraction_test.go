@@ -0,0 +1,104 @@
+package farkle
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// Opponent scores that have opened, and are below the score to win, are
+// divided into buckets of consecutive scores, each represented by its lowest.
+func TestScoreBuckets(t *testing.T) {
+	openScores := int(scoreToWin - openingScore)
+	for _, numBuckets := range []int{1, openScores + 2} {
+		if _, err := NewScoreBuckets(numBuckets); err == nil {
+			t.Errorf("created %d buckets of %d scores", numBuckets, openScores)
+		}
+	}
+
+	for _, numBuckets := range []int{2, 5, 17, openScores + 1} {
+		t.Run(fmt.Sprint(numBuckets), func(t *testing.T) {
+			buckets, err := NewScoreBuckets(numBuckets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			representatives := make(map[uint8]bool)
+			for score := 0; score <= 0xff; score++ {
+				rep := buckets.Representative(uint8(score))
+				if score == 0 || score >= int(scoreToWin) {
+					if rep != uint8(score) {
+						t.Errorf("score %d is represented by %d", score, rep)
+					}
+					continue
+				} else if score < int(openingScore) {
+					continue
+				}
+
+				if rep < openingScore || rep > uint8(score) || uint8(score)-rep >= buckets.width {
+					t.Errorf("score %d is represented by %d, with buckets of width %d", score, rep, buckets.width)
+				} else if buckets.Representative(rep) != rep {
+					t.Errorf("representative %d of score %d is not its own representative", rep, score)
+				}
+				representatives[rep] = true
+			}
+			// Including the bucket of players who have not opened.
+			if n := len(representatives) + 1; n > numBuckets {
+				t.Errorf("scores are in %d buckets, want at most %d", n, numBuckets)
+			}
+		})
+	}
+}
+
+// States in the same buckets share a value, and only the opponents' scores
+// are bucketed.
+func TestBucketedDB(t *testing.T) {
+	buckets, err := NewScoreBuckets(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewBucketedDB(NewInMemoryDB(3), buckets)
+	state := NewGameState(3)
+	state.NumDiceToRoll = 3
+	state.ScoreThisRound = openingScore + 1
+	state.PlayerScores = [maxNumPlayers]uint8{openingScore + 1, openingScore + 2, openingScore + 3}
+	abstract := buckets.AbstractState(state)
+	if want := [maxNumPlayers]uint8{openingScore + 1, openingScore, openingScore}; abstract.PlayerScores != want ||
+		abstract.ScoreThisRound != state.ScoreThisRound || abstract.NumDiceToRoll != state.NumDiceToRoll {
+		t.Errorf("abstract state of %v = %v", state, abstract)
+	} else if buckets.IsAbstract(state) || !buckets.IsAbstract(abstract) {
+		t.Errorf("%v or %v is not abstract as expected", state, abstract)
+	}
+
+	db.Put(state.ID(), [maxNumPlayers]float64{0.5, 0.25, 0.25})
+	if got := db.Get(abstract.ID()); got[0] != 0.5 {
+		t.Errorf("value of %v = %v, want the value of %v", abstract, got, state)
+	}
+	state.PlayerScores[0]++
+	if got := db.Get(state.ID()); got[0] == 0.5 {
+		t.Errorf("%v shares the value of a state with another score for the current player", state)
+	}
+}
+
+// The current and next players are always kept, and the other seats go to the
+// highest-scoring opponents, in turn order.
+func TestReduceGameState(t *testing.T) {
+	scores := []uint8{10, 20, 15, 40, 30, 5}
+	gs, seats, err := ReduceGameState(scores, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 3, 4}; !slices.Equal(seats, want) {
+		t.Errorf("seats = %v, want %v", seats, want)
+	}
+	if want := [maxNumPlayers]uint8{10, 20, 40, 30}; gs.PlayerScores != want ||
+		gs.NumPlayers != 4 || gs.ScoreThisRound != 4 || gs.NumDiceToRoll != 3 {
+		t.Errorf("reduced state = %v", gs)
+	}
+
+	if _, _, err := ReduceGameState(nil, 0, 6); err == nil {
+		t.Error("reduced a game without players")
+	}
+	if _, _, err := ReduceGameState(scores, 0, MaxNumDice+1); err == nil {
+		t.Error("reduced a state with too many dice to roll")
+	}
+}
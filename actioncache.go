@@ -0,0 +1,130 @@
+package farkle
+
+import (
+	"container/list"
+	"sync"
+)
+
+// An Advisor that caches the results of SelectAction for the most recently
+// used (state, roll) pairs, for servers in which most requests are for a few
+// popular states, e.g. the first roll of the game. Safe for concurrent use if
+// db is (see ConcurrentDB). Each call reads the values of one version of a
+// ReloadableDB (see AcquireDB).
+type ActionCache struct {
+	db       DB
+	capacity int
+
+	mx sync.Mutex
+	// The cached results, from most to least recently used.
+	order   *list.List
+	entries map[actionCacheKey]*list.Element
+	hits    int64
+	misses  int64
+	// Incremented by Clear, so that results computed before are not stored.
+	generation int
+}
+
+type actionCacheKey struct {
+	stateID int
+	rollID  uint16
+}
+
+type actionCacheEntry struct {
+	key    actionCacheKey
+	action Action
+	value  [maxNumPlayers]float64
+}
+
+// Cache up to capacity results of SelectAction with db.
+func NewActionCache(db DB, capacity int) *ActionCache {
+	return &ActionCache{
+		db:       db,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[actionCacheKey]*list.Element, capacity),
+	}
+}
+
+// As SelectAction with the database of the cache.
+func (c *ActionCache) SelectAction(state GameState, rollID uint16) (Action, [maxNumPlayers]float64) {
+	key := actionCacheKey{stateID: state.ID(), rollID: rollID}
+	c.mx.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.hits++
+		entry := elem.Value.(*actionCacheEntry)
+		c.mx.Unlock()
+		return entry.action, entry.value
+	}
+	c.misses++
+	generation := c.generation
+	c.mx.Unlock()
+
+	// Concurrent misses for the same key may both compute the result,
+	// which is the same, so only one of them is stored. The database is
+	// acquired after the generation is read, so that a result from a version
+	// before a Clear is not stored.
+	db, release := AcquireDB(c.db)
+	action, value := SelectAction(state, rollID, db)
+	release()
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if _, ok := c.entries[key]; !ok && c.capacity > 0 && c.generation == generation {
+		c.entries[key] = c.order.PushFront(&actionCacheEntry{key: key, action: action, value: value})
+		if c.order.Len() > c.capacity {
+			oldest := c.order.Remove(c.order.Back()).(*actionCacheEntry)
+			delete(c.entries, oldest.key)
+		}
+	}
+	return action, value
+}
+
+// Remove all results from the cache, e.g. after the database is reloaded
+// (see ReloadableDB). The statistics are kept.
+func (c *ActionCache) Clear() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.order.Init()
+	clear(c.entries)
+	c.generation++
+}
+
+func (c *ActionCache) Recommend(state GameState, roll Roll) (Action, [maxNumPlayers]float64) {
+	return c.SelectAction(state, GetRollID(roll))
+}
+
+func (c *ActionCache) EvaluateAction(state GameState, action Action) [maxNumPlayers]float64 {
+	return DBAdvisor{DB: c.db}.EvaluateAction(state, action)
+}
+
+func (c *ActionCache) WinProb(state GameState) [maxNumPlayers]float64 {
+	return DBAdvisor{DB: c.db}.WinProb(state)
+}
+
+// Statistics about the use of an ActionCache.
+type ActionCacheStats struct {
+	// The number of results in the cache, and the most it holds.
+	Size     int   `json:"size"`
+	Capacity int   `json:"capacity"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+// The fraction of lookups that were found in the cache.
+func (s ActionCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (c *ActionCache) Stats() ActionCacheStats {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return ActionCacheStats{
+		Size:     c.order.Len(),
+		Capacity: c.capacity,
+		Hits:     c.hits,
+		Misses:   c.misses,
+	}
+}
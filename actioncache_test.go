@@ -0,0 +1,58 @@
+package farkle
+
+import (
+	"testing"
+)
+
+func checkCachedActions(t *testing.T, cache *ActionCache, db DB, states []GameState, rollIDs []uint16) {
+	t.Helper()
+	for i, state := range states {
+		wantAction, wantValue := SelectAction(state, rollIDs[i], db)
+		action, value := cache.SelectAction(state, rollIDs[i])
+		if action != wantAction || value != wantValue {
+			t.Fatalf("%v, roll %v: cached %v (%v), want %v (%v)",
+				state, rollsByID[rollIDs[i]], action, value, wantAction, wantValue)
+		}
+	}
+}
+
+func checkCacheStats(t *testing.T, cache *ActionCache, want ActionCacheStats) {
+	t.Helper()
+	if got := cache.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+// Cached results are those of SelectAction, and the least recently used
+// are evicted first.
+func TestActionCache(t *testing.T) {
+	db := &fakeDB{numPlayers: 2, seed: benchSeed}
+	states, rollIDs := sampleGameStates(2)
+	states, rollIDs = states[:100], rollIDs[:100]
+
+	cache := NewActionCache(db, len(states))
+	checkCachedActions(t, cache, db, states, rollIDs)
+	checkCachedActions(t, cache, db, states, rollIDs)
+	checkCacheStats(t, cache, ActionCacheStats{Size: 100, Capacity: 100, Hits: 100, Misses: 100})
+
+	// The results of the old values are not returned after Clear.
+	db.seed++
+	cache.Clear()
+	checkCachedActions(t, cache, db, states, rollIDs)
+	checkCacheStats(t, cache, ActionCacheStats{Size: 100, Capacity: 100, Hits: 100, Misses: 200})
+
+	cache = NewActionCache(db, 2)
+	for _, i := range []int{0, 1, 0, 2} {
+		cache.SelectAction(states[i], rollIDs[i])
+	}
+	checkCacheStats(t, cache, ActionCacheStats{Size: 2, Capacity: 2, Hits: 1, Misses: 3})
+	// The second state was evicted, and the first was used more recently.
+	for _, i := range []int{0, 2, 1} {
+		cache.SelectAction(states[i], rollIDs[i])
+	}
+	checkCacheStats(t, cache, ActionCacheStats{Size: 2, Capacity: 2, Hits: 3, Misses: 4})
+
+	cache = NewActionCache(db, 0)
+	checkCachedActions(t, cache, db, states[:10], rollIDs[:10])
+	checkCacheStats(t, cache, ActionCacheStats{Size: 0, Capacity: 0, Hits: 0, Misses: 10})
+}
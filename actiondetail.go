@@ -0,0 +1,135 @@
+package farkle
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// A candidate action in response to a roll, with statistics that help
+// explain its value, e.g. to show in a user interface.
+type ActionDetail struct {
+	Action Action
+	// The value of the action, as returned by EvaluateAction.
+	Value [maxNumPlayers]float64
+	// The number of dice the next roll would be of, if continuing.
+	NumDiceLeft int
+	// The probability of farkling on the next roll. 0 if the action banks.
+	PFarkle float64
+	// The expected number of points banked at the end of the turn,
+	// including the points already won, if the current player takes the
+	// action and then plays the policy of the database. Farkles bank 0
+	// points. If the action banks, the points banked. Not computed by
+	// EvaluatePositions.
+	ExpectedTurnScore float64
+}
+
+// All legal actions in response to the given roll, with details of each,
+// ordered from best to worst for the current player. Empty if the roll is a
+// farkle. This is much more expensive than SelectAction, since the expected
+// turn score considers every way the rest of the turn could go.
+func SelectActionDetailed(state GameState, roll Roll, db DB) []ActionDetail {
+	result := evaluateActions(state, roll, db)
+	turnScores := make(map[GameState]float64)
+	for i, detail := range result {
+		next := ApplyAction(state, Action{HeldDiceID: detail.Action.HeldDiceID, ContinueRolling: true})
+		if detail.Action.ContinueRolling {
+			result[i].ExpectedTurnScore = incr * expectedTurnScore(next, db, turnScores)
+		} else {
+			result[i].ExpectedTurnScore = incr * float64(next.ScoreThisRound)
+		}
+	}
+	return result
+}
+
+// Evaluate every legal action in each of the given positions, as
+// SelectActionDetailed but without expected turn scores, which are expensive.
+// The values of all of the states reached are read from db in one batch
+// first, if it supports it, so that thousands of positions can be evaluated
+// quickly.
+func EvaluatePositions(positions []Position, db DB) [][]ActionDetail {
+	if p, ok := db.(prefetchDB); ok {
+		var gsIDs []int
+		for _, pos := range positions {
+			for _, action := range LegalActions(pos.State, pos.Roll) {
+				gsIDs = append(gsIDs, ApplyAction(pos.State, action).ID())
+			}
+		}
+		slices.Sort(gsIDs)
+		p.prefetch(slices.Compact(gsIDs))
+	}
+
+	result := make([][]ActionDetail, len(positions))
+	for i, pos := range positions {
+		result[i] = evaluateActions(pos.State, pos.Roll, db)
+	}
+	return result
+}
+
+// The legal actions in response to the given roll, with their values and
+// chances of farkling, from best to worst.
+func evaluateActions(state GameState, roll Roll, db DB) []ActionDetail {
+	var result []ActionDetail
+	for _, action := range LegalActions(state, roll) {
+		detail := ActionDetail{
+			Action: action,
+			Value:  EvaluateAction(state, action, db),
+		}
+		if action.ContinueRolling {
+			next := ApplyAction(state, action)
+			detail.NumDiceLeft = int(next.NumDiceToRoll)
+			detail.PFarkle = farkleProb(int(next.NumDiceToRoll))
+		}
+		result = append(result, detail)
+	}
+
+	// In single-player games, values are the expected number of turns remaining.
+	sort.SliceStable(result, func(i, j int) bool {
+		if state.NumPlayers == 1 {
+			return result[i].Value[0] < result[j].Value[0]
+		}
+		return result[i].Value[0] > result[j].Value[0]
+	})
+	return result
+}
+
+// The probability that a roll of the given number of dice is a farkle.
+func farkleProb(numDice int) float64 {
+	p := 0.0
+	for _, wRoll := range allRolls[numDice] {
+		if IsFarkle(wRoll.Roll) {
+			p += wRoll.Prob
+		}
+	}
+	return p
+}
+
+// The expected score banked at the end of the turn (in the units of
+// GameState) from the given state, when the current player plays the
+// policy of db. Memoized in memo, since many sequences of holds lead to
+// the same state.
+func expectedTurnScore(state GameState, db DB, memo map[GameState]float64) float64 {
+	if v, ok := memo[state]; ok {
+		return v
+	}
+
+	v := 0.0
+	for _, wRoll := range allRolls[state.NumDiceToRoll] {
+		if IsFarkle(wRoll.Roll) {
+			continue
+		}
+
+		action, _ := SelectAction(state, wRoll.ID, db)
+		next := ApplyAction(state, Action{HeldDiceID: action.HeldDiceID, ContinueRolling: true})
+		// Scores saturate at the maximum, so continuing from there
+		// would never end. Treat it as banking instead.
+		if !action.ContinueRolling || next.ScoreThisRound == math.MaxUint8 {
+			v += wRoll.Prob * float64(next.ScoreThisRound)
+		} else {
+			v += wRoll.Prob * expectedTurnScore(next, db, memo)
+		}
+	}
+
+	memo[state] = v
+	return v
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package farkle
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// The CPUs this process may run on.
+func allowedCPUs() ([]int, error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return nil, err
+	}
+
+	var cpus []int
+	for cpu := 0; len(cpus) < set.Count(); cpu++ {
+		if set.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// Run the calling goroutine only on the given CPU. The goroutine is locked to
+// its thread, which exits with the goroutine rather than running others.
+func pinToCPU(cpu int) error {
+	runtime.LockOSThread()
+	var set unix.CPUSet
+	set.Set(cpu)
+	return unix.SchedSetaffinity(0, &set)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package farkle
+
+import "fmt"
+
+func allowedCPUs() ([]int, error) {
+	return nil, fmt.Errorf("pinning workers to CPUs is only supported on Linux")
+}
+
+func pinToCPU(cpu int) error {
+	return fmt.Errorf("pinning workers to CPUs is only supported on Linux")
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Probability that the current player reaches the target score (in points)
+// within each of the next numTurns turns, when following the given policy.
+// result[k] is the probability of having reached the target after k+1 turns.
+// The scores of the other players are held fixed at their values in state.
+func ProbReachWithin(state farkle.GameState, policy farkle.Strategy, target, numTurns int) ([]float64, error) {
+	targetScore, err := farkle.ParseScore(target)
+	if err != nil {
+		return nil, err
+	}
+	if targetScore == 0 {
+		return nil, fmt.Errorf("target score must be positive: %d", target)
+	}
+	goal := int(targetScore)
+
+	// Statistics of a turn from each starting score, computed as needed.
+	turnStats := make(map[int]TurnStats)
+	statsFrom := func(score int) (TurnStats, error) {
+		if stats, ok := turnStats[score]; ok {
+			return stats, nil
+		}
+		s := state
+		s.PlayerScores[0] = uint8(score)
+		stats, err := AnalyzeTurn(s, policy)
+		if err != nil {
+			return TurnStats{}, err
+		}
+		turnStats[score] = stats
+		return stats, nil
+	}
+
+	// Distribution of the current player's score, with the goal absorbing.
+	scoreProbs := make([]float64, goal+1)
+	scoreProbs[min(int(state.PlayerScores[0]), goal)] = 1
+	result := make([]float64, numTurns)
+	for k := range result {
+		next := make([]float64, goal+1)
+		next[goal] = scoreProbs[goal]
+		for score, p := range scoreProbs[:goal] {
+			if p == 0 {
+				continue
+			}
+
+			stats, err := statsFrom(score)
+			if err != nil {
+				return nil, err
+			}
+			for banked, q := range stats.ScoreProbs {
+				next[min(score+banked, goal)] += p * q
+			}
+		}
+
+		scoreProbs = next
+		result[k] = scoreProbs[goal]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,178 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// The distribution of the length of games, e.g. to schedule tournaments or to
+// compare the model against the length of games played by people.
+type GameLength struct {
+	// TurnProbs[k] is the probability that the game lasts k turns in total,
+	// counting the turns of all players.
+	TurnProbs []float64
+	// RollProbs[k] is the probability that the dice are rolled k times in
+	// the game. It is nil if only the expected number of rolls is known.
+	RollProbs     []float64
+	ExpectedTurns float64
+	ExpectedRolls float64
+	// The number of games the distribution was estimated from, or 0 if it
+	// was computed exactly.
+	NumGames int
+}
+
+// The smallest number of turns k such that the game lasts at most k turns
+// with probability at least q.
+func (l GameLength) TurnQuantile(q float64) int {
+	return quantile(l.TurnProbs, q)
+}
+
+// The smallest number of rolls k such that the game lasts at most k rolls
+// with probability at least q, or -1 if the distribution is unknown.
+func (l GameLength) RollQuantile(q float64) int {
+	if l.RollProbs == nil {
+		return -1
+	}
+	return quantile(l.RollProbs, q)
+}
+
+// Standard deviation of the number of turns.
+func (l GameLength) TurnStdDev() float64 {
+	return stdDev(l.TurnProbs, l.ExpectedTurns)
+}
+
+func quantile(probs []float64, q float64) int {
+	cdf := 0.0
+	for k, p := range probs {
+		cdf += p
+		if cdf >= q-1e-12 {
+			return k
+		}
+	}
+	return len(probs) - 1
+}
+
+func stdDev(probs []float64, mean float64) float64 {
+	variance := 0.0
+	for k, p := range probs {
+		d := float64(k) - mean
+		variance += p * d * d
+	}
+	return math.Sqrt(variance)
+}
+
+// Compute the distribution of the length of a one-player game, in which the
+// player follows the given policy until reaching the target score, exactly
+// by dynamic programming over the player's score at the start of each turn.
+// The distribution is truncated after maxTurns turns, or once less than tol
+// probability remains that the game is not over.
+func SolitaireGameLength(policy farkle.Strategy, maxTurns int, tol float64) (GameLength, error) {
+	target, err := farkle.ParseScore(farkle.CurrentRules().TargetScore)
+	if err != nil {
+		return GameLength{}, err
+	}
+	goal := int(target)
+
+	// Statistics of a turn from each starting score, computed as needed.
+	turnStats := make([]*TurnStats, goal)
+	statsFrom := func(score int) (*TurnStats, error) {
+		if turnStats[score] == nil {
+			state := farkle.NewGameState(1)
+			state.PlayerScores[0] = uint8(score)
+			stats, err := AnalyzeTurn(state, policy)
+			if err != nil {
+				return nil, err
+			}
+			turnStats[score] = &stats
+		}
+		return turnStats[score], nil
+	}
+
+	// Distribution of the player's score among games that are not yet over.
+	scoreProbs := make([]float64, goal)
+	scoreProbs[0] = 1
+	remaining := 1.0
+	result := GameLength{TurnProbs: []float64{0}}
+	for k := 1; k <= maxTurns && remaining > tol; k++ {
+		next := make([]float64, goal)
+		pOver := 0.0
+		for score, p := range scoreProbs {
+			if p == 0 {
+				continue
+			}
+
+			stats, err := statsFrom(score)
+			if err != nil {
+				return GameLength{}, err
+			}
+			result.ExpectedRolls += p * stats.ExpectedRolls
+			for banked, q := range stats.ScoreProbs {
+				if score+banked >= goal {
+					pOver += p * q
+				} else {
+					next[score+banked] += p * q
+				}
+			}
+		}
+
+		scoreProbs = next
+		remaining -= pOver
+		result.TurnProbs = append(result.TurnProbs, pOver)
+		result.ExpectedTurns += float64(k) * pOver
+	}
+
+	return result, nil
+}
+
+// Estimate the distribution of the length of games between the given
+// strategies, with strategies[i] in seat i, by playing numGames games.
+func SimulateGameLength(strategies []farkle.Strategy, numGames int, rng *rand.Rand) (GameLength, error) {
+	if numGames <= 0 {
+		return GameLength{}, fmt.Errorf("number of games must be positive: %d", numGames)
+	}
+
+	var turnCounts, rollCounts []int
+	count := func(counts []int, k int) []int {
+		for len(counts) <= k {
+			counts = append(counts, 0)
+		}
+		counts[k]++
+		return counts
+	}
+
+	result := GameLength{NumGames: numGames}
+	for i := 0; i < numGames; i++ {
+		rolls := 0
+		gameResult, err := farkle.PlayGameObserved(strategies, rng,
+			func(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+				rolls++
+			})
+		if err != nil {
+			return GameLength{}, err
+		}
+
+		turnCounts = count(turnCounts, gameResult.NumTurns)
+		rollCounts = count(rollCounts, rolls)
+		result.ExpectedTurns += float64(gameResult.NumTurns) / float64(numGames)
+		result.ExpectedRolls += float64(rolls) / float64(numGames)
+	}
+
+	result.TurnProbs = normalize(turnCounts)
+	result.RollProbs = normalize(rollCounts)
+	return result, nil
+}
+
+func normalize(counts []int) []float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	result := make([]float64, len(counts))
+	for k, c := range counts {
+		result[k] = float64(c) / float64(total)
+	}
+	return result
+}
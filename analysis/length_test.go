@@ -0,0 +1,50 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/testutil"
+)
+
+// The expected length of miniature one-player games, from the distribution of
+// their length with the optimal policy, agrees with the value of the start of
+// the game solved exactly and, where it is known, by hand (see
+// farkle.TestMiniatureGames).
+func TestSolitaireGameLength(t *testing.T) {
+	for _, game := range []struct {
+		rules string
+		want  float64
+	}{
+		{"pocket-farkle,target=50,dice=2", 9.0 / 5},
+		{"pocket-farkle,target=100,dice=2", 27.0 / 11},
+		{"pocket-farkle,target=300,dice=3", math.NaN()},
+		{"standard,target=300,dice=3,opening=0", math.NaN()},
+	} {
+		t.Run(game.rules, func(t *testing.T) {
+			testutil.SetRules(t, game.rules)
+			db := farkle.NewInMemoryDB(1)
+			farkle.SolveExact(db, "")
+			solved := farkle.CalculateWinProb(farkle.NewGameState(1), db)[0]
+			if !math.IsNaN(game.want) && math.Abs(solved-game.want) > 1e-12 {
+				t.Errorf("SolveExact: expected turns = %v, want %v", solved, game.want)
+			}
+
+			length, err := SolitaireGameLength(farkle.OptimalStrategy{DB: db}, 10000, 1e-15)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(length.ExpectedTurns-solved) > 1e-9 {
+				t.Errorf("SolitaireGameLength: expected turns = %v, want %v", length.ExpectedTurns, solved)
+			}
+			total := 0.0
+			for _, p := range length.TurnProbs {
+				total += p
+			}
+			if math.Abs(total-1) > 1e-12 {
+				t.Errorf("SolitaireGameLength: probabilities add up to %v", total)
+			}
+		})
+	}
+}
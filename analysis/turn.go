@@ -0,0 +1,135 @@
+// Package analysis computes statistics of Farkle play under a given policy,
+// exactly by dynamic programming over all possible rolls rather than by
+// simulation, except for the length of games with more than one player, which
+// is estimated by playing them. It does not require a solution database,
+// unless the policy does.
+package analysis
+
+import (
+	"math"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Points per unit of score in farkle.GameState.
+const incr = 50
+
+// Statistics of a single turn played with a given policy.
+type TurnStats struct {
+	// ScoreProbs[i] is the probability of banking 50*i points this turn.
+	// Farkles bank 0 points.
+	ScoreProbs []float64
+	// Expected number of points banked.
+	ExpectedScore float64
+	// Expected number of times the dice are rolled.
+	ExpectedRolls float64
+	// Probability that the turn ends in a farkle.
+	PFarkle float64
+}
+
+type turnValue struct {
+	scoreProbs []float64
+	rolls      float64
+	pFarkle    float64
+}
+
+type turnKey struct {
+	turnScore uint8
+	numDice   uint8
+}
+
+// Computes the outcome of a turn from each intermediate position.
+type turnAnalyzer struct {
+	policy farkle.Strategy
+	state  farkle.GameState
+	rolls  [farkle.MaxNumDice + 1][]farkle.WeightedRoll
+	memo   map[turnKey]*turnValue
+}
+
+// Compute statistics of a turn starting from the given state, when the
+// current player follows the given policy. Only the player scores of the
+// state are used: the turn begins with no points and all dice to roll.
+func AnalyzeTurn(state farkle.GameState, policy farkle.Strategy) (TurnStats, error) {
+	ta := &turnAnalyzer{
+		policy: policy,
+		state:  state,
+		memo:   make(map[turnKey]*turnValue),
+	}
+	for n := range ta.rolls {
+		ta.rolls[n] = farkle.PossibleRolls(n)
+	}
+
+	v, err := ta.value(0, uint8(farkle.CurrentRules().TurnDice()))
+	if err != nil {
+		return TurnStats{}, err
+	}
+
+	stats := TurnStats{
+		ScoreProbs:    v.scoreProbs,
+		ExpectedRolls: v.rolls,
+		PFarkle:       v.pFarkle,
+	}
+	for i, p := range v.scoreProbs {
+		stats.ExpectedScore += incr * float64(i) * p
+	}
+	return stats, nil
+}
+
+func (ta *turnAnalyzer) value(turnScore, numDice uint8) (*turnValue, error) {
+	key := turnKey{turnScore, numDice}
+	if v, ok := ta.memo[key]; ok {
+		return v, nil
+	}
+
+	state := ta.state
+	state.ScoreThisRound = turnScore
+	state.NumDiceToRoll = numDice
+
+	v := &turnValue{rolls: 1}
+	for _, wRoll := range ta.rolls[numDice] {
+		if farkle.IsFarkle(wRoll.Roll) {
+			addProb(&v.scoreProbs, 0, wRoll.Prob)
+			v.pFarkle += wRoll.Prob
+			continue
+		}
+
+		action, err := ta.policy.SelectAction(state, wRoll.Roll)
+		if err != nil {
+			return nil, err
+		}
+		if err := farkle.ValidateAction(state, wRoll.Roll, action); err != nil {
+			return nil, err
+		}
+
+		next := farkle.ApplyAction(state, farkle.Action{
+			HeldDiceID:      action.HeldDiceID,
+			ContinueRolling: true,
+		})
+		// Scores saturate at the maximum, so continuing from there
+		// would never end. Treat it as banking instead.
+		if !action.ContinueRolling || next.ScoreThisRound == math.MaxUint8 {
+			addProb(&v.scoreProbs, int(next.ScoreThisRound), wRoll.Prob)
+			continue
+		}
+
+		sub, err := ta.value(next.ScoreThisRound, next.NumDiceToRoll)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range sub.scoreProbs {
+			addProb(&v.scoreProbs, i, wRoll.Prob*p)
+		}
+		v.rolls += wRoll.Prob * sub.rolls
+		v.pFarkle += wRoll.Prob * sub.pFarkle
+	}
+
+	ta.memo[key] = v
+	return v, nil
+}
+
+func addProb(probs *[]float64, i int, p float64) {
+	for len(*probs) <= i {
+		*probs = append(*probs, 0)
+	}
+	(*probs)[i] += p
+}
@@ -0,0 +1,130 @@
+package farkle
+
+import "fmt"
+
+// Classification of a move by how much win probability it gives up.
+type MoveQuality int
+
+const (
+	Best MoveQuality = iota
+	Good
+	Inaccuracy
+	Mistake
+	Blunder
+)
+
+// Thresholds of equity loss (absolute drop in win probability)
+// above which a move is considered to be of each quality.
+var moveQualityThresholds = []struct {
+	Quality MoveQuality
+	MinLoss float64
+}{
+	{Blunder, 0.05},
+	{Mistake, 0.02},
+	{Inaccuracy, 0.005},
+	{Good, 1e-9},
+}
+
+func (q MoveQuality) String() string {
+	switch q {
+	case Best:
+		return "best"
+	case Good:
+		return "good"
+	case Inaccuracy:
+		return "inaccuracy"
+	case Mistake:
+		return "mistake"
+	case Blunder:
+		return "blunder"
+	}
+	return fmt.Sprintf("MoveQuality(%d)", int(q))
+}
+
+func classifyMove(equityLoss float64) MoveQuality {
+	for _, t := range moveQualityThresholds {
+		if equityLoss >= t.MinLoss {
+			return t.Quality
+		}
+	}
+	return Best
+}
+
+// Analysis of a single move in a recorded game.
+type MoveAnnotation struct {
+	Event         ReplayEvent
+	OptimalAction Action
+	// Win probability of the player who moved, after the optimal
+	// and after the selected action.
+	PWinOptimal float64
+	PWinAction  float64
+	// Drop in win probability compared to the optimal action (always >= 0).
+	EquityLoss float64
+	Quality    MoveQuality
+}
+
+// Summary of the moves made by one player in a recorded game.
+type PlayerSummary struct {
+	Seat     int
+	NumMoves int
+	// Total and average drop in win probability over all moves.
+	TotalLoss   float64
+	AverageLoss float64
+	// Percentage of moves that were optimal.
+	Accuracy float64
+	// Number of moves of each quality.
+	NumByQuality map[MoveQuality]int
+}
+
+// Annotate each move in a recorded game with the win probability it gave up
+// compared to optimal play, and summarize the moves made by each player.
+// Farkles are not moves, since the player had no choice.
+func AnnotateReplay(events []ReplayEvent, db DB) ([]MoveAnnotation, []PlayerSummary) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	numPlayers := int(events[0].State.NumPlayers)
+	summaries := make([]PlayerSummary, numPlayers)
+	for seat := range summaries {
+		summaries[seat] = PlayerSummary{
+			Seat:         seat,
+			NumByQuality: make(map[MoveQuality]int),
+		}
+	}
+
+	var annotations []MoveAnnotation
+	for _, event := range events {
+		if IsFarkle(event.Roll) {
+			continue
+		}
+
+		optAction, pWinOpt := SelectAction(event.State, GetRollID(event.Roll), db)
+		pWinAction := EvaluateAction(event.State, event.Action, db)
+		equityLoss := max(0, pWinOpt[0]-pWinAction[0])
+		annotation := MoveAnnotation{
+			Event:         event,
+			OptimalAction: optAction,
+			PWinOptimal:   pWinOpt[0],
+			PWinAction:    pWinAction[0],
+			EquityLoss:    equityLoss,
+			Quality:       classifyMove(equityLoss),
+		}
+		annotations = append(annotations, annotation)
+
+		summary := &summaries[event.Seat]
+		summary.NumMoves++
+		summary.TotalLoss += equityLoss
+		summary.NumByQuality[annotation.Quality]++
+	}
+
+	for i := range summaries {
+		summary := &summaries[i]
+		if summary.NumMoves > 0 {
+			summary.AverageLoss = summary.TotalLoss / float64(summary.NumMoves)
+			summary.Accuracy = 100 * float64(summary.NumByQuality[Best]) / float64(summary.NumMoves)
+		}
+	}
+
+	return annotations, summaries
+}
@@ -0,0 +1,215 @@
+package farkle
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The maximum number of states calculated together by calcStateValues.
+const bandBatchSize = 256
+
+// Calculate the value of each of the given states, as in calcStateValue, into
+// result. The states must all be before the end of the game and have the same
+// number of players, dice to roll and score this round, so that the same rolls
+// and actions apply to all of them. Each action is evaluated for the whole
+// batch at once, and the states it leads to are at the same offset from each
+// of the states, so sorting the batch by ID turns the reads from db into
+// a few streams through the database instead of scattered accesses.
+// There may be at most bandBatchSize states.
+func calcStateValues(states []GameState, db DB, result [][maxNumPlayers]float64) {
+	if len(states) > bandBatchSize {
+		panic(fmt.Errorf("batch of %d states is larger than the maximum %d",
+			len(states), bandBatchSize))
+	}
+
+	var parts [bandBatchSize]stateIDParts
+	var best [bandBatchSize][maxNumPlayers]float64
+	for j, state := range states {
+		if state.NumPlayers != states[0].NumPlayers || !sameBand(state, states[0]) {
+			panic(fmt.Errorf("states %v and %v are not in the same band", states[0], state))
+		}
+		parts[j] = newStateIDParts(state)
+		result[j] = [maxNumPlayers]float64{}
+	}
+
+	first := states[0]
+	solitaire := first.NumPlayers == 1
+	for _, wRoll := range allRolls[first.NumDiceToRoll] {
+		potentialActions := rollIDToPotentialActions[wRoll.ID]
+		effects := rollIDToActionEffects[wRoll.ID]
+		if len(potentialActions) == 0 {
+			for j := range states {
+				pSubtree := fromNextTurn(db.Get(parts[j].farkleID()), int(first.NumPlayers))
+				mixInto(&result[j], wRoll.Prob, &pSubtree)
+			}
+			continue
+		}
+
+		for j := range states {
+			best[j] = [maxNumPlayers]float64{math.Inf(-1)}
+			if solitaire {
+				best[j][0] = math.Inf(1)
+			}
+		}
+		for i, action := range potentialActions {
+			for j := range states {
+				action, newStateID, allowed := parts[j].takeAction(action, effects[i])
+				if !allowed {
+					continue
+				}
+
+				pSubtree := db.Get(newStateID)
+				if !action.ContinueRolling {
+					pSubtree = fromNextTurn(pSubtree, int(first.NumPlayers))
+				}
+				if (solitaire && pSubtree[0] < best[j][0]) || (!solitaire && pSubtree[0] > best[j][0]) {
+					best[j] = pSubtree
+				}
+			}
+		}
+
+		for j := range states {
+			mixInto(&result[j], wRoll.Prob, &best[j])
+		}
+	}
+}
+
+// Collects all states at each depth and calculates the states with the same
+// number of dice to roll and score this round together, in batches sorted by
+// ID, with valueTable.values. The values are stored once all states at the
+// depth have been calculated.
+type bandUpdater struct {
+	tables   []valueTable
+	prefetch bool
+	states   []GameState
+	depth    uint64
+	start    time.Time
+}
+
+func (u *bandUpdater) Start(depth uint64) {
+	u.states = u.states[:0]
+	u.depth = depth
+	u.start = time.Now()
+}
+
+func (u *bandUpdater) Add(state GameState) {
+	u.states = append(u.states, state)
+}
+
+func (u *bandUpdater) Finish() DepthStats {
+	if u.prefetch {
+		prefetchStates(u.tables, u.states)
+	}
+
+	slices.SortFunc(u.states, func(a, b GameState) int {
+		return cmp.Or(
+			cmp.Compare(a.NumDiceToRoll, b.NumDiceToRoll),
+			cmp.Compare(a.ScoreThisRound, b.ScoreThisRound),
+			cmp.Compare(a.ID(), b.ID()))
+	})
+
+	n := len(u.states)
+	values := make([][maxNumPlayers]float64, len(u.tables)*n)
+	updated := make([]bool, n)
+	numWorkers := runtime.NumCPU()
+	workCh := make(chan [2]int, numWorkers)
+	var wg sync.WaitGroup
+	stats := make([]DepthStats, numWorkers)
+	for k := range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range workCh {
+				stats[k].merge(u.calcValues(batch[0], batch[1], values, updated))
+			}
+		}()
+	}
+
+	for start := 0; start < n; {
+		end := start + 1
+		for end < min(n, start+bandBatchSize) && sameBand(u.states[start], u.states[end]) {
+			end++
+		}
+		workCh <- [2]int{start, end}
+		start = end
+	}
+	close(workCh)
+	wg.Wait()
+
+	for j, state := range u.states {
+		if !updated[j] {
+			continue
+		}
+		for i, table := range u.tables {
+			table.db.Put(state.ID(), values[i*n+j])
+		}
+	}
+
+	result := DepthStats{Depth: u.depth}
+	for _, workerStats := range stats {
+		result.merge(workerStats)
+	}
+	result.Elapsed = time.Since(u.start)
+	return result
+}
+
+// Whether the same rolls and actions apply to both states.
+func sameBand(a, b GameState) bool {
+	return a.NumDiceToRoll == b.NumDiceToRoll && a.ScoreThisRound == b.ScoreThisRound &&
+		!a.IsGameOver() && !b.IsGameOver()
+}
+
+// Calculate the new values of the states in [start, end), which are in the
+// same band or a single state at the end of the game, in all tables, without
+// storing them. The value of state j in table i is stored in
+// values[i*len(u.states)+j].
+func (u *bandUpdater) calcValues(start, end int, values [][maxNumPlayers]float64, updated []bool) DepthStats {
+	var stats DepthStats
+	var batch [bandBatchSize]GameState
+	var indices [bandBatchSize]int
+	m := 0
+	for j := start; j < end; j++ {
+		if !allDecided(u.tables, u.states[j].ID()) {
+			batch[m], indices[m] = u.states[j], j
+			m++
+		}
+	}
+	if m == 0 {
+		return stats
+	}
+
+	n := len(u.states)
+	var result [bandBatchSize][maxNumPlayers]float64
+	var changes [bandBatchSize]float64
+	for i, table := range u.tables {
+		if batch[0].IsGameOver() {
+			result[0] = calcTableValue(table, batch[0])
+		} else {
+			table.values(batch[:m], result[:m])
+		}
+		for k, j := range indices[:m] {
+			values[i*n+j] = result[k]
+			changes[k] = max(changes[k], valueChange(table, batch[k], result[k]))
+		}
+	}
+	for k, j := range indices[:m] {
+		updated[j] = true
+		stats.add(changes[k])
+	}
+	return stats
+}
+
+// Whether all of the tables can calculate the values of batches of states.
+func allBatched(tables []valueTable) bool {
+	for _, table := range tables {
+		if table.values == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package farkle
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// calcStateValues makes the same choices as SelectAction, so it calculates
+// the same values as calcStateValue, for every state of miniature games and
+// for random states of the standard game, including states in which the
+// current player is not yet on the board or has overflowed their score this
+// round. The values in the database are random, so that the choices differ
+// between states and ties are unlikely.
+func TestCalcStateValues(t *testing.T) {
+	for _, game := range []struct {
+		rules      string
+		numPlayers int
+	}{
+		{"pocket-farkle,target=300,dice=3", 1},
+		{"standard,target=300,dice=3,opening=100", 1},
+		{"pocket-farkle,target=50,dice=2", 2},
+		{"standard,target=150,dice=2,opening=0", 2},
+		{"standard,target=150,dice=2,opening=100", 2},
+	} {
+		t.Run(game.rules, func(t *testing.T) {
+			setTestRules(t, game.rules)
+			var states []GameState
+			for _, ds := range miniatureGameStates(t, game.numPlayers) {
+				states = append(states, ds.state)
+			}
+			checkCalcStateValues(t, states)
+		})
+	}
+
+	t.Run("standard", func(t *testing.T) {
+		setTestRules(t, "standard")
+		rng := rand.New(rand.NewSource(benchSeed))
+		var states []GameState
+		for len(states) < 20000 {
+			state := randomState(rng)
+			switch rng.Intn(4) {
+			case 0:
+				state.ScoreThisRound = math.MaxUint8
+			case 1:
+				state.PlayerScores[0] = 0
+			}
+			if !state.IsGameOver() {
+				states = append(states, state)
+			}
+		}
+		checkCalcStateValues(t, states)
+	})
+}
+
+// Calculate the values of the states in batches, as bandUpdater does, and
+// compare them with the values calculated one at a time.
+func checkCalcStateValues(t *testing.T, states []GameState) {
+	t.Helper()
+	states = slices.DeleteFunc(slices.Clone(states), GameState.IsGameOver)
+	slices.SortFunc(states, func(a, b GameState) int {
+		return cmp.Or(
+			cmp.Compare(a.NumPlayers, b.NumPlayers),
+			cmp.Compare(a.NumDiceToRoll, b.NumDiceToRoll),
+			cmp.Compare(a.ScoreThisRound, b.ScoreThisRound),
+			cmp.Compare(a.ID(), b.ID()))
+	})
+
+	db := fakeDB{numPlayers: maxNumPlayers}
+	result := make([][maxNumPlayers]float64, bandBatchSize)
+	for start := 0; start < len(states); {
+		end := start + 1
+		for end < min(len(states), start+bandBatchSize) &&
+			states[end].NumPlayers == states[start].NumPlayers && sameBand(states[start], states[end]) {
+			end++
+		}
+
+		batch := states[start:end]
+		calcStateValues(batch, db, result)
+		for j, state := range batch {
+			want := calcStateValue(state, db)
+			for i := range state.NumPlayers {
+				if math.Abs(result[j][i]-want[i]) > 1e-12 {
+					t.Fatalf("value of %v in a batch of %d = %v, want %v",
+						state, len(batch), result[j][:state.NumPlayers], want[:state.NumPlayers])
+				}
+			}
+		}
+		start = end
+	}
+}
@@ -0,0 +1,222 @@
+package farkle
+
+import (
+	"flag"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+var (
+	benchNumPlayers = flag.Int("bench_players", 2, "Number of players in benchmarks")
+	benchDBPath     = flag.String("bench_db", "",
+		"Existing solution database for the FileDB benchmarks, instead of a new, empty one")
+)
+
+const (
+	// The number of sampled game states per benchmark.
+	benchNumStates = 1000
+	benchSeed      = 12345
+	// The starting score of all players and the size of the micro-solve.
+	microSolveScore  = 9500
+	microSolveStates = 20000
+)
+
+func BenchmarkApplyAction(b *testing.B) {
+	states, rollIDs := sampleGameStates(*benchNumPlayers)
+	db := fakeDB{numPlayers: *benchNumPlayers, uniform: true}
+	actions := make([]Action, len(states))
+	for i, state := range states {
+		actions[i], _ = SelectAction(state, rollIDs[i], db)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i % len(states)
+		ApplyAction(states[j], actions[j])
+	}
+}
+
+func BenchmarkSelectAction(b *testing.B) {
+	states, rollIDs := sampleGameStates(*benchNumPlayers)
+	db := fakeDB{numPlayers: *benchNumPlayers, uniform: true}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i % len(states)
+		SelectAction(states[j], rollIDs[j], db)
+	}
+}
+
+func BenchmarkCalculateWinProb(b *testing.B) {
+	states, _ := sampleGameStates(*benchNumPlayers)
+	db := fakeDB{numPlayers: *benchNumPlayers, uniform: true}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateWinProb(states[i%len(states)], db)
+	}
+}
+
+// Enumerate the first benchNumStates states reachable from a late-game position.
+func BenchmarkGameStatesFrom(b *testing.B) {
+	initialState := microSolveInitialState(*benchNumPlayers)
+	workDir := b.TempDir()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		for range GameStatesFrom(initialState, workDir) {
+			n++
+			if n >= benchNumStates {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkInMemoryDBGet(b *testing.B) {
+	db := NewInMemoryDB(*benchNumPlayers)
+	ids := sampleStateIDs(*benchNumPlayers)
+	for _, id := range ids {
+		db.Put(id, db.Get(id))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.Get(ids[i%len(ids)])
+	}
+}
+
+func BenchmarkInMemoryDBPut(b *testing.B) {
+	db := NewInMemoryDB(*benchNumPlayers)
+	ids := sampleStateIDs(*benchNumPlayers)
+	pWin := [maxNumPlayers]float64{0.5, 0.5}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.Put(ids[i%len(ids)], pWin)
+	}
+}
+
+func BenchmarkFileDBGet(b *testing.B) {
+	db := openBenchFileDB(b)
+	ids := sampleStateIDs(*benchNumPlayers)
+	// Fault in the pages of the database before timing reads.
+	for _, id := range ids {
+		db.Put(id, db.Get(id))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.Get(ids[i%len(ids)])
+	}
+}
+
+// Rewrites existing values, so the database contents are unchanged.
+func BenchmarkFileDBPut(b *testing.B) {
+	db := openBenchFileDB(b)
+	ids := sampleStateIDs(*benchNumPlayers)
+	values := make([][maxNumPlayers]float64, len(ids))
+	for i, id := range ids {
+		values[i] = db.Get(id)
+		db.Put(id, values[i])
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i % len(ids)
+		db.Put(ids[j], values[j])
+	}
+}
+
+// The database of -bench_db, or else a new one in a temporary directory. It is
+// closed after the benchmark is timed.
+func openBenchFileDB(b *testing.B) *FileDB {
+	path := *benchDBPath
+	if path == "" {
+		path = filepath.Join(b.TempDir(), "bench.db")
+	}
+	db, err := NewFileDB(path, *benchNumPlayers)
+	if err != nil {
+		b.Fatalf("Unable to open database: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+// A standardized solve of a small subset of the game tree: value iteration
+// sweeps over the first microSolveStates states reachable from a late-game
+// position, reported in states/s.
+func BenchmarkMicroSolve(b *testing.B) {
+	var states []depthState
+	for depth, state := range GameStatesFrom(microSolveInitialState(*benchNumPlayers), b.TempDir()) {
+		states = append(states, depthState{uint64(depth), state})
+		if len(states) >= microSolveStates {
+			break
+		}
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].depth < states[j].depth
+	})
+	db := NewInMemoryDB(*benchNumPlayers)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UpdateAll(db, func(yield func(uint64, GameState) bool) {
+			for _, ds := range states {
+				if !yield(ds.depth, ds.state) {
+					return
+				}
+			}
+		}, "")
+	}
+	b.ReportMetric(float64(b.N*len(states))/b.Elapsed().Seconds(), "states/s")
+}
+
+type depthState struct {
+	depth uint64
+	state GameState
+}
+
+func microSolveInitialState(numPlayers int) GameState {
+	state := NewGameState(numPlayers)
+	for i := 0; i < numPlayers; i++ {
+		state.PlayerScores[i] = microSolveScore / incr
+	}
+	return state
+}
+
+// Generate a reproducible set of random, non-terminal game states,
+// and a random roll for each state.
+func sampleGameStates(numPlayers int) ([]GameState, []uint16) {
+	rng := rand.New(rand.NewSource(benchSeed))
+	states := make([]GameState, 0, benchNumStates)
+	rollIDs := make([]uint16, 0, benchNumStates)
+	for len(states) < benchNumStates {
+		state := NewGameState(numPlayers)
+		state.NumDiceToRoll = uint8(1 + rng.Intn(MaxNumDice))
+		state.ScoreThisRound = uint8(rng.Intn(60))
+		for i := 0; i < numPlayers; i++ {
+			state.PlayerScores[i] = uint8(rng.Intn(220))
+		}
+		if state.IsGameOver() {
+			continue
+		}
+
+		var roll Roll
+		for i := 0; i < int(state.NumDiceToRoll); i++ {
+			roll[1+rng.Intn(numSides)]++
+		}
+
+		states = append(states, state)
+		rollIDs = append(rollIDs, GetRollID(roll))
+	}
+
+	return states, rollIDs
+}
+
+func sampleStateIDs(numPlayers int) []int {
+	states, _ := sampleGameStates(numPlayers)
+	ids := make([]int, len(states))
+	for i, state := range states {
+		ids[i] = state.ID()
+	}
+	return ids
+}
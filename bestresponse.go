@@ -0,0 +1,129 @@
+package farkle
+
+import (
+	"fmt"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// Values for the best response of one player (the hero) to opponents who all
+// play a fixed strategy, rather than optimally. Since game states do not record
+// which player is the hero, there is a table of values for each seat:
+// Tables[k] holds the values of states in which the player k seats after
+// the hero is to move. As in other databases, values are relative to the
+// player to move.
+type BestResponse struct {
+	Tables   []DB
+	Opponent Strategy
+}
+
+func NewBestResponse(tables []DB, opponent Strategy) (*BestResponse, error) {
+	if len(tables) < 2 {
+		return nil, fmt.Errorf("best response requires at least 2 players, got %d", len(tables))
+	}
+	for _, db := range tables {
+		if db.NumPlayers() != len(tables) {
+			return nil, fmt.Errorf("expected one table per player, got %d for %d players",
+				len(tables), db.NumPlayers())
+		}
+		if db.Metadata() != tables[0].Metadata() {
+			return nil, fmt.Errorf("tables have different objectives: %v and %v",
+				db.Metadata(), tables[0].Metadata())
+		}
+	}
+
+	return &BestResponse{
+		Tables:   tables,
+		Opponent: opponent,
+	}, nil
+}
+
+// Recalculate the value of all states in the given iterator, in all tables.
+// If the opponent strategy fails or selects an illegal action, the values of
+// the remaining states are left unchanged and the first error is returned.
+func (br *BestResponse) UpdateAll(states iter.Seq2[uint64, GameState], chkpntPath string) (UpdateStats, error) {
+	return br.UpdateAllWithOptions(states, UpdateOptions{CheckpointPath: chkpntPath})
+}
+
+// As UpdateAll, with the given options.
+func (br *BestResponse) UpdateAllWithOptions(states iter.Seq2[uint64, GameState], opts UpdateOptions) (UpdateStats, error) {
+	var failed atomic.Bool
+	var firstErr error
+	var once sync.Once
+	tables := make([]valueTable, len(br.Tables))
+	for k, db := range br.Tables {
+		tables[k] = valueTable{
+			db: db,
+			value: func(state GameState) [maxNumPlayers]float64 {
+				if !failed.Load() {
+					pWin, err := br.calcStateValue(state, k)
+					if err == nil {
+						return pWin
+					}
+					once.Do(func() { firstErr = err })
+					failed.Store(true)
+				}
+				return db.Get(state.ID())
+			},
+		}
+	}
+
+	stats := updateTables(tables, states, opts)
+	return stats, firstErr
+}
+
+// The value of the given state, in which the player k seats after the hero is to move.
+func (br *BestResponse) calcStateValue(state GameState, k int) ([maxNumPlayers]float64, error) {
+	db := br.Tables[k]
+	nextDB := br.Tables[(k+1)%len(br.Tables)]
+
+	var pWin [maxNumPlayers]float64
+	for _, wRoll := range allRolls[state.NumDiceToRoll] {
+		var pSubgame [maxNumPlayers]float64
+		if k == 0 {
+			_, pSubgame = selectAction(state, wRoll.ID, db, nextDB)
+		} else {
+			action, err := br.opponentAction(state, wRoll.Roll)
+			if err != nil {
+				return pWin, err
+			}
+			pSubgame = evaluateAction(state, action, db, nextDB)
+		}
+		mixInto(&pWin, wRoll.Prob, &pSubgame)
+	}
+
+	return pWin, nil
+}
+
+func (br *BestResponse) opponentAction(state GameState, roll Roll) (Action, error) {
+	action, err := br.Opponent.SelectAction(state, roll)
+	if err == nil {
+		err = ValidateAction(state, roll, action)
+	}
+	if err != nil {
+		return Action{}, fmt.Errorf("opponent strategy %v in %v with roll %v: %w", br.Opponent, state, roll, err)
+	}
+	// Banking if the score this round overflowed, as in SelectAction.
+	return bankIfOverflowed(state.ScoreThisRound, action), nil
+}
+
+// The value for the hero of the given state, in which the player
+// k seats after the hero is to move.
+func (br *BestResponse) HeroValue(state GameState, k int) float64 {
+	n := len(br.Tables)
+	if state.IsGameOver() {
+		return calcEndGameValue(state, br.Tables[0].Metadata())[(n-k)%n]
+	}
+	return br.Tables[k].Get(state.ID())[(n-k)%n]
+}
+
+// Play as the hero, maximizing value against the opponent strategy.
+func (br *BestResponse) SelectAction(state GameState, roll Roll) (Action, error) {
+	action, _ := selectAction(state, GetRollID(roll), br.Tables[0], br.Tables[1])
+	return action, nil
+}
+
+func (br *BestResponse) String() string {
+	return fmt.Sprintf("best-response(%v)", br.Opponent)
+}
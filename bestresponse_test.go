@@ -0,0 +1,104 @@
+package farkle
+
+import (
+	"iter"
+	"testing"
+)
+
+// The best response to an opponent is worth at least the value of the game in
+// every seat, since the opponent plays no better than optimally. Starting
+// from the values of the game, each cycle of value iteration can only raise
+// the values of the hero, so each is a lower bound of the best response. So
+// the values are checked for a few cycles rather than until they converge,
+// which takes tens of cycles. Against the optimal strategy, the values of the
+// game are already the best response.
+func TestBestResponse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves a two-player game")
+	}
+	setTestRules(t, "pocket-farkle,target=50,dice=2")
+	states := miniatureGameStates(t, 2)
+	initialState := NewGameState(2)
+	exact := NewInMemoryDB(2)
+	SolveExact(exact, "")
+	want := exact.Get(initialState.ID())
+
+	newBestResponse := func(opponent Strategy) *BestResponse {
+		tables := []DB{NewInMemoryDB(2), NewInMemoryDB(2)}
+		for _, ds := range states {
+			for _, db := range tables {
+				db.Put(ds.state.ID(), exact.Get(ds.state.ID()))
+			}
+		}
+		br, err := NewBestResponse(tables, opponent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return br
+	}
+
+	br := newBestResponse(OptimalStrategy{DB: exact})
+	stats, err := br.UpdateAll(depthStates(states), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := stats.Total().MaxChange; change > 1e-12 {
+		t.Errorf("%v: values changed by %v from the values of the game", br, change)
+	}
+
+	br = newBestResponse(ThresholdStrategy{BankAt: 100})
+	last := want
+	for range 3 {
+		if _, err := br.UpdateAll(depthStates(states), ""); err != nil {
+			t.Fatal(err)
+		}
+		for seat := range 2 {
+			got := br.HeroValue(initialState, (2-seat)%2)
+			if got < last[seat]-1e-12 {
+				t.Errorf("%v: value in seat %d fell from %v to %v", br, seat, last[seat], got)
+			}
+			last[seat] = got
+		}
+	}
+	for seat := range 2 {
+		if last[seat] <= want[seat] {
+			t.Errorf("%v: value in seat %d = %v, no more than the value of the game %v",
+				br, seat, last[seat], want[seat])
+		}
+	}
+}
+
+// An opponent that selects an illegal action fails the update, rather than
+// panicking in a worker.
+func TestBestResponseIllegalAction(t *testing.T) {
+	setTestRules(t, "pocket-farkle,target=50,dice=2")
+	states := miniatureGameStates(t, 2)
+	br, err := NewBestResponse([]DB{NewInMemoryDB(2), NewInMemoryDB(2)}, illegalStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := br.UpdateAll(depthStates(states), ""); err == nil {
+		t.Error("UpdateAll succeeded with an opponent that selects illegal actions")
+	}
+}
+
+func depthStates(states []depthState) iter.Seq2[uint64, GameState] {
+	return func(yield func(uint64, GameState) bool) {
+		for _, ds := range states {
+			if !yield(ds.depth, ds.state) {
+				return
+			}
+		}
+	}
+}
+
+// Always holds nothing and banks, which is illegal unless the roll is a farkle.
+type illegalStrategy struct{}
+
+func (illegalStrategy) SelectAction(state GameState, roll Roll) (Action, error) {
+	return Action{}, nil
+}
+
+func (illegalStrategy) String() string {
+	return "illegal"
+}
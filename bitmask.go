@@ -1,30 +1,117 @@
 package farkle
 
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	// Bits are stored in pages of this many bytes,
+	// which are only allocated once a bit in them is set.
+	bitMaskPageSize  = 64 * 1024
+	bitMaskPageWords = bitMaskPageSize / 8
+	bitMaskPageBits  = bitMaskPageSize * 8
+)
+
 type bitMask struct {
-	values []uint64
+	pages []*[bitMaskPageWords]uint64
 }
 
 func newBitMask(n int) *bitMask {
-	numInts := n/64 + 1
+	numPages := n/bitMaskPageBits + 1
 	return &bitMask{
-		values: make([]uint64, numInts),
+		pages: make([]*[bitMaskPageWords]uint64, numPages),
 	}
 }
 
 func (bm *bitMask) Set(i int) {
-	idx := i / 64
+	page := bm.pages[i/bitMaskPageBits]
+	if page == nil {
+		page = new([bitMaskPageWords]uint64)
+		bm.pages[i/bitMaskPageBits] = page
+	}
+
+	idx := (i % bitMaskPageBits) / 64
 	shift := i % 64
-	bm.values[idx] |= (uint64(1) << shift)
+	page[idx] |= (uint64(1) << shift)
 }
 
 func (bm *bitMask) Clear(i int) {
-	idx := i / 64
+	page := bm.pages[i/bitMaskPageBits]
+	if page == nil {
+		return
+	}
+
+	idx := (i % bitMaskPageBits) / 64
 	shift := i % 64
-	bm.values[idx] &= ^(uint64(1) << shift)
+	page[idx] &= ^(uint64(1) << shift)
 }
 
 func (bm *bitMask) IsSet(i int) bool {
-	idx := i / 64
+	page := bm.pages[i/bitMaskPageBits]
+	if page == nil {
+		return false
+	}
+
+	idx := (i % bitMaskPageBits) / 64
 	shift := i % 64
-	return (bm.values[idx] & (uint64(1) << shift)) != 0
+	return (page[idx] & (uint64(1) << shift)) != 0
+}
+
+// The number of bytes allocated for pages.
+func (bm *bitMask) allocatedBytes() int {
+	n := 0
+	for _, page := range bm.pages {
+		if page != nil {
+			n += bitMaskPageSize
+		}
+	}
+	return n
+}
+
+// The i'th 64-bit word of the mask.
+func (bm *bitMask) word(i int) uint64 {
+	page := bm.pages[i/bitMaskPageWords]
+	if page == nil {
+		return 0
+	}
+	return page[i%bitMaskPageWords]
+}
+
+// Set the i'th 64-bit word of the mask, allocating its page only if a bit
+// in it is set.
+func (bm *bitMask) setWord(i int, word uint64) {
+	page := bm.pages[i/bitMaskPageWords]
+	if page == nil {
+		if word == 0 {
+			return
+		}
+		page = new([bitMaskPageWords]uint64)
+		bm.pages[i/bitMaskPageWords] = page
+	}
+	page[i%bitMaskPageWords] = word
+}
+
+// Write the first numWords words of the mask, in little-endian order.
+func (bm *bitMask) writeWords(w io.Writer, numWords int) error {
+	buf := make([]byte, 8)
+	for i := range numWords {
+		binary.LittleEndian.PutUint64(buf, bm.word(i))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read the first numWords words of the mask, as written by writeWords.
+func (bm *bitMask) readWords(r io.Reader, numWords int) error {
+	buf := make([]byte, 8)
+	for i := range numWords {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		bm.setWord(i, binary.LittleEndian.Uint64(buf))
+	}
+	return nil
 }
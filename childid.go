@@ -0,0 +1,112 @@
+package farkle
+
+import (
+	"math"
+)
+
+// The effect of a potential action on the state, precomputed for every action
+// after every roll so that the solver computes the IDs of the states that
+// follow with integer arithmetic, instead of applying each action to a
+// GameState and computing its ID.
+type actionEffect struct {
+	// The score of the held dice.
+	score uint8
+	// The number of dice to roll if the player continues rolling.
+	numDiceToRoll uint8
+}
+
+// For each roll ID, the effect of each action in rollIDToPotentialActions.
+// The roll determines the number of dice rolled, so the effects do not
+// depend on the state.
+var rollIDToActionEffects = calcActionEffects()
+
+func calcActionEffects() [][]actionEffect {
+	result := make([][]actionEffect, len(rollIDToPotentialActions))
+	for rollID, actions := range rollIDToPotentialActions {
+		numDice := rollNumDice[rollID]
+		effects := make([]actionEffect, len(actions))
+		for i, action := range actions {
+			numDiceToRoll := numDice - rollNumDice[action.HeldDiceID]
+			if numDiceToRoll == 0 {
+				numDiceToRoll = turnNumDice
+			}
+			effects[i] = actionEffect{
+				score:         scoreCache[action.HeldDiceID],
+				numDiceToRoll: numDiceToRoll,
+			}
+		}
+		result[rollID] = effects
+	}
+	return result
+}
+
+// The parts of the ID of a state (see GameState.ID) that the IDs of the
+// states after each action are computed from.
+type stateIDParts struct {
+	diceShift      int
+	scoreThisRound uint8
+	currentScore   uint8
+	// The player scores in the ID of the state.
+	scores int
+	// The scores of the other players in the ID of the state after the
+	// current player banks, without their own.
+	nextScores int
+}
+
+func newStateIDParts(state GameState) stateIDParts {
+	numPlayers := int(state.NumPlayers)
+	p := stateIDParts{
+		diceShift:      (numPlayers + 1) * numScoreBits,
+		scoreThisRound: state.ScoreThisRound,
+		currentScore:   state.PlayerScores[0],
+	}
+	for i, score := range state.PlayerScores[:numPlayers] {
+		p.scores += int(score) << ((numPlayers - i) * numScoreBits)
+	}
+	p.nextScores = (p.scores - int(p.currentScore)<<(numPlayers*numScoreBits)) << numScoreBits
+	return p
+}
+
+// The ID of the state after the current player farkles, as in ApplyAction.
+func (p *stateIDParts) farkleID() int {
+	return int(turnNumDice-1)<<p.diceShift + p.nextScores + int(p.currentScore)<<numScoreBits
+}
+
+// The ID of the state after an action with the given effect, as in
+// ApplyAction, and the score of the current player if they bank.
+func (p *stateIDParts) childID(e actionEffect, continueRolling bool) (int, uint8) {
+	scoreThisRound := p.scoreThisRound + e.score
+	if scoreThisRound < p.scoreThisRound {
+		scoreThisRound = math.MaxUint8 // Overflow
+	}
+
+	if continueRolling {
+		return int(e.numDiceToRoll-1)<<p.diceShift + p.scores + int(scoreThisRound), p.currentScore
+	}
+
+	banked := p.currentScore + scoreThisRound
+	if banked < p.currentScore {
+		banked = math.MaxUint8 // Overflow
+	}
+	return int(turnNumDice-1)<<p.diceShift + p.nextScores + int(banked)<<numScoreBits, banked
+}
+
+// The action as it is taken in the state, the ID of the state after it (as
+// in childID), and whether it is allowed. Players whose score this round
+// overflowed bank instead of rolling again: we assume that this is unlikely,
+// and approximate the value as if they stopped. Players who are not yet on
+// the board cannot bank less than the opening score.
+func (p *stateIDParts) takeAction(action Action, e actionEffect) (Action, int, bool) {
+	action = bankIfOverflowed(p.scoreThisRound, action)
+	newStateID, banked := p.childID(e, action.ContinueRolling)
+	allowed := action.ContinueRolling || p.currentScore > 0 || banked >= openingScore
+	return action, newStateID, allowed
+}
+
+// The action, or banking instead if the score this round has overflowed.
+func bankIfOverflowed(scoreThisRound uint8, action Action) Action {
+	if scoreThisRound == math.MaxUint8 {
+		action.ContinueRolling = false
+	}
+	return action
+}
@@ -0,0 +1,83 @@
+package farkle
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// The IDs computed by stateIDParts are the IDs of the states that ApplyAction
+// returns, for every action after every roll in random states, under rules
+// with different scores and numbers of dice. Half of the scores are chosen
+// from the edges of their range, so that there are states in which the score
+// this round or the banked score overflows, and states in which the current
+// player is not yet on the board.
+func TestChildID(t *testing.T) {
+	for _, rules := range []string{"standard", "pocket-farkle", "kingdom-come", "standard,dice=3"} {
+		t.Run(rules, func(t *testing.T) {
+			setTestRules(t, rules)
+			rng := rand.New(rand.NewSource(benchSeed))
+			for i := 0; i < 2000; i++ {
+				state := randomEdgeState(rng)
+				if err := checkChildIDs(state); err != nil {
+					t.Fatalf("%v: %v", state, err)
+				}
+			}
+		})
+	}
+}
+
+// Scores near 0 and math.MaxUint8 where the arithmetic of stateIDParts
+// differs from that of the other scores.
+var edgeScores = []uint8{0, 1, 2, math.MaxUint8 - 2, math.MaxUint8 - 1, math.MaxUint8}
+
+// A random state before the end of the game, as randomState, in which each
+// score is one of edgeScores with probability 1/2.
+func randomEdgeState(rng *rand.Rand) GameState {
+	score := func() uint8 {
+		if rng.Intn(2) == 0 {
+			return edgeScores[rng.Intn(len(edgeScores))]
+		}
+		return uint8(rng.Intn(256))
+	}
+
+	for {
+		state := randomState(rng)
+		for i := range state.PlayerScores[:state.NumPlayers] {
+			state.PlayerScores[i] = score()
+		}
+		state.ScoreThisRound = score()
+		if !state.IsGameOver() {
+			return state
+		}
+	}
+}
+
+func checkChildIDs(state GameState) error {
+	parts := newStateIDParts(state)
+	for _, wRoll := range allRolls[state.NumDiceToRoll] {
+		potentialActions := rollIDToPotentialActions[wRoll.ID]
+		if len(potentialActions) == 0 {
+			// A farkle, as in selectAction.
+			if got, want := parts.farkleID(), ApplyAction(state, Action{}).ID(); got != want {
+				return fmt.Errorf("farkle after %v: ID = %d, want %d", wRoll.Roll, got, want)
+			}
+			continue
+		}
+
+		for i, action := range potentialActions {
+			next := ApplyAction(state, action)
+			gotID, gotBanked := parts.childID(rollIDToActionEffects[wRoll.ID][i], action.ContinueRolling)
+			wantBanked := state.PlayerScores[0]
+			if !action.ContinueRolling {
+				wantBanked = next.PlayerScores[state.NumPlayers-1]
+			}
+			if gotID != next.ID() || gotBanked != wantBanked {
+				return fmt.Errorf("%+v after %v: ID = %d, banked %d; want %d (%v), banked %d",
+					action, wRoll.Roll, gotID, gotBanked, next.ID(), next, wantBanked)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	OutputPath string
+	Epsilon    float64
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	flag.StringVar(&params.OutputPath, "output", "2player.sparse", "Path to write the sparse database to")
+	flag.Float64Var(&params.Epsilon, "epsilon", 1e-9,
+		"States in which a player wins with probability within this of 1 are stored as won")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the database was solved with: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("compact-db")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	db, err := farkle.OpenFileDBReadOnly(params.DBPath, params.NumPlayers)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	sparse, err := farkle.NewSparseDBFrom(db, params.Epsilon)
+	if err != nil {
+		glog.Errorf("Unable to compact database: %v", err)
+		os.Exit(1)
+	}
+	glog.Infof("Storing values of %d uncertain game states", sparse.Len())
+
+	if err := sparse.Save(params.OutputPath); err != nil {
+		glog.Errorf("Error saving sparse database: %v", err)
+		os.Exit(1)
+	}
+}
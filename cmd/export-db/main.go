@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/cli/export"
+)
+
+func main() {
+	cli.Main(export.Command)
+}
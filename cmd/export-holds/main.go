@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	OutputPath string
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.OutputPath, "output", "holds.json",
+		"Path to write the table of legal holds to")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("export-holds")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	table := farkle.NewHoldTable()
+	data, err := json.Marshal(table)
+	if err != nil {
+		glog.Errorf("Error encoding table: %v", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(params.OutputPath, data, 0644); err != nil {
+		glog.Errorf("Error writing table: %v", err)
+		os.Exit(1)
+	}
+
+	glog.Infof("Wrote legal holds of %d rolls (%d bytes) to %s",
+		len(table.Rolls), len(data), params.OutputPath)
+}
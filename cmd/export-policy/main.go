@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	OutputPath string
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	flag.StringVar(&params.OutputPath, "output", "2player.turndb",
+		"Path to write the compact policy to")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the database was solved with: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("export-policy")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	db, err := farkle.OpenFileDBReadOnly(params.DBPath, params.NumPlayers)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	if err := farkle.CheckRules(db); err != nil {
+		glog.Errorf("%s: %v", params.DBPath, err)
+		os.Exit(1)
+	}
+
+	f, err := os.Create(params.OutputPath)
+	if err != nil {
+		glog.Errorf("Unable to create output file: %v", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	n, err := farkle.NewTurnDBFrom(db).WriteTo(f)
+	if err != nil {
+		glog.Errorf("Error writing policy: %v", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		glog.Errorf("Error writing policy: %v", err)
+		os.Exit(1)
+	}
+
+	glog.Infof("Wrote %d byte policy to %s", n, params.OutputPath)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	DBPaths    string
+	Targets    string
+	NumPlayers int
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.DBPaths, "dbs", "2:2player.db",
+		"Comma-separated NUM_PLAYERS:PATH solution databases to report the win probability of each seat from")
+	flag.StringVar(&params.Targets, "targets", "",
+		"Comma-separated target scores to sweep, e.g. 2000,4000,6000, solving the game in memory for each (optional)")
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players for -targets")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the databases were solved with: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("farkle-advantage")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	if params.DBPaths != "" {
+		fmt.Printf("%-8s %s\n", "Players", "Win probability by seat")
+		for _, spec := range strings.Split(params.DBPaths, ",") {
+			numPlayers, pWin, err := seatWinProbsFromDB(strings.TrimSpace(spec))
+			if err != nil {
+				glog.Errorf("%s: %v", spec, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%-8d %s\n", numPlayers, formatSeats(pWin))
+		}
+	}
+
+	if params.Targets != "" {
+		if params.NumPlayers < 2 || params.NumPlayers > 4 {
+			glog.Errorf("Expected 2 to 4 players, got %d", params.NumPlayers)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n%-8s %s (%d players)\n", "Target", "Win probability by seat", params.NumPlayers)
+		for _, s := range strings.Split(params.Targets, ",") {
+			target, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				glog.Errorf("Invalid target score %q: %v", s, err)
+				os.Exit(1)
+			}
+
+			pWin, err := seatWinProbsForTarget(params.NumPlayers, target)
+			if err != nil {
+				glog.Errorf("Target score %d: %v", target, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%-8d %s\n", target, formatSeats(pWin))
+		}
+	}
+}
+
+// Look up the win probability of each seat at the start of the game in the
+// database given as NUM_PLAYERS:PATH.
+func seatWinProbsFromDB(spec string) (int, []float64, error) {
+	n, path, ok := strings.Cut(spec, ":")
+	if !ok {
+		return 0, nil, fmt.Errorf("expected NUM_PLAYERS:PATH")
+	}
+	numPlayers, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid number of players: %w", err)
+	} else if numPlayers < 2 || numPlayers > 4 {
+		return 0, nil, fmt.Errorf("expected 2 to 4 players, got %d", numPlayers)
+	}
+
+	db, err := farkle.OpenFileDBReadOnly(path, numPlayers)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer db.Close()
+	if err := farkle.CheckRules(db); err != nil {
+		return 0, nil, err
+	}
+	if obj := db.Metadata().Objective; obj != farkle.WinProbability {
+		return 0, nil, fmt.Errorf("database was solved for %v, not win probability", obj)
+	}
+
+	return numPlayers, seatWinProbs(numPlayers, db), nil
+}
+
+// Solve the game with the given target score in memory, and return the win
+// probability of each seat at the start of the game.
+func seatWinProbsForTarget(numPlayers, target int) ([]float64, error) {
+	rules := farkle.CurrentRules()
+	defer farkle.SetRules(rules)
+
+	rules.TargetScore = target
+	if err := farkle.SetRules(rules); err != nil {
+		return nil, err
+	}
+
+	db := farkle.NewInMemoryDB(numPlayers)
+	if err := farkle.SolveFrom(farkle.NewGameState(numPlayers), db); err != nil {
+		return nil, err
+	}
+	return seatWinProbs(numPlayers, db), nil
+}
+
+// The win probability of each seat at the start of the game. Seat 0 rolls
+// first, and is the current player of the initial state.
+func seatWinProbs(numPlayers int, db farkle.DB) []float64 {
+	pWin := farkle.CalculateWinProb(farkle.NewGameState(numPlayers), db)
+	return pWin[:numPlayers]
+}
+
+func formatSeats(pWin []float64) string {
+	fair := 1 / float64(len(pWin))
+	parts := make([]string, len(pWin))
+	for seat, p := range pWin {
+		parts[seat] = fmt.Sprintf("%d: %.2f%% (%+.2f)", seat+1, 100*p, 100*(p-fair))
+	}
+	return strings.Join(parts, "  ")
+}
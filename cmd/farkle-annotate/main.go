@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	ReplayPath string
+	DBPath     string
+	Seats      string
+	Rules      string
+}
+
+var qualityMarks = map[farkle.MoveQuality]string{
+	farkle.Best:       "",
+	farkle.Good:       "",
+	farkle.Inaccuracy: "?!",
+	farkle.Mistake:    "?",
+	farkle.Blunder:    "??",
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.ReplayPath, "replay", "", "Path to recorded game")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	flag.StringVar(&params.Seats, "seats", "", "Comma-separated seats to annotate (default all)")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the game was played by: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("farkle-annotate")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(params.ReplayPath)
+	if err != nil {
+		glog.Errorf("Unable to open replay: %v", err)
+		os.Exit(1)
+	}
+	events, err := farkle.ReadReplay(f)
+	f.Close()
+	if err != nil {
+		glog.Errorf("Error reading replay: %v", err)
+		os.Exit(1)
+	} else if len(events) == 0 {
+		glog.Errorf("Replay %s has no events", params.ReplayPath)
+		os.Exit(1)
+	}
+
+	numPlayers := int(events[0].State.NumPlayers)
+	seats, err := parseSeats(params.Seats, numPlayers)
+	if err != nil {
+		glog.Errorf("Invalid -seats: %v", err)
+		os.Exit(1)
+	}
+
+	db, err := farkle.OpenFileDBReadOnly(params.DBPath, numPlayers)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	if err := farkle.CheckRules(db); err != nil {
+		glog.Errorf("%s: %v", params.DBPath, err)
+		os.Exit(1)
+	}
+
+	annotations, summaries := farkle.AnnotateReplay(events, db)
+	for _, a := range annotations {
+		if !seats[a.Event.Seat] {
+			continue
+		}
+
+		fmt.Printf("Turn %d, player %d: rolled %s -> %s%s",
+			a.Event.Turn, a.Event.Seat, a.Event.Roll, a.Event.Action, qualityMarks[a.Quality])
+		if a.Quality == farkle.Best {
+			fmt.Printf(" (pWin %.1f%%)\n", 100*a.PWinAction)
+		} else {
+			fmt.Printf(" %s: -%.2f%% (best was %s, pWin %.1f%%)\n",
+				a.Quality, 100*a.EquityLoss, a.OptimalAction, 100*a.PWinOptimal)
+		}
+	}
+
+	fmt.Println()
+	for _, s := range summaries {
+		if !seats[s.Seat] {
+			continue
+		}
+
+		fmt.Printf("Player %d: %d moves, accuracy %.1f%%, average loss %.2f%%, "+
+			"%d inaccuracies, %d mistakes, %d blunders\n",
+			s.Seat, s.NumMoves, s.Accuracy, 100*s.AverageLoss,
+			s.NumByQuality[farkle.Inaccuracy], s.NumByQuality[farkle.Mistake],
+			s.NumByQuality[farkle.Blunder])
+	}
+}
+
+func parseSeats(s string, numPlayers int) (map[int]bool, error) {
+	result := make(map[int]bool, numPlayers)
+	if s == "" {
+		for seat := 0; seat < numPlayers; seat++ {
+			result[seat] = true
+		}
+		return result, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		seat, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		} else if seat < 0 || seat >= numPlayers {
+			return nil, fmt.Errorf("seat %d out of range for %d players", seat, numPlayers)
+		}
+		result[seat] = true
+	}
+
+	return result, nil
+}
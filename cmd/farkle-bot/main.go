@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/gameserver"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	Server     string
+	GameID     string
+	Code       string
+	MatchSize  int
+	Name       string
+	PlayerKey  string
+	Strategy   string
+	DBPath     string
+	NumPlayers int
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.Server, "server", "http://localhost:8090", "URL of farkle-server")
+	flag.StringVar(&params.GameID, "game", "", "ID of the game to join")
+	flag.StringVar(&params.Code, "code", "", "Join code of the lobby to join, instead of -game")
+	flag.IntVar(&params.MatchSize, "match", 0,
+		"Instead of -game or -code, wait for a match with this many players")
+	flag.StringVar(&params.Name, "name", "farkle-bot", "Name to play as")
+	flag.StringVar(&params.PlayerKey, "player_key", "",
+		"Secret that identifies the bot across games in the server's statistics (optional)")
+	flag.StringVar(&params.Strategy, "strategy", "threshold:300",
+		"Strategy to play: optimal (with -db) or threshold:BANK_AT[:MIN_DICE]")
+	flag.StringVar(&params.DBPath, "db", "", "Path to solution database for the optimal strategy")
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players of the database")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("farkle-bot")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	var strategy farkle.Strategy
+	if params.Strategy == "optimal" {
+		db, err := farkle.OpenFileDBReadOnly(params.DBPath, params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to open database: %v", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		if err := farkle.CheckRules(db); err != nil {
+			glog.Errorf("%s: %v", params.DBPath, err)
+			os.Exit(1)
+		}
+		strategy = farkle.OptimalStrategy{DB: db}
+	} else if strategy, err = farkle.ParseThresholdStrategy(params.Strategy); err != nil {
+		glog.Errorf("Invalid strategy: %v", err)
+		os.Exit(1)
+	}
+
+	c := &client{server: strings.TrimSuffix(params.Server, "/")}
+	joinReq := gameserver.JoinRequest{Name: params.Name, PlayerKey: params.PlayerKey}
+	var joined gameserver.JoinResponse
+	switch {
+	case params.GameID != "":
+		err = c.post("/api/games/"+params.GameID+"/join", joinReq, &joined)
+	case params.Code != "":
+		err = c.post("/api/lobbies/"+params.Code+"/join", joinReq, &joined)
+	case params.MatchSize > 0:
+		glog.Infof("Waiting for a %d-player match", params.MatchSize)
+		err = c.post("/api/matchmaking", gameserver.MatchRequest{
+			Name:       params.Name,
+			PlayerKey:  params.PlayerKey,
+			NumPlayers: params.MatchSize,
+		}, &joined)
+	default:
+		err = fmt.Errorf("one of -game, -code or -match is required")
+	}
+	if err != nil {
+		glog.Errorf("Unable to join game: %v", err)
+		os.Exit(1)
+	}
+	glog.Infof("Joined game %s in seat %d", joined.GameID, joined.Seat)
+
+	c.token = joined.Token
+	result, err := play(c, joined.GameID, strategy)
+	if err != nil {
+		glog.Errorf("Error playing game: %v", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Final scores: %v, winners: %v\n", result.Scores, result.Winners)
+}
+
+// Play the game until it is over: wait for our turn, then roll and act on
+// each roll until the turn is over.
+func play(c *client, gameID string, strategy farkle.Strategy) (farkle.GameResult, error) {
+	game := "/api/games/" + gameID
+	for {
+		var snapshot gameserver.Snapshot
+		if err := c.get(game+"/turn", &snapshot); err != nil {
+			return farkle.GameResult{}, err
+		} else if snapshot.Result != nil {
+			return *snapshot.Result, nil
+		}
+
+		roll := snapshot.Roll // If we reconnected after rolling.
+		if roll == nil {
+			var resp gameserver.RollResponse
+			if err := c.post(game+"/roll", nil, &resp); err != nil {
+				return farkle.GameResult{}, err
+			}
+			glog.V(1).Infof("Rolled %s", resp.Roll)
+			if farkle.IsFarkle(resp.Roll) {
+				continue
+			}
+			roll = &resp.Roll
+			snapshot.Roll = roll
+		}
+
+		state, err := snapshot.State()
+		if err != nil {
+			return farkle.GameResult{}, err
+		}
+		action, err := strategy.SelectAction(state, *roll)
+		if err != nil {
+			return farkle.GameResult{}, err
+		}
+		glog.V(1).Infof("Playing %s", action)
+		if err := c.post(game+"/action", action, &snapshot); err != nil {
+			return farkle.GameResult{}, err
+		}
+	}
+}
+
+// Client of the farkle-server API for a player.
+type client struct {
+	server string
+	token  string
+}
+
+func (c *client) get(path string, resp any) error {
+	return c.do(http.MethodGet, path, nil, resp)
+}
+
+func (c *client) post(path string, req, resp any) error {
+	return c.do(http.MethodPost, path, req, resp)
+}
+
+func (c *client) do(method, path string, req, resp any) error {
+	var body io.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequest(method, c.server+path, body)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(httpResp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, path, httpResp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
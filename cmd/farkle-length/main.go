@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/analysis"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	Strategy   string
+	DBPath     string
+	NumPlayers int
+	NumGames   int
+	Seed       int64
+	Rules      string
+}
+
+// The quantiles of game length to report.
+var quantiles = []float64{0.1, 0.25, 0.5, 0.75, 0.9, 0.99}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.Strategy, "strategy", "optimal",
+		"Strategy every player follows: optimal (with -db) or threshold:BANK_AT[:MIN_DICE]")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database for the optimal strategy")
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.IntVar(&params.NumGames, "num_games", 10000,
+		"Number of games to simulate. One-player games are also computed exactly.")
+	flag.Int64Var(&params.Seed, "seed", 12345, "Random seed")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("farkle-length")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+	if params.NumPlayers < 1 || params.NumPlayers > 4 {
+		glog.Errorf("Expected 1 to 4 players, got %d", params.NumPlayers)
+		os.Exit(1)
+	}
+
+	var strategy farkle.Strategy
+	if params.Strategy == "optimal" {
+		db, err := farkle.OpenFileDBReadOnly(params.DBPath, params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to open database: %v", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		if err := farkle.CheckRules(db); err != nil {
+			glog.Errorf("%s: %v", params.DBPath, err)
+			os.Exit(1)
+		}
+		strategy = farkle.OptimalStrategy{DB: db}
+	} else if strategy, err = farkle.ParseThresholdStrategy(params.Strategy); err != nil {
+		glog.Errorf("Invalid strategy: %v", err)
+		os.Exit(1)
+	}
+
+	if params.NumPlayers == 1 {
+		glog.Info("Computing the exact length of one-player games")
+		exact, err := analysis.SolitaireGameLength(strategy, 10000, 1e-9)
+		if err != nil {
+			glog.Errorf("Error computing game length: %v", err)
+			os.Exit(1)
+		}
+		printLength("Exact", exact, 1)
+		fmt.Println()
+	}
+
+	if params.NumGames > 0 {
+		glog.Infof("Simulating %d games", params.NumGames)
+		strategies := make([]farkle.Strategy, params.NumPlayers)
+		for i := range strategies {
+			strategies[i] = strategy
+		}
+		rng := rand.New(rand.NewSource(params.Seed))
+		simulated, err := analysis.SimulateGameLength(strategies, params.NumGames, rng)
+		if err != nil {
+			glog.Errorf("Error simulating games: %v", err)
+			os.Exit(1)
+		}
+		printLength(fmt.Sprintf("Simulated (%d games)", simulated.NumGames), simulated, params.NumPlayers)
+	}
+}
+
+func printLength(title string, l analysis.GameLength, numPlayers int) {
+	fmt.Println(title)
+	fmt.Printf("  Turns: %.2f +/- %.2f (%.2f per player)\n",
+		l.ExpectedTurns, l.TurnStdDev(), l.ExpectedTurns/float64(numPlayers))
+	fmt.Printf("  Rolls: %.2f\n", l.ExpectedRolls)
+
+	header := []string{fmt.Sprintf("  %-9s", "Quantile")}
+	turns := []string{fmt.Sprintf("  %-9s", "Turns")}
+	rolls := []string{fmt.Sprintf("  %-9s", "Rolls")}
+	for _, q := range quantiles {
+		header = append(header, fmt.Sprintf("%6s", fmt.Sprintf("%g%%", 100*q)))
+		turns = append(turns, fmt.Sprintf("%6d", l.TurnQuantile(q)))
+		rolls = append(rolls, fmt.Sprintf("%6d", l.RollQuantile(q)))
+	}
+	fmt.Println(strings.Join(header, " "))
+	fmt.Println(strings.Join(turns, " "))
+	if l.RollProbs != nil {
+		fmt.Println(strings.Join(rolls, " "))
+	}
+}
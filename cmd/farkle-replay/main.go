@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	ReplayPath string
+	DBPath     string
+	Step       bool
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.ReplayPath, "replay", "", "Path to recorded game")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database (empty to skip win probabilities)")
+	flag.BoolVar(&params.Step, "step", false, "Wait for enter after each roll")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the game was played by: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("farkle-replay")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(params.ReplayPath)
+	if err != nil {
+		glog.Errorf("Unable to open replay: %v", err)
+		os.Exit(1)
+	}
+	events, err := farkle.ReadReplay(f)
+	f.Close()
+	if err != nil {
+		glog.Errorf("Error reading replay: %v", err)
+		os.Exit(1)
+	} else if len(events) == 0 {
+		glog.Errorf("Replay %s has no events", params.ReplayPath)
+		os.Exit(1)
+	}
+
+	var db farkle.DB
+	if params.DBPath != "" {
+		numPlayers := int(events[0].State.NumPlayers)
+		db, err = farkle.OpenFileDBReadOnly(params.DBPath, numPlayers)
+		if err != nil {
+			glog.Errorf("Unable to open database: %v", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		if err := farkle.CheckRules(db); err != nil {
+			glog.Errorf("%s: %v", params.DBPath, err)
+			os.Exit(1)
+		}
+	}
+
+	showReplay(events, db, params.Step)
+}
+
+func showReplay(events []farkle.ReplayEvent, db farkle.DB, step bool) {
+	numPlayers := int(events[0].State.NumPlayers)
+	history := make([][]float64, numPlayers)
+	stdin := bufio.NewReader(os.Stdin)
+	for _, event := range events {
+		fmt.Printf("Turn %d, player %d: scores %v, turn score %d, %d dice\n",
+			event.Turn, event.Seat, seatScores(event.State, event.Seat),
+			50*int(event.State.ScoreThisRound), event.State.NumDiceToRoll)
+		if farkle.IsFarkle(event.Roll) {
+			fmt.Printf("  rolled %s -> FARKLE!\n", event.Roll)
+		} else {
+			fmt.Printf("  rolled %s -> %s\n", event.Roll, event.Action)
+		}
+
+		if db != nil {
+			before := seatWinProb(event.State, event.Seat, db)
+			nextSeat := event.Seat
+			if !event.Action.ContinueRolling {
+				nextSeat = (event.Seat + 1) % numPlayers
+			}
+			after := seatWinProb(event.Result, nextSeat, db)
+
+			changes := make([]string, numPlayers)
+			for seat := range changes {
+				changes[seat] = fmt.Sprintf("player %d: %.1f%% → %.1f%%",
+					seat, 100*before[seat], 100*after[seat])
+				history[seat] = append(history[seat], after[seat])
+			}
+			fmt.Printf("  pWin %s\n", strings.Join(changes, ", "))
+		}
+
+		if step {
+			stdin.ReadString('\n')
+		}
+	}
+
+	last := events[len(events)-1]
+	finalSeat := last.Seat
+	if !last.Action.ContinueRolling {
+		finalSeat = (last.Seat + 1) % numPlayers
+	}
+	fmt.Printf("\nFinal scores: %v\n", seatScores(last.Result, finalSeat))
+
+	if db != nil {
+		fmt.Println("\nWin probability over time:")
+		for seat, pWins := range history {
+			fmt.Printf("  player %d: %s\n", seat, sparkline(pWins))
+		}
+	}
+}
+
+// Player scores, in points, ordered by seat rather than relative to the current player.
+func seatScores(state farkle.GameState, currentSeat int) []int {
+	n := int(state.NumPlayers)
+	result := make([]int, n)
+	for i := 0; i < n; i++ {
+		result[(currentSeat+i)%n] = 50 * int(state.PlayerScores[i])
+	}
+	return result
+}
+
+// Win probability of each player, ordered by seat.
+func seatWinProb(state farkle.GameState, currentSeat int, db farkle.DB) []float64 {
+	pWin := farkle.CalculateWinProb(state, db)
+	n := int(state.NumPlayers)
+	result := make([]float64, n)
+	for i := 0; i < n; i++ {
+		result[(currentSeat+i)%n] = pWin[i]
+	}
+	return result
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+func sparkline(values []float64) string {
+	result := make([]rune, len(values))
+	for i, v := range values {
+		idx := int(v * float64(len(sparks)))
+		result[i] = sparks[max(0, min(idx, len(sparks)-1))]
+	}
+	return string(result)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/gameserver"
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+	"github.com/timpalpant/go-farkle/stats"
+)
+
+type Params struct {
+	Addr       string
+	DBPath     string
+	NumPlayers int
+	Rules      string
+	StorePath  string
+	StatsPath  string
+	AdminAddr  string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.Addr, "addr", ":8090", "Address to serve on")
+	flag.StringVar(&params.DBPath, "db", "",
+		"Path to solution database for optimal bots and annotations (optional)")
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players of the database")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	flag.StringVar(&params.StorePath, "store", "",
+		"Path to SQLite database to persist lobbies and results in (optional)")
+	flag.StringVar(&params.StatsPath, "stats", "",
+		"Path to SQLite database to record player statistics and ratings in (optional)")
+	flag.StringVar(&params.AdminAddr, "admin_addr", "",
+		"Address to serve POST /reload on, to switch to a newer version of -db (optional, SIGHUP also reloads)")
+	config.Parse("farkle-server")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	var db farkle.DB
+	config := gameserver.Config{}
+	if params.DBPath != "" {
+		reloadable, err := farkle.OpenReloadableDB(params.DBPath, params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to open database: %v", err)
+			os.Exit(1)
+		}
+		defer reloadable.Close()
+		// Games in progress carry on with the new version.
+		cli.HandleReload(reloadable.Reload, params.AdminAddr)
+		// Games are played concurrently.
+		db = farkle.ConcurrentDB(reloadable)
+		config.Advisor = farkle.DBAdvisor{DB: db}
+		config.AdvisorNumPlayers = params.NumPlayers
+	}
+	config.NewBot = func(spec string, numPlayers int) (farkle.Strategy, error) {
+		name, _, _ := strings.Cut(spec, ":")
+		switch name {
+		case "optimal":
+			if db == nil {
+				return nil, fmt.Errorf("the optimal bot requires -db")
+			} else if numPlayers != params.NumPlayers {
+				return nil, fmt.Errorf("the optimal bot only plays %d-player games", params.NumPlayers)
+			}
+			return farkle.OptimalStrategy{DB: db}, nil
+		case "threshold":
+			return farkle.ParseThresholdStrategy(spec)
+		}
+		return nil, fmt.Errorf("unknown bot: %s", name)
+	}
+
+	if params.StorePath != "" {
+		store, err := gameserver.OpenStore(params.StorePath)
+		if err != nil {
+			glog.Errorf("Unable to open store: %v", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		config.Store = store
+	}
+
+	if params.StatsPath != "" {
+		st, err := stats.OpenStore(params.StatsPath)
+		if err != nil {
+			glog.Errorf("Unable to open statistics: %v", err)
+			os.Exit(1)
+		}
+		defer st.Close()
+		config.Stats = st
+	}
+
+	server, err := gameserver.NewServer(config)
+	if err != nil {
+		glog.Errorf("Unable to restore games: %v", err)
+		os.Exit(1)
+	}
+
+	glog.Infof("Serving on %s", params.Addr)
+	if err := http.ListenAndServe(params.Addr, server.Handler()); err != nil {
+		glog.Errorf("Error serving: %v", err)
+		os.Exit(1)
+	}
+}
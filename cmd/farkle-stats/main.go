@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+	"github.com/timpalpant/go-farkle/stats"
+)
+
+type Params struct {
+	StatsPath string
+	MinGames  int
+	Limit     int
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.StatsPath, "stats", "stats.sqlite",
+		"Path to statistics database (see play-farkle -stats and farkle-server -stats)")
+	flag.IntVar(&params.MinGames, "min_games", 1, "Only list players with at least this many games")
+	flag.IntVar(&params.Limit, "limit", 20, "Number of players to list (0 for all)")
+	config.Parse("farkle-stats")
+	farkle.SetLogger(glogslog.New())
+
+	if _, err := os.Stat(params.StatsPath); err != nil {
+		glog.Errorf("Unable to open statistics: %v", err)
+		os.Exit(1)
+	}
+	store, err := stats.OpenStore(params.StatsPath)
+	if err != nil {
+		glog.Errorf("Unable to open statistics: %v", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	players, err := store.Leaderboard(params.MinGames)
+	if err != nil {
+		glog.Errorf("Unable to read leaderboard: %v", err)
+		os.Exit(1)
+	}
+	if params.Limit > 0 && len(players) > params.Limit {
+		players = players[:params.Limit]
+	}
+	printLeaderboard(players)
+}
+
+func printLeaderboard(players []stats.PlayerStats) {
+	fmt.Printf("%-4s %-24s %6s %6s %7s %9s %8s\n",
+		"Rank", "Player", "Elo", "Games", "Won", "Avg loss", "Farkles")
+	for rank, p := range players {
+		avgLoss := "-"
+		if p.AnalyzedMoves > 0 {
+			avgLoss = fmt.Sprintf("%.2f%%", 100*p.AverageLoss())
+		}
+		fmt.Printf("%-4d %-24s %6.0f %6d %6.1f%% %9s %7.1f%%\n",
+			rank+1, p.Name, p.Rating, p.Games, 100*float64(p.Wins)/float64(p.Games),
+			avgLoss, 100*p.FarkleRate())
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/cli/simulate"
+)
+
+func main() {
+	cli.Main(simulate.Command)
+}
@@ -0,0 +1,121 @@
+//go:build js && wasm
+
+// Command farkle-wasm exposes the Farkle scoring engine to JavaScript.
+//
+// Build with:
+//
+//	GOOS=js GOARCH=wasm go build -o farkle.wasm
+//
+// and load it with the wasm_exec.js shim shipped with Go. Once running, the
+// functions are available on the global `farkle` object. Dice are passed as
+// arrays of numbers (e.g. [1, 5, 5, 3]) and scores are returned in points.
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+func main() {
+	api := map[string]any{
+		"calculateScore": js.FuncOf(wrap(calculateScore)),
+		"legalHolds":     js.FuncOf(wrap(legalHolds)),
+		"isFarkle":       js.FuncOf(wrap(isFarkle)),
+		"scoreBreakdown": js.FuncOf(wrap(scoreBreakdown)),
+		"setRules":       js.FuncOf(wrap(setRules)),
+	}
+	js.Global().Set("farkle", js.ValueOf(api))
+
+	// Keep the Go runtime alive so the exported functions remain callable.
+	select {}
+}
+
+// calculateScore(dice) -> points
+func calculateScore(args []js.Value) any {
+	held := rollFromJS(args[0])
+	return 50 * int(farkle.CalculateScore(held))
+}
+
+// legalHolds(dice) -> [[dice], ...]
+func legalHolds(args []js.Value) any {
+	roll := rollFromJS(args[0])
+	holds := farkle.LegalHolds(roll)
+	result := make([]any, len(holds))
+	for i, hold := range holds {
+		result[i] = rollToJS(hold)
+	}
+	return result
+}
+
+// isFarkle(dice) -> bool
+func isFarkle(args []js.Value) any {
+	roll := rollFromJS(args[0])
+	return farkle.IsFarkle(roll)
+}
+
+// scoreBreakdown(dice) -> [{trick, dice, points}, ...]
+func scoreBreakdown(args []js.Value) any {
+	held := rollFromJS(args[0])
+	tricks := farkle.ScoreBreakdown(held)
+	result := make([]any, len(tricks))
+	for i, trick := range tricks {
+		result[i] = map[string]any{
+			"trick":  trick.Type.String(),
+			"dice":   rollToJS(trick.Dice),
+			"points": 50 * int(trick.Score()),
+		}
+	}
+	return result
+}
+
+// setRules(name) -> name, e.g. setRules("kingdom-come")
+func setRules(args []js.Value) any {
+	preset, err := farkle.ParsePreset(args[0].String())
+	if err == nil {
+		err = farkle.SetPreset(preset)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return preset.String()
+}
+
+// Convert invalid input, which panics in the library, into
+// an {error: message} result instead of crashing the runtime.
+func wrap(fn func(args []js.Value) any) func(this js.Value, args []js.Value) any {
+	return func(this js.Value, args []js.Value) (result any) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = map[string]any{"error": fmt.Sprint(r)}
+			}
+		}()
+
+		if len(args) < 1 {
+			panic(fmt.Errorf("expected an array of dice"))
+		}
+		return fn(args)
+	}
+}
+
+func rollFromJS(v js.Value) farkle.Roll {
+	dice := make([]uint8, v.Length())
+	for i := range dice {
+		die := v.Index(i).Int()
+		if die < 1 || die > 6 {
+			panic(fmt.Errorf("not a valid die: %d", die))
+		}
+		dice[i] = uint8(die)
+	}
+	return farkle.NewRoll(dice...)
+}
+
+func rollToJS(roll farkle.Roll) []any {
+	dice := roll.Dice()
+	result := make([]any, len(dice))
+	for i, die := range dice {
+		result[i] = int(die)
+	}
+	return result
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/cli/serve"
+)
+
+func main() {
+	cli.Main(serve.Command)
+}
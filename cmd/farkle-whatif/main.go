@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/analysis"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	RulesA     string
+	RulesB     string
+	Target     int
+	NumPlayers int
+	NumGames   int
+	Seed       int64
+}
+
+// The outcome of optimal play under one set of rules.
+type report struct {
+	// The win probability of each seat at the start of the game,
+	// or nil for one player.
+	seatWinProbs []float64
+	length       analysis.GameLength
+	// The smallest turn score, in points, at which the first player banks in
+	// their first turn with each number of dice left to roll, or 0 if they
+	// always roll again.
+	bankAt [farkle.MaxNumDice + 1]int
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.RulesA, "rules_a", "standard",
+		"Rules to compare: a preset (standard, facebook, pocket-farkle or kingdom-come), "+
+			"optionally followed by comma-separated overrides, e.g. standard,opening=0,holds=lenient")
+	flag.StringVar(&params.RulesB, "rules_b", "facebook", "Rules to compare against -rules_a")
+	flag.IntVar(&params.Target, "target", 2000,
+		"Score to win of the reduced game solved under both rules, or 0 to keep the target of each")
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.IntVar(&params.NumGames, "num_games", 10000,
+		"Number of games to simulate to estimate the length of games with more than one player")
+	flag.Int64Var(&params.Seed, "seed", 12345, "Random seed")
+	config.Parse("farkle-whatif")
+	farkle.SetLogger(glogslog.New())
+
+	if params.NumPlayers < 1 || params.NumPlayers > 4 {
+		glog.Errorf("Expected 1 to 4 players, got %d", params.NumPlayers)
+		os.Exit(1)
+	}
+
+	var reports [2]report
+	for i, spec := range []string{params.RulesA, params.RulesB} {
+		rules, err := farkle.ParseRules(spec)
+		if err != nil {
+			glog.Errorf("Invalid rules %q: %v", spec, err)
+			os.Exit(1)
+		}
+		if params.Target > 0 {
+			rules.TargetScore = params.Target
+		}
+		if err := farkle.SetRules(rules); err != nil {
+			glog.Errorf("Invalid rules %q: %v", spec, err)
+			os.Exit(1)
+		}
+
+		glog.Infof("Solving %d-player game with %s rules to %d points",
+			params.NumPlayers, spec, rules.TargetScore)
+		reports[i], err = evaluate(params)
+		if err != nil {
+			glog.Errorf("%s: %v", spec, err)
+			os.Exit(1)
+		}
+	}
+
+	printReports(params, reports)
+}
+
+// Solve the game with the rules in effect and play it optimally.
+func evaluate(params Params) (report, error) {
+	var result report
+	start := farkle.NewGameState(params.NumPlayers)
+	db := farkle.NewInMemoryDB(params.NumPlayers)
+	if err := farkle.SolveFrom(start, db); err != nil {
+		return result, err
+	}
+
+	strategy := farkle.OptimalStrategy{DB: db}
+	var err error
+	if params.NumPlayers == 1 {
+		result.length, err = analysis.SolitaireGameLength(strategy, 10000, 1e-9)
+	} else {
+		pWin := farkle.CalculateWinProb(start, db)
+		result.seatWinProbs = pWin[:params.NumPlayers]
+
+		strategies := make([]farkle.Strategy, params.NumPlayers)
+		for i := range strategies {
+			strategies[i] = strategy
+		}
+		rng := rand.New(rand.NewSource(params.Seed))
+		result.length, err = analysis.SimulateGameLength(strategies, params.NumGames, rng)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	for numDice := 1; numDice <= farkle.MaxNumDice; numDice++ {
+		result.bankAt[numDice] = openingBankThreshold(db, params.NumPlayers, numDice)
+	}
+	return result, nil
+}
+
+// The smallest turn score at which the first player banks rather than rolling
+// numDice dice again in the first turn of the game, or 0 if there is none.
+func openingBankThreshold(db farkle.DB, numPlayers, numDice int) int {
+	rules := farkle.CurrentRules()
+	for points := max(rules.OpeningScore, 50); points < rules.TargetScore+1000; points += 50 {
+		turnScore, _ := farkle.ParseScore(points)
+		roll := farkle.NewGameState(numPlayers)
+		roll.ScoreThisRound = turnScore
+		roll.NumDiceToRoll = uint8(numDice)
+
+		// The scores rotate to the next player after banking.
+		bank := farkle.NewGameState(numPlayers)
+		bank.PlayerScores[numPlayers-1] = turnScore
+
+		rollValue := farkle.CalculateWinProb(roll, db)
+		bankValue := farkle.CalculateWinProb(bank, db)
+		if numPlayers == 1 {
+			// The expected number of turns remaining, including this one.
+			if bankValue[0]+1 <= rollValue[0] {
+				return points
+			}
+		} else if bankValue[numPlayers-1] >= rollValue[0] {
+			return points
+		}
+	}
+	return 0
+}
+
+func printReports(params Params, reports [2]report) {
+	a, b := reports[0], reports[1]
+	fmt.Printf("%-22s %14s %14s %10s\n", "", truncate(params.RulesA), truncate(params.RulesB), "B - A")
+	for seat := range a.seatWinProbs {
+		pA, pB := a.seatWinProbs[seat], b.seatWinProbs[seat]
+		fmt.Printf("%-22s %13.2f%% %13.2f%% %+10.2f\n",
+			fmt.Sprintf("Seat %d wins", seat+1), 100*pA, 100*pB, 100*(pB-pA))
+	}
+	fmt.Printf("%-22s %14.2f %14.2f %+10.2f\n", "Expected turns",
+		a.length.ExpectedTurns, b.length.ExpectedTurns, b.length.ExpectedTurns-a.length.ExpectedTurns)
+	fmt.Printf("%-22s %14.2f %14.2f %+10.2f\n", "Expected rolls",
+		a.length.ExpectedRolls, b.length.ExpectedRolls, b.length.ExpectedRolls-a.length.ExpectedRolls)
+	fmt.Printf("%-22s %14d %14d %+10d\n", "Median turns",
+		a.length.TurnQuantile(0.5), b.length.TurnQuantile(0.5), b.length.TurnQuantile(0.5)-a.length.TurnQuantile(0.5))
+
+	fmt.Println("\nFirst turn: bank once the turn score reaches")
+	for numDice := 1; numDice <= farkle.MaxNumDice; numDice++ {
+		dice := "dice"
+		if numDice == 1 {
+			dice = "die"
+		}
+		fmt.Printf("%-22s %14s %14s\n", fmt.Sprintf("  with %d %s left", numDice, dice),
+			formatThreshold(a.bankAt[numDice]), formatThreshold(b.bankAt[numDice]))
+	}
+}
+
+func formatThreshold(points int) string {
+	if points == 0 {
+		return "never"
+	}
+	return fmt.Sprint(points)
+}
+
+// Shorten rules for a column header.
+func truncate(spec string) string {
+	if len(spec) > 14 {
+		return spec[:11] + "..."
+	}
+	return spec
+}
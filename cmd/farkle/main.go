@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/cli/export"
+	"github.com/timpalpant/go-farkle/internal/cli/play"
+	"github.com/timpalpant/go-farkle/internal/cli/query"
+	"github.com/timpalpant/go-farkle/internal/cli/serve"
+	"github.com/timpalpant/go-farkle/internal/cli/simulate"
+	"github.com/timpalpant/go-farkle/internal/cli/solve"
+	"github.com/timpalpant/go-farkle/internal/cli/verify"
+)
+
+var commands = []cli.Command{
+	solve.Command,
+	play.Command,
+	query.Command,
+	simulate.Command,
+	export.Command,
+	serve.Command,
+	verify.Command,
+}
+
+func main() {
+	cli.Dispatch(commands)
+}
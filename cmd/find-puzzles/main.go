@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"flag"
+	"math/rand"
+	"os"
+	"slices"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	Category   string
+	NumSamples int
+	MinMargin  float64
+	MaxPuzzles int
+	OutputPath string
+	Seed       int64
+	Rules      string
+}
+
+func main() {
+	var params Params
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	flag.StringVar(&params.Category, "category", "",
+		"Only sample positions of this kind: final, one-die or opening (default all)")
+	flag.IntVar(&params.NumSamples, "num_samples", 100000, "Number of random positions to analyze")
+	flag.Float64Var(&params.MinMargin, "min_margin", 0.05,
+		"Minimum difference in win probability between the best and second-best actions")
+	flag.IntVar(&params.MaxPuzzles, "max_puzzles", 100, "Keep this many puzzles with the largest margins")
+	flag.StringVar(&params.OutputPath, "output", "puzzles.jsonl", "Path to write the puzzle pack to")
+	flag.Int64Var(&params.Seed, "seed", 12345, "Random seed")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the database was solved with: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("find-puzzles")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	categories := []farkle.PracticeCategory{
+		farkle.FinalTurnPractice, farkle.OneDiePractice, farkle.OpeningPractice,
+	}
+	if params.Category != "" {
+		category, err := farkle.ParsePracticeCategory(params.Category)
+		if err != nil {
+			glog.Errorf("Invalid -category: %v", err)
+			os.Exit(1)
+		}
+		categories = []farkle.PracticeCategory{category}
+	}
+
+	db, err := farkle.OpenFileDBReadOnly(params.DBPath, params.NumPlayers)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	if err := farkle.CheckRules(db); err != nil {
+		glog.Errorf("%s: %v", params.DBPath, err)
+		os.Exit(1)
+	}
+
+	rng := rand.New(rand.NewSource(params.Seed))
+	puzzles, err := findPuzzles(db, categories, params, rng)
+	if err != nil {
+		glog.Errorf("Error finding puzzles: %v", err)
+		os.Exit(1)
+	}
+
+	if err := savePuzzles(params.OutputPath, puzzles); err != nil {
+		glog.Errorf("Error saving puzzles: %v", err)
+		os.Exit(1)
+	}
+	glog.Infof("Wrote %d puzzles to %s", len(puzzles), params.OutputPath)
+}
+
+// The puzzles with the largest margins among random positions, cycling
+// through the given categories.
+func findPuzzles(db farkle.DB, categories []farkle.PracticeCategory, params Params, rng *rand.Rand) ([]farkle.Puzzle, error) {
+	var puzzles []farkle.Puzzle
+	seen := make(map[farkle.Position]bool)
+	for i := 0; i < params.NumSamples; i++ {
+		if i%10000 == 0 {
+			glog.Infof("...%d positions analyzed, %d puzzles found", i, len(puzzles))
+		}
+
+		category := categories[i%len(categories)]
+		pos, err := farkle.RandomPosition(category, params.NumPlayers, rng)
+		if err != nil {
+			return nil, err
+		}
+		if seen[pos] {
+			continue
+		}
+		seen[pos] = true
+
+		if puzzle, ok := farkle.FindPuzzle(pos, db, params.MinMargin); ok {
+			puzzles = append(puzzles, puzzle)
+		}
+	}
+
+	slices.SortStableFunc(puzzles, func(a, b farkle.Puzzle) int {
+		return cmp.Compare(b.Margin, a.Margin)
+	})
+	return puzzles[:min(len(puzzles), params.MaxPuzzles)], nil
+}
+
+func savePuzzles(path string, puzzles []farkle.Puzzle) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := farkle.WritePuzzles(w, puzzles); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
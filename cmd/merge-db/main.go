@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	SrcPaths   string
+	DeltaPaths string
+	Policy     string
+}
+
+func main() {
+	var params Params
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.StringVar(&params.DBPath, "db", "2player.db",
+		"Path to the database to merge into, which is created if it does not exist")
+	flag.StringVar(&params.SrcPaths, "src", "", "Comma-separated paths of databases to merge")
+	flag.StringVar(&params.DeltaPaths, "deltas", "",
+		"Comma-separated paths of deltas saved by solve-farkle -delta_dir to apply in order, after -src")
+	flag.StringVar(&params.Policy, "policy", "solved", "How to merge values: solved, average or overwrite")
+	config.Parse("merge-db")
+	farkle.SetLogger(glogslog.New())
+
+	policy, err := farkle.ParseMergePolicy(params.Policy)
+	if err != nil {
+		glog.Errorf("Invalid -policy: %v", err)
+		os.Exit(1)
+	}
+	if params.SrcPaths == "" && params.DeltaPaths == "" {
+		glog.Error("Nothing to merge: -src or -deltas is required")
+		os.Exit(1)
+	}
+
+	// A new database is created for the objective of the first source.
+	var dst *farkle.FileDB
+	openDst := func(meta farkle.Metadata) {
+		if dst != nil {
+			return
+		}
+
+		dst, err = farkle.NewFileDBWithMetadata(params.DBPath, params.NumPlayers, meta)
+		if err != nil {
+			glog.Errorf("Unable to open database: %v", err)
+			os.Exit(1)
+		}
+	}
+	defer func() {
+		if dst != nil {
+			dst.Close()
+		}
+	}()
+
+	for _, srcPath := range splitPaths(params.SrcPaths) {
+		src, err := farkle.OpenFileDBReadOnly(srcPath, params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to open database: %v", err)
+			os.Exit(1)
+		}
+		openDst(src.Metadata())
+
+		glog.Infof("Merging %s into %s", srcPath, params.DBPath)
+		nChanged, err := farkle.MergeDBs(dst, src, policy)
+		src.Close()
+		if err != nil {
+			glog.Errorf("Error merging %s: %v", srcPath, err)
+			os.Exit(1)
+		}
+		glog.Infof("Updated %d states from %s", nChanged, srcPath)
+	}
+
+	for _, deltaPath := range splitPaths(params.DeltaPaths) {
+		delta, err := farkle.LoadDelta(deltaPath)
+		if err != nil {
+			glog.Errorf("Unable to load delta: %v", err)
+			os.Exit(1)
+		}
+		openDst(delta.Metadata)
+
+		glog.Infof("Applying %d states from %s", len(delta.Values), deltaPath)
+		if err := delta.ApplyTo(dst); err != nil {
+			glog.Errorf("Error applying %s: %v", deltaPath, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func splitPaths(paths string) []string {
+	if paths == "" {
+		return nil
+	}
+	return strings.Split(paths, ",")
+}
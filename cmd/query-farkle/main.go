@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/cli/query"
+)
+
+func main() {
+	cli.Main(query.Command)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	Addr       string
+}
+
+func main() {
+	var params Params
+	flag.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flag.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	flag.StringVar(&params.Addr, "addr", "localhost:6070",
+		"Address to serve on. Anyone who can connect can overwrite the database")
+	config.Parse("serve-db")
+	farkle.SetLogger(glogslog.New())
+
+	db, err := farkle.NewFileDB(params.DBPath, params.NumPlayers)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+
+	l, err := net.Listen("tcp", params.Addr)
+	if err != nil {
+		glog.Errorf("Unable to listen: %v", err)
+		os.Exit(1)
+	}
+
+	// Stop serving on interrupt, so the database is closed cleanly.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		glog.Infof("Shutting down")
+		l.Close()
+	}()
+
+	glog.Infof("Serving %v database %s on %s", db.Metadata(), params.DBPath, params.Addr)
+	if err := farkle.ServeDB(l, db); err != nil {
+		glog.Errorf("Error serving: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		glog.Errorf("Error closing database: %v", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+type Params struct {
+	State        string
+	Roll         string
+	Objective    string
+	RiskAversion float64
+	CacheGB      float64
+	OutputPath   string
+	Rules        string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.State, "state", `{"scores":[9000,9500],"turnScore":0,"numDice":6}`,
+		"Position to solve, as JSON. Scores are in points and the player to move is first")
+	flag.StringVar(&params.Roll, "roll", "", "Also show the optimal action for this roll, as JSON, e.g. [1,1,5,2,3,4] (optional)")
+	flag.StringVar(&params.Objective, "objective", "win", "Objective to optimize: win, margin or risk")
+	flag.Float64Var(&params.RiskAversion, "risk_aversion", 0, "Risk aversion per 1000 points for -objective risk")
+	flag.StringVar(&params.OutputPath, "output", "",
+		"Save the solved states to this path as a database that can be opened with -db (optional)")
+	flag.Float64Var(&params.CacheGB, "cache_gb", 0,
+		"Approximate memory budget in GiB for sort buffers and caches (0 for the defaults)")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules to solve: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("solve-position")
+	farkle.SetLogger(glogslog.New())
+
+	if params.CacheGB > 0 {
+		farkle.SetMemoryBudget(int64(params.CacheGB * (1 << 30)))
+	}
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	var state farkle.GameState
+	if err := json.Unmarshal([]byte(params.State), &state); err != nil {
+		glog.Errorf("Invalid state: %v", err)
+		os.Exit(1)
+	}
+
+	objective, err := farkle.ParseObjective(params.Objective)
+	if err != nil {
+		glog.Errorf("Invalid objective: %v", err)
+		os.Exit(1)
+	}
+	meta := farkle.Metadata{Objective: objective, Rules: farkle.CurrentRules().Fingerprint()}
+	if objective == farkle.RiskAdjustedMargin {
+		meta.RiskAversion = params.RiskAversion
+	}
+
+	start := time.Now()
+	db := farkle.NewInMemoryDBWithMetadata(int(state.NumPlayers), meta)
+	if err := farkle.SolveFrom(state, db); err != nil {
+		glog.Errorf("Error solving: %v", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Solved %d states in %v\n", db.Len(), time.Since(start))
+
+	if params.OutputPath != "" {
+		if err := saveDB(db, params.OutputPath); err != nil {
+			glog.Errorf("Error saving database: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	value := farkle.CalculateWinProb(state, db)
+	fmt.Printf("%v: %v\n", meta, value[:state.NumPlayers])
+
+	if params.Roll != "" {
+		var roll farkle.Roll
+		if err := json.Unmarshal([]byte(params.Roll), &roll); err != nil {
+			glog.Errorf("Invalid roll: %v", err)
+			os.Exit(1)
+		}
+		if roll.NumDice() != state.NumDiceToRoll {
+			glog.Errorf("Expected roll of %d dice, got %d", state.NumDiceToRoll, roll.NumDice())
+			os.Exit(1)
+		}
+
+		action, value := farkle.SelectAction(state, farkle.GetRollID(roll), db)
+		fmt.Printf("Optimal action for %v: %v (%v)\n", roll, action, value[:state.NumPlayers])
+	}
+}
+
+func saveDB(db farkle.WriterToDB, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := db.WriteTo(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
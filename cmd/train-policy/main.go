@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+	"github.com/timpalpant/go-farkle/neural"
+)
+
+type Params struct {
+	EventLogPath  string
+	OutputPath    string
+	ExportCSVPath string
+	Hidden        string
+	NumEpochs     int
+	LearningRate  float64
+	HoldoutFrac   float64
+	Seed          int64
+	Rules         string
+}
+
+func main() {
+	var params Params
+	flag.StringVar(&params.EventLogPath, "event_log", "games.jsonl",
+		"Event log with action values to train on (see farkle-tournament -event_log)")
+	flag.StringVar(&params.OutputPath, "output", "policy.json", "Path to write the trained network")
+	flag.StringVar(&params.ExportCSVPath, "export_csv", "",
+		"Also write the training examples to this CSV file, e.g. to train with other tools (optional)")
+	flag.StringVar(&params.Hidden, "hidden", "32,32", "Comma-separated number of units in each hidden layer")
+	flag.IntVar(&params.NumEpochs, "num_epochs", 20, "Number of passes over the training examples")
+	flag.Float64Var(&params.LearningRate, "learning_rate", 0.05, "SGD learning rate")
+	flag.Float64Var(&params.HoldoutFrac, "holdout_frac", 0.1, "Fraction of games held out to evaluate the network")
+	flag.Int64Var(&params.Seed, "seed", 12345, "Random seed")
+	flag.StringVar(&params.Rules, "rules", "standard",
+		"Rules the games were played by: standard, facebook, pocket-farkle or kingdom-come")
+	config.Parse("train-policy")
+	farkle.SetLogger(glogslog.New())
+
+	rules, err := farkle.ParseRules(params.Rules)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	hidden, err := parseHidden(params.Hidden)
+	if err != nil {
+		glog.Errorf("Invalid -hidden: %v", err)
+		os.Exit(1)
+	}
+
+	glog.Infof("Loading events from %s", params.EventLogPath)
+	events, err := loadEvents(params.EventLogPath)
+	if err != nil {
+		glog.Errorf("Error loading event log: %v", err)
+		os.Exit(1)
+	}
+
+	// Hold out whole games, since decisions within a game are correlated.
+	rng := rand.New(rand.NewSource(params.Seed))
+	var train, holdout []farkle.GameEvent
+	isHeldOut := make(map[int]bool)
+	for _, event := range events {
+		heldOut, ok := isHeldOut[event.Game]
+		if !ok {
+			heldOut = rng.Float64() < params.HoldoutFrac
+			isHeldOut[event.Game] = heldOut
+		}
+		if heldOut {
+			holdout = append(holdout, event)
+		} else {
+			train = append(train, event)
+		}
+	}
+
+	trainExamples, err := neural.ExamplesFromEvents(train)
+	if err != nil {
+		glog.Errorf("Error building training examples: %v", err)
+		os.Exit(1)
+	}
+	holdoutExamples, err := neural.ExamplesFromEvents(holdout)
+	if err != nil {
+		glog.Errorf("Error building holdout examples: %v", err)
+		os.Exit(1)
+	}
+	glog.Infof("Loaded %d events: %d training and %d holdout examples",
+		len(events), len(trainExamples), len(holdoutExamples))
+	if len(trainExamples) == 0 {
+		glog.Error("No training examples")
+		os.Exit(1)
+	}
+
+	if params.ExportCSVPath != "" {
+		if err := exportCSV(params.ExportCSVPath, trainExamples); err != nil {
+			glog.Errorf("Error exporting examples: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	net := neural.NewMLP(neural.NumFeatures, hidden, rng)
+	for epoch := 0; epoch < params.NumEpochs; epoch++ {
+		loss := net.Train(trainExamples, 1, params.LearningRate, rng)[0]
+		if len(holdoutExamples) > 0 {
+			glog.Infof("Epoch %d: training MSE %.6f, holdout MSE %.6f, holdout agreement %.2f%%",
+				epoch, loss, net.Loss(holdoutExamples), 100*agreement(net, holdout))
+		} else {
+			glog.Infof("Epoch %d: training MSE %.6f", epoch, loss)
+		}
+	}
+
+	glog.Infof("Saving network to %s", params.OutputPath)
+	if err := saveNet(params.OutputPath, net); err != nil {
+		glog.Errorf("Error saving network: %v", err)
+		os.Exit(1)
+	}
+}
+
+func parseHidden(s string) ([]int, error) {
+	var result []int
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func loadEvents(path string) ([]farkle.GameEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return farkle.ReadEventLog(bufio.NewReader(f))
+}
+
+// The fraction of decisions in which the network picks an action with
+// the same value as the best action.
+func agreement(net *neural.MLP, events []farkle.GameEvent) float64 {
+	strategy := neural.Strategy{Net: net}
+	nDecisions, nAgree := 0, 0
+	for _, event := range events {
+		if len(event.Actions) < 2 {
+			continue
+		}
+
+		best := 0.0
+		for _, value := range event.ActionValues {
+			best = max(best, value)
+		}
+		action, _ := strategy.SelectAction(event.State, event.Roll)
+		for i, a := range event.Actions {
+			if a == action && event.ActionValues[i] == best {
+				nAgree++
+			}
+		}
+		nDecisions++
+	}
+
+	if nDecisions == 0 {
+		return 0
+	}
+	return float64(nAgree) / float64(nDecisions)
+}
+
+func exportCSV(path string, examples []neural.Example) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriterSize(f, 1024*1024)
+	if err := neural.WriteCSV(w, examples); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func saveNet(path string, net *neural.MLP) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := net.Save(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,79 @@
+package farkle
+
+import "fmt"
+
+// How many of the states before the end of the game a database holds solved
+// values for. States that are not solved have their initial value, and the
+// end of the game needs no solving.
+type Coverage struct {
+	Solved int
+	States int
+}
+
+func (c Coverage) Fraction() float64 {
+	if c.States == 0 {
+		return 0
+	}
+	return float64(c.Solved) / float64(c.States)
+}
+
+func (c Coverage) String() string {
+	return fmt.Sprintf("%d of %d states solved (%.2f%%)", c.Solved, c.States, 100*c.Fraction())
+}
+
+// DB that distinguishes states that have been solved from those that have not.
+type CoverageDB interface {
+	DB
+	// Count the solved states, which may read the whole database.
+	Coverage() (Coverage, error)
+}
+
+// The number of states before the end of the game with the rules in effect,
+// which may not all be reachable.
+func numOpenStates(numPlayers int) int {
+	// The current player has not won, and the others may have any score.
+	result := int(turnNumDice) * (1 << numScoreBits) * int(scoreToWin)
+	for i := 1; i < numPlayers; i++ {
+		result <<= numScoreBits
+	}
+	return result
+}
+
+// Whether the state with the given ID is solved, rather than being the end
+// of the game or unreachable with the dice of the rules in effect.
+func isOpenState(numPlayers, gsID int) bool {
+	state := GameStateFromID(numPlayers, gsID)
+	return !state.IsGameOver() && state.NumDiceToRoll <= turnNumDice
+}
+
+// Count the solved states of a sparse database. The values of all states are
+// indistinguishable from solved values in databases that are not sparse, such
+// as those created by earlier versions, so they cannot be counted.
+func (db *FileDB) Coverage() (Coverage, error) {
+	if !db.sparse {
+		return Coverage{}, fmt.Errorf("%s is not sparse, so unsolved states cannot be told apart",
+			db.f.Name())
+	}
+
+	db.advise(adviseSequential)
+	defer db.advise(adviseNormal)
+	result := Coverage{States: numOpenStates(db.numPlayers)}
+	valueSize := 8 * db.numPlayers
+	for gsID := 0; gsID < calcNumDistinctStates(db.numPlayers); gsID++ {
+		if !isUnset(db.data[valueSize*gsID:valueSize*(gsID+1)]) && isOpenState(db.numPlayers, gsID) {
+			result.Solved++
+		}
+	}
+	return result, nil
+}
+
+// Count the states that have been stored.
+func (db *InMemoryDB) Coverage() (Coverage, error) {
+	result := Coverage{States: numOpenStates(db.numPlayers)}
+	for gsID := range db.values {
+		if isOpenState(db.numPlayers, gsID) {
+			result.Solved++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package farkle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Databases count the solved states before the end of the game, out of all
+// such states, including values of zero in sparse databases.
+func TestCoverage(t *testing.T) {
+	setTestRules(t, "standard,target=300,dice=3,opening=100")
+	numOpen := 0
+	for gsID := 0; gsID < calcNumDistinctStates(1); gsID++ {
+		if isOpenState(1, gsID) {
+			numOpen++
+		}
+	}
+	if n := numOpenStates(1); n != numOpen {
+		t.Fatalf("numOpenStates = %d, want %d", n, numOpen)
+	}
+
+	open, over := NewGameState(1), NewGameState(1)
+	over.PlayerScores[0] = scoreToWin
+	path := filepath.Join(t.TempDir(), "test.db")
+	fileDB, err := NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileDB.Close()
+	memDB := NewInMemoryDB(1)
+	for _, db := range []CoverageDB{fileDB, memDB} {
+		if got, err := db.Coverage(); err != nil {
+			t.Fatal(err)
+		} else if want := (Coverage{States: numOpen}); got != want {
+			t.Errorf("%T: coverage of a new database = %v, want %v", db, got, want)
+		}
+		db.Put(open.ID(), [maxNumPlayers]float64{0})
+		db.Put(over.ID(), [maxNumPlayers]float64{1})
+		if got, err := db.Coverage(); err != nil {
+			t.Fatal(err)
+		} else if want := (Coverage{Solved: 1, States: numOpen}); got != want {
+			t.Errorf("%T: coverage = %v, want %v", db, got, want)
+		}
+	}
+
+	// Databases that are not sparse cannot tell unsolved states apart.
+	densePath := filepath.Join(t.TempDir(), "dense.db")
+	f, err := os.Create(densePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memDB.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dense, err := OpenFileDBReadOnly(densePath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dense.Close()
+	if _, err := dense.Coverage(); err == nil {
+		t.Error("counted the solved states of a database that is not sparse")
+	}
+}
@@ -2,20 +2,30 @@ package farkle
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
-
-	"github.com/golang/glog"
-	"golang.org/x/sys/unix"
+	"sync"
+	"sync/atomic"
 )
 
+// A database of the value of each game state.
+//
+// Databases are not safe for concurrent use unless documented otherwise:
+// FileDBs opened read-only, RemoteDBs and SyncDBs are. CalculateWinProb,
+// SelectAction and the other functions that read a database keep no state of
+// their own, so they are safe to call concurrently with a database that is.
+// Use ConcurrentDB to share a database between goroutines.
 type DB interface {
 	// The number of game players.
 	NumPlayers() int
+	// Description of the values stored in the database.
+	Metadata() Metadata
 	// Store the result for a game state in the database.
 	Put(gsId int, pWin [maxNumPlayers]float64)
 	// Retrieve a stored result for the given game state.
@@ -23,84 +33,328 @@ type DB interface {
 	io.Closer
 }
 
-// DB that stores results in a memory-mapped flat file.
+// DB that can be saved to a stream, e.g. to persist a solve in memory.
+// FileDB and InMemoryDB write the FileDB format, which can be opened
+// with NewFileDB.
+type WriterToDB interface {
+	DB
+	io.WriterTo
+}
+
+// DB that can be loaded from a stream written by a WriterToDB.
+type ReaderFromDB interface {
+	DB
+	io.ReaderFrom
+}
+
+// FileDB files begin with a header of dbHeaderSize bytes describing their
+// contents: dbMagic, the format version and the number of players (uint32
+// each), the metadata, the flags (uint32), a SHA-256 checksum of the data
+// following the header and the RollTableVersion (uint32). The rest of the
+// header is zero. Files written before the header was introduced have no
+// header, and hold win probabilities.
+// Versions 1 and 2, with a 16-bit rules fingerprint, are no longer read.
+const (
+	dbMagic         = "FARKLEDB"
+	dbFormatVersion = 3
+
+	// In sparse databases, values whose bytes are all zero have not been
+	// stored, and are the initial value of their state. Stored values of
+	// all zeros are written with -0 as the value of the first player.
+	// Older versions cannot read them, rather than reading zeros.
+	dbSparseFormatVersion = 4
+
+	dbHeaderSize     = 128
+	dbFlagsOffset    = 16 + metadataSize
+	dbChecksumOffset = dbFlagsOffset + 4
+	// Zero in files written before the roll table version was recorded.
+	dbRollTableOffset = dbChecksumOffset + sha256.Size
+
+	// The checksum in the header is valid.
+	dbFlagChecksum uint32 = 1 << 0
+	// The database has been modified since it was last closed.
+	dbFlagDirty uint32 = 1 << 1
+)
+
+// DB that stores results in a memory-mapped flat file. FileDBs opened
+// read-only are safe for concurrent use. FileDBs opened for writing may be
+// written concurrently for different states, while other states are read,
+// but not with concurrent Puts of the same state.
 type FileDB struct {
 	numPlayers int
+	meta       Metadata
+	readOnly   bool
 	f          *os.File
 
-	mmap  []byte
-	nPuts int64
+	mmap   []byte
+	header []byte // mmap, excluding the data. Empty for headerless files.
+	data   []byte // mmap, excluding the header.
+	sparse bool
+	nPuts  atomic.Int64
+	// Set by markDirty, once, before the first Put.
+	dirty     bool
+	dirtyOnce sync.Once
 }
 
+// Open the database at the given path, or create a new database for the
+// WinProbability objective with the rules in effect if it does not exist.
+// Existing databases may have any objective and rules.
 func NewFileDB(path string, numPlayers int) (*FileDB, error) {
+	meta := Metadata{Objective: WinProbability, Rules: rulesFingerprint}
+	return openFileDB(path, numPlayers, meta, false, false)
+}
+
+// Open the database at the given path, or create a new database with the
+// given metadata if it does not exist. Existing databases must have been
+// created with the same metadata.
+func NewFileDBWithMetadata(path string, numPlayers int, meta Metadata) (*FileDB, error) {
+	return openFileDB(path, numPlayers, meta, true, false)
+}
+
+// Open an existing database for reading only, e.g. to play with a solution.
+// Any number of processes may open the same database read-only, but not
+// while it is open for writing. Put panics.
+func OpenFileDBReadOnly(path string, numPlayers int) (*FileDB, error) {
+	return openFileDB(path, numPlayers, Metadata{}, false, true)
+}
+
+func openFileDB(path string, numPlayers int, meta Metadata, requireMeta, readOnly bool) (*FileDB, error) {
 	numStates := calcNumDistinctStates(numPlayers)
 	numEntries := numPlayers * numStates
-	fileSize := int64(8 * numEntries)
+	dataSize := int64(8 * numEntries)
 
 	var f *os.File
+	headerSize := int64(dbHeaderSize)
+	version := uint32(dbSparseFormatVersion)
+	created := false
 	stat, err := os.Stat(path)
-	if errors.Is(err, os.ErrNotExist) {
-		glog.Infof("Initializing new database at %s with %d states", path, numStates)
+	if errors.Is(err, os.ErrNotExist) && !readOnly {
+		created = true
+		Logger().Info("Initializing new database", "metadata", meta, "path", path, "states", numStates)
+		if err := checkDiskSpace(path, headerSize+dataSize); err != nil {
+			return nil, err
+		}
 		f, err = os.Create(path)
 		if err != nil {
 			return nil, err
 		}
-		if err := initDB(f, numStates, numPlayers); err != nil {
+		if err := lockFile(f, true); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, err := f.Write(encodeHeader(numPlayers, meta, version)); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		// Every value is unset, so the data can be a hole in a sparse file.
+		if err := f.Truncate(headerSize + dataSize); err != nil {
 			_ = f.Close()
 			return nil, err
 		}
 	} else if err != nil {
 		return nil, err
-	} else if stat.Size() != fileSize {
+	} else if stat.Size() != dataSize && stat.Size() != headerSize+dataSize {
 		return nil, fmt.Errorf(
 			"%s is not the correct size for %d-player database: "+
-				"got %d, expected %d", path, numPlayers, stat.Size(), fileSize)
+				"got %d, expected %d", path, numPlayers, stat.Size(), headerSize+dataSize)
 	} else {
-		f, err = os.OpenFile(path, os.O_RDWR, 0755)
+		flag := os.O_RDWR
+		if readOnly {
+			flag = os.O_RDONLY
+		}
+		f, err = os.OpenFile(path, flag, 0755)
 		if err != nil {
 			return nil, err
 		}
+		if err := lockFile(f, !readOnly); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		storedMeta := Metadata{Objective: WinProbability}
+		if stat.Size() == dataSize {
+			Logger().Info("Database has no header, assuming it holds win probabilities", "path", path)
+			headerSize = 0
+			version = dbFormatVersion
+		} else {
+			_, storedMeta, version, err = readHeader(f, numPlayers)
+			if err != nil {
+				_ = f.Close()
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		if requireMeta && storedMeta != meta {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s was solved for %v, not %v",
+				path, storedMeta, meta)
+		}
+		meta = storedMeta
 	}
 
-	flags := unix.MAP_SHARED
-	prot := unix.PROT_READ | unix.PROT_WRITE
-	mmap, err := unix.Mmap(int(f.Fd()), 0, int(fileSize), prot, flags)
+	mmap, err := mmapFile(f, int(headerSize+dataSize), !readOnly)
 	if err != nil {
 		_ = f.Close()
 		return nil, err
 	}
 
-	return &FileDB{
+	db := &FileDB{
 		f:          f,
 		mmap:       mmap,
+		header:     mmap[:headerSize],
+		data:       mmap[headerSize:],
+		sparse:     version == dbSparseFormatVersion,
 		numPlayers: numPlayers,
-	}, nil
+		meta:       meta,
+		readOnly:   readOnly,
+	}
+	if created {
+		db.markDirty()
+	} else if db.flags()&dbFlagDirty != 0 {
+		Logger().Warn("Database was not closed cleanly, and may be incomplete", "path", path)
+	}
+	return db, nil
+}
+
+func encodeHeader(numPlayers int, meta Metadata, version uint32) []byte {
+	header := make([]byte, dbHeaderSize)
+	copy(header, dbMagic)
+	binary.LittleEndian.PutUint32(header[8:], version)
+	binary.LittleEndian.PutUint32(header[12:], uint32(numPlayers))
+	encodeMetadata(header[16:16+metadataSize], meta)
+	binary.LittleEndian.PutUint32(header[dbRollTableOffset:], RollTableVersion)
+	return header
 }
 
-func initDB(w io.Writer, numStates, numPlayers int) error {
-	bufW := bufio.NewWriterSize(w, 4*1024*1024)
+// Read and validate the header of a database for numPlayers players (or any
+// number of players, if 0), returning the header, its metadata and the format
+// version.
+func readHeader(r io.Reader, numPlayers int) ([]byte, Metadata, uint32, error) {
+	header := make([]byte, dbHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, Metadata{}, 0, err
+	}
 
-	defaultValue := make([]byte, 8*numPlayers)
-	bits := math.Float64bits(1.0 / float64(numPlayers))
-	for i := 0; i < numPlayers; i++ {
-		buf := defaultValue[8*i : 8*(i+1)]
-		binary.LittleEndian.PutUint64(buf, bits)
+	if string(header[:8]) != dbMagic {
+		return nil, Metadata{}, 0, fmt.Errorf("not a farkle database")
+	}
+	version := binary.LittleEndian.Uint32(header[8:])
+	if version != dbFormatVersion && version != dbSparseFormatVersion {
+		return nil, Metadata{}, 0, fmt.Errorf("unsupported database version: %d", version)
+	}
+	if n := int(binary.LittleEndian.Uint32(header[12:])); numPlayers != 0 && n != numPlayers {
+		return nil, Metadata{}, 0, fmt.Errorf("database is for %d players, not %d", n, numPlayers)
+	}
+	if err := checkRollTableVersion(binary.LittleEndian.Uint32(header[dbRollTableOffset:])); err != nil {
+		return nil, Metadata{}, 0, err
+	}
+
+	meta, err := decodeMetadata(header[16 : 16+metadataSize])
+	return header, meta, version, err
+}
+
+// Check that the database at the given path is complete and uncorrupted,
+// using the checksum stored in its header when it was last closed.
+// Databases without a header cannot be verified.
+func VerifyDB(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	for i := 0; i < numStates; i++ {
-		if i%100000000 == 0 {
-			glog.Infof("...%d", i)
+	header, _, _, err := readHeader(f, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	numPlayers := int(binary.LittleEndian.Uint32(header[12:]))
+	if numPlayers < 1 || numPlayers > maxNumPlayers {
+		return fmt.Errorf("%s: invalid number of players: %d", path, numPlayers)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	expectedSize := int64(dbHeaderSize + 8*numPlayers*calcNumDistinctStates(numPlayers))
+	if stat.Size() != expectedSize {
+		return fmt.Errorf("%s is truncated or corrupt: got %d bytes, expected %d",
+			path, stat.Size(), expectedSize)
+	}
+
+	flags := binary.LittleEndian.Uint32(header[dbFlagsOffset:])
+	if flags&dbFlagDirty != 0 {
+		return fmt.Errorf("%s was not closed cleanly, and may be incomplete", path)
+	} else if flags&dbFlagChecksum == 0 {
+		return fmt.Errorf("%s has no checksum", path)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, bufio.NewReaderSize(f, 4*1024*1024)); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if !bytes.Equal(h.Sum(nil), header[dbChecksumOffset:dbChecksumOffset+sha256.Size]) {
+		return fmt.Errorf("%s is corrupt: checksum does not match", path)
+	}
+
+	return nil
+}
+
+// Write the values of all states in db in the FileDB format, with a checksum.
+func writeFileDB(w io.Writer, db DB) (int64, error) {
+	numPlayers := db.NumPlayers()
+	writeData := func(w io.Writer) error {
+		bufW := bufio.NewWriterSize(w, 4*1024*1024)
+		for gsID := 0; gsID < calcNumDistinctStates(numPlayers); gsID++ {
+			pWin := db.Get(gsID)
+			if _, err := bufW.Write(encodeValue(pWin[:numPlayers])); err != nil {
+				return err
+			}
 		}
+		return bufW.Flush()
+	}
 
-		state := GameStateFromID(numPlayers, i)
-		if state.IsGameOver() {
-			pWin := calcEndGameValue(state)
-			bufW.Write(encodeValue(pWin[:state.NumPlayers]))
-		} else {
-			bufW.Write(defaultValue)
+	// The checksum precedes the data, so calculate it first.
+	h := sha256.New()
+	if err := writeData(h); err != nil {
+		return 0, err
+	}
+
+	cw := &countingWriter{w: w}
+	header := encodeHeaderWithChecksum(numPlayers, db.Metadata(), dbFormatVersion, h.Sum(nil))
+	if _, err := cw.Write(header); err != nil {
+		return cw.n, err
+	}
+	err := writeData(cw)
+	return cw.n, err
+}
+
+func encodeHeaderWithChecksum(numPlayers int, meta Metadata, version uint32, checksum []byte) []byte {
+	header := encodeHeader(numPlayers, meta, version)
+	binary.LittleEndian.PutUint32(header[dbFlagsOffset:], dbFlagChecksum)
+	copy(header[dbChecksumOffset:], checksum)
+	return header
+}
+
+// The value of a game state before it has been solved: the end game result
+// for terminal states, or an even game for all players otherwise.
+func InitialValue(numPlayers, gsID int, meta Metadata) [maxNumPlayers]float64 {
+	state := GameStateFromID(numPlayers, gsID)
+	if state.IsGameOver() {
+		return calcEndGameValue(state, meta)
+	}
+
+	return unsolvedValue(numPlayers, meta)
+}
+
+func unsolvedValue(numPlayers int, meta Metadata) [maxNumPlayers]float64 {
+	var result [maxNumPlayers]float64
+	if meta.Objective == WinProbability {
+		for i := 0; i < numPlayers; i++ {
+			result[i] = 1.0 / float64(numPlayers)
 		}
 	}
-	return bufW.Flush()
+	return result
 }
 
 func encodeValue(pWin []float64) []byte {
@@ -117,29 +371,49 @@ func (db *FileDB) NumPlayers() int {
 	return db.numPlayers
 }
 
+func (db *FileDB) safeForConcurrentUse() bool {
+	return db.readOnly
+}
+
+func (db *FileDB) safeForConcurrentPuts() bool {
+	return !db.readOnly
+}
+
+func (db *FileDB) Metadata() Metadata {
+	return db.meta
+}
+
 func (db *FileDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	if db.readOnly {
+		panic(fmt.Errorf("put into read-only database %s", db.f.Name()))
+	}
+	db.dirtyOnce.Do(db.markDirty)
+
 	idx := 8 * db.numPlayers * gsID
 
-	buf := db.mmap[idx : idx+8*db.numPlayers]
+	buf := db.data[idx : idx+8*db.numPlayers]
 	for i, p := range pWin[:db.numPlayers] {
 		value := math.Float64bits(p)
 		binary.LittleEndian.PutUint64(buf[8*i:8*(i+1)], value)
 	}
+	if db.sparse && isUnset(buf) {
+		binary.LittleEndian.PutUint64(buf, negativeZero)
+	}
 
-	db.nPuts++
-	if db.nPuts%100000 == 0 {
-		glog.Infof(
-			"%d puts into database. Last put: %d -> %v",
-			db.nPuts, gsID, pWin[:db.numPlayers])
+	if n := db.nPuts.Add(1); n%100000 == 0 {
+		Logger().Info("Puts into database", "count", n, "id", gsID, "value", pWin[:db.numPlayers])
 	}
 }
 
 func (db *FileDB) Get(gsID int) [maxNumPlayers]float64 {
 	idx := 8 * db.numPlayers * gsID
 
-	buf := db.mmap[idx : idx+8*db.numPlayers]
-	var result [maxNumPlayers]float64
+	buf := db.data[idx : idx+8*db.numPlayers]
+	if db.sparse {
+		return decodeSparseValue(buf, db.numPlayers, gsID, db.meta)
+	}
 
+	var result [maxNumPlayers]float64
 	for i := 0; i < db.numPlayers; i++ {
 		value := binary.LittleEndian.Uint64(buf[8*i : 8*(i+1)])
 		result[i] = math.Float64frombits(value)
@@ -148,13 +422,153 @@ func (db *FileDB) Get(gsID int) [maxNumPlayers]float64 {
 	return result
 }
 
+// The bits of -0, which replace those of the first player's value when all
+// are zero in a sparse database.
+const negativeZero = 1 << 63
+
+// Whether the encoded value of a state in a sparse database is unset.
+func isUnset(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeSparseValue(buf []byte, numPlayers, gsID int, meta Metadata) [maxNumPlayers]float64 {
+	if isUnset(buf) {
+		return InitialValue(numPlayers, gsID, meta)
+	}
+
+	var result [maxNumPlayers]float64
+	for i := 0; i < numPlayers; i++ {
+		value := binary.LittleEndian.Uint64(buf[8*i : 8*(i+1)])
+		if value != negativeZero {
+			result[i] = math.Float64frombits(value)
+		}
+	}
+	return result
+}
+
+// How the pages of a FileDB will be accessed, as a hint to the kernel.
+type pageAdvice int
+
+const (
+	adviseNormal pageAdvice = iota
+	// The pages will be read in order, once.
+	adviseSequential
+	// The pages will be needed soon, and should be read ahead.
+	adviseWillNeed
+	// Back the pages with transparent huge pages (Linux only).
+	adviseHugePage
+)
+
+// Advise the kernel how the whole database will be accessed.
+func (db *FileDB) advise(advice pageAdvice) {
+	if err := madvise(db.mmap, advice); err != nil {
+		Logger().Debug("madvise failed", "path", db.f.Name(), "err", err)
+	}
+}
+
+// Advise the kernel how the values of the given states, sorted by ID, will be
+// accessed. Adjacent states are advised together, a contiguous run of pages
+// at a time.
+func (db *FileDB) adviseStates(gsIDs []int, advice pageAdvice) {
+	valueSize := 8 * db.numPlayers
+	pageSize := pageSize()
+	headerSize := len(db.mmap) - len(db.data)
+	start, end := -1, -1
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		if err := madvise(db.mmap[start:min(end, len(db.mmap))], advice); err != nil {
+			Logger().Debug("madvise failed", "path", db.f.Name(), "err", err)
+		}
+	}
+
+	for _, gsID := range gsIDs {
+		offset := headerSize + valueSize*gsID
+		pageStart := offset - offset%pageSize
+		pageEnd := offset + valueSize
+		if start >= 0 && pageStart <= end {
+			end = max(end, pageEnd)
+			continue
+		}
+
+		flush()
+		start, end = pageStart, pageEnd
+	}
+	flush()
+}
+
+// Ask the kernel to back the database with transparent huge pages, which
+// reduces TLB misses during the random accesses of value iteration. This is
+// only a hint: it fails if the kernel does not support huge pages for the
+// file, and the kernel may still use regular pages.
+func (db *FileDB) UseHugePages() error {
+	if err := madvise(db.mmap, adviseHugePage); err != nil {
+		return fmt.Errorf("huge pages are not supported for %s: %w", db.f.Name(), err)
+	}
+	return nil
+}
+
+// Read the pages holding the values of the given states, sorted by ID,
+// ahead of time.
+func (db *FileDB) prefetch(gsIDs []int) {
+	db.adviseStates(gsIDs, adviseWillNeed)
+}
+
+// Write a copy of the database, which can be opened with NewFileDB.
+// Databases without a header are copied with one.
+func (db *FileDB) WriteTo(w io.Writer) (int64, error) {
+	db.advise(adviseSequential)
+	defer db.advise(adviseNormal)
+	version := uint32(dbFormatVersion)
+	if db.sparse {
+		version = dbSparseFormatVersion
+	}
+	checksum := sha256.Sum256(db.data)
+	cw := &countingWriter{w: w}
+	if _, err := cw.Write(encodeHeaderWithChecksum(db.numPlayers, db.meta, version, checksum[:])); err != nil {
+		return cw.n, err
+	}
+	_, err := cw.Write(db.data)
+	return cw.n, err
+}
+
+func (db *FileDB) flags() uint32 {
+	if len(db.header) == 0 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(db.header[dbFlagsOffset:])
+}
+
+func (db *FileDB) setFlags(flags uint32) {
+	if len(db.header) > 0 {
+		binary.LittleEndian.PutUint32(db.header[dbFlagsOffset:], flags)
+	}
+}
+
+// Flag the database as modified, invalidating its checksum until it is closed.
+func (db *FileDB) markDirty() {
+	db.setFlags((db.flags() | dbFlagDirty) &^ dbFlagChecksum)
+	db.dirty = true
+}
+
 func (db *FileDB) Close() error {
 	defer db.f.Close()
 
-	if err := unix.Msync(db.mmap, unix.MS_SYNC); err != nil {
-		return err
+	if db.dirty && len(db.header) > 0 {
+		Logger().Info("Updating checksum", "path", db.f.Name())
+		db.advise(adviseSequential)
+		checksum := sha256.Sum256(db.data)
+		copy(db.header[dbChecksumOffset:], checksum[:])
+		db.setFlags(dbFlagChecksum)
 	}
-	if err := unix.Munmap(db.mmap); err != nil {
+
+	if err := munmapFile(db.mmap, !db.readOnly); err != nil {
 		return err
 	}
 
@@ -0,0 +1,188 @@
+package farkle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The full rules fingerprint is stored in each format that holds metadata,
+// so a database solved with other rules is detected even if their
+// fingerprints agree in the 16 bits that older formats stored.
+func TestMetadataFormats(t *testing.T) {
+	setTestRules(t, "standard,target=2000,weights=2:1:1:1:1:1")
+	meta := Metadata{Objective: RiskAdjustedMargin, RiskAversion: 0.5, Rules: rulesFingerprint}
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "test.db")
+	db, err := NewFileDBWithMetadata(path, 1, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put(1, [maxNumPlayers]float64{2.5})
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDB(path); err != nil {
+		t.Fatal(err)
+	}
+	db, err = OpenFileDBReadOnly(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkMetadata(t, "FileDB", db.Metadata(), meta)
+
+	var buf bytes.Buffer
+	if _, err := db.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	memDB := NewInMemoryDB(1)
+	if _, err := memDB.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata(t, "InMemoryDB.ReadFrom", memDB.Metadata(), meta)
+	if got := memDB.Get(1); got[0] != 2.5 {
+		t.Errorf("InMemoryDB.ReadFrom: value = %v, want 2.5", got[0])
+	}
+
+	buf.Reset()
+	if _, err := NewTurnDBFrom(memDB).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	turnDB, err := ReadTurnDB(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata(t, "TurnDB", turnDB.Metadata(), meta)
+
+	deltaDB := NewDeltaDB(memDB, 0)
+	deltaDB.Put(2, [maxNumPlayers]float64{3.5})
+	deltaPath := filepath.Join(dir, "test.delta")
+	if err := deltaDB.SaveDelta(deltaPath); err != nil {
+		t.Fatal(err)
+	}
+	delta, err := LoadDelta(deltaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata(t, "Delta", delta.Metadata, meta)
+	if got := delta.Values[2]; len(delta.Values) != 1 || got[0] != 3.5 {
+		t.Errorf("Delta: values = %v, want {2: 3.5}", delta.Values)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go ServeDB(l, memDB)
+	remoteDB, err := DialRemoteDB(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remoteDB.Close()
+	checkMetadata(t, "RemoteDB", remoteDB.Metadata(), meta)
+	if got := remoteDB.Get(1); got[0] != 2.5 {
+		t.Errorf("RemoteDB: value = %v, want 2.5", got[0])
+	}
+}
+
+// Databases in the formats that stored a 16-bit rules fingerprint are not
+// read, rather than read with the wrong rules.
+func TestOldFileDBVersions(t *testing.T) {
+	for _, version := range []uint32{1, 2} {
+		path := filepath.Join(t.TempDir(), "old.db")
+		header := make([]byte, 64)
+		copy(header, dbMagic)
+		binary.LittleEndian.PutUint32(header[8:], version)
+		binary.LittleEndian.PutUint32(header[12:], 1)
+		data := make([]byte, 8*calcNumDistinctStates(1))
+		if err := os.WriteFile(path, append(header, data...), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if db, err := OpenFileDBReadOnly(path, 1); err == nil {
+			db.Close()
+			t.Errorf("opened a version %d database", version)
+		}
+		if err := VerifyDB(path); err == nil {
+			t.Errorf("verified a version %d database", version)
+		}
+	}
+}
+
+// Databases written with another roll table are not read. Databases written
+// before the version was recorded were written with version 1.
+func TestRollTableVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	setVersion := func(version uint32) {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary.LittleEndian.PutUint32(data[dbRollTableOffset:], version)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setVersion(0)
+	if db, err := OpenFileDBReadOnly(path, 1); err != nil {
+		t.Errorf("database without a roll table version: %v", err)
+	} else {
+		db.Close()
+	}
+	setVersion(RollTableVersion + 1)
+	if db, err := OpenFileDBReadOnly(path, 1); err == nil {
+		db.Close()
+		t.Error("opened a database with another roll table version")
+	}
+	if err := VerifyDB(path); err == nil {
+		t.Error("verified a database with another roll table version")
+	}
+
+	var buf bytes.Buffer
+	if _, err := NewTurnDBFrom(NewInMemoryDB(1)).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(data[16+metadataSize:], RollTableVersion+1)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTurnDB(&buf); err == nil {
+		t.Error("read a turn database with another roll table version")
+	}
+}
+
+func checkMetadata(t *testing.T, name string, got, want Metadata) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s: metadata = %+v, want %+v", name, got, want)
+	}
+}
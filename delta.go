@@ -0,0 +1,190 @@
+package farkle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+)
+
+// Files written by SaveDelta begin with a header of deltaHeaderSize bytes:
+// deltaMagic, the format version and the number of players (uint32 each), the
+// metadata and the number of states (uint64).
+const (
+	deltaMagic         = "FARKLEDL"
+	deltaFormatVersion = 2
+	deltaHeaderSize    = 16 + metadataSize + 8
+)
+
+// DB that records the states whose value changes by more than epsilon
+// when they are Put into the underlying database, so that the changes in
+// each value iteration cycle can be saved with SaveDelta. Changes smaller
+// than epsilon are not recorded, so replaying the deltas from a database
+// reconstructs each value to within epsilon per cycle.
+type DeltaDB struct {
+	DB
+	epsilon float64
+	changed map[int][maxNumPlayers]float64
+}
+
+func NewDeltaDB(db DB, epsilon float64) *DeltaDB {
+	return &DeltaDB{
+		DB:      db,
+		epsilon: epsilon,
+		changed: make(map[int][maxNumPlayers]float64),
+	}
+}
+
+// The number of states that have changed since the last SaveDelta.
+func (db *DeltaDB) Len() int {
+	return len(db.changed)
+}
+
+func (db *DeltaDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	prev := db.DB.Get(gsID)
+	for i := range pWin[:db.NumPlayers()] {
+		if math.Abs(pWin[i]-prev[i]) > db.epsilon {
+			db.changed[gsID] = pWin
+			break
+		}
+	}
+
+	db.DB.Put(gsID, pWin)
+}
+
+func (db *DeltaDB) IsDecided(gsID int) bool {
+	decided, ok := db.DB.(decidedDB)
+	return ok && decided.IsDecided(gsID)
+}
+
+func (db *DeltaDB) prefetch(gsIDs []int) {
+	if p, ok := db.DB.(prefetchDB); ok {
+		p.prefetch(gsIDs)
+	}
+}
+
+// Save the new values of all states that have changed since the last
+// SaveDelta to a file, which can be read with LoadDelta.
+func (db *DeltaDB) SaveDelta(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriterSize(f, 4*1024*1024)
+
+	numPlayers := db.NumPlayers()
+	header := make([]byte, deltaHeaderSize)
+	copy(header, deltaMagic)
+	binary.LittleEndian.PutUint32(header[8:], deltaFormatVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(numPlayers))
+	encodeMetadata(header[16:16+metadataSize], db.Metadata())
+	binary.LittleEndian.PutUint64(header[16+metadataSize:], uint64(len(db.changed)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8)
+	for _, gsID := range slices.Sorted(maps.Keys(db.changed)) {
+		pWin := db.changed[gsID]
+		binary.LittleEndian.PutUint64(buf, uint64(gsID))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		if _, err := w.Write(encodeValue(pWin[:numPlayers])); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	clear(db.changed)
+	return nil
+}
+
+// The values of the states that changed in one value iteration cycle.
+type Delta struct {
+	NumPlayers int
+	Metadata   Metadata
+	Values     map[int][maxNumPlayers]float64
+}
+
+// Load a delta saved with DeltaDB.SaveDelta.
+func LoadDelta(path string) (*Delta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 4*1024*1024)
+
+	header := make([]byte, deltaHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if string(header[:8]) != deltaMagic {
+		return nil, fmt.Errorf("%s is not a farkle database delta", path)
+	}
+
+	numPlayers := int(binary.LittleEndian.Uint32(header[12:]))
+	if numPlayers < 1 || numPlayers > maxNumPlayers {
+		return nil, fmt.Errorf("%s: invalid number of players: %d", path, numPlayers)
+	}
+	if version := binary.LittleEndian.Uint32(header[8:]); version != deltaFormatVersion {
+		return nil, fmt.Errorf("%s: unsupported delta version: %d", path, version)
+	}
+	meta, err := decodeMetadata(header[16 : 16+metadataSize])
+	n := binary.LittleEndian.Uint64(header[16+metadataSize:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delta := &Delta{
+		NumPlayers: numPlayers,
+		Metadata:   meta,
+		Values:     make(map[int][maxNumPlayers]float64),
+	}
+
+	numStates := calcNumDistinctStates(numPlayers)
+	buf := make([]byte, 8*(1+numPlayers))
+	for i := uint64(0); i < n; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		gsID := int(binary.LittleEndian.Uint64(buf))
+		if gsID < 0 || gsID >= numStates {
+			return nil, fmt.Errorf("%s: invalid game state: %d", path, gsID)
+		}
+
+		var pWin [maxNumPlayers]float64
+		for j := range pWin[:numPlayers] {
+			pWin[j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*(j+1):]))
+		}
+		delta.Values[gsID] = pWin
+	}
+
+	return delta, nil
+}
+
+// Store the values of all states in the delta in the given database,
+// which must be for the same number of players and objective.
+func (d *Delta) ApplyTo(db DB) error {
+	if db.NumPlayers() != d.NumPlayers || db.Metadata() != d.Metadata {
+		return fmt.Errorf("cannot apply %d-player %v delta to %d-player %v database",
+			d.NumPlayers, d.Metadata, db.NumPlayers(), db.Metadata())
+	}
+
+	for _, gsID := range slices.Sorted(maps.Keys(d.Values)) {
+		db.Put(gsID, d.Values[gsID])
+	}
+	return nil
+}
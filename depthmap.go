@@ -1,69 +1,36 @@
 package farkle
 
 import (
-	"encoding/binary"
-	"os"
-
-	"golang.org/x/sys/unix"
+	"fmt"
+	"math"
 )
 
-// TODO: Figure out how to generalize the FileDB struct
-// without incurring allocations.
+// The depth of each game state in the game tree, or 0 if it has not been
+// calculated yet, stored in a memory-mapped file.
 type depthMap struct {
-	f         *os.File
-	valueSize int
-	mmap      []byte
+	depths *mmapArray[uint32]
 }
 
 func newDepthMap(path string, numStates int) (*depthMap, error) {
-	f, err := os.Create(path)
+	depths, err := newMmapArray[uint32](path, numStates)
 	if err != nil {
 		return nil, err
 	}
 
-	valueSize := 8
-	fileSize := valueSize * numStates
-	if err := f.Truncate(int64(fileSize)); err != nil {
-		_ = f.Close()
-		return nil, err
-	}
-
-	flags := unix.MAP_SHARED
-	prot := unix.PROT_READ | unix.PROT_WRITE
-	mmap, err := unix.Mmap(int(f.Fd()), 0, fileSize, prot, flags)
-	if err != nil {
-		_ = f.Close()
-		return nil, err
-	}
-
-	return &depthMap{
-		f:         f,
-		mmap:      mmap,
-		valueSize: valueSize,
-	}, nil
+	return &depthMap{depths: depths}, nil
 }
 
 func (dm *depthMap) Set(id int, depth int) {
-	idx := dm.valueSize * id
-	buf := dm.mmap[idx : idx+dm.valueSize]
-	binary.LittleEndian.PutUint64(buf, uint64(depth))
+	if depth > math.MaxUint32 {
+		panic(fmt.Errorf("depth of game state %d is too large: %d", id, depth))
+	}
+	dm.depths.Set(id, uint32(depth))
 }
 
 func (dm *depthMap) Get(id int) int {
-	idx := dm.valueSize * id
-	buf := dm.mmap[idx : idx+dm.valueSize]
-	return int(binary.LittleEndian.Uint64(buf))
+	return int(dm.depths.Get(id))
 }
 
 func (dm *depthMap) Close() error {
-	defer dm.f.Close()
-
-	if err := unix.Msync(dm.mmap, unix.MS_SYNC); err != nil {
-		return err
-	}
-	if err := unix.Munmap(dm.mmap); err != nil {
-		return err
-	}
-
-	return dm.f.Close()
+	return dm.depths.Close()
 }
@@ -0,0 +1,87 @@
+package farkle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Descriptions of rolls and actions in plain sentences, with the number of
+// each die in words, for screen readers and other text-only interfaces.
+
+// Describe the dice in words, e.g. "one 1, two 3s, one 4 and two 6s".
+func DescribeDice(roll Roll) string {
+	if roll.NumDice() == 0 {
+		return "no dice"
+	}
+
+	var parts []string
+	for die, count := range roll {
+		if count == 0 {
+			continue
+		}
+		part := fmt.Sprintf("%s %d", countWords[count], die)
+		if count > 1 {
+			part += "s"
+		}
+		parts = append(parts, part)
+	}
+	return joinWords(parts, "and")
+}
+
+// Describe the dice that may be kept from a roll, e.g. "You may keep one 1
+// for 100 points, one 5 for 50 points or one 1 and one 5 for 150 points."
+// Each legal hold is listed with its score, from LegalHolds and ExplainScore.
+func DescribeHolds(roll Roll) string {
+	holds := LegalHolds(roll)
+	if len(holds) == 0 {
+		return "None of the dice score, so that is a farkle."
+	}
+
+	options := make([]string, len(holds))
+	for i, held := range holds {
+		total, _, _ := ExplainScore(held)
+		options[i] = fmt.Sprintf("%s for %d points", DescribeDice(held), total)
+	}
+	return fmt.Sprintf("You may keep %s.", joinWords(options, "or"))
+}
+
+// Describe the action taken in response to a roll in the given state, e.g.
+// "Keep one 1 for 100 points, making 400 this turn, and roll the other
+// five dice."
+func DescribeAction(state GameState, roll Roll, action Action) string {
+	if IsFarkle(roll) {
+		if state.ScoreThisRound == 0 {
+			return "Farkle. The turn ends."
+		}
+		return fmt.Sprintf("Farkle. The turn ends, losing %d points.", incr*int(state.ScoreThisRound))
+	}
+
+	held := action.HeldDice()
+	total, _, _ := ExplainScore(held)
+	turnScore := incr*int(state.ScoreThisRound) + total
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Keep %s for %d points, making %d this turn, ", DescribeDice(held), total, turnScore)
+	if !action.ContinueRolling {
+		fmt.Fprintf(&sb, "and bank them.")
+		return sb.String()
+	}
+
+	remaining := int(state.NumDiceToRoll) - int(held.NumDice())
+	switch remaining {
+	case 0:
+		fmt.Fprintf(&sb, "and roll all %s dice again.", countWords[turnNumDice])
+	case 1:
+		sb.WriteString("and roll the last die.")
+	default:
+		fmt.Fprintf(&sb, "and roll the other %s dice.", countWords[remaining])
+	}
+	return sb.String()
+}
+
+// Join the items into a list, e.g. "a, b and c" with conjunction "and".
+func joinWords(items []string, conjunction string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
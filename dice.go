@@ -2,7 +2,10 @@ package farkle
 
 import (
 	"fmt"
+	"hash/fnv"
+	"math"
 	"math/rand"
+	"slices"
 )
 
 const MaxNumDice = 6
@@ -32,14 +35,192 @@ func NewRoll(dice ...uint8) Roll {
 }
 
 func NewRandomRoll(numDice int) Roll {
+	return rollDice(numDice, rand.Intn)
+}
+
+// Sample a roll of the given number of dice from the distribution of distinct
+// rolls, returning its roll ID too. This draws one random number instead of
+// one per die, and needs no lookup of the roll ID, so it is cheaper than
+// NewRandomRoll in simulations. It uses the random numbers differently, so it
+// does not reproduce the rolls of NewRandomRoll for the same seed.
+func SampleRoll(numDice int, rng *rand.Rand) (Roll, uint16) {
+	table := rollAliasTables[numDice]
+	// The integer part picks a column, and the fraction one of its two rolls.
+	x := rng.Float64() * float64(len(table))
+	i := int(x)
+	col := table[i]
+	j := col.alias
+	if x-float64(i) < col.threshold {
+		j = i
+	}
+	wRoll := allRolls[numDice][j]
+	return wRoll.Roll, wRoll.ID
+}
+
+// A column of an alias table (Vose's method): roll i of the column is chosen
+// with probability threshold, and roll alias otherwise.
+type aliasColumn struct {
+	threshold float64
+	alias     int
+}
+
+// For each number of dice, the alias table of the distinct rolls in allRolls.
+var rollAliasTables = calcRollAliasTables()
+
+func calcRollAliasTables() [MaxNumDice + 1][]aliasColumn {
+	var result [MaxNumDice + 1][]aliasColumn
+	for nDice, rolls := range allRolls {
+		n := len(rolls)
+		table := make([]aliasColumn, n)
+		scaled := make([]float64, n)
+		var small, large []int
+		for i, wRoll := range rolls {
+			scaled[i] = wRoll.Prob * float64(n)
+			if scaled[i] < 1 {
+				small = append(small, i)
+			} else {
+				large = append(large, i)
+			}
+		}
+
+		for len(small) > 0 && len(large) > 0 {
+			s, l := small[len(small)-1], large[len(large)-1]
+			small = small[:len(small)-1]
+			table[s] = aliasColumn{threshold: scaled[s], alias: l}
+			scaled[l] -= 1 - scaled[s]
+			if scaled[l] < 1 {
+				large = large[:len(large)-1]
+				small = append(small, l)
+			}
+		}
+		// The rest are full, up to rounding.
+		for _, i := range slices.Concat(small, large) {
+			table[i] = aliasColumn{threshold: 1, alias: i}
+		}
+		result[nDice] = table
+	}
+	return result
+}
+
+// The resolution of the random numbers drawn for each loaded die.
+const dieResolution = 1 << 30
+
+// For loaded dice, the cumulative probability of each face and those below
+// it, scaled to dieResolution, or nil for fair dice.
+var faceThresholds []int
+
+// For each player, in order of play, the probability of each roll of their
+// dice (see Rules.PlayerDice), indexed as allRolls.
+var playerRollProbs = calcPlayerRollProbs(DefaultRules)
+
+func calcPlayerRollProbs(r Rules) [maxNumPlayers][MaxNumDice + 1][]float64 {
+	var result [maxNumPlayers][MaxNumDice + 1][]float64
+	for player := range result {
+		result[player] = calcRollProbs(r.PlayerDice(player))
+	}
+	return result
+}
+
+// Set the probabilities of the faces of the dice to those of the rules: the
+// probability of each roll in allRolls, the alias tables, faceThresholds and
+// playerRollProbs. The roll IDs do not change.
+func setFaceProbs(r Rules) {
+	probs := calcRollProbs(r.DieWeights)
+	for nDice, rolls := range allRolls {
+		for i := range rolls {
+			rolls[i].Prob = probs[nDice][i]
+		}
+	}
+	rollAliasTables = calcRollAliasTables()
+	faceThresholds = calcFaceThresholds(r.DieWeights)
+	playerRollProbs = calcPlayerRollProbs(r)
+}
+
+// The probability of each roll of dice with the given weights, indexed as
+// allRolls.
+func calcRollProbs(w DieWeights) [MaxNumDice + 1][]float64 {
+	var result [MaxNumDice + 1][]float64
+	faceProbs := w.Probs()
+	for nDice, rolls := range allRolls {
+		total := math.Pow(numSides, float64(nDice))
+		result[nDice] = make([]float64, len(rolls))
+		for i, wRoll := range rolls {
+			if w.Fair() {
+				// Exactly as in makeWeightedRolls.
+				result[nDice][i] = float64(numOrderings(wRoll.Roll)) / total
+				continue
+			}
+
+			p := float64(numOrderings(wRoll.Roll))
+			for die, count := range wRoll.Roll[1:] {
+				p *= math.Pow(faceProbs[die], float64(count))
+			}
+			result[nDice][i] = p
+		}
+	}
+	return result
+}
+
+func calcFaceThresholds(w DieWeights) []int {
+	if w.Fair() {
+		return nil
+	}
+
+	result := make([]int, numSides)
+	last := 0
+	cumulative := 0.0
+	for i, p := range w.Probs() {
+		cumulative += p
+		result[i] = int(cumulative * dieResolution)
+		if p > 0 {
+			last = i
+		}
+	}
+	// Faces with no weight are never rolled, despite rounding.
+	for i := last; i < numSides; i++ {
+		result[i] = dieResolution
+	}
+	return result
+}
+
+// The number of orders in which the dice of the roll may be rolled.
+func numOrderings(roll Roll) int {
+	result := 1
+	n := 0
+	for _, count := range roll {
+		for k := 1; k <= int(count); k++ {
+			n++
+			// The product of consecutive integers is divisible by k.
+			result = result * n / k
+		}
+	}
+	return result
+}
+
+// Roll the given number of dice, using intn as the source of randomness.
+func rollDice(numDice int, intn func(int) int) Roll {
 	var roll Roll
 	for i := 0; i < numDice; i++ {
-		die := 1 + rand.Intn(numSides)
-		roll[die]++
+		roll[rollDie(intn)]++
 	}
 	return roll
 }
 
+// Roll one die, loaded as in the rules in effect, using intn as the source of
+// randomness.
+func rollDie(intn func(int) int) int {
+	if faceThresholds == nil {
+		return 1 + intn(numSides)
+	}
+	x := intn(dieResolution)
+	for i, threshold := range faceThresholds {
+		if x < threshold {
+			return i + 1
+		}
+	}
+	return numSides
+}
+
 func RepeatedRoll(die uint8, n uint8) Roll {
 	if die < 1 || die > numSides {
 		panic(fmt.Errorf("cannot create Roll with die = %d", die))
@@ -125,7 +306,8 @@ type WeightedRoll struct {
 	Prob float64
 }
 
-// Make all distinct combinations of N dice.
+// Make all distinct combinations of N dice, in canonical order
+// (ascending lexicographic order of their sorted dice).
 func makeWeightedRolls(nDice int) []WeightedRoll {
 	rollToFreq := make(map[Roll]int)
 	totalCount := 0
@@ -134,20 +316,37 @@ func makeWeightedRolls(nDice int) []WeightedRoll {
 		totalCount++
 	}
 
-	result := make([]WeightedRoll, 0, len(rollToFreq))
-	rollID := uint16(0)
-	for roll, count := range rollToFreq {
+	rolls := make([]Roll, 0, len(rollToFreq))
+	for roll := range rollToFreq {
+		rolls = append(rolls, roll)
+	}
+	// Map iteration order is random, so the rolls must be sorted
+	// for roll IDs to be stable across runs.
+	slices.SortFunc(rolls, func(a, b Roll) int {
+		return slices.Compare(a.Dice(), b.Dice())
+	})
+
+	result := make([]WeightedRoll, 0, len(rolls))
+	for rollID, roll := range rolls {
 		result = append(result, WeightedRoll{
 			Roll: roll,
-			ID:   rollID,
-			Prob: float64(count) / float64(totalCount),
+			ID:   uint16(rollID),
+			Prob: float64(rollToFreq[roll]) / float64(totalCount),
 		})
-		rollID++
 	}
 
 	return result
 }
 
+// Version of the roll table, i.e. the assignment of IDs to rolls.
+// Anything persisted that is keyed by roll ID must record this version,
+// and be regenerated if it changes.
+const RollTableVersion = 1
+
+// Checksum of the roll table for RollTableVersion. It is verified at startup,
+// so that any change to the assignment of roll IDs is caught and the version bumped.
+const rollTableChecksum = 0x38567b2061a62c5b
+
 // All possible distinct rolls of N dice.
 var allRolls = func() [MaxNumDice + 1][]WeightedRoll {
 	var result [MaxNumDice + 1][]WeightedRoll
@@ -199,6 +398,11 @@ var rollsByID = func() []Roll {
 	return result
 }()
 
+// All distinct rolls of N dice, and the probability of each.
+func PossibleRolls(numDice int) []WeightedRoll {
+	return slices.Clone(allRolls[numDice])
+}
+
 func GetRollID(roll Roll) uint16 {
 	id, ok := rollToID[roll]
 	if !ok {
@@ -207,6 +411,28 @@ func GetRollID(roll Roll) uint16 {
 	return id
 }
 
+// Hash of all rolls in order of their roll IDs.
+func calcRollTableChecksum() uint64 {
+	h := fnv.New64a()
+	for _, roll := range rollsByID {
+		h.Write(roll[:])
+	}
+	return h.Sum64()
+}
+
+// Check the roll table version recorded in persisted data. Zero is the
+// version of data written before it was recorded, which was version 1.
+func checkRollTableVersion(version uint32) error {
+	if version == 0 {
+		version = 1
+	}
+	if version != RollTableVersion {
+		return fmt.Errorf("written with roll table version %d, not %d, and must be regenerated",
+			version, RollTableVersion)
+	}
+	return nil
+}
+
 // Lookup of the number of dice for each roll ID.
 var rollNumDice = func() []uint8 {
 	result := make([]uint8, nDistinctRolls)
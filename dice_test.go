@@ -0,0 +1,45 @@
+package farkle
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// SampleRoll draws each roll, and its ID, with the probability of the roll
+// under the rules in effect (a chi-square test with a fixed seed).
+func TestSampleRoll(t *testing.T) {
+	const numSamples = 1 << 20
+	for _, spec := range []string{"standard", "standard,weights=2:1:1:1:1:3"} {
+		setTestRules(t, spec)
+		rng := rand.New(rand.NewSource(benchSeed))
+		for numDice := 1; numDice <= MaxNumDice; numDice++ {
+			rolls := PossibleRolls(numDice)
+			index := make(map[uint16]int, len(rolls))
+			for i, wRoll := range rolls {
+				index[wRoll.ID] = i
+			}
+
+			counts := make([]int, len(rolls))
+			for range numSamples {
+				roll, rollID := SampleRoll(numDice, rng)
+				if GetRollID(roll) != rollID {
+					t.Fatalf("%s: sampled roll %v with ID %d, want %d", spec, roll, rollID, GetRollID(roll))
+				}
+				counts[index[rollID]]++
+			}
+
+			chiSquare := 0.0
+			for i, wRoll := range rolls {
+				expected := numSamples * wRoll.Prob
+				chiSquare += (float64(counts[i]) - expected) * (float64(counts[i]) - expected) / expected
+			}
+			// Five standard deviations above the mean of the distribution.
+			df := float64(len(rolls) - 1)
+			if limit := df + 5*math.Sqrt(2*df); chiSquare > limit {
+				t.Errorf("%s: rolls of %d dice have chi-square %.1f with %v degrees of freedom, want at most %.1f",
+					spec, numDice, chiSquare, df, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || dragonfly)
+
+package farkle
+
+// Free disk space cannot be checked on this platform.
+func checkDiskSpace(path string, size int64) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package farkle
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Check that the filesystem holding path has room for a file of the given
+// size, so that a sparse database does not fill the disk late into a solve.
+func checkDiskSpace(path string, size int64) error {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(filepath.Dir(path), &stat); err != nil {
+		Logger().Warn("Unable to check free disk space", "path", path, "err", err)
+		return nil
+	}
+
+	available := int64(stat.Bavail) * int64(stat.Bsize)
+	if available < size {
+		return fmt.Errorf("%s needs %d MiB of disk space, but only %d MiB is available",
+			path, size>>20, available>>20)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package farkle
+
+import (
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// The resources needed to solve the game with the rules in effect, estimated
+// before committing to a solve that may take days.
+type SolveEstimate struct {
+	NumPlayers int
+	// The number of distinct states, each of which has a value in the database.
+	NumStates int
+	// The number of states before the end of the game. This is an upper bound
+	// on the number of states in the sorted game states and updated in each
+	// value iteration cycle, since not all of them are reachable.
+	NumOpenStates int
+	// The size of the database file.
+	DBBytes int64
+	// The size of the sorted game states file, at most.
+	GameStatesBytes int64
+	// The temporary disk space used to enumerate and sort the game states, at most.
+	ScratchBytes int64
+	// The number of states updated per second by each CPU, measured on random
+	// states with the values held in memory.
+	StatesPerSecondPerCPU float64
+	NumCPU                int
+}
+
+// The time each value iteration cycle takes, if the database fits in memory
+// and the workers keep every CPU busy. Solves that page the database in and
+// out take longer.
+func (e SolveEstimate) CycleTime() time.Duration {
+	if e.StatesPerSecondPerCPU == 0 {
+		return 0
+	}
+	seconds := float64(e.NumOpenStates) / (e.StatesPerSecondPerCPU * float64(e.NumCPU))
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Estimate the resources needed to solve the game for numPlayers players,
+// timing updates of random states for sampleTime to measure the speed of this
+// machine.
+func EstimateSolve(numPlayers int, sampleTime time.Duration, rng *rand.Rand) SolveEstimate {
+	numStates := calcNumDistinctStates(numPlayers)
+	numOpen := numOpenStates(numPlayers)
+	gameStatesBytes := int64(numOpen) * int64(8+numPlayers+3)
+	result := SolveEstimate{
+		NumPlayers:      numPlayers,
+		NumStates:       numStates,
+		NumOpenStates:   numOpen,
+		DBBytes:         dbHeaderSize + 8*int64(numPlayers)*int64(numStates),
+		GameStatesBytes: gameStatesBytes,
+		// The depth of every state, and the states being sorted.
+		ScratchBytes: 4*int64(numStates) + gameStatesBytes,
+		NumCPU:       runtime.NumCPU(),
+	}
+
+	// Values of states that have not been solved yet are as quick to look up
+	// as any others, so an empty database suffices.
+	db := NewInMemoryDB(numPlayers)
+	n := 0
+	start := time.Now()
+	for n == 0 || time.Since(start) < sampleTime {
+		state := NewGameState(numPlayers)
+		state.PlayerScores[0] = uint8(rng.Intn(int(scoreToWin)))
+		for i := 1; i < numPlayers; i++ {
+			state.PlayerScores[i] = uint8(rng.Intn(1 << numScoreBits))
+		}
+		state.ScoreThisRound = uint8(rng.Intn(1 << numScoreBits))
+		state.NumDiceToRoll = uint8(1 + rng.Intn(int(turnNumDice)))
+		db.Put(state.ID(), calcStateValue(state, db))
+		n++
+	}
+	result.StatesPerSecondPerCPU = float64(n) / time.Since(start).Seconds()
+
+	return result
+}
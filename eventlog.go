@@ -0,0 +1,110 @@
+package farkle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// A decision made during a game, with the actions that were available and the
+// outcome of the game, e.g. as training data for a policy. Event logs are
+// stored as JSON lines, with one GameEvent per roll.
+type GameEvent struct {
+	// Index of the game in the log, starting from 0.
+	Game int `json:"game"`
+	// Seat of the player who rolled. Seat 0 is the player who went first.
+	Seat int `json:"seat"`
+	// Game state before the roll, from the point of view of the current player.
+	State GameState `json:"state"`
+	Roll  Roll      `json:"roll"`
+	// All legal actions for this roll. Empty if the roll is a farkle.
+	Actions []Action `json:"actions"`
+	// The value of each legal action for the current player, if the log
+	// was written with a database.
+	ActionValues []float64 `json:"actionValues,omitempty"`
+	// The action that was taken.
+	Action Action `json:"action"`
+	// Final score of each player, in points, ordered by seat.
+	FinalScores []int `json:"finalScores"`
+	// The result of the game for the player who rolled:
+	// 1 for a win, 1/k for a k-way tie and 0 for a loss.
+	Outcome float64 `json:"outcome"`
+}
+
+// Records the decisions made in games as they are played (see
+// PlayGameObserved). The events of each game are written once it is finished,
+// so that they include its outcome.
+type EventLogWriter struct {
+	enc     *json.Encoder
+	db      DB
+	game    int
+	pending []GameEvent
+}
+
+// Write events to w. If db is not nil, each event includes the value
+// of every legal action according to db.
+func NewEventLogWriter(w io.Writer, db DB) *EventLogWriter {
+	return &EventLogWriter{enc: json.NewEncoder(w), db: db}
+}
+
+// Record a roll and the action taken in response to it. This is a GameObserver.
+func (lw *EventLogWriter) Observe(seat int, state GameState, roll Roll, action Action) {
+	actions := LegalActions(state, roll)
+	if actions == nil {
+		actions = []Action{}
+	}
+	event := GameEvent{
+		Game:    lw.game,
+		Seat:    seat,
+		State:   state,
+		Roll:    roll,
+		Actions: actions,
+		Action:  action,
+	}
+	if lw.db != nil {
+		event.ActionValues = make([]float64, len(actions))
+		for i, a := range actions {
+			event.ActionValues[i] = EvaluateAction(state, a, lw.db)[0]
+		}
+	}
+
+	lw.pending = append(lw.pending, event)
+}
+
+// Write all events of the finished game with the given result.
+func (lw *EventLogWriter) FinishGame(result GameResult) error {
+	for _, event := range lw.pending {
+		event.FinalScores = result.Scores
+		for _, winner := range result.Winners {
+			if winner == event.Seat {
+				event.Outcome = 1 / float64(len(result.Winners))
+			}
+		}
+
+		if err := lw.enc.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	lw.pending = lw.pending[:0]
+	lw.game++
+	return nil
+}
+
+// Read all events in an event log.
+func ReadEventLog(r io.Reader) ([]GameEvent, error) {
+	var result []GameEvent
+	dec := json.NewDecoder(r)
+	for {
+		var event GameEvent
+		if err := dec.Decode(&event); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return result, err
+		}
+
+		result = append(result, event)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,295 @@
+package farkle
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Solve the game exactly, in a single pass over the scores of the players
+// from the end of the game backwards, instead of by value iteration.
+//
+// Banking always increases the total score of the players, so the states
+// after banking are solved before the states of the turn. Farkling passes
+// the turn on with the same scores, so the players' turns with scores that
+// are rotations of each other (e.g. 500-1000 and 1000-500) depend on each
+// other in a cycle. For a given strategy, the value of each state of a turn
+// is an affine function of the value after a farkle, which the turn reaches
+// with some probability. The values of the states in each cycle are solved
+// from these exactly, and the strategy re-optimized until it no longer
+// changes (policy iteration), which takes a few rounds.
+//
+// All states before the end of the game are solved, including those that
+// cannot be reached, except for scores below the opening score. The total
+// score reached is saved to chkpntPath (optional), so that an interrupted
+// solve can be resumed. Returns statistics about the update of the states
+// with each total score, as depths.
+//
+// The cycles of turns with each total score are solved concurrently, and
+// their states are disjoint, so each worker stores its values without waiting
+// for the others if db may be written concurrently (e.g. a FileDB).
+func SolveExact(db DB, chkpntPath string) UpdateStats {
+	s := &exactSolver{
+		db:         concurrentPutsDB(db),
+		meta:       db.Metadata(),
+		numPlayers: db.NumPlayers(),
+	}
+	maxSum := s.numPlayers * math.MaxUint8
+	numDone := int(loadCheckpoint(chkpntPath))
+	lastCheckpointTime := time.Now()
+
+	var stats UpdateStats
+	for sum := maxSum - numDone; sum >= 0; sum-- {
+		if chkpntPath != "" && time.Since(lastCheckpointTime) > checkpointInterval {
+			if err := saveCheckpoint(chkpntPath, uint64(maxSum-sum)); err != nil {
+				Logger().Warn("Unable to save checkpoint", "err", err)
+			}
+			lastCheckpointTime = time.Now()
+		}
+
+		Logger().Info("Solving game states", "total_score", incr*sum)
+		stats.Depths = append(stats.Depths, s.solveSum(sum))
+	}
+	return stats
+}
+
+type exactSolver struct {
+	// Safe for concurrent Puts of different states.
+	db         DB
+	meta       Metadata
+	numPlayers int
+}
+
+// Solve the states whose scores add up to sum, all of which only depend on
+// states with a greater total score, and on each other.
+func (s *exactSolver) solveSum(sum int) DepthStats {
+	start := time.Now()
+	var cycles [][maxNumPlayers]uint8
+	var scores [maxNumPlayers]uint8
+	s.enumerateScores(scores, 0, sum, func(scores [maxNumPlayers]uint8) {
+		if s.isCanonical(scores) {
+			cycles = append(cycles, scores)
+		}
+	})
+
+	numWorkers := runtime.NumCPU()
+	workCh := make(chan [maxNumPlayers]uint8, numWorkers)
+	var wg sync.WaitGroup
+	var statsMx sync.Mutex
+	result := DepthStats{Depth: uint64(sum)}
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			var tables [maxNumPlayers]TurnSolution
+			var workerStats DepthStats
+			for scores := range workCh {
+				s.solveCycle(scores, &tables, &workerStats)
+			}
+			statsMx.Lock()
+			result.merge(workerStats)
+			statsMx.Unlock()
+		}()
+	}
+	for _, scores := range cycles {
+		workCh <- scores
+	}
+	close(workCh)
+	wg.Wait()
+
+	result.Elapsed = time.Since(start)
+	return result
+}
+
+// Call f with all scores of the players from i on that add up to sum, with
+// the scores of the players before i given.
+func (s *exactSolver) enumerateScores(scores [maxNumPlayers]uint8, i, sum int, f func([maxNumPlayers]uint8)) {
+	if i == s.numPlayers-1 {
+		if sum <= math.MaxUint8 {
+			scores[i] = uint8(sum)
+			f(scores)
+		}
+		return
+	}
+
+	for score := max(0, sum-(s.numPlayers-1-i)*math.MaxUint8); score <= min(sum, math.MaxUint8); score++ {
+		scores[i] = uint8(score)
+		s.enumerateScores(scores, i+1, sum-score, f)
+	}
+}
+
+// Whether the given scores are the first of their rotations, and there is a
+// player to move with them before the end of the game.
+func (s *exactSolver) isCanonical(scores [maxNumPlayers]uint8) bool {
+	open := false
+	for i, score := range scores[:s.numPlayers] {
+		if openingScore > 0 && score > 0 && score < openingScore {
+			return false // Unreachable.
+		}
+		open = open || score < scoreToWin
+
+		rotated := rotateScores(scores, s.numPlayers, i)
+		for j := range scores[:s.numPlayers] {
+			if rotated[j] != scores[j] {
+				if rotated[j] < scores[j] {
+					return false
+				}
+				break
+			}
+		}
+	}
+	return open
+}
+
+// The scores after the turn passes k times without any player banking.
+func rotateScores(scores [maxNumPlayers]uint8, numPlayers, k int) [maxNumPlayers]uint8 {
+	var result [maxNumPlayers]uint8
+	for i := range result[:numPlayers] {
+		result[i] = scores[(i+k)%numPlayers]
+	}
+	return result
+}
+
+// Solve the turns of the players with the given scores, and with all of
+// their rotations, and store the values of their states.
+func (s *exactSolver) solveCycle(scores [maxNumPlayers]uint8, tables *[maxNumPlayers]TurnSolution, stats *DepthStats) {
+	// The turns in the cycle, each of which farkles into the next.
+	var turns [maxNumPlayers]GameState
+	k := 0
+	for k == 0 || rotateScores(scores, s.numPlayers, k) != scores {
+		turns[k] = NewGameState(s.numPlayers)
+		turns[k].PlayerScores = rotateScores(scores, s.numPlayers, k)
+		k++
+	}
+
+	var x [maxNumPlayers][maxNumPlayers]float64
+	gameOver := -1
+	for r, turn := range turns[:k] {
+		if turn.IsGameOver() {
+			x[r] = calcEndGameValue(turn, s.meta)
+			gameOver = r
+		} else {
+			x[r] = s.db.Get(turn.ID())
+		}
+	}
+
+	if gameOver >= 0 {
+		// The cycle is broken by the end of the game, so each turn can be
+		// solved once the turn after it is.
+		for i := 1; i < k; i++ {
+			r := (gameOver - i + k) % k
+			if turns[r].IsGameOver() {
+				continue
+			}
+			solveTurn(&tables[r], turns[r], s.db, s.meta, fromNextTurn(x[(r+1)%k], s.numPlayers))
+			x[r] = tables[r].values[0][turnNumDice]
+		}
+	} else {
+		s.solveTurns(turns[:k], &x, tables)
+	}
+
+	for r, turn := range turns[:k] {
+		if !turn.IsGameOver() {
+			s.storeTurn(turn, &tables[r], stats)
+		}
+	}
+}
+
+// The maximum number of rounds of policy iteration for a cycle of turns.
+const maxPolicyIterations = 100
+
+// Solve a cycle of turns, none of which is at the end of the game, starting
+// from the values x of the first state of each turn.
+func (s *exactSolver) solveTurns(turns []GameState, x *[maxNumPlayers][maxNumPlayers]float64, tables *[maxNumPlayers]TurnSolution) {
+	k := len(turns)
+	n := s.numPlayers
+	for i := 0; i < maxPolicyIterations; i++ {
+		// With the strategy that is optimal for the current values after
+		// farkles, x[r] = c[r] + q[r] * U x[r+1], where U unrotates the values
+		// of the next player's turn.
+		var c [maxNumPlayers][maxNumPlayers]float64
+		var q [maxNumPlayers]float64
+		for r, turn := range turns {
+			solveTurn(&tables[r], turn, s.db, s.meta, fromNextTurn(x[(r+1)%k], s.numPlayers))
+			q[r] = tables[r].pFarkle[0][turnNumDice]
+			c[r] = tables[r].values[0][turnNumDice]
+			next := unrotate(x[(r+1)%k], uint8(n))
+			for p := range c[r][:n] {
+				c[r][p] -= q[r] * next[p]
+			}
+		}
+
+		// Going around the cycle once, x[0] = b + coef * U^k x[0].
+		b := c[k-1]
+		coef := q[k-1]
+		for r := k - 2; r >= 0; r-- {
+			b = s.affine(c[r], q[r], b)
+			coef *= q[r]
+		}
+		// U^k has order n/k, so x[0] = sum((coef U^k)^j b) / (1 - coef^(n/k)).
+		var x0, term [maxNumPlayers]float64
+		term = b
+		for j := 0; j < n/k; j++ {
+			mixInto(&x0, 1, &term)
+			for range k {
+				term = unrotate(term, uint8(n))
+			}
+			for p := range term[:n] {
+				term[p] *= coef
+			}
+		}
+		scale := 1 / (1 - math.Pow(coef, float64(n/k)))
+		for p := range x0[:n] {
+			x0[p] *= scale
+		}
+
+		next := *x
+		next[0] = x0
+		for r := k - 1; r > 0; r-- {
+			next[r] = s.affine(c[r], q[r], next[(r+1)%k])
+		}
+
+		change := 0.0
+		for r := range turns {
+			for p := range n {
+				change = max(change, math.Abs(next[r][p]-x[r][p])/max(1, math.Abs(next[r][p])))
+			}
+		}
+		*x = next
+		if change < 1e-13 {
+			return
+		}
+	}
+
+	Logger().Warn("Strategy did not converge", "state", turns[0])
+}
+
+// c + q * U y, where U unrotates the values of the next player's turn.
+func (s *exactSolver) affine(c [maxNumPlayers]float64, q float64, y [maxNumPlayers]float64) [maxNumPlayers]float64 {
+	y = unrotate(y, uint8(s.numPlayers))
+	for p := range c[:s.numPlayers] {
+		c[p] += q * y[p]
+	}
+	return c
+}
+
+// Store the values of all states of a solved turn.
+func (s *exactSolver) storeTurn(turn GameState, t *TurnSolution, stats *DepthStats) {
+	state := turn
+	for scoreThisRound := range t.values {
+		state.ScoreThisRound = uint8(scoreThisRound)
+		for numDice := 1; numDice <= int(turnNumDice); numDice++ {
+			state.NumDiceToRoll = uint8(numDice)
+			gsID := state.ID()
+			pWin := t.values[scoreThisRound][numDice]
+			prev := s.db.Get(gsID)
+			change := 0.0
+			for p := range pWin[:s.numPlayers] {
+				change = max(change, math.Abs(pWin[p]-prev[p]))
+			}
+			stats.add(change)
+			s.db.Put(gsID, pWin)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package farkle
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// SolveExact finds the same values as value iteration run to convergence, in
+// one- and two-player games and with a FileDB, which it writes without a lock.
+func TestSolveExactMatchesValueIteration(t *testing.T) {
+	for _, game := range []struct {
+		rules      string
+		numPlayers int
+	}{
+		{"pocket-farkle,target=300,dice=3", 1},
+		{"standard,target=300,dice=3,opening=0", 1},
+		{"pocket-farkle,target=50,dice=2", 2},
+	} {
+		t.Run(game.rules, func(t *testing.T) {
+			if testing.Short() && game.numPlayers > 1 {
+				t.Skip("solves a two-player game by value iteration")
+			}
+			setTestRules(t, game.rules)
+			states := miniatureGameStates(t, game.numPlayers)
+
+			exact, err := NewFileDB(filepath.Join(t.TempDir(), "exact.db"), game.numPlayers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer exact.Close()
+			SolveExact(exact, "")
+
+			db := NewInMemoryDB(game.numPlayers)
+			solveByValueIteration(t, db, states, UpdateOptions{}, 1e-15)
+			checkValues(t, "SolveExact", states, exact, db, 1e-12)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package farkle
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Request sent to an external strategy for each roll.
+type externalRequest struct {
+	State GameState `json:"state"`
+	Roll  Roll      `json:"roll"`
+}
+
+// A strategy implemented by an external process.
+//
+// The process is sent one JSON object per line on stdin for each roll:
+//
+//	{"state":{"scores":[0,350],"turnScore":0,"numDice":6},"roll":[1,1,2,3,5,6]}
+//
+// and must reply with one JSON object per line on stdout:
+//
+//	{"held":[1,1,5],"continue":true}
+//
+// Scores are in points and the current player is always first.
+// Farkles are handled without consulting the process.
+type ExternalStrategy struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+	enc    *json.Encoder
+}
+
+// Start the given command as an external strategy.
+// Its stderr is passed through to our own.
+func NewExternalStrategy(name string, args ...string) (*ExternalStrategy, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &ExternalStrategy{
+		name:   strings.Join(append([]string{name}, args...), " "),
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewScanner(stdout),
+		enc:    json.NewEncoder(stdin),
+	}, nil
+}
+
+func (s *ExternalStrategy) SelectAction(state GameState, roll Roll) (Action, error) {
+	if IsFarkle(roll) {
+		return Action{}, nil
+	}
+
+	if err := s.enc.Encode(externalRequest{State: state, Roll: roll}); err != nil {
+		return Action{}, fmt.Errorf("%s: error sending roll: %w", s.name, err)
+	}
+	if !s.stdout.Scan() {
+		err := s.stdout.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return Action{}, fmt.Errorf("%s: error reading action: %w", s.name, err)
+	}
+
+	var action Action
+	if err := json.Unmarshal(s.stdout.Bytes(), &action); err != nil {
+		return Action{}, fmt.Errorf("%s: invalid action %q: %w", s.name, s.stdout.Text(), err)
+	}
+	return action, nil
+}
+
+func (s *ExternalStrategy) String() string {
+	return s.name
+}
+
+// Close stdin of the external process, and wait for it to exit.
+func (s *ExternalStrategy) Close() error {
+	if err := s.stdin.Close(); err != nil {
+		return err
+	}
+	return s.cmd.Wait()
+}
@@ -0,0 +1,166 @@
+// Package farkledata downloads pre-solved databases, so that the game can be
+// played without running the solver first.
+package farkledata
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Downloads are written to this suffix of the destination path,
+// and resumed from there if interrupted.
+const partialSuffix = ".partial"
+
+// Download the database at url to path, unless path already exists.
+// If sha256Hex is not empty, the downloaded file must have that SHA-256 hash.
+// Either way, it must pass farkle.VerifyDB before it is moved into place.
+func EnsureDB(ctx context.Context, url, path, sha256Hex string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if url == "" {
+		return fmt.Errorf("%s does not exist, and there is no URL to download it from", path)
+	}
+
+	return Download(ctx, url, path, sha256Hex)
+}
+
+// Download the database at url to path, resuming a previous partial download
+// if there is one. The result is verified as in EnsureDB.
+func Download(ctx context.Context, url, path, sha256Hex string) error {
+	partialPath := path + partialSuffix
+	if err := download(ctx, url, partialPath); err != nil {
+		return err
+	}
+
+	if sha256Hex != "" {
+		if err := checkSHA256(partialPath, sha256Hex); err != nil {
+			// Start over next time rather than resuming a corrupt download.
+			_ = os.Remove(partialPath)
+			return err
+		}
+	}
+
+	if err := farkle.VerifyDB(partialPath); err != nil {
+		_ = os.Remove(partialPath)
+		return err
+	}
+
+	return os.Rename(partialPath, path)
+}
+
+func download(ctx context.Context, url, path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		farkle.Logger().Info("Resuming download", "url", url, "offset", offset)
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	} else {
+		farkle.Logger().Info("Downloading", "url", url)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server does not support resuming, so start from the beginning.
+		if offset > 0 {
+			farkle.Logger().Info("Server does not support resuming, restarting download")
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Already downloaded in full.
+		return nil
+	default:
+		return fmt.Errorf("error downloading %s: %s", url, resp.Status)
+	}
+
+	total := offset + resp.ContentLength
+	w := &progressWriter{w: f, n: offset, total: total}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("error downloading %s: %w", url, err)
+	}
+
+	return f.Close()
+}
+
+// Log progress of a download every 100 MiB.
+type progressWriter struct {
+	w     io.Writer
+	n     int64
+	total int64 // Or less than n, if unknown.
+}
+
+const progressInterval = 100 * 1024 * 1024
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if (w.n+int64(n))/progressInterval > w.n/progressInterval {
+		if w.total >= w.n {
+			farkle.Logger().Info("Downloaded", "MiB", (w.n+int64(n))>>20, "totalMiB", w.total>>20)
+		} else {
+			farkle.Logger().Info("Downloaded", "MiB", (w.n+int64(n))>>20)
+		}
+	}
+	w.n += int64(n)
+	return n, err
+}
+
+func checkSHA256(path, sha256Hex string) error {
+	expected, err := hex.DecodeString(sha256Hex)
+	if err != nil {
+		return fmt.Errorf("invalid SHA-256: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := h.Sum(nil); string(got) != string(expected) {
+		return fmt.Errorf("downloaded file has SHA-256 %x, expected %s", got, sha256Hex)
+	}
+
+	return nil
+}
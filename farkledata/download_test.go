@@ -0,0 +1,164 @@
+package farkledata
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// A valid database, to be served for download.
+func testDB(t *testing.T) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "solved.db")
+	db, err := farkle.NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put(1, [4]float64{0.5})
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Serves data, recording the Range header of each request. If ranges is
+// false, the server ignores them and always sends the whole file.
+type testServer struct {
+	data   []byte
+	ranges bool
+
+	mx       sync.Mutex
+	requests []string
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	s.requests = append(s.requests, r.Header.Get("Range"))
+	s.mx.Unlock()
+	if s.ranges {
+		http.ServeContent(w, r, "solved.db", time.Time{}, bytes.NewReader(s.data))
+	} else {
+		w.Write(s.data)
+	}
+}
+
+func serve(t *testing.T, data []byte, ranges bool) (*testServer, string) {
+	t.Helper()
+	s := &testServer{data: data, ranges: ranges}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts.URL + "/solved.db"
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func checkDownloaded(t *testing.T, path string, want []byte) {
+	t.Helper()
+	if got, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, want) {
+		t.Errorf("downloaded %d bytes, want %d", len(got), len(want))
+	}
+	if _, err := os.Stat(path + partialSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial download was not removed: %v", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	data := testDB(t)
+	_, url := serve(t, data, true)
+	path := filepath.Join(t.TempDir(), "farkle.db")
+	if err := Download(context.Background(), url, path, sha256Hex(data)); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, path, data)
+}
+
+// Interrupted downloads are resumed where they left off, or restarted if the
+// server does not support resuming.
+func TestDownloadResume(t *testing.T) {
+	data := testDB(t)
+	for _, ranges := range []bool{true, false} {
+		s, url := serve(t, data, ranges)
+		path := filepath.Join(t.TempDir(), "farkle.db")
+		partial := data[:len(data)/2]
+		if err := os.WriteFile(path+partialSuffix, partial, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Download(context.Background(), url, path, sha256Hex(data)); err != nil {
+			t.Fatal(err)
+		}
+		checkDownloaded(t, path, data)
+		if want := "bytes=" + strconv.Itoa(len(partial)) + "-"; len(s.requests) != 1 || s.requests[0] != want {
+			t.Errorf("requests with Range %q, want %q", s.requests, want)
+		}
+	}
+}
+
+// Downloads that are corrupt are not moved into place, and are started over
+// the next time.
+func TestDownloadCorrupt(t *testing.T) {
+	data := testDB(t)
+	testCases := []struct {
+		name      string
+		data      []byte
+		sha256Hex string
+	}{
+		{"wrong SHA-256", data, sha256Hex(data[1:])},
+		{"truncated", data[:len(data)-1], ""},
+		{"not a database", bytes.Repeat([]byte{0xff}, len(data)), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, url := serve(t, tc.data, true)
+			path := filepath.Join(t.TempDir(), "farkle.db")
+			if err := Download(context.Background(), url, path, tc.sha256Hex); err == nil {
+				t.Fatal("downloaded a corrupt database")
+			}
+			for _, p := range []string{path, path + partialSuffix} {
+				if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s exists after a corrupt download: %v", filepath.Base(p), err)
+				}
+			}
+		})
+	}
+}
+
+// Databases that already exist are not downloaded again.
+func TestEnsureDB(t *testing.T) {
+	data := testDB(t)
+	s, url := serve(t, data, true)
+	path := filepath.Join(t.TempDir(), "farkle.db")
+	if err := EnsureDB(context.Background(), "", path, ""); err == nil {
+		t.Error("no error for a missing database without a URL")
+	}
+	for range 2 {
+		if err := EnsureDB(context.Background(), url, path, sha256Hex(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkDownloaded(t, path, data)
+	if len(s.requests) != 1 {
+		t.Errorf("%d requests, want 1", len(s.requests))
+	}
+}
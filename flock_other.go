@@ -0,0 +1,11 @@
+//go:build !unix || aix
+
+package farkle
+
+import "os"
+
+// Advisory file locks are not available on this platform,
+// so databases are not protected from concurrent writers.
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build unix && !aix
+
+package farkle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Take an advisory lock on f, which is released when it is closed.
+// Any number of processes may hold a shared lock, or one an exclusive lock.
+func lockFile(f *os.File, exclusive bool) error {
+	how := unix.LOCK_SH
+	if exclusive {
+		how = unix.LOCK_EX
+	}
+	if err := unix.Flock(int(f.Fd()), how|unix.LOCK_NB); err != nil {
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return fmt.Errorf("database is in use by another process")
+		}
+		return err
+	}
+	return nil
+}
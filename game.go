@@ -0,0 +1,233 @@
+package farkle
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// A game in progress, which enforces the rules and keeps track of whose turn
+// it is, so that every front-end plays the game the same way. Players take
+// turns in order of seat, starting from seat 0. Each roll must be answered
+// with an action (Action{} for a farkle) before the dice are rolled again.
+type Game struct {
+	observers []Observer
+
+	rng      *rand.Rand
+	state    GameState
+	seat     int
+	roll     Roll
+	dice     RollDetailed
+	rolled   bool
+	numTurns int
+	// The seat of the player who forfeited the game, if any.
+	forfeited int
+	forfeit   bool
+}
+
+func NewGame(numPlayers int, rng *rand.Rand) *Game {
+	return &Game{
+		rng:   rng,
+		state: NewGameState(numPlayers),
+	}
+}
+
+// Notify o of all future events in the game.
+func (g *Game) AddObserver(o Observer) {
+	g.observers = append(g.observers, o)
+}
+
+func (g *Game) NumPlayers() int {
+	return int(g.state.NumPlayers)
+}
+
+// The state of the game from the point of view of the current player.
+func (g *Game) State() GameState {
+	return g.state
+}
+
+// The seat of the player whose turn it is.
+func (g *Game) CurrentPlayer() int {
+	return g.seat
+}
+
+// The seat of the player at the given index of State().PlayerScores.
+func (g *Game) Seat(player int) int {
+	return (g.seat + player) % g.NumPlayers()
+}
+
+// The total number of turns taken by all players.
+func (g *Game) NumTurns() int {
+	return g.numTurns
+}
+
+func (g *Game) IsOver() bool {
+	return g.forfeit || g.state.IsGameOver()
+}
+
+// The player in the given seat forfeits, e.g. because they ran out of time,
+// ending the game. The player with the highest score among the others wins.
+func (g *Game) Forfeit(seat int) error {
+	if g.IsOver() {
+		return errors.New("game is over")
+	} else if seat < 0 || seat >= g.NumPlayers() {
+		return fmt.Errorf("invalid seat: %d", seat)
+	}
+
+	g.forfeit = true
+	g.forfeited = seat
+	g.rolled = false
+	result := g.Result()
+	for _, o := range g.observers {
+		o.OnGameOver(result)
+	}
+	return nil
+}
+
+// Roll the dice for the current player.
+func (g *Game) Roll() (Roll, error) {
+	if g.IsOver() {
+		return Roll{}, errors.New("game is over")
+	} else if g.rolled {
+		return Roll{}, errors.New("must respond to the last roll before rolling again")
+	}
+
+	g.dice = rollDiceDetailed(int(g.state.NumDiceToRoll), g.rng.Intn)
+	g.roll = g.dice.Roll()
+	g.rolled = true
+	for _, o := range g.observers {
+		o.OnRoll(g.seat, g.state, g.roll)
+	}
+	return g.roll, nil
+}
+
+// The dice of the last roll, in the order they were rolled, e.g. to show
+// them in a user interface. Empty before the first roll.
+func (g *Game) RolledDice() RollDetailed {
+	return g.dice
+}
+
+// Respond to the last roll, passing the turn to the next player if the
+// action does not continue rolling.
+func (g *Game) Apply(action Action) error {
+	if !g.rolled {
+		return errors.New("must roll before taking an action")
+	}
+	if err := ValidateAction(g.state, g.roll, action); err != nil {
+		return err
+	}
+
+	for _, o := range g.observers {
+		o.OnAction(g.seat, g.state, g.roll, action)
+	}
+
+	seat, state := g.seat, g.state
+	before := g.Scores()[seat]
+	g.state = ApplyAction(g.state, action)
+	g.rolled = false
+	if !action.ContinueRolling {
+		g.seat = (g.seat + 1) % g.NumPlayers()
+		g.numTurns++
+	}
+
+	isFarkle := IsFarkle(g.roll)
+	total := g.Scores()[seat]
+	for _, o := range g.observers {
+		if isFarkle {
+			o.OnFarkle(seat, state)
+		} else if !action.ContinueRolling {
+			o.OnBank(seat, total-before, total)
+		}
+		if g.IsOver() {
+			o.OnGameOver(g.Result())
+		}
+	}
+	return nil
+}
+
+// Each player's score in points, ordered by seat.
+func (g *Game) Scores() []int {
+	result := make([]int, g.NumPlayers())
+	for i, score := range g.state.PlayerScores[:g.NumPlayers()] {
+		result[g.Seat(i)] = incr * int(score)
+	}
+	return result
+}
+
+// The result of the game, once it is over.
+func (g *Game) Result() GameResult {
+	result := GameResult{
+		Scores:   g.Scores(),
+		NumTurns: g.numTurns,
+	}
+	if g.forfeit {
+		result.Forfeited = []int{g.forfeited}
+	}
+	winningScore := -1
+	for seat, score := range result.Scores {
+		if g.forfeit && seat == g.forfeited {
+			continue
+		} else if score > winningScore {
+			winningScore = score
+			result.Winners = nil
+		}
+		if score == winningScore {
+			result.Winners = append(result.Winners, seat)
+		}
+	}
+	return result
+}
+
+// The seat of the winner, if the game is over and was not tied.
+func (g *Game) Winner() (int, bool) {
+	if !g.IsOver() {
+		return 0, false
+	}
+
+	winners := g.Result().Winners
+	if len(winners) != 1 {
+		return 0, false
+	}
+	return winners[0], true
+}
+
+// Receives the events of a Game as they happen, e.g. to log, display or
+// collect statistics about games. Embed NopObserver to handle only some events.
+type Observer interface {
+	// The player in the given seat rolled, in the given state.
+	OnRoll(seat int, state GameState, roll Roll)
+	// The player in the given seat took an action in response to the roll,
+	// which is about to be applied to the given state.
+	OnAction(seat int, state GameState, roll Roll, action Action)
+	// The player in the given seat farkled, losing the turn score of the given state.
+	OnFarkle(seat int, state GameState)
+	// The player in the given seat ended their turn by banking the given
+	// number of points, for the given total score. Points banked before
+	// opening are lost, so they may be 0.
+	OnBank(seat, points, total int)
+	// The game is over.
+	OnGameOver(result GameResult)
+}
+
+// Observer that ignores all events.
+type NopObserver struct{}
+
+func (NopObserver) OnRoll(seat int, state GameState, roll Roll)                  {}
+func (NopObserver) OnAction(seat int, state GameState, roll Roll, action Action) {}
+func (NopObserver) OnFarkle(seat int, state GameState)                           {}
+func (NopObserver) OnBank(seat, points, total int)                               {}
+func (NopObserver) OnGameOver(result GameResult)                                 {}
+
+// Observer that calls observe with every action.
+func ActionObserver(observe GameObserver) Observer {
+	return actionObserver{observe: observe}
+}
+
+type actionObserver struct {
+	NopObserver
+	observe GameObserver
+}
+
+func (o actionObserver) OnAction(seat int, state GameState, roll Roll, action Action) {
+	o.observe(seat, state, roll, action)
+}
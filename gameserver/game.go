@@ -0,0 +1,564 @@
+package gameserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/stats"
+)
+
+var (
+	errNotYourTurn = errors.New("it is not your turn")
+	errWaiting     = errors.New("waiting for players to join")
+	errGameFull    = errors.New("all seats are taken")
+	errNotAPlayer  = errors.New("not a player in this game")
+)
+
+// A seat at a hosted game, taken by a remote client or a bot.
+type Seat struct {
+	Name string `json:"name"`
+	// The strategy of the bot in this seat, e.g. "threshold:300", if any.
+	Bot string `json:"bot,omitempty"`
+	// Whether a human has joined this seat.
+	Joined bool `json:"joined"`
+
+	token    string
+	strategy farkle.Strategy
+	// The ID of the remote player who joined this seat, in statistics.
+	player string
+}
+
+// Something that happened in a game, as streamed to clients.
+// Events are numbered from 1 in the order they happened.
+type Event struct {
+	ID int `json:"id"`
+	// "join", "roll", "action", "farkle", "bank", "forfeit" (when the player
+	// runs out of time, or a bot fails to act) or "gameOver".
+	Type string `json:"type"`
+	// The seat of the player concerned, if any.
+	Seat int `json:"seat"`
+
+	// Set for "roll" and "action" events.
+	Roll *farkle.Roll `json:"roll,omitempty"`
+	// Set for "roll" events: the dice in the order they were rolled, e.g. to
+	// animate them.
+	Dice []int `json:"dice,omitempty"`
+	// Set for "action" events.
+	Action *farkle.Action `json:"action,omitempty"`
+	// Set for "bank" events: the points banked and the player's new score.
+	Points int `json:"points,omitempty"`
+	Total  int `json:"total,omitempty"`
+	// Set for "join" events.
+	Name string `json:"name,omitempty"`
+	// Set for "gameOver" events.
+	Result *farkle.GameResult `json:"result,omitempty"`
+
+	// Set for "action" events in annotated games: the optimal action, and
+	// the win probability of the action taken and the optimal action.
+	Optimal     *farkle.Action `json:"optimal,omitempty"`
+	PWin        float64        `json:"pWin,omitempty"`
+	OptimalPWin float64        `json:"optimalPWin,omitempty"`
+
+	// Each player's probability of winning after the event, by seat, if the
+	// server can evaluate the game. After a roll, this assumes the optimal
+	// action will be taken.
+	WinProbs []float64 `json:"winProbs,omitempty"`
+}
+
+// The state of a hosted game, as sent to clients.
+type Snapshot struct {
+	ID string `json:"id"`
+	// The name of the lobby the game was created in, if any.
+	Name  string `json:"name,omitempty"`
+	Seats []Seat `json:"seats"`
+	// Each player's score in points, by seat.
+	Scores      []int `json:"scores"`
+	CurrentSeat int   `json:"currentSeat"`
+	TurnScore   int   `json:"turnScore"`
+	NumDice     int   `json:"numDice"`
+	// The roll awaiting an action from the current player, if any.
+	Roll      *farkle.Roll       `json:"roll,omitempty"`
+	Started   bool               `json:"started"`
+	Result    *farkle.GameResult `json:"result,omitempty"`
+	Annotated bool               `json:"annotated"`
+	// Each player's current probability of winning, by seat, if the server
+	// can evaluate the game.
+	WinProbs []float64 `json:"winProbs,omitempty"`
+	// The time each remote player has for each move, if limited, and the
+	// time by which the current player must move.
+	MoveTimeLimitMs int        `json:"moveTimeLimitMs,omitempty"`
+	Deadline        *time.Time `json:"deadline,omitempty"`
+	// The ID of the last event, from which to stream further events.
+	LastEventID int `json:"lastEventId"`
+}
+
+// The state of the game from the point of view of the current player, e.g.
+// to choose their action with a farkle.Strategy.
+func (s Snapshot) State() (farkle.GameState, error) {
+	n := len(s.Scores)
+	state := farkle.NewGameState(n)
+	state.NumDiceToRoll = uint8(s.NumDice)
+	var err error
+	if state.ScoreThisRound, err = farkle.ParseScore(s.TurnScore); err != nil {
+		return state, err
+	}
+	for i := 0; i < n; i++ {
+		if state.PlayerScores[i], err = farkle.ParseScore(s.Scores[(s.CurrentSeat+i)%n]); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// A game hosted by the server. Games are played by the players in each seat
+// taking turns to roll and act, and bots take their turns as soon as it is
+// their turn. All methods are safe for concurrent use.
+type hostedGame struct {
+	id      string
+	lobby   Lobby
+	advisor farkle.Advisor // For annotations, if not nil.
+	// For win probabilities, and to compare the players' moves to, if the
+	// server's advisor supports the game.
+	evaluator farkle.Advisor
+	store     *Store       // Where the game is persisted, if not nil.
+	stats     *stats.Store // Where the players' statistics are recorded, if not nil.
+	// The time remote players have to make each move, or 0 for no limit.
+	moveTimeLimit time.Duration
+	// Called when the game is over, with g.mx held, if not nil.
+	onGameOver func()
+
+	mx      sync.Mutex
+	game    *farkle.Game
+	seats   []Seat
+	roll    *farkle.Roll
+	events  []Event
+	changed chan struct{} // Closed and replaced when events are added.
+	// Each player's probability of winning after the last event, by seat.
+	winProbs []float64
+	// The current player forfeits if they do not move by the deadline, when
+	// the clock fires. Moves are numbered so that the clock of an earlier
+	// move is ignored.
+	deadline time.Time
+	clock    *time.Timer
+	moveNum  int
+}
+
+func newHostedGame(id string, seats []Seat, advisor farkle.Advisor, rng *mathrand.Rand) *hostedGame {
+	g := &hostedGame{
+		id:      id,
+		advisor: advisor,
+		game:    farkle.NewGame(len(seats), rng),
+		seats:   seats,
+		changed: make(chan struct{}),
+	}
+	g.game.AddObserver(g)
+	return g
+}
+
+func (g *hostedGame) started() bool {
+	for _, seat := range g.seats {
+		if seat.strategy == nil && !seat.Joined {
+			return false
+		}
+	}
+	return true
+}
+
+// Take the first open seat, returning its number and the token that
+// identifies the player in future requests, e.g. after reconnecting.
+func (g *hostedGame) join(req JoinRequest) (int, string, error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	for i := range g.seats {
+		seat := &g.seats[i]
+		if seat.strategy != nil || seat.Joined {
+			continue
+		}
+
+		token, err := newToken()
+		if err != nil {
+			return 0, "", err
+		}
+		seat.Joined = true
+		seat.token = token
+		seat.player = playerID(req.PlayerKey, g.id, i)
+		if req.Name != "" {
+			seat.Name = req.Name
+		}
+		g.addEvent(Event{Type: "join", Seat: i, Name: seat.Name})
+		g.saveSeat(i)
+		g.recordStats()
+		g.advance()
+		return i, token, nil
+	}
+
+	return 0, "", errGameFull
+}
+
+// The seat of the player with the given token.
+func (g *hostedGame) seatOf(token string) (int, bool) {
+	for i, seat := range g.seats {
+		if seat.token != "" && seat.token == token {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Roll the dice for the player with the given token. Farkles end the
+// turn immediately, since there is no choice to make.
+func (g *hostedGame) rollFor(token string) (farkle.Roll, error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if err := g.checkTurn(token); err != nil {
+		return farkle.Roll{}, err
+	}
+	roll, err := g.rollDice()
+	g.advance()
+	return roll, err
+}
+
+// Take an action in response to the last roll, for the player with the given token.
+func (g *hostedGame) applyFor(token string, action farkle.Action) error {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if err := g.checkTurn(token); err != nil {
+		return err
+	}
+	if err := g.apply(action); err != nil {
+		return err
+	}
+	g.advance()
+	return nil
+}
+
+// Wait until it is the turn of the player with the given token, or the game
+// is over. Returns the snapshot of the game if so, or otherwise a channel
+// that is closed when the game changes.
+func (g *hostedGame) waitTurn(token string) (Snapshot, bool, <-chan struct{}, error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	seat, ok := g.seatOf(token)
+	if !ok {
+		return Snapshot{}, false, nil, errNotAPlayer
+	}
+	if g.game.IsOver() || (g.started() && seat == g.game.CurrentPlayer()) {
+		return g.snapshotLocked(), true, nil, nil
+	}
+	return Snapshot{}, false, g.changed, nil
+}
+
+// Start the clock for the next move, if it is a remote player's and moves
+// are timed.
+func (g *hostedGame) startClock() {
+	g.moveNum++
+	if g.clock != nil {
+		g.clock.Stop()
+		g.clock = nil
+	}
+	g.deadline = time.Time{}
+	if g.moveTimeLimit == 0 || !g.started() || g.game.IsOver() ||
+		g.seats[g.game.CurrentPlayer()].strategy != nil {
+		return
+	}
+
+	moveNum := g.moveNum
+	g.deadline = time.Now().Add(g.moveTimeLimit)
+	g.clock = time.AfterFunc(g.moveTimeLimit, func() { g.timeout(moveNum) })
+}
+
+// The current player ran out of time for the given move, and forfeits the
+// game unless they have moved since.
+func (g *hostedGame) timeout(moveNum int) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if moveNum != g.moveNum || g.game.IsOver() {
+		return
+	}
+	g.forfeit(g.game.CurrentPlayer())
+}
+
+func (g *hostedGame) forfeit(seat int) {
+	g.addEvent(Event{Type: "forfeit", Seat: seat})
+	g.roll = nil
+	g.deadline = time.Time{}
+	if err := g.game.Forfeit(seat); err != nil {
+		farkle.Logger().Error("Error forfeiting game", "game", g.id, "err", err)
+	}
+}
+
+func (g *hostedGame) checkTurn(token string) error {
+	seat, ok := g.seatOf(token)
+	if !ok {
+		return errNotAPlayer
+	} else if !g.started() {
+		return errWaiting
+	} else if seat != g.game.CurrentPlayer() {
+		return errNotYourTurn
+	}
+	return nil
+}
+
+// Roll for the current player, ending their turn if they farkle.
+func (g *hostedGame) rollDice() (farkle.Roll, error) {
+	roll, err := g.game.Roll()
+	if err != nil {
+		return roll, err
+	}
+
+	g.roll = &roll
+	if farkle.IsFarkle(roll) {
+		return roll, g.apply(farkle.Action{})
+	}
+	return roll, nil
+}
+
+func (g *hostedGame) apply(action farkle.Action) error {
+	if err := g.game.Apply(action); err != nil {
+		return err
+	}
+	g.roll = nil
+	return nil
+}
+
+// Start the clock for the next move, and if it is a bot's, let the bots take
+// their turns in the background, so that the request of the player who moved
+// last is not held up. With g.mx held.
+func (g *hostedGame) advance() {
+	g.startClock()
+	if !g.botsTurn() {
+		return
+	}
+	go func() {
+		g.mx.Lock()
+		defer g.mx.Unlock()
+		g.playBots()
+		g.startClock()
+	}()
+}
+
+func (g *hostedGame) botsTurn() bool {
+	return g.started() && !g.game.IsOver() && g.seats[g.game.CurrentPlayer()].strategy != nil
+}
+
+// Take the turns of bots, until it is a human's turn or the game is over.
+// Bots that fail to act forfeit the game.
+func (g *hostedGame) playBots() {
+	for g.botsTurn() {
+		seat := g.game.CurrentPlayer()
+		if err := g.playBot(g.seats[seat].strategy); err != nil {
+			farkle.Logger().Error("Bot failed to act", "game", g.id, "bot", g.seats[seat].Bot, "err", err)
+			g.forfeit(seat)
+		}
+	}
+}
+
+// Roll for the current player, a bot, and take its action.
+func (g *hostedGame) playBot(strategy farkle.Strategy) error {
+	roll, err := g.rollDice()
+	if err != nil || farkle.IsFarkle(roll) {
+		return err
+	}
+	action, err := strategy.SelectAction(g.game.State(), roll)
+	if err != nil {
+		return err
+	}
+	return g.apply(action)
+}
+
+func (g *hostedGame) snapshot() Snapshot {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	return g.snapshotLocked()
+}
+
+func (g *hostedGame) snapshotLocked() Snapshot {
+	state := g.game.State()
+	s := Snapshot{
+		ID:          g.id,
+		Name:        g.lobby.Name,
+		Seats:       slices.Clone(g.seats),
+		Scores:      g.game.Scores(),
+		CurrentSeat: g.game.CurrentPlayer(),
+		TurnScore:   50 * int(state.ScoreThisRound),
+		NumDice:     int(state.NumDiceToRoll),
+		Roll:        g.roll,
+		Started:     g.started(),
+		Annotated:   g.advisor != nil,
+		WinProbs:    g.winProbs,
+		LastEventID: len(g.events),
+	}
+	if g.moveTimeLimit > 0 {
+		s.MoveTimeLimitMs = int(g.moveTimeLimit / time.Millisecond)
+	}
+	if !g.deadline.IsZero() {
+		deadline := g.deadline
+		s.Deadline = &deadline
+	}
+	if s.WinProbs == nil && g.evaluator != nil {
+		s.WinProbs = g.bySeat(g.evaluator.WinProb(state))
+	}
+	if g.game.IsOver() {
+		result := g.game.Result()
+		s.Result = &result
+	}
+	return s
+}
+
+// The events after the one with the given ID, and a channel that is closed
+// when there are more.
+func (g *hostedGame) eventsSince(id int) ([]Event, <-chan struct{}) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	id = max(0, min(id, len(g.events)))
+	return g.events[id:], g.changed
+}
+
+func (g *hostedGame) addEvent(e Event) {
+	e.ID = len(g.events) + 1
+	if e.WinProbs != nil {
+		g.winProbs = e.WinProbs
+	}
+	g.events = append(g.events, e)
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// Each player's probability of winning, by seat, from their probabilities
+// relative to the current player.
+func (g *hostedGame) bySeat(pWin [4]float64) []float64 {
+	result := make([]float64, len(g.seats))
+	for i := range result {
+		result[g.game.Seat(i)] = pWin[i]
+	}
+	return result
+}
+
+// Each player's probability of winning after the current player's turn ended.
+func (g *hostedGame) winProbsAfterTurn() []float64 {
+	if g.evaluator == nil || g.game.IsOver() {
+		return nil // Given by the gameOver event.
+	}
+	return g.bySeat(g.evaluator.WinProb(g.game.State()))
+}
+
+func (g *hostedGame) OnRoll(seat int, state farkle.GameState, roll farkle.Roll) {
+	e := Event{Type: "roll", Seat: seat, Roll: &roll, Dice: g.game.RolledDice().Dice}
+	// Farkles are followed by a farkle event with the new win probabilities.
+	if g.evaluator != nil && !farkle.IsFarkle(roll) {
+		_, pWin := g.evaluator.Recommend(state, roll)
+		e.WinProbs = g.bySeat(pWin)
+	}
+	g.addEvent(e)
+}
+
+func (g *hostedGame) OnAction(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+	if farkle.IsFarkle(roll) {
+		return // Reported by OnFarkle.
+	}
+
+	e := Event{Type: "action", Seat: seat, Roll: &roll, Action: &action}
+	if g.advisor != nil {
+		optimal, optimalPWin := g.advisor.Recommend(state, roll)
+		e.Optimal = &optimal
+		e.OptimalPWin = optimalPWin[0]
+		e.PWin = g.advisor.EvaluateAction(state, action)[0]
+	}
+	if g.evaluator != nil {
+		e.WinProbs = g.bySeat(g.evaluator.EvaluateAction(state, action))
+	}
+	g.addEvent(e)
+}
+
+func (g *hostedGame) OnFarkle(seat int, state farkle.GameState) {
+	g.addEvent(Event{Type: "farkle", Seat: seat, WinProbs: g.winProbsAfterTurn()})
+}
+
+func (g *hostedGame) OnBank(seat, points, total int) {
+	g.addEvent(Event{Type: "bank", Seat: seat, Points: points, Total: total,
+		WinProbs: g.winProbsAfterTurn()})
+}
+
+func (g *hostedGame) OnGameOver(result farkle.GameResult) {
+	e := Event{Type: "gameOver", Result: &result}
+	if g.evaluator != nil {
+		e.WinProbs = make([]float64, len(g.seats))
+		for _, winner := range result.Winners {
+			e.WinProbs[winner] = 1 / float64(len(result.Winners))
+		}
+	}
+	g.addEvent(e)
+	if g.store != nil {
+		if err := g.store.saveResult(g.id, result); err != nil {
+			farkle.Logger().Warn("Error saving result of game", "game", g.id, "err", err)
+		}
+	}
+	if g.onGameOver != nil {
+		g.onGameOver()
+	}
+}
+
+// Record the statistics of the players, once all of the seats are taken.
+func (g *hostedGame) recordStats() {
+	if g.stats == nil || !g.started() {
+		return
+	}
+	players := make([]stats.Player, len(g.seats))
+	for i, seat := range g.seats {
+		players[i] = stats.Player{ID: seat.player, Name: seat.Name}
+		if seat.strategy != nil {
+			players[i].ID = "bot:" + seat.Bot
+		}
+	}
+	g.game.AddObserver(stats.NewRecorder(g.stats, players, g.evaluator))
+}
+
+// The ID in statistics of the player with the given key, who joined the
+// given seat. Keys are hashed, so that they cannot be recovered from the
+// statistics. Players without a key are a new guest in every game.
+func playerID(key, gameID string, seat int) string {
+	if key == "" {
+		return fmt.Sprintf("guest:%s:%d", gameID, seat)
+	}
+	h := sha256.Sum256([]byte(key))
+	return "player:" + hex.EncodeToString(h[:8])
+}
+
+// Persist a player joining the given seat, so they can reconnect after the
+// server restarts if the game has not started.
+func (g *hostedGame) saveSeat(i int) {
+	if g.store == nil {
+		return
+	}
+	if err := g.store.saveSeat(g.id, i, g.seats[i]); err != nil {
+		farkle.Logger().Warn("Error saving seat", "game", g.id, "seat", i, "err", err)
+	}
+	if g.started() {
+		if err := g.store.setStatus(g.id, statusPlaying); err != nil {
+			farkle.Logger().Warn("Error saving status of game", "game", g.id, "err", err)
+		}
+	}
+}
+
+// A random token that is infeasible to guess.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,182 @@
+package gameserver
+
+import (
+	"crypto/rand"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Characters of join codes, without those that are easily confused (0/O, 1/I).
+// There are 32, so that random bytes map to them uniformly.
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const joinCodeLength = 6
+
+// A game that players join with a code, e.g. to invite friends.
+type Lobby struct {
+	GameID string `json:"gameId"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	// Private lobbies are not listed, so they can only be joined with the code.
+	Private bool `json:"private"`
+}
+
+type LobbyRequest struct {
+	CreateRequest
+	Name    string `json:"name"`
+	Private bool   `json:"private"`
+}
+
+type LobbyResponse struct {
+	Lobby Lobby    `json:"lobby"`
+	Game  Snapshot `json:"game"`
+}
+
+type MatchRequest struct {
+	Name string `json:"name"`
+	// As in JoinRequest.
+	PlayerKey string `json:"playerKey,omitempty"`
+	// The number of players in the game, 2 if not given.
+	NumPlayers int `json:"numPlayers"`
+}
+
+// A player waiting for a match, who is sent their seat once there are enough
+// players waiting.
+type waiter struct {
+	JoinRequest
+	matched chan matchResult
+}
+
+type matchResult struct {
+	JoinResponse
+	err error
+}
+
+func (s *Server) handleCreateLobby(w http.ResponseWriter, r *http.Request) {
+	var req LobbyRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	lobby := Lobby{Name: req.Name, Private: req.Private}
+	g, err := s.createGame(req.CreateRequest, lobby, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeResponse(w, LobbyResponse{Lobby: g.lobby, Game: g.snapshot()})
+}
+
+// List the public lobbies that have open seats.
+func (s *Server) handleListLobbies(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	var games []*hostedGame
+	for _, id := range s.order {
+		if g := s.games[id]; g.lobby.Code != "" && !g.lobby.Private {
+			games = append(games, g)
+		}
+	}
+	s.mx.Unlock()
+
+	result := []LobbyResponse{}
+	for _, g := range games {
+		if snapshot := g.snapshot(); !snapshot.Started {
+			result = append(result, LobbyResponse{Lobby: g.lobby, Game: snapshot})
+		}
+	}
+	writeResponse(w, result)
+}
+
+func (s *Server) handleJoinLobby(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(r.PathValue("code"))
+	s.mx.Lock()
+	g, ok := s.lobbies[code]
+	s.mx.Unlock()
+	if !ok {
+		http.Error(w, "no such lobby", http.StatusNotFound)
+		return
+	}
+	s.handleJoin(g, w, r)
+}
+
+// Wait until enough players are waiting for a game of the requested size,
+// then create one and respond with the player's seat. Players who disconnect
+// before then leave the queue.
+func (s *Server) handleMatchmaking(w http.ResponseWriter, r *http.Request) {
+	var req MatchRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.NumPlayers == 0 {
+		req.NumPlayers = 2
+	}
+	if req.NumPlayers < 2 || req.NumPlayers > 4 {
+		http.Error(w, "games require 2 to 4 players", http.StatusBadRequest)
+		return
+	}
+
+	n := req.NumPlayers
+	me := &waiter{JoinRequest: JoinRequest{Name: req.Name, PlayerKey: req.PlayerKey}, matched: make(chan matchResult, 1)}
+	s.mx.Lock()
+	s.waiting[n] = append(s.waiting[n], me)
+	var matched []*waiter
+	if len(s.waiting[n]) == n {
+		matched = s.waiting[n]
+		s.rng.Shuffle(n, func(i, j int) { matched[i], matched[j] = matched[j], matched[i] })
+		delete(s.waiting, n)
+	}
+	s.mx.Unlock()
+	if matched != nil {
+		s.startMatch(matched)
+	}
+
+	select {
+	case result := <-me.matched:
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, result.JoinResponse)
+	case <-r.Context().Done():
+		s.mx.Lock()
+		s.waiting[n] = slices.DeleteFunc(s.waiting[n], func(other *waiter) bool { return other == me })
+		s.mx.Unlock()
+	}
+}
+
+// Create a game for the matched players, and seat them in order.
+func (s *Server) startMatch(players []*waiter) {
+	req := CreateRequest{Seats: make([]Seat, len(players))}
+	for i, p := range players {
+		req.Seats[i].Name = p.Name
+	}
+	g, err := s.createGame(req, Lobby{}, false)
+	if err != nil {
+		farkle.Logger().Error("Error creating match", "err", err)
+	}
+
+	for _, p := range players {
+		result := matchResult{err: err}
+		if err == nil {
+			result.GameID = g.id
+			result.Seat, result.Token, result.err = g.join(p.JoinRequest)
+		}
+		p.matched <- result
+	}
+}
+
+// A random code of joinCodeLength characters.
+func newJoinCode() (string, error) {
+	b := make([]byte, joinCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = joinCodeAlphabet[int(b[i])%len(joinCodeAlphabet)]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,629 @@
+// Package gameserver hosts Farkle games between remote clients and bots over
+// HTTP. The rules are enforced by the server with farkle.Game, so clients
+// cannot cheat, and every event in a game is streamed to players and
+// spectators as server-sent events.
+//
+// The API, with all bodies in JSON:
+//
+//	POST /api/games                  {"seats": [{"name": "alice"}, {"bot": "threshold:300"}], "annotate": true}
+//	                                 -> Snapshot
+//	GET  /api/games                  -> []Snapshot
+//	GET  /api/games/{id}             -> Snapshot
+//	POST /api/games/{id}/join        {"name": "bob", "playerKey": "..."} -> {"seat": 0, "token": "..."}
+//	GET  /api/games/{id}/turn        -> Snapshot, once it is the player's turn or the game is over
+//	POST /api/games/{id}/roll        -> {"roll": [1, 1, 5, 2, 3, 4]}
+//	POST /api/games/{id}/action      {"held": [1, 1, 5], "continue": true}
+//	GET  /api/games/{id}/events      -> text/event-stream of Event
+//	GET  /api/games/{id}/spectate    -> text/event-stream of Snapshot, on every change
+//
+//	POST /api/lobbies                {"name": "friday night", "seats": [{}, {}, {"bot": "optimal"}], "private": true}
+//	                                 -> {"lobby": {"code": "K7QX2M", ...}, "game": Snapshot}
+//	GET  /api/lobbies                -> [{"lobby": Lobby, "game": Snapshot}] of public lobbies with open seats
+//	POST /api/lobbies/{code}/join    {"name": "bob"} -> {"gameId": "...", "seat": 1, "token": "..."}
+//	POST /api/matchmaking            {"name": "bob", "numPlayers": 2} -> {"gameId": "...", "seat": 1, "token": "..."}
+//
+// Lobbies are games that players join with a short code, which can be shared
+// to invite friends. Matchmaking responds once enough players are waiting for
+// a game of the same size, seated in random order.
+//
+// Players are identified by the token they are given when they join, sent as
+// "Authorization: Bearer TOKEN" with roll and action requests. Clients that
+// lose their connection can reconnect with the same token, and resume the
+// event stream after the last event they received by sending its ID in the
+// Last-Event-ID header (as browsers' EventSource does) or the since parameter.
+//
+// Games created with "moveTimeLimitMs" are timed, e.g. for competitions
+// between bots written by others, with the server as the arbiter. Each remote
+// player then has that long for every roll and every action, and forfeits the
+// game if they run out of time: the player with the highest score among the
+// others wins. Bots can play with a loop of waiting for their turn with
+// /turn, whose response includes the deadline for the move, then rolling and
+// acting until their turn is over. See cmd/farkle-bot for an example.
+//
+// If the server's advisor can evaluate a game, every event and snapshot
+// includes each player's probability of winning, for spectators to follow
+// like the evaluation bar of a chess broadcast.
+//
+// With a Store, games and tokens are persisted, so lobbies remain open and
+// the players who joined them keep their seats when the server restarts.
+// Games that were in progress are abandoned, since their state is only kept
+// in memory.
+//
+// Finished games are removed from the server after Config.FinishedGameTTL,
+// and their join codes may then be reused. Bots take their turns in the
+// background, and forfeit the game if they fail to act.
+package gameserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/stats"
+)
+
+// Configuration of a Server.
+type Config struct {
+	// Create the strategy of a bot, e.g. from "threshold:300", for a game
+	// with the given number of players. If nil, games cannot have bots.
+	NewBot func(spec string, numPlayers int) (farkle.Strategy, error)
+	// Used to annotate the actions in games created with annotations, with
+	// the optimal action and win probabilities. If nil, games cannot be
+	// annotated.
+	Advisor farkle.Advisor
+	// The number of players in games that can be annotated, if the advisor
+	// only supports one, e.g. farkle.DBAdvisor. 0 for any number.
+	AdvisorNumPlayers int
+	// Where games are persisted, if not nil.
+	Store *Store
+	// Where the results of players, and their ratings, are recorded if not
+	// nil. Moves are compared to the Advisor's, if it supports the game.
+	Stats *stats.Store
+	// How long finished games remain on the server, e.g. for their players
+	// to see the result, before they are removed. 10 minutes if 0.
+	FinishedGameTTL time.Duration
+}
+
+const defaultFinishedGameTTL = 10 * time.Minute
+
+// Hosts any number of concurrent games.
+type Server struct {
+	config Config
+
+	mx      sync.Mutex
+	games   map[string]*hostedGame
+	order   []string               // Game IDs in order of creation.
+	lobbies map[string]*hostedGame // By join code.
+	waiting map[int][]*waiter      // Players waiting for a match, by number of players.
+	rng     *rand.Rand
+}
+
+// Create a new server, restoring the open games in the store, if any.
+func NewServer(config Config) (*Server, error) {
+	s := &Server{
+		config:  config,
+		games:   make(map[string]*hostedGame),
+		lobbies: make(map[string]*hostedGame),
+		waiting: make(map[int][]*waiter),
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if config.Store != nil {
+		if err := s.restore(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+type CreateRequest struct {
+	Seats    []Seat `json:"seats"`
+	Annotate bool   `json:"annotate"`
+	// The time remote players have to make each move, i.e. to roll or to
+	// act on their roll, in milliseconds. Players who run out of time
+	// forfeit the game. 0 for no limit.
+	MoveTimeLimitMs int `json:"moveTimeLimitMs"`
+}
+
+type JoinRequest struct {
+	Name string `json:"name"`
+	// A secret that identifies the player across games, for their statistics
+	// and rating. Players without one are rated as a new guest in every game.
+	PlayerKey string `json:"playerKey,omitempty"`
+}
+
+type JoinResponse struct {
+	GameID string `json:"gameId"`
+	Seat   int    `json:"seat"`
+	Token  string `json:"token"`
+}
+
+type RollResponse struct {
+	Roll farkle.Roll `json:"roll"`
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/games", s.handleCreate)
+	mux.HandleFunc("GET /api/games", s.handleList)
+	mux.HandleFunc("GET /api/games/{id}", s.withGame(s.handleGet))
+	mux.HandleFunc("POST /api/games/{id}/join", s.withGame(s.handleJoin))
+	mux.HandleFunc("GET /api/games/{id}/turn", s.withGame(s.handleTurn))
+	mux.HandleFunc("POST /api/games/{id}/roll", s.withGame(s.handleRoll))
+	mux.HandleFunc("POST /api/games/{id}/action", s.withGame(s.handleAction))
+	mux.HandleFunc("GET /api/games/{id}/events", s.withGame(s.handleEvents))
+	mux.HandleFunc("GET /api/games/{id}/spectate", s.withGame(s.handleSpectate))
+	mux.HandleFunc("POST /api/lobbies", s.handleCreateLobby)
+	mux.HandleFunc("GET /api/lobbies", s.handleListLobbies)
+	mux.HandleFunc("POST /api/lobbies/{code}/join", s.handleJoinLobby)
+	mux.HandleFunc("POST /api/matchmaking", s.handleMatchmaking)
+	return mux
+}
+
+// Create a new game, returning its snapshot.
+func (s *Server) CreateGame(req CreateRequest) (Snapshot, error) {
+	g, err := s.createGame(req, Lobby{}, false)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return g.snapshot(), nil
+}
+
+// Create a new game in the given lobby, with a new join code if withCode.
+func (s *Server) createGame(req CreateRequest, lobby Lobby, withCode bool) (*hostedGame, error) {
+	n := len(req.Seats)
+	if n < 2 || n > 4 {
+		return nil, fmt.Errorf("games require 2 to 4 seats, got %d", n)
+	} else if req.MoveTimeLimitMs < 0 {
+		return nil, fmt.Errorf("invalid move time limit: %d ms", req.MoveTimeLimitMs)
+	}
+	advisor, err := s.advisor(req.Annotate, n)
+	if err != nil {
+		return nil, err
+	}
+
+	seats := make([]Seat, n)
+	for i, seat := range req.Seats {
+		seats[i] = Seat{Name: seat.Name, Bot: seat.Bot}
+		if seats[i].Name == "" {
+			seats[i].Name = fmt.Sprintf("Player %d", i+1)
+		}
+		if seat.Bot == "" {
+			continue
+		}
+		strategy, err := s.newBot(seat.Bot, n)
+		if err != nil {
+			return nil, err
+		}
+		seats[i].strategy = strategy
+		if seat.Name == "" {
+			seats[i].Name = seat.Bot
+		}
+	}
+
+	s.mx.Lock()
+	if lobby.GameID, err = s.newGameID(); err != nil {
+		s.mx.Unlock()
+		return nil, err
+	}
+	if withCode {
+		for lobby.Code == "" || s.lobbies[lobby.Code] != nil {
+			if lobby.Code, err = newJoinCode(); err != nil {
+				s.mx.Unlock()
+				return nil, err
+			}
+		}
+	}
+	g := newHostedGame(lobby.GameID, seats, advisor, rand.New(rand.NewSource(s.rng.Int63())))
+	g.lobby = lobby
+	g.moveTimeLimit = time.Duration(req.MoveTimeLimitMs) * time.Millisecond
+	if s.config.Store != nil {
+		status := statusOpen
+		if g.started() {
+			status = statusPlaying
+		}
+		if err := s.config.Store.createGame(lobby, req, seats, status); err != nil {
+			s.mx.Unlock()
+			return nil, err
+		}
+		g.store = s.config.Store
+	}
+	s.setEvaluator(g)
+	g.recordStats()
+	s.addGame(g)
+	s.mx.Unlock()
+
+	farkle.Logger().Info("Created game", "game", g.id, "seats", n)
+	// Games between bots alone start right away.
+	g.mx.Lock()
+	g.advance()
+	g.mx.Unlock()
+	return g, nil
+}
+
+// A new ID for a game, which no other game on the server or in its store
+// has. With s.mx held.
+func (s *Server) newGameID() (string, error) {
+	for {
+		token, err := newToken()
+		if err != nil {
+			return "", err
+		}
+		id := token[:8]
+		if s.games[id] != nil {
+			continue
+		}
+		if s.config.Store != nil {
+			if exists, err := s.config.Store.hasGame(id); err != nil {
+				return "", err
+			} else if exists {
+				continue
+			}
+		}
+		return id, nil
+	}
+}
+
+// Add a game to the server, with s.mx held. The game is removed once it has
+// been over for the FinishedGameTTL.
+func (s *Server) addGame(g *hostedGame) {
+	s.games[g.id] = g
+	s.order = append(s.order, g.id)
+	if g.lobby.Code != "" {
+		s.lobbies[g.lobby.Code] = g
+	}
+
+	ttl := s.config.FinishedGameTTL
+	if ttl == 0 {
+		ttl = defaultFinishedGameTTL
+	}
+	g.onGameOver = func() {
+		time.AfterFunc(ttl, func() { s.removeGame(g) })
+	}
+}
+
+func (s *Server) removeGame(g *hostedGame) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.games, g.id)
+	s.order = slices.DeleteFunc(s.order, func(id string) bool { return id == g.id })
+	if g.lobby.Code != "" && s.lobbies[g.lobby.Code] == g {
+		delete(s.lobbies, g.lobby.Code)
+	}
+	farkle.Logger().Info("Removed finished game", "game", g.id)
+}
+
+// The advisor for a game with n players, or nil if it is not annotated.
+func (s *Server) advisor(annotate bool, n int) (farkle.Advisor, error) {
+	if !annotate {
+		return nil, nil
+	} else if s.config.Advisor == nil {
+		return nil, errors.New("annotations are not available on this server")
+	} else if s.config.AdvisorNumPlayers != 0 && s.config.AdvisorNumPlayers != n {
+		return nil, fmt.Errorf("annotations are only available in %d-player games",
+			s.config.AdvisorNumPlayers)
+	}
+	return s.config.Advisor, nil
+}
+
+func (s *Server) newBot(spec string, n int) (farkle.Strategy, error) {
+	if s.config.NewBot == nil {
+		return nil, errors.New("bots are not available on this server")
+	}
+	strategy, err := s.config.NewBot(spec, n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bot %q: %w", spec, err)
+	}
+	return strategy, nil
+}
+
+// Restore the games in the store that were waiting for players.
+func (s *Server) restore() error {
+	stored, err := s.config.Store.loadOpenGames()
+	if err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, sg := range stored {
+		g, err := s.restoreGame(sg)
+		if err != nil {
+			farkle.Logger().Warn("Unable to restore game", "game", sg.lobby.GameID, "err", err)
+			if err := s.config.Store.setStatus(sg.lobby.GameID, statusAbandoned); err != nil {
+				return err
+			}
+			continue
+		}
+		s.addGame(g)
+	}
+	farkle.Logger().Info("Restored open games", "games", len(s.games))
+	return nil
+}
+
+func (s *Server) restoreGame(sg storedGame) (*hostedGame, error) {
+	n := len(sg.seats)
+	advisor, err := s.advisor(sg.req.Annotate, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, seat := range sg.seats {
+		if seat.Bot == "" {
+			continue
+		}
+		if sg.seats[i].strategy, err = s.newBot(seat.Bot, n); err != nil {
+			return nil, err
+		}
+	}
+
+	g := newHostedGame(sg.lobby.GameID, sg.seats, advisor, rand.New(rand.NewSource(s.rng.Int63())))
+	g.lobby = sg.lobby
+	g.moveTimeLimit = time.Duration(sg.req.MoveTimeLimitMs) * time.Millisecond
+	g.store = s.config.Store
+	s.setEvaluator(g)
+	return g, nil
+}
+
+// Set up the evaluation of the game's moves and win probabilities, and the
+// recording of its players' statistics.
+func (s *Server) setEvaluator(g *hostedGame) {
+	// Games are not evaluated if the advisor does not support them.
+	g.evaluator, _ = s.advisor(true, len(g.seats))
+	g.stats = s.config.Stats
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	snapshot, err := s.CreateGame(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	writeResponse(w, snapshot)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	games := make([]*hostedGame, len(s.order))
+	for i, id := range s.order {
+		games[i] = s.games[id]
+	}
+	s.mx.Unlock()
+
+	result := make([]Snapshot, len(games))
+	for i, g := range games {
+		result[i] = g.snapshot()
+	}
+	writeResponse(w, result)
+}
+
+func (s *Server) withGame(handle func(*hostedGame, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mx.Lock()
+		g, ok := s.games[r.PathValue("id")]
+		s.mx.Unlock()
+		if !ok {
+			http.Error(w, "no such game", http.StatusNotFound)
+			return
+		}
+		handle(g, w, r)
+	}
+}
+
+func (s *Server) handleGet(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, g.snapshot())
+}
+
+func (s *Server) handleJoin(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	// The name is optional, so the body may be empty.
+	var req JoinRequest
+	body := http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	if err := json.NewDecoder(body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeRequestError(w, err)
+		return
+	}
+
+	seat, token, err := g.join(req)
+	if errors.Is(err, errGameFull) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, JoinResponse{GameID: g.id, Seat: seat, Token: token})
+}
+
+// Wait until it is the turn of the player with the given token, or the game
+// is over, then respond with the snapshot of the game.
+func (s *Server) handleTurn(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	for {
+		snapshot, ready, changed, err := g.waitTurn(token)
+		if err != nil {
+			writeGameError(w, err)
+			return
+		} else if ready {
+			writeResponse(w, snapshot)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleRoll(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	roll, err := g.rollFor(bearerToken(r))
+	if err != nil {
+		writeGameError(w, err)
+		return
+	}
+	writeResponse(w, RollResponse{Roll: roll})
+}
+
+func (s *Server) handleAction(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	var action farkle.Action
+	if !decodeRequest(w, r, &action) {
+		return
+	}
+
+	if err := g.applyFor(bearerToken(r), action); err != nil {
+		writeGameError(w, err)
+		return
+	}
+	writeResponse(w, g.snapshot())
+}
+
+// Stream the events of the game, until the client disconnects or the game
+// is over.
+func (s *Server) handleEvents(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+	lastID := 0
+	if since != "" {
+		var err error
+		if lastID, err = strconv.Atoi(since); err != nil {
+			http.Error(w, "invalid event ID: "+since, http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		events, changed := g.eventsSince(lastID)
+		for _, e := range events {
+			data, err := json.Marshal(e)
+			if err != nil {
+				farkle.Logger().Error("Error encoding event", "err", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+			lastID = e.ID
+			if e.Type == "gameOver" {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Stream a snapshot of the game whenever it changes, until the client
+// disconnects or the game is over. Spectators that only display the game,
+// e.g. with each player's win probability as an evaluation bar, need not
+// follow the individual events.
+func (s *Server) handleSpectate(g *hostedGame, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for {
+		// Take the channel first, so that no change is missed.
+		_, changed := g.eventsSince(math.MaxInt)
+		snapshot := g.snapshot()
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			farkle.Logger().Error("Error encoding snapshot", "err", err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: state\ndata: %s\n\n", snapshot.LastEventID, data); err != nil {
+			return
+		}
+		flusher.Flush()
+		if snapshot.Result != nil {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+func writeGameError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errNotAPlayer) {
+		status = http.StatusForbidden
+	} else if errors.Is(err, errNotYourTurn) || errors.Is(err, errWaiting) {
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// The largest request body that is read. Requests are a few names and
+// seats, or an action, so this is far more than any valid request.
+const maxRequestBytes = 64 << 10
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err := dec.Decode(req); err != nil {
+		writeRequestError(w, err)
+		return false
+	}
+	return true
+}
+
+func writeRequestError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(w, "invalid request: "+err.Error(), status)
+}
+
+func writeResponse(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		farkle.Logger().Error("Error writing response", "err", err)
+	}
+}
@@ -0,0 +1,280 @@
+package gameserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Fails to act on every roll.
+type brokenBot struct{}
+
+func (brokenBot) SelectAction(state farkle.GameState, roll farkle.Roll) (farkle.Action, error) {
+	return farkle.Action{}, errors.New("broken")
+}
+
+func newTestServer(t *testing.T, config Config) (*Server, *httptest.Server) {
+	t.Helper()
+	config.NewBot = func(spec string, numPlayers int) (farkle.Strategy, error) {
+		if spec == "broken" {
+			return brokenBot{}, nil
+		}
+		return farkle.ParseThresholdStrategy(spec)
+	}
+	s, err := NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// Send a request with the given body, if not nil, and decode the response
+// into resp, if not nil, returning the status code.
+func doRequest(t *testing.T, method, url, token string, body, resp any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	if resp != nil && r.StatusCode < 300 {
+		if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r.StatusCode
+}
+
+// Wait for the game to be over, returning its final snapshot.
+func waitGameOver(t *testing.T, ts *httptest.Server, id string) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var snapshot Snapshot
+		if status := doRequest(t, "GET", ts.URL+"/api/games/"+id, "", nil, &snapshot); status != http.StatusOK {
+			t.Fatalf("GET game %s: status %d", id, status)
+		}
+		if snapshot.Result != nil {
+			return snapshot
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("game %s is not over", id)
+	return Snapshot{}
+}
+
+// Games between bots are played in the background, after the game is created.
+func TestBotGame(t *testing.T) {
+	_, ts := newTestServer(t, Config{})
+	var created Snapshot
+	req := CreateRequest{Seats: []Seat{{Bot: "threshold:300"}, {Bot: "threshold:1000"}}}
+	if status := doRequest(t, "POST", ts.URL+"/api/games", "", req, &created); status != http.StatusCreated {
+		t.Fatalf("POST /api/games: status %d", status)
+	}
+
+	snapshot := waitGameOver(t, ts, created.ID)
+	if len(snapshot.Result.Winners) == 0 {
+		t.Errorf("game %s is over without a winner: %+v", created.ID, snapshot.Result)
+	}
+	if max(snapshot.Scores[0], snapshot.Scores[1]) < farkle.CurrentRules().TargetScore {
+		t.Errorf("game %s is over with scores %v", created.ID, snapshot.Scores)
+	}
+}
+
+// Bots that fail to act forfeit the game, rather than leave it stuck.
+func TestBrokenBotForfeits(t *testing.T) {
+	_, ts := newTestServer(t, Config{})
+	var created Snapshot
+	req := CreateRequest{Seats: []Seat{{Bot: "broken"}, {Bot: "threshold:300"}}}
+	if status := doRequest(t, "POST", ts.URL+"/api/games", "", req, &created); status != http.StatusCreated {
+		t.Fatalf("POST /api/games: status %d", status)
+	}
+
+	snapshot := waitGameOver(t, ts, created.ID)
+	if want := []int{1}; fmt.Sprint(snapshot.Result.Winners) != fmt.Sprint(want) {
+		t.Errorf("winners = %v, want %v", snapshot.Result.Winners, want)
+	}
+}
+
+// A remote player joins a game against a bot, and plays it to the end.
+func TestPlayAgainstBot(t *testing.T) {
+	_, ts := newTestServer(t, Config{})
+	var created Snapshot
+	req := CreateRequest{Seats: []Seat{{Bot: "threshold:300"}, {}}}
+	if status := doRequest(t, "POST", ts.URL+"/api/games", "", req, &created); status != http.StatusCreated {
+		t.Fatalf("POST /api/games: status %d", status)
+	} else if created.Started {
+		t.Fatal("game started before the remote player joined")
+	}
+
+	game := ts.URL + "/api/games/" + created.ID
+	var joined JoinResponse
+	if status := doRequest(t, "POST", game+"/join", "", JoinRequest{Name: "alice"}, &joined); status != http.StatusOK {
+		t.Fatalf("POST join: status %d", status)
+	} else if joined.Seat != 1 {
+		t.Fatalf("joined seat %d, want 1", joined.Seat)
+	}
+	if status := doRequest(t, "POST", game+"/join", "", JoinRequest{}, nil); status != http.StatusConflict {
+		t.Errorf("joining a full game: status %d, want %d", status, http.StatusConflict)
+	}
+	if status := doRequest(t, "POST", game+"/roll", "not-a-token", nil, nil); status != http.StatusForbidden {
+		t.Errorf("rolling without a seat: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	strategy := farkle.ThresholdStrategy{BankAt: 500}
+	for {
+		var snapshot Snapshot
+		if status := doRequest(t, "GET", game+"/turn", joined.Token, nil, &snapshot); status != http.StatusOK {
+			t.Fatalf("GET turn: status %d", status)
+		} else if snapshot.Result != nil {
+			break
+		} else if snapshot.CurrentSeat != joined.Seat {
+			t.Fatalf("turn returned in seat %d's turn", snapshot.CurrentSeat)
+		}
+
+		var rolled RollResponse
+		if status := doRequest(t, "POST", game+"/roll", joined.Token, nil, &rolled); status != http.StatusOK {
+			t.Fatalf("POST roll: status %d", status)
+		} else if farkle.IsFarkle(rolled.Roll) {
+			continue
+		}
+		state, err := snapshot.State()
+		if err != nil {
+			t.Fatal(err)
+		}
+		action, err := strategy.SelectAction(state, rolled.Roll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := doRequest(t, "POST", game+"/action", joined.Token, action, nil); status != http.StatusOK {
+			t.Fatalf("POST action %v: status %d", action, status)
+		}
+	}
+}
+
+// Finished games, and their lobbies, are removed after the FinishedGameTTL.
+func TestFinishedGamesRemoved(t *testing.T) {
+	s, ts := newTestServer(t, Config{FinishedGameTTL: time.Millisecond})
+	var created LobbyResponse
+	req := LobbyRequest{
+		CreateRequest: CreateRequest{Seats: []Seat{{Bot: "threshold:300"}, {Bot: "threshold:300"}}},
+		Name:          "bots",
+	}
+	if status := doRequest(t, "POST", ts.URL+"/api/lobbies", "", req, &created); status != http.StatusCreated {
+		t.Fatalf("POST /api/lobbies: status %d", status)
+	}
+	waitGameOver(t, ts, created.Game.ID)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s.mx.Lock()
+		n := len(s.games) + len(s.order) + len(s.lobbies)
+		s.mx.Unlock()
+		if n == 0 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("finished game %s was not removed", created.Game.ID)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if status := doRequest(t, "GET", ts.URL+"/api/games/"+created.Game.ID, "", nil, nil); status != http.StatusNotFound {
+		t.Errorf("GET removed game: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+// New games do not reuse the IDs of games in the store, even those that
+// are no longer on the server.
+func TestNewGameID(t *testing.T) {
+	store, err := OpenStore(t.TempDir() + "/store.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	s, _ := newTestServer(t, Config{Store: store})
+
+	g, err := s.createGame(CreateRequest{Seats: []Seat{{}, {}}}, Lobby{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := store.hasGame(g.id); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Fatalf("game %s is not in the store", g.id)
+	}
+	if exists, err := store.hasGame("00000000"); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Fatal("store has a game that was never created")
+	}
+
+	s.mx.Lock()
+	id, err := s.newGameID()
+	s.mx.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(id) != 8 || id == g.id {
+		t.Errorf("new game ID = %q, with %q taken", id, g.id)
+	}
+}
+
+// Players with a key are the same player in every game, and the key cannot
+// be read from their ID. Players without one are a new guest in each game.
+func TestPlayerID(t *testing.T) {
+	const key = "correct horse battery staple"
+	id := playerID(key, "game1", 0)
+	if other := playerID(key, "game2", 1); other != id {
+		t.Errorf("player with the same key has IDs %q and %q", id, other)
+	} else if strings.Contains(id, key) {
+		t.Errorf("ID %q contains the player's key", id)
+	}
+	if other := playerID("another key", "game1", 0); other == id {
+		t.Errorf("players with different keys have the same ID %q", id)
+	}
+	if playerID("", "game1", 0) == playerID("", "game2", 0) {
+		t.Error("guests in different games have the same ID")
+	}
+}
+
+// Request bodies are read up to maxRequestBytes, so that large ones cannot
+// exhaust the server's memory.
+func TestRequestTooLarge(t *testing.T) {
+	_, ts := newTestServer(t, Config{})
+	name := strings.Repeat("x", maxRequestBytes)
+	req := CreateRequest{Seats: []Seat{{Name: name}, {}}}
+	if status := doRequest(t, "POST", ts.URL+"/api/games", "", req, nil); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST /api/games: status %d, want %d", status, http.StatusRequestEntityTooLarge)
+	}
+
+	var created Snapshot
+	req = CreateRequest{Seats: []Seat{{}, {}}}
+	if status := doRequest(t, "POST", ts.URL+"/api/games", "", req, &created); status != http.StatusCreated {
+		t.Fatalf("POST /api/games: status %d", status)
+	}
+	joinURL := ts.URL + "/api/games/" + created.ID + "/join"
+	if status := doRequest(t, "POST", joinURL, "", JoinRequest{Name: name}, nil); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST join: status %d, want %d", status, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,227 @@
+package gameserver
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+	_ "modernc.org/sqlite"
+)
+
+// Status of a game in the store.
+const (
+	statusOpen    = "open"    // Waiting for players to join.
+	statusPlaying = "playing" // All seats are taken.
+	statusOver    = "over"
+	// The server restarted while the game was being played. Games in
+	// progress are only kept in memory, so they cannot be resumed.
+	statusAbandoned = "abandoned"
+)
+
+// Persists games and their players in a SQLite database, so that lobbies and
+// the tokens of the players who joined them survive restarts of the server,
+// and the results of finished games are kept:
+//
+//	games (id, code, name, private, annotate, move_time_limit_ms, status, created_at)
+//	seats (game_id, seat, name, bot, token, player, score, won)
+//
+// where player is the ID in statistics of the player who joined the seat.
+type Store struct {
+	db *sql.DB
+}
+
+// Open the store at the given path, creating it if it does not exist.
+func OpenStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows only one writer at a time.
+	db.SetMaxOpenConns(1)
+
+	s := &Store{db: db}
+	if err := s.init(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// The version of the tables, in SQLite's user_version. Stores with other
+// versions, e.g. from before seats had a player ID, are not read.
+const storeVersion = 1
+
+func (s *Store) init() error {
+	var version, numTables int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&numTables); err != nil {
+		return err
+	}
+	if numTables > 0 && version != storeVersion {
+		return fmt.Errorf("stores of version %d are not supported, expected version %d", version, storeVersion)
+	}
+
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS games (
+		id TEXT PRIMARY KEY,
+		code TEXT UNIQUE,
+		name TEXT NOT NULL,
+		private INTEGER NOT NULL,
+		annotate INTEGER NOT NULL,
+		move_time_limit_ms INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		created_at INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS seats (
+		game_id TEXT NOT NULL REFERENCES games (id),
+		seat INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		bot TEXT NOT NULL,
+		token TEXT NOT NULL,
+		player TEXT NOT NULL,
+		score INTEGER,
+		won INTEGER,
+		PRIMARY KEY (game_id, seat))`); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, storeVersion))
+	return err
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// A game to restore when the server starts.
+type storedGame struct {
+	lobby Lobby
+	req   CreateRequest // Without the seats.
+	seats []Seat
+}
+
+func (s *Store) createGame(lobby Lobby, req CreateRequest, seats []Seat, status string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var code sql.NullString
+	if lobby.Code != "" {
+		code = sql.NullString{String: lobby.Code, Valid: true}
+	}
+	if _, err := tx.Exec(`INSERT INTO games VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		lobby.GameID, code, lobby.Name, lobby.Private, req.Annotate, req.MoveTimeLimitMs,
+		status, time.Now().Unix()); err != nil {
+		return err
+	}
+	for i, seat := range seats {
+		if _, err := tx.Exec(`INSERT INTO seats (game_id, seat, name, bot, token, player) VALUES (?, ?, ?, ?, ?, ?)`,
+			lobby.GameID, i, seat.Name, seat.Bot, seat.token, seat.player); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Whether a game with the given ID was ever stored.
+func (s *Store) hasGame(gameID string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM games WHERE id = ?`, gameID).Scan(&n)
+	return n > 0, err
+}
+
+func (s *Store) saveSeat(gameID string, i int, seat Seat) error {
+	_, err := s.db.Exec(`UPDATE seats SET name = ?, token = ?, player = ? WHERE game_id = ? AND seat = ?`,
+		seat.Name, seat.token, seat.player, gameID, i)
+	return err
+}
+
+func (s *Store) setStatus(gameID, status string) error {
+	_, err := s.db.Exec(`UPDATE games SET status = ? WHERE id = ?`, status, gameID)
+	return err
+}
+
+func (s *Store) saveResult(gameID string, result farkle.GameResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for seat, score := range result.Scores {
+		won := false
+		for _, winner := range result.Winners {
+			won = won || winner == seat
+		}
+		if _, err := tx.Exec(`UPDATE seats SET score = ?, won = ? WHERE game_id = ? AND seat = ?`,
+			score, won, gameID, seat); err != nil {
+			return err
+		}
+	}
+	// Join codes may be reused once the game is over.
+	if _, err := tx.Exec(`UPDATE games SET status = ?, code = NULL WHERE id = ?`, statusOver, gameID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Load the games that are still waiting for players, and mark the games
+// that were being played as abandoned.
+func (s *Store) loadOpenGames() ([]storedGame, error) {
+	if _, err := s.db.Exec(`UPDATE games SET status = ?, code = NULL WHERE status = ?`,
+		statusAbandoned, statusPlaying); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT id, code, name, private, annotate, move_time_limit_ms FROM games
+		WHERE status = ? ORDER BY created_at`, statusOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []storedGame
+	for rows.Next() {
+		var g storedGame
+		var code sql.NullString
+		if err := rows.Scan(&g.lobby.GameID, &code, &g.lobby.Name, &g.lobby.Private,
+			&g.req.Annotate, &g.req.MoveTimeLimitMs); err != nil {
+			return nil, err
+		}
+		g.lobby.Code = code.String
+		result = append(result, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range result {
+		if result[i].seats, err = s.loadSeats(result[i].lobby.GameID); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *Store) loadSeats(gameID string) ([]Seat, error) {
+	rows, err := s.db.Query(`SELECT name, bot, token, player FROM seats WHERE game_id = ? ORDER BY seat`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Seat
+	for rows.Next() {
+		var seat Seat
+		if err := rows.Scan(&seat.Name, &seat.Bot, &seat.token, &seat.player); err != nil {
+			return nil, err
+		}
+		seat.Joined = seat.Bot == "" && seat.token != ""
+		result = append(result, seat)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,89 @@
+package gameserver
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	store, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// Lobbies that are waiting for players are restored when the server
+// restarts, with the tokens of the players who joined them. Games in
+// progress are only kept in memory, so they are abandoned.
+func TestRestoreOpenGames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.sqlite")
+	_, ts := newTestServer(t, Config{Store: openTestStore(t, path)})
+
+	var open LobbyResponse
+	req := LobbyRequest{CreateRequest: CreateRequest{Seats: []Seat{{}, {}}}, Name: "open"}
+	if status := doRequest(t, "POST", ts.URL+"/api/lobbies", "", req, &open); status != http.StatusCreated {
+		t.Fatalf("POST /api/lobbies: status %d", status)
+	}
+	var alice JoinResponse
+	joinURL := ts.URL + "/api/lobbies/" + open.Lobby.Code + "/join"
+	if status := doRequest(t, "POST", joinURL, "", JoinRequest{Name: "alice"}, &alice); status != http.StatusOK {
+		t.Fatalf("POST join: status %d", status)
+	}
+
+	var playing LobbyResponse
+	req.Name = "playing"
+	if status := doRequest(t, "POST", ts.URL+"/api/lobbies", "", req, &playing); status != http.StatusCreated {
+		t.Fatalf("POST /api/lobbies: status %d", status)
+	}
+	for range 2 {
+		joinURL := ts.URL + "/api/lobbies/" + playing.Lobby.Code + "/join"
+		if status := doRequest(t, "POST", joinURL, "", JoinRequest{}, nil); status != http.StatusOK {
+			t.Fatalf("POST join: status %d", status)
+		}
+	}
+
+	// Restart the server with the same store.
+	restarted, ts := newTestServer(t, Config{Store: openTestStore(t, path)})
+	restarted.mx.Lock()
+	_, hasOpen := restarted.lobbies[open.Lobby.Code]
+	_, hasPlaying := restarted.games[playing.Game.ID]
+	restarted.mx.Unlock()
+	if !hasOpen {
+		t.Fatalf("lobby %s was not restored", open.Lobby.Code)
+	} else if hasPlaying {
+		t.Fatalf("game %s in progress was restored", playing.Game.ID)
+	}
+
+	var restored Snapshot
+	if status := doRequest(t, "GET", ts.URL+"/api/games/"+open.Game.ID, "", nil, &restored); status != http.StatusOK {
+		t.Fatalf("GET restored game: status %d", status)
+	}
+	if seat := restored.Seats[alice.Seat]; !seat.Joined || seat.Name != "alice" {
+		t.Errorf("restored seat %d = %+v, want alice's", alice.Seat, seat)
+	}
+
+	// The second player joins by code, and alice plays with her token.
+	joinURL = ts.URL + "/api/lobbies/" + open.Lobby.Code + "/join"
+	if status := doRequest(t, "POST", joinURL, "", JoinRequest{Name: "bob"}, nil); status != http.StatusOK {
+		t.Fatalf("POST join after restart: status %d", status)
+	}
+	var turn Snapshot
+	turnURL := ts.URL + "/api/games/" + open.Game.ID + "/turn"
+	if status := doRequest(t, "GET", turnURL, alice.Token, nil, &turn); status != http.StatusOK {
+		t.Fatalf("GET turn with the token from before the restart: status %d", status)
+	} else if !turn.Started || turn.CurrentSeat != alice.Seat {
+		t.Errorf("turn = %+v, want alice's turn in the started game", turn)
+	}
+
+	var status string
+	if err := restarted.config.Store.db.QueryRow(`SELECT status FROM games WHERE id = ?`,
+		playing.Game.ID).Scan(&status); err != nil {
+		t.Fatal(err)
+	} else if status != statusAbandoned {
+		t.Errorf("status of the game in progress = %q, want %q", status, statusAbandoned)
+	}
+}
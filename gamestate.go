@@ -24,7 +24,7 @@ func NewGameState(numPlayers int) GameState {
 	}
 
 	return GameState{
-		NumDiceToRoll: MaxNumDice,
+		NumDiceToRoll: turnNumDice,
 		NumPlayers:    uint8(numPlayers),
 	}
 }
@@ -50,8 +50,23 @@ func (gs GameState) String() string {
 		gs.NumDiceToRoll, incr*int(gs.ScoreThisRound), scores[:gs.NumPlayers])
 }
 
+// Convert a score in points to the internal representation.
+// Scores must be a non-negative multiple of 50, up to the maximum of 12,750.
+func ParseScore(points int) (uint8, error) {
+	if points < 0 || points%incr != 0 || points/incr > 255 {
+		return 0, fmt.Errorf("invalid score: %d", points)
+	}
+	return uint8(points / incr), nil
+}
+
 // A unique ID for this game state within the set of all
 // possible games with a certain number of players.
+//
+// Opponent scores are not canonicalized by sorting: opponents are ordered by
+// turn, which decides who plays next and who gets the last turn once a player
+// reaches the score to win, so states that permute opponents with different
+// scores are not equivalent. Permuting opponents with identical scores does
+// not change the state, so such states already share an ID.
 func (gs GameState) ID() int {
 	// The IDs should be arranged so that there is locality in the
 	// as process all states.
@@ -74,7 +89,7 @@ func GameStateFromID(numPlayers, id int) GameState {
 	}
 
 	return GameState{
-		NumDiceToRoll:  uint8(id >> ((numPlayers + 1) * numScoreBits)),
+		NumDiceToRoll:  uint8(id>>((numPlayers+1)*numScoreBits)) + 1,
 		ScoreThisRound: uint8(id & 0xff),
 		NumPlayers:     uint8(numPlayers),
 		PlayerScores:   playerScores,
@@ -139,6 +154,12 @@ func (gs GameState) SerializeTo(buf []byte) int {
 	return nBytes
 }
 
+// The number of distinct game states with the given number of players.
+// All game state IDs are less than this.
+func NumGameStates(numPlayers int) int {
+	return calcNumDistinctStates(numPlayers)
+}
+
 func calcNumDistinctStates(numPlayers int) int {
 	return MaxNumDice << ((numPlayers + 1) * numScoreBits)
 }
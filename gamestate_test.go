@@ -0,0 +1,89 @@
+package farkle
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Play random games with random legal actions under each of invariantRules,
+// checking each transition, and that a game that is over accepts no more rolls
+// or actions.
+func TestGameEndInvariants(t *testing.T) {
+	numGames := 1000
+	if testing.Short() {
+		numGames = 100
+	}
+	rng := rand.New(rand.NewSource(12345))
+	for _, r := range invariantRules {
+		setRulesIfChanged(t, r)
+		for i := 0; i < numGames; i++ {
+			numPlayers := 1 + rng.Intn(maxNumPlayers)
+			seed := rng.Int63()
+			if err := safely(func() error { return checkRandomGame(numPlayers, seed) }); err != nil {
+				t.Errorf("%v rules, %d-player game with seed %d: %v", r.Fingerprint(), numPlayers, seed, err)
+			}
+		}
+	}
+}
+
+// Play a game with random legal actions, checking each transition, and check
+// that it stays over once it is over.
+func checkRandomGame(numPlayers int, seed int64) error {
+	rng := rand.New(rand.NewSource(seed))
+	game := NewGame(numPlayers, rng)
+	for turns := 0; !game.IsOver(); {
+		if turns > 10000 {
+			return fmt.Errorf("game did not end after %d turns", turns)
+		}
+		state := game.State()
+		roll, err := game.Roll()
+		if err != nil {
+			return err
+		}
+
+		action := Action{}
+		if actions := LegalActions(state, roll); len(actions) > 0 {
+			action = actions[rng.Intn(len(actions))]
+		}
+		if err := checkTransition(state, action); err != nil {
+			return fmt.Errorf("%v, %v: %w", state, action, err)
+		}
+		if err := game.Apply(action); err != nil {
+			return fmt.Errorf("%v, %v: %w", state, action, err)
+		}
+		if !action.ContinueRolling {
+			turns++
+		}
+	}
+
+	state, result := game.State(), game.Result()
+	if _, err := game.Roll(); err == nil {
+		return fmt.Errorf("rolled after the game was over")
+	} else if err := game.Apply(Action{}); err == nil {
+		return fmt.Errorf("applied an action after the game was over")
+	} else if game.State() != state || !game.IsOver() {
+		return fmt.Errorf("state changed after the game was over")
+	} else if len(result.Winners) == 0 {
+		return fmt.Errorf("game over without a winner")
+	}
+	return nil
+}
+
+// GameStateFromID returns the state the ID was created from, including the
+// number of dice to roll, which is stored less one.
+func TestGameStateFromID(t *testing.T) {
+	for numPlayers := 1; numPlayers <= 3; numPlayers++ {
+		for numDice := uint8(1); numDice <= MaxNumDice; numDice++ {
+			state := NewGameState(numPlayers)
+			state.NumDiceToRoll = numDice
+			state.ScoreThisRound = 7
+			for i := range numPlayers {
+				state.PlayerScores[i] = uint8(10*i + 3)
+			}
+			if got := GameStateFromID(numPlayers, state.ID()); got != state {
+				t.Errorf("GameStateFromID(%d, %d) = %v, want %v", numPlayers, state.ID(), got, state)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package farkle
 
 import (
 	"bufio"
+	"cmp"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -9,12 +10,12 @@ import (
 	"math"
 	"os"
 	"runtime"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/bsm/extsort"
-	"github.com/golang/glog"
 )
 
 // Action is the choice made by a player after rolling.
@@ -37,6 +38,11 @@ func (a Action) String() string {
 	return fmt.Sprintf("{Held: %s, %s}", roll, contStr)
 }
 
+// The dice held by this action.
+func (a Action) HeldDice() Roll {
+	return rollsByID[a.HeldDiceID]
+}
+
 func ApplyAction(state GameState, action Action) GameState {
 	trickScore := scoreCache[action.HeldDiceID]
 	newScore := state.ScoreThisRound + trickScore
@@ -56,7 +62,7 @@ func ApplyAction(state GameState, action Action) GameState {
 	}
 	state.NumDiceToRoll -= numDiceHeld
 	if state.NumDiceToRoll == 0 {
-		state.NumDiceToRoll = MaxNumDice
+		state.NumDiceToRoll = turnNumDice
 	}
 
 	if !action.ContinueRolling {
@@ -69,36 +75,47 @@ func ApplyAction(state GameState, action Action) GameState {
 		copy(state.PlayerScores[:state.NumPlayers], state.PlayerScores[1:state.NumPlayers])
 		state.PlayerScores[state.NumPlayers-1] = newScore
 		state.ScoreThisRound = 0
-		state.NumDiceToRoll = MaxNumDice
+		state.NumDiceToRoll = turnNumDice
 	}
 
 	return state
 }
 
-// Find the action that maximizes current player win probability.
+// Find the action that maximizes current player win probability
+// (or other value, depending on the objective of the database).
+// In single-player games, minimizes the expected number of turns remaining instead.
+// Safe for concurrent use if db is (see ConcurrentDB).
 func SelectAction(state GameState, rollID uint16, db DB) (Action, [maxNumPlayers]float64) {
-	var bestWinProb [maxNumPlayers]float64
+	if state.NumPlayers == 1 {
+		return selectSolitaireAction(state, rollID, db)
+	}
+
+	return selectAction(state, rollID, db, db)
+}
+
+// Find the action that maximizes current player win probability, looking up
+// the values of states in which the current player continues rolling in db,
+// and the values of states after they bank (or farkle) in nextDB.
+func selectAction(state GameState, rollID uint16, db, nextDB DB) (Action, [maxNumPlayers]float64) {
+	// Values may be negative, e.g. for the ScoreMargin objective.
+	bestWinProb := [maxNumPlayers]float64{math.Inf(-1)}
 	var bestAction Action
-	notYetOnBoard := (state.PlayerScores[0] == 0)
 	potentialActions := rollIDToPotentialActions[rollID]
-	for _, action := range potentialActions {
-		if state.ScoreThisRound == math.MaxUint8 && action.ContinueRolling {
-			// Overflowed score this round. Our assumption is that this is unlikely.
-			// Approximate the solution using the probability as if they stopped.
-			action.ContinueRolling = false
-		}
-
-		newState := ApplyAction(state, action)
-		if notYetOnBoard && !action.ContinueRolling && newState.PlayerScores[state.NumPlayers-1] < 500/incr {
-			// Not a valid state: You must get at least 500 to get on the board.
+	effects := rollIDToActionEffects[rollID]
+	parts := newStateIDParts(state)
+	for i, action := range potentialActions {
+		action, newStateID, allowed := parts.takeAction(action, effects[i])
+		if !allowed {
 			continue
 		}
 
-		pSubtree := db.Get(newState.ID())
-		if !action.ContinueRolling {
+		var pSubtree [maxNumPlayers]float64
+		if action.ContinueRolling {
+			pSubtree = db.Get(newStateID)
+		} else {
 			// Probabilities are rotated since we advanced to the
 			// next player in next state.
-			pSubtree = unrotate(pSubtree, state.NumPlayers)
+			pSubtree = unrotate(nextDB.Get(newStateID), state.NumPlayers)
 		}
 		if pSubtree[0] > bestWinProb[0] {
 			bestWinProb = pSubtree
@@ -108,13 +125,35 @@ func SelectAction(state GameState, rollID uint16, db DB) (Action, [maxNumPlayers
 
 	if len(potentialActions) == 0 {
 		newState := ApplyAction(state, bestAction)
-		pSubtree := db.Get(newState.ID())
+		pSubtree := nextDB.Get(newState.ID())
 		bestWinProb = unrotate(pSubtree, state.NumPlayers)
 	}
 
 	return bestAction, bestWinProb
 }
 
+// The win probability of each player after the current player takes the given
+// action, relative to the current player as in state.
+func EvaluateAction(state GameState, action Action, db DB) [maxNumPlayers]float64 {
+	if state.NumPlayers == 1 {
+		return evaluateSolitaireAction(state, action, db)
+	}
+
+	return evaluateAction(state, action, db, db)
+}
+
+// As EvaluateAction, but looking up the values of states after
+// the current player banks (or farkles) in nextDB.
+func evaluateAction(state GameState, action Action, db, nextDB DB) [maxNumPlayers]float64 {
+	newState := ApplyAction(state, action)
+	if action.ContinueRolling {
+		return db.Get(newState.ID())
+	}
+
+	// Probabilities are rotated since we advanced to the next player.
+	return unrotate(nextDB.Get(newState.ID()), state.NumPlayers)
+}
+
 func unrotate(pWin [maxNumPlayers]float64, numPlayers uint8) [maxNumPlayers]float64 {
 	var result [maxNumPlayers]float64
 	copy(result[1:numPlayers], pWin[:numPlayers])
@@ -122,7 +161,9 @@ func unrotate(pWin [maxNumPlayers]float64, numPlayers uint8) [maxNumPlayers]floa
 	return result
 }
 
-var rollIDToPotentialActions = func() [][]Action {
+var rollIDToPotentialActions = calcPotentialActions()
+
+func calcPotentialActions() [][]Action {
 	result := make([][]Action, len(rollIDToPotentialHolds))
 	for rollID, holds := range rollIDToPotentialHolds {
 		actions := make([]Action, 0, 2*len(holds))
@@ -139,52 +180,326 @@ var rollIDToPotentialActions = func() [][]Action {
 	}
 
 	return result
-}()
+}
 
 const checkpointInterval = 5 * time.Minute
 
 // Recalculate the value of all states in the given iterator,
 // updating the value of each state in the database.
-func UpdateAll(db DB, states iter.Seq2[uint64, GameState], chkpntPath string) {
-	// Recalculate all other states.
-	var mx sync.RWMutex
-	var wg sync.WaitGroup
-	numWorkers := runtime.NumCPU()
-	workCh := make(chan GameState, numWorkers)
-	currentDepth := loadCheckpoint(chkpntPath)
+// Returns statistics about the update of the states at each depth.
+func UpdateAll(db DB, states iter.Seq2[uint64, GameState], chkpntPath string) UpdateStats {
+	return UpdateAllWithOptions(db, states, UpdateOptions{CheckpointPath: chkpntPath})
+}
+
+// Options for value iteration.
+type UpdateOptions struct {
+	// Path to save the depth reached to, so that an interrupted
+	// cycle can be resumed (optional).
+	CheckpointPath string
+	// Divide the states at each depth into contiguous ranges of IDs, so that
+	// each worker touches a contiguous region of the database, and run each
+	// worker on its own CPU (Linux only). This can improve throughput on
+	// machines with several NUMA nodes.
+	PinWorkers bool
+	// Before updating the states at each depth, ask the kernel to read the
+	// pages of the database holding their values ahead of time. This reduces
+	// page fault stalls when the database does not fit in memory.
+	Prefetch bool
+	// Update the states at each depth with the same number of dice to roll
+	// and score this round together, in batches sorted by ID, so that the
+	// values they lead to are read in a few streams through the database.
+	// PinWorkers is not supported with it.
+	Batched bool
+}
+
+// As UpdateAll, with the given options.
+func UpdateAllWithOptions(db DB, states iter.Seq2[uint64, GameState], opts UpdateOptions) UpdateStats {
+	return updateTables([]valueTable{{
+		db: db,
+		value: func(state GameState) [maxNumPlayers]float64 {
+			return calcStateValue(state, db)
+		},
+		values: func(states []GameState, result [][maxNumPlayers]float64) {
+			calcStateValues(states, db, result)
+		},
+	}}, states, opts)
+}
+
+// A database of values to update for each game state,
+// and how to calculate the value of non-terminal states.
+type valueTable struct {
+	db    DB
+	value func(state GameState) [maxNumPlayers]float64
+	// Calculate the values of a batch of states, as in calcStateValues
+	// (optional, for UpdateOptions.Batched).
+	values func(states []GameState, result [][maxNumPlayers]float64)
+}
+
+// Statistics about the update of all states at one depth of the game tree.
+type DepthStats struct {
+	Depth uint64
+	// The number of states whose value was recalculated.
+	NumStates int
+	// The mean and maximum absolute change in the value of a state,
+	// over all players (and tables).
+	MeanChange float64
+	MaxChange  float64
+	Elapsed    time.Duration
+}
+
+func (s DepthStats) StatesPerSecond() float64 {
+	return float64(s.NumStates) / s.Elapsed.Seconds()
+}
+
+// Add the change in the value of one state.
+func (s *DepthStats) add(change float64) {
+	s.MeanChange += (change - s.MeanChange) / float64(s.NumStates+1)
+	s.MaxChange = max(s.MaxChange, change)
+	s.NumStates++
+}
+
+// Combine the statistics for another subset of the states at this depth.
+func (s *DepthStats) merge(other DepthStats) {
+	if n := s.NumStates + other.NumStates; n > 0 {
+		s.MeanChange = (s.MeanChange*float64(s.NumStates) + other.MeanChange*float64(other.NumStates)) / float64(n)
+	}
+	s.MaxChange = max(s.MaxChange, other.MaxChange)
+	s.NumStates += other.NumStates
+}
+
+// Statistics about one value iteration cycle, in the order the depths were updated.
+type UpdateStats struct {
+	Depths []DepthStats
+}
+
+// The statistics for all depths combined.
+func (s UpdateStats) Total() DepthStats {
+	var total DepthStats
+	for _, depth := range s.Depths {
+		total.merge(depth)
+		total.Elapsed += depth.Elapsed
+	}
+	return total
+}
+
+// As UpdateAll, but updating the value of each state in all of the given tables.
+//
+// Each state is deeper than all of its children, except those that lead back
+// to a state on the path being enumerated (a new turn after farkles), which is
+// deeper and so updated later in the cycle. States at the same depth never
+// depend on each other, and the values at each depth are stored before the
+// next depth starts, so every update already sees the values of its children
+// from this cycle, as in Gauss-Seidel iteration. Storing each value as soon as
+// it is calculated, one state at a time, would not converge any faster.
+func updateTables(tables []valueTable, states iter.Seq2[uint64, GameState], opts UpdateOptions) UpdateStats {
+	var stats UpdateStats
+	var updater depthUpdater = &concurrentUpdater{tables: tables}
+	if opts.Batched && !allBatched(tables) {
+		Logger().Warn("Batched updates are not supported for these tables")
+		opts.Batched = false
+	}
+	if opts.Batched {
+		updater = &bandUpdater{tables: tables, prefetch: opts.Prefetch}
+	} else if opts.PinWorkers || opts.Prefetch {
+		updater = newPartitionedUpdater(tables, opts)
+	}
+
+	currentDepth := loadCheckpoint(opts.CheckpointPath)
+	started := false
 	lastCheckpointTime := time.Now()
 	for depth, state := range states {
-		if depth != currentDepth {
+		if depth != currentDepth || !started {
 			// Wait for previous depth to complete.
-			close(workCh)
-			wg.Wait()
+			if started {
+				stats.Depths = append(stats.Depths, updater.Finish())
+			}
 
-			if time.Since(lastCheckpointTime) > checkpointInterval {
-				if err := saveCheckpoint(chkpntPath, depth); err != nil {
-					glog.Warningf("Unable to save checkpoint: %v", err)
+			if opts.CheckpointPath != "" && time.Since(lastCheckpointTime) > checkpointInterval {
+				if err := saveCheckpoint(opts.CheckpointPath, depth); err != nil {
+					Logger().Warn("Unable to save checkpoint", "err", err)
 				}
 
 				lastCheckpointTime = time.Now()
 			}
 
-			// Start up workers for next depth.
-			glog.Infof("Processing game states with depth=%d", depth)
+			Logger().Info("Processing game states", "depth", depth)
 			currentDepth = depth
-			workCh = make(chan GameState, numWorkers)
-			wg.Add(numWorkers)
-			for i := 0; i < numWorkers; i++ {
-				go func() {
-					updateWorker(db, workCh, &mx)
-					wg.Done()
-				}()
-			}
+			started = true
+			updater.Start(depth)
+		}
+
+		updater.Add(state)
+	}
+
+	if started {
+		stats.Depths = append(stats.Depths, updater.Finish())
+	}
+	return stats
+}
+
+// Updates the values of all states at one depth at a time.
+type depthUpdater interface {
+	Start(depth uint64)
+	Add(state GameState)
+	// Wait for all states at this depth to be updated.
+	Finish() DepthStats
+}
+
+// Updates states as they are added, with one worker per CPU. The values that
+// one worker stores are never read by the others at the same depth (see
+// updateTables), so the results do not depend on the number of workers or
+// scheduling.
+type concurrentUpdater struct {
+	tables  []valueTable
+	mx      sync.RWMutex
+	wg      sync.WaitGroup
+	workCh  chan GameState
+	statsMx sync.Mutex
+	stats   DepthStats
+	start   time.Time
+}
+
+func (u *concurrentUpdater) Start(depth uint64) {
+	u.stats = DepthStats{Depth: depth}
+	u.start = time.Now()
+	numWorkers := runtime.NumCPU()
+	u.workCh = make(chan GameState, numWorkers)
+	u.wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			workerStats := updateWorker(u.tables, u.workCh, &u.mx)
+			u.statsMx.Lock()
+			u.stats.merge(workerStats)
+			u.statsMx.Unlock()
+			u.wg.Done()
+		}()
+	}
+}
+
+func (u *concurrentUpdater) Add(state GameState) {
+	u.workCh <- state
+}
+
+func (u *concurrentUpdater) Finish() DepthStats {
+	close(u.workCh)
+	u.wg.Wait()
+	u.stats.Elapsed = time.Since(u.start)
+	return u.stats
+}
+
+// Collects all states at each depth and divides them into contiguous ranges,
+// one for each worker.
+type partitionedUpdater struct {
+	tables []valueTable
+	// Sort the states by ID, so that each worker touches a contiguous region
+	// of the database, and run each worker on its own CPU.
+	cpus []int
+	// Prefetch the pages of the database holding the states at each depth.
+	prefetch bool
+
+	mx     sync.RWMutex
+	states []GameState
+	depth  uint64
+	start  time.Time
+}
+
+func newPartitionedUpdater(tables []valueTable, opts UpdateOptions) *partitionedUpdater {
+	u := &partitionedUpdater{
+		tables:   tables,
+		prefetch: opts.Prefetch,
+	}
+	if opts.PinWorkers {
+		cpus, err := allowedCPUs()
+		if err != nil {
+			Logger().Warn("Unable to pin workers", "err", err)
 		}
+		u.cpus = cpus
+	}
+	return u
+}
+
+func (u *partitionedUpdater) Start(depth uint64) {
+	u.states = u.states[:0]
+	u.depth = depth
+	u.start = time.Now()
+}
+
+func (u *partitionedUpdater) Add(state GameState) {
+	u.states = append(u.states, state)
+}
 
-		workCh <- state
+func (u *partitionedUpdater) Finish() DepthStats {
+	if u.prefetch {
+		prefetchStates(u.tables, u.states)
+	}
+
+	n := len(u.states)
+	order := make([]int, n)
+	for j := range order {
+		order[j] = j
+	}
+	numWorkers := runtime.NumCPU()
+	if u.cpus != nil {
+		numWorkers = len(u.cpus)
+		slices.SortFunc(order, func(a, b int) int {
+			return cmp.Compare(u.states[a].ID(), u.states[b].ID())
+		})
 	}
 
-	close(workCh)
+	var wg sync.WaitGroup
+	stats := make([]DepthStats, numWorkers)
+	chunkSize := max((n+numWorkers-1)/numWorkers, 1)
+	for k := 0; k*chunkSize < n; k++ {
+		chunk := order[k*chunkSize : min((k+1)*chunkSize, n)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if u.cpus != nil {
+				if err := pinToCPU(u.cpus[k]); err != nil {
+					Logger().Warn("Unable to pin worker", "cpu", u.cpus[k], "err", err)
+				}
+			}
+
+			stats[k] = updateWorker(u.tables, u.chunkCh(chunk), &u.mx)
+		}()
+	}
 	wg.Wait()
+
+	result := DepthStats{Depth: u.depth}
+	for _, workerStats := range stats {
+		result.merge(workerStats)
+	}
+	result.Elapsed = time.Since(u.start)
+	return result
+}
+
+// A closed channel containing the states with the given indices.
+func (u *partitionedUpdater) chunkCh(chunk []int) <-chan GameState {
+	ch := make(chan GameState, len(chunk))
+	for _, j := range chunk {
+		ch <- u.states[j]
+	}
+	close(ch)
+	return ch
+}
+
+// The new value of the given state in the table.
+func calcTableValue(table valueTable, state GameState) [maxNumPlayers]float64 {
+	if state.IsGameOver() {
+		return calcEndGameValue(state, table.db.Metadata())
+	}
+	return table.value(state)
+}
+
+// The maximum absolute change for any player from the
+// value of the given state currently stored in the table.
+func valueChange(table valueTable, state GameState, pWin [maxNumPlayers]float64) float64 {
+	change := 0.0
+	prev := table.db.Get(state.ID())
+	for j := range pWin[:state.NumPlayers] {
+		change = max(change, math.Abs(pWin[j]-prev[j]))
+	}
+	return change
 }
 
 func loadCheckpoint(path string) uint64 {
@@ -200,8 +515,7 @@ func loadCheckpoint(path string) uint64 {
 
 	n, err := strconv.ParseInt(string(depthStr), 10, 64)
 	if err != nil {
-		glog.Warningf("Unable to parse checkpoint `%s`: %v",
-			string(depthStr), err)
+		Logger().Warn("Unable to parse checkpoint", "checkpoint", string(depthStr), "err", err)
 		return 0
 	}
 
@@ -223,42 +537,97 @@ func saveCheckpoint(path string, depth uint64) error {
 	return f.Close()
 }
 
-func updateWorker(db DB, workCh <-chan GameState, mx *sync.RWMutex) {
+func updateWorker(tables []valueTable, workCh <-chan GameState, mx *sync.RWMutex) DepthStats {
 	// We batch updates to the database to reduce lock contention.
 	batchSize := 1024 // Arbitrary, tunable
 	batchIDs := make([]int, 0, batchSize)
-	batchUpdates := make([][maxNumPlayers]float64, 0, batchSize)
+	var stats DepthStats
+	batchUpdates := make([][][maxNumPlayers]float64, len(tables))
+	for i := range batchUpdates {
+		batchUpdates[i] = make([][maxNumPlayers]float64, 0, batchSize)
+	}
+
+	flush := func() {
+		mx.Lock()
+		defer mx.Unlock()
+		for i, table := range tables {
+			for j, id := range batchIDs {
+				table.db.Put(id, batchUpdates[i][j])
+			}
+			batchUpdates[i] = batchUpdates[i][:0]
+		}
+		batchIDs = batchIDs[:0]
+	}
+
 	for state := range workCh {
-		var pWin [maxNumPlayers]float64
-		if state.IsGameOver() {
-			pWin = calcEndGameValue(state)
-		} else {
-			mx.RLock()
-			pWin = calcStateValue(state, db)
+		mx.RLock()
+		if allDecided(tables, state.ID()) {
 			mx.RUnlock()
+			continue
+		}
+
+		change := 0.0
+		for i, table := range tables {
+			pWin := calcTableValue(table, state)
+			batchUpdates[i] = append(batchUpdates[i], pWin)
+			change = max(change, valueChange(table, state, pWin))
 		}
+		mx.RUnlock()
+		stats.add(change)
 
 		batchIDs = append(batchIDs, state.ID())
-		batchUpdates = append(batchUpdates, pWin)
 		if len(batchIDs) == cap(batchIDs) {
-			mx.Lock()
-			for i, id := range batchIDs {
-				db.Put(id, batchUpdates[i])
+			flush()
+		}
+	}
+
+	flush()
+	return stats
+}
+
+// Databases that can read the values of states ahead of time.
+type prefetchDB interface {
+	// Read the values of the given states, sorted by ID, in the background.
+	prefetch(gsIDs []int)
+}
+
+// Prefetch the values of the given states in all tables that support it.
+func prefetchStates(tables []valueTable, states []GameState) {
+	var gsIDs []int
+	for _, table := range tables {
+		db, ok := table.db.(prefetchDB)
+		if !ok {
+			continue
+		}
+		if gsIDs == nil {
+			gsIDs = make([]int, len(states))
+			for i, state := range states {
+				gsIDs[i] = state.ID()
 			}
-			mx.Unlock()
-			batchIDs = batchIDs[:0]
-			batchUpdates = batchUpdates[:0]
+			slices.Sort(gsIDs)
 		}
+		db.prefetch(gsIDs)
 	}
+}
+
+// Databases in which the values of some states are final.
+type decidedDB interface {
+	IsDecided(gsID int) bool
+}
 
-	mx.Lock()
-	defer mx.Unlock()
-	for i, id := range batchIDs {
-		db.Put(id, batchUpdates[i])
+// Whether the value of the given state is final in all of the tables,
+// so that it need not be recalculated.
+func allDecided(tables []valueTable, gsID int) bool {
+	for _, table := range tables {
+		db, ok := table.db.(decidedDB)
+		if !ok || !db.IsDecided(gsID) {
+			return false
+		}
 	}
+	return true
 }
 
-func calcEndGameValue(state GameState) [maxNumPlayers]float64 {
+func calcEndGameWinProb(state GameState) [maxNumPlayers]float64 {
 	winningScore := state.HighestScore()
 	winners := make([]int, 0, maxNumPlayers)
 	for player, score := range state.PlayerScores[:state.NumPlayers] {
@@ -278,18 +647,38 @@ func calcEndGameValue(state GameState) [maxNumPlayers]float64 {
 	return result
 }
 
+// Calculate the win probability (or other value, depending on the objective of
+// the database) of each player in the given state, assuming all players play
+// optimally with respect to the values stored in the database.
+// For single-player games, this is the expected number of turns remaining.
+// Safe for concurrent use if db is (see ConcurrentDB).
+func CalculateWinProb(state GameState, db DB) [maxNumPlayers]float64 {
+	if state.IsGameOver() {
+		return calcEndGameValue(state, db.Metadata())
+	}
+
+	return calcStateValue(state, db)
+}
+
 func calcStateValue(state GameState, db DB) [maxNumPlayers]float64 {
 	var pWin [maxNumPlayers]float64
 	for _, wRoll := range allRolls[state.NumDiceToRoll] {
 		_, pSubgame := SelectAction(state, wRoll.ID, db)
-		for i, p := range pSubgame[:state.NumPlayers] {
-			pWin[i] += wRoll.Prob * p
-		}
+		mixInto(&pWin, wRoll.Prob, &pSubgame)
 	}
 
 	return pWin
 }
 
+// Accumulate w * p into pWin. Entries for players beyond NumPlayers are
+// always zero, so the whole array is mixed. Unrolling the loop by hand
+// measured no faster.
+func mixInto(pWin *[maxNumPlayers]float64, w float64, p *[maxNumPlayers]float64) {
+	for i := range pWin {
+		pWin[i] += w * p[i]
+	}
+}
+
 // Save all game states from the given iterator to a file.
 func SaveGameStates(states iter.Seq2[uint64, GameState], path string) error {
 	f, err := os.Create(path)
@@ -299,7 +688,7 @@ func SaveGameStates(states iter.Seq2[uint64, GameState], path string) error {
 	defer f.Close()
 	w := bufio.NewWriterSize(f, 4*1024*1024)
 
-	glog.Infof("Saving game states to: %s", path)
+	Logger().Info("Saving game states", "path", path)
 	buf := make([]byte, maxSizeOfGameState+8)
 	i := 0
 	for depth, state := range states {
@@ -311,7 +700,7 @@ func SaveGameStates(states iter.Seq2[uint64, GameState], path string) error {
 
 		i++
 		if i%10000000 == 0 {
-			glog.Infof("...%d", i)
+			Logger().Info("Saved game states", "count", i)
 		}
 	}
 
@@ -355,16 +744,27 @@ func IterGameStates(numPlayers int, path string) (iter.Seq2[uint64, GameState],
 // Game states are sorted by depth in descending order such that end game states
 // are enumerated before early game states.
 func SortedGameStates(numPlayers int, workDir string) iter.Seq2[uint64, GameState] {
+	Logger().Info("Enumerating all game states",
+		"states", calcNumDistinctStates(numPlayers), "players", numPlayers)
+	return sortGameStates(allGameStates(numPlayers, workDir), workDir)
+}
+
+// As SortedGameStates, but only the game states reachable from the given state.
+func SortedGameStatesFrom(initialState GameState, workDir string) iter.Seq2[uint64, GameState] {
+	Logger().Info("Enumerating game states", "from", initialState)
+	return sortGameStates(GameStatesFrom(initialState, workDir), workDir)
+}
+
+// Sort game states by depth in descending order.
+func sortGameStates(states iter.Seq2[int, GameState], workDir string) iter.Seq2[uint64, GameState] {
 	sorter := extsort.New(&extsort.Options{
 		WorkDir:    workDir,
 		Compare:    compareGameStateDepth,
-		BufferSize: 16 * 1024 * 1024, // 16 GiB
+		BufferSize: sortBufferSize(),
 	})
 
-	glog.Infof("Enumerating all %d %d-player game states",
-		calcNumDistinctStates(numPlayers), numPlayers)
 	i := 0
-	for depth, gs := range allGameStates(numPlayers, workDir) {
+	for depth, gs := range states {
 		data := make([]byte, maxSizeOfGameState+8)
 		binary.LittleEndian.PutUint64(data[:8], uint64(depth))
 		n := gs.SerializeTo(data[8:])
@@ -374,11 +774,11 @@ func SortedGameStates(numPlayers int, workDir string) iter.Seq2[uint64, GameStat
 
 		i++
 		if i%100000 == 0 {
-			glog.Infof("...%d", i)
+			Logger().Info("Enumerated game states", "count", i)
 		}
 	}
 
-	glog.Info("Sorting game states by depth")
+	Logger().Info("Sorting game states by depth")
 	iter, err := sorter.Sort()
 	if err != nil {
 		panic(fmt.Errorf("error sorting game states: %w", err))
@@ -423,8 +823,14 @@ func compareGameStateDepth(d1, d2 []byte) int {
 // depth in the game tree. Depth=0 is an endgame state. Non-endgame
 // states have a depth 1 greater than all of their child subgames.
 func allGameStates(numPlayers int, workDir string) iter.Seq2[int, GameState] {
+	return GameStatesFrom(NewGameState(numPlayers), workDir)
+}
+
+// Return an iterator over all distinct game states reachable from the given
+// state, and their depth in the game tree, as in allGameStates.
+func GameStatesFrom(initialState GameState, workDir string) iter.Seq2[int, GameState] {
+	numPlayers := int(initialState.NumPlayers)
 	return func(yield func(int, GameState) bool) {
-		initialState := NewGameState(numPlayers)
 		inStack := newBitMask(calcNumDistinctStates(numPlayers))
 		depthFile, err := os.CreateTemp(workDir, fmt.Sprintf("depthmap-%dplayer-*.mmap", numPlayers))
 		if err != nil {
@@ -438,6 +844,7 @@ func allGameStates(numPlayers int, workDir string) iter.Seq2[int, GameState] {
 		}
 		defer depthMap.Close()
 		recursiveEnumerateStates(initialState, inStack, depthMap, yield)
+		Logger().Debug("Enumeration stack used", "KiB", inStack.allocatedBytes()/1024)
 	}
 }
 
@@ -458,23 +865,18 @@ func recursiveEnumerateStates(state GameState, inStack *bitMask, depthMap *depth
 	inStack.Set(gsID)
 	defer inStack.Clear(gsID)
 
-	notYetOnBoard := (state.PlayerScores[0] == 0)
 	maxChildDepth := 0
+	parts := newStateIDParts(state)
 	for _, wRoll := range allRolls[state.NumDiceToRoll] {
 		potentialActions := rollIDToPotentialActions[wRoll.ID]
-		for _, action := range potentialActions {
-			if state.ScoreThisRound == math.MaxUint8 && action.ContinueRolling {
-				// Overflowed score this round. Our assumption is that this is unlikely.
-				// Approximate the solution using the probability as if they stopped.
-				action.ContinueRolling = false
-			}
-
-			newState := ApplyAction(state, action)
-			if notYetOnBoard && !action.ContinueRolling && newState.PlayerScores[state.NumPlayers-1] < 500/incr {
-				// Not a valid state: You must get at least 500 to get on the board.
+		effects := rollIDToActionEffects[wRoll.ID]
+		for i, action := range potentialActions {
+			action, _, allowed := parts.takeAction(action, effects[i])
+			if !allowed {
 				continue
 			}
 
+			newState := ApplyAction(state, action)
 			depth, ok := recursiveEnumerateStates(newState, inStack, depthMap, yield)
 			maxChildDepth = max(maxChildDepth, depth)
 			if !ok {
@@ -501,4 +903,9 @@ func init() {
 	if scoreCache[0] != 0 {
 		panic(fmt.Errorf("farkle should have zero score! got %d", scoreCache[0]))
 	}
+
+	if checksum := calcRollTableChecksum(); checksum != rollTableChecksum {
+		panic(fmt.Errorf("roll table checksum %#x does not match version %d (%#x)",
+			checksum, RollTableVersion, uint64(rollTableChecksum)))
+	}
 }
@@ -0,0 +1,126 @@
+package farkle
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Apply every legal action in random positions under each of invariantRules:
+// scores never decrease or wrap around, the number of dice to roll stays
+// within the dice of the rules, and banking rotates the scores without
+// changing the other players' scores.
+func TestApplyActionInvariants(t *testing.T) {
+	numStates := 10000
+	if testing.Short() {
+		numStates = 1000
+	}
+	rng := rand.New(rand.NewSource(12345))
+	for _, r := range invariantRules {
+		setRulesIfChanged(t, r)
+		for i := 0; i < numStates; i++ {
+			state := randomState(rng)
+			roll := rollDice(int(state.NumDiceToRoll), rng.Intn)
+			actions := LegalActions(state, roll)
+			if len(actions) == 0 {
+				actions = []Action{{}} // Farkle.
+			}
+			for _, action := range actions {
+				if err := safely(func() error { return checkTransition(state, action) }); err != nil {
+					t.Errorf("%v rules, %v, %v: %v", r.Fingerprint(), state, action, err)
+				}
+			}
+		}
+	}
+}
+
+func FuzzApplyAction(f *testing.F) {
+	for which := range invariantRules {
+		f.Add(uint8(which), []byte{0, 0}, uint8(0), uint8(6), []byte{0, 4, 1, 2, 3, 5}, uint8(0))
+		f.Add(uint8(which), []byte{199, 150, 30}, uint8(40), uint8(2), []byte{0, 0}, uint8(1))
+		f.Add(uint8(which), []byte{255, 255, 255, 255}, uint8(255), uint8(1), []byte{4}, uint8(3))
+	}
+	f.Fuzz(func(t *testing.T, which uint8, scores []byte, turnScore, numDice uint8, dice []byte, choice uint8) {
+		setRulesIfChanged(t, invariantRules[int(which)%len(invariantRules)])
+		if len(scores) == 0 {
+			return
+		}
+		state := NewGameState(min(len(scores), maxNumPlayers))
+		copy(state.PlayerScores[:state.NumPlayers], scores)
+		state.ScoreThisRound = turnScore
+		state.NumDiceToRoll = 1 + numDice%turnNumDice
+		if state.IsGameOver() {
+			return
+		}
+
+		var roll Roll
+		for i := 0; i < int(state.NumDiceToRoll); i++ {
+			b := byte(i)
+			if i < len(dice) {
+				b = dice[i]
+			}
+			roll[1+int(b)%numSides]++
+		}
+		action := Action{} // Farkle.
+		if actions := LegalActions(state, roll); len(actions) > 0 {
+			action = actions[int(choice)%len(actions)]
+		}
+		if err := checkTransition(state, action); err != nil {
+			t.Errorf("%v, %v: %v", state, action, err)
+		}
+	})
+}
+
+// A random position that is not the end of the game, including scores that
+// are far past the target and turn scores that are about to overflow.
+func randomState(rng *rand.Rand) GameState {
+	for {
+		state := NewGameState(1 + rng.Intn(maxNumPlayers))
+		for i := range state.PlayerScores[:state.NumPlayers] {
+			state.PlayerScores[i] = uint8(rng.Intn(256))
+		}
+		state.ScoreThisRound = uint8(rng.Intn(256))
+		state.NumDiceToRoll = uint8(1 + rng.Intn(int(turnNumDice)))
+		if !state.IsGameOver() {
+			return state
+		}
+	}
+}
+
+func checkTransition(state GameState, action Action) error {
+	next := ApplyAction(state, action)
+	n := int(state.NumPlayers)
+	if next.NumPlayers != state.NumPlayers {
+		return fmt.Errorf("number of players changed to %d", next.NumPlayers)
+	} else if next.NumDiceToRoll < 1 || next.NumDiceToRoll > turnNumDice {
+		return fmt.Errorf("%d dice to roll", next.NumDiceToRoll)
+	}
+
+	if action.ContinueRolling {
+		if next.PlayerScores != state.PlayerScores {
+			return fmt.Errorf("scores changed to %v without banking", next.PlayerScores[:n])
+		} else if next.ScoreThisRound < state.ScoreThisRound {
+			return fmt.Errorf("turn score decreased to %d", incr*int(next.ScoreThisRound))
+		}
+		return nil
+	}
+
+	if next.ScoreThisRound != 0 || next.NumDiceToRoll != turnNumDice {
+		return fmt.Errorf("next turn starts with %d points and %d dice",
+			incr*int(next.ScoreThisRound), next.NumDiceToRoll)
+	}
+	for i := 1; i < n; i++ {
+		if next.PlayerScores[i-1] != state.PlayerScores[i] {
+			return fmt.Errorf("scores %v did not rotate to %v", state.PlayerScores[:n], next.PlayerScores[:n])
+		}
+	}
+	banked := next.PlayerScores[n-1]
+	if banked < state.PlayerScores[0] {
+		return fmt.Errorf("score decreased from %d to %d", incr*int(state.PlayerScores[0]), incr*int(banked))
+	} else if IsFarkle(action.HeldDice()) && action != (Action{}) {
+		return fmt.Errorf("held dice that do not score")
+	} else if action == (Action{}) && banked != state.PlayerScores[0] {
+		return fmt.Errorf("farkle changed the score to %d", incr*int(banked))
+	}
+	return nil
+}
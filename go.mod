@@ -4,9 +4,22 @@ go 1.23.4
 
 require github.com/golang/glog v1.2.3
 
-require golang.org/x/sys v0.18.0
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/bsm/extsort v0.6.1
+	golang.org/x/sys v0.30.0
+	modernc.org/sqlite v1.36.0
+)
 
 require (
-	github.com/bsm/extsort v0.6.1 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/klauspost/compress v1.16.3 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/exp v0.0.0-20230315142452-642cacee5cc0 // indirect
+	modernc.org/libc v1.61.13 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.8.2 // indirect
 )
@@ -0,0 +1,227 @@
+package farkle
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+)
+
+// Values computed by this package, derived independently by hand or by brute
+// force, in testdata/golden.json. They catch changes in results of the scoring
+// engine and the solver that would otherwise go unnoticed.
+type goldenValues struct {
+	// Under the standard rules, counted over all ordered outcomes.
+	FarkleProbs     []goldenFraction `json:"farkleProbs"`
+	FirstRollScores []goldenFraction `json:"firstRollScores"`
+	// Miniature one-player games, also solved by bruteForceSolitaire.
+	SolitaireTurns []struct {
+		Rules string  `json:"rules"`
+		Turns float64 `json:"turns"`
+	} `json:"solitaireTurns"`
+}
+
+type goldenFraction struct {
+	NumDice int     `json:"numDice"`
+	Count   float64 `json:"count"`
+	OutOf   float64 `json:"outOf"`
+}
+
+func (f goldenFraction) value() float64 {
+	return f.Count / f.OutOf
+}
+
+func loadGoldenValues(t *testing.T) goldenValues {
+	t.Helper()
+	buf, err := os.ReadFile("testdata/golden.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result goldenValues
+	if err := json.Unmarshal(buf, &result); err != nil {
+		t.Fatalf("Invalid golden values: %v", err)
+	}
+	return result
+}
+
+// The probability of farkling a single roll of each number of dice.
+func TestGoldenFarkleProbs(t *testing.T) {
+	setTestRules(t, "standard")
+	for _, want := range loadGoldenValues(t).FarkleProbs {
+		pFarkle := 0.0
+		for _, wRoll := range PossibleRolls(want.NumDice) {
+			if IsFarkle(wRoll.Roll) {
+				pFarkle += wRoll.Prob
+			}
+		}
+		if math.Abs(pFarkle-want.value()) > 1e-12 {
+			t.Errorf("P(farkle) with %d dice = %.12g, want %.12g", want.NumDice, pFarkle, want.value())
+		}
+	}
+}
+
+// The expected score in points of the best hold from a single roll of each
+// number of dice, i.e. of banking at the first opportunity. With up to two
+// dice only 1s (100) and 5s (50) score, 25 points per die. Three dice add the
+// bonus of each three of a kind over the singles, each with probability
+// 1/216: 200, 300, 400, 500-150 and 600.
+func TestGoldenFirstRollScores(t *testing.T) {
+	setTestRules(t, "standard")
+	for _, want := range loadGoldenValues(t).FirstRollScores {
+		state := NewGameState(1)
+		state.NumDiceToRoll = uint8(want.NumDice)
+		expected := 0.0
+		for _, wRoll := range PossibleRolls(want.NumDice) {
+			best := 0
+			for _, action := range LegalActions(state, wRoll.Roll) {
+				best = max(best, incr*int(ApplyAction(state, action).ScoreThisRound))
+			}
+			expected += wRoll.Prob * float64(best)
+		}
+		if math.Abs(expected-want.value()) > 1e-9 {
+			t.Errorf("Expected score banking the first roll of %d dice = %.9g, want %.9g",
+				want.NumDice, expected, want.value())
+		}
+	}
+}
+
+// The expected number of turns to reach the target in miniature one-player
+// games, solved by SolveFrom and by bruteForceSolitaire.
+func TestGoldenSolitaire(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves several games")
+	}
+	for _, want := range loadGoldenValues(t).SolitaireTurns {
+		t.Run(want.Rules, func(t *testing.T) {
+			rules := setTestRules(t, want.Rules)
+			db := NewInMemoryDB(1)
+			if err := SolveFrom(NewGameState(1), db); err != nil {
+				t.Fatal(err)
+			}
+			if got := CalculateWinProb(NewGameState(1), db)[0]; math.Abs(got-want.Turns) > 1e-6 {
+				t.Errorf("SolveFrom: expected turns = %.9g, want %.9g", got, want.Turns)
+			}
+			if got := bruteForceSolitaire(rules); math.Abs(got-want.Turns) > 1e-9 {
+				t.Errorf("Brute force: expected turns = %.9g, want %.9g", got, want.Turns)
+			}
+		})
+	}
+}
+
+// The expected number of turns to reach the target score with optimal play
+// in a one-player game with lenient holds, no opening score and at most three
+// dice, computed by value iteration over every ordered roll of the dice.
+// It shares no code with the scoring engine or the solver.
+func bruteForceSolitaire(rules Rules) float64 {
+	goal := rules.TargetScore / incr
+	numDice := rules.TurnDice()
+	threeOnes := rules.ThreeOnesScore / incr
+
+	// The score in units of incr of holding the given number of each face,
+	// or -1 if some held die does not score.
+	holdScore := func(held [numSides + 1]int) int {
+		for face := 1; face <= numSides; face++ {
+			if held[face] == 3 {
+				if face == 1 {
+					return threeOnes
+				}
+				return 2 * face
+			}
+		}
+		if held[2]+held[3]+held[4]+held[6] > 0 {
+			return -1
+		}
+		return 2*held[1] + held[5]
+	}
+
+	// The distinct rolls of n dice, as counts of each face, with their
+	// probability.
+	type roll struct {
+		counts [numSides + 1]int
+		prob   float64
+	}
+	rolls := make([][]roll, numDice+1)
+	for n := 1; n <= numDice; n++ {
+		index := make(map[[numSides + 1]int]int)
+		total := int(math.Pow(numSides, float64(n)))
+		for outcome := 0; outcome < total; outcome++ {
+			var counts [numSides + 1]int
+			for i, x := 0, outcome; i < n; i, x = i+1, x/numSides {
+				counts[1+x%numSides]++
+			}
+			if i, ok := index[counts]; ok {
+				rolls[n][i].prob += 1 / float64(total)
+			} else {
+				index[counts] = len(rolls[n])
+				rolls[n] = append(rolls[n], roll{counts, 1 / float64(total)})
+			}
+		}
+	}
+
+	// value[score][turnScore][n] is the expected number of turns remaining,
+	// including this one, before rolling n dice.
+	value := make([][][]float64, goal)
+	for score := range value {
+		value[score] = make([][]float64, goal-score)
+		for turnScore := range value[score] {
+			value[score][turnScore] = make([]float64, numDice+1)
+		}
+	}
+	afterBank := func(score int) float64 {
+		if score >= goal {
+			return 1
+		}
+		return 1 + value[score][0][numDice]
+	}
+
+	for iter := 0; iter < 100000; iter++ {
+		maxChange := 0.0
+		for score := goal - 1; score >= 0; score-- {
+			for turnScore := len(value[score]) - 1; turnScore >= 0; turnScore-- {
+				for n := 1; n <= numDice; n++ {
+					v := 0.0
+					for _, r := range rolls[n] {
+						best := afterBank(score) // Farkle.
+						found := false
+						var visit func(face int, held [numSides + 1]int, size int)
+						visit = func(face int, held [numSides + 1]int, size int) {
+							if face > numSides {
+								points := holdScore(held)
+								if size == 0 || points < 0 {
+									return
+								}
+								newTurnScore := turnScore + points
+								remaining := n - size
+								if remaining == 0 {
+									remaining = numDice
+								}
+								option := afterBank(score + newTurnScore)
+								if score+newTurnScore < goal {
+									option = min(option, value[score][newTurnScore][remaining])
+								}
+								if !found || option < best {
+									best, found = option, true
+								}
+								return
+							}
+							for c := 0; c <= r.counts[face]; c++ {
+								held[face] = c
+								visit(face+1, held, size+c)
+							}
+						}
+						visit(1, [numSides + 1]int{}, 0)
+						v += r.prob * best
+					}
+
+					maxChange = max(maxChange, math.Abs(v-value[score][turnScore][n]))
+					value[score][turnScore][n] = v
+				}
+			}
+		}
+		if maxChange < 1e-12 {
+			break
+		}
+	}
+
+	return value[0][0][numDice]
+}
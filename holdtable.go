@@ -0,0 +1,44 @@
+package farkle
+
+import (
+	"fmt"
+)
+
+// The legal holds of every roll under the rules in effect, for clients to
+// download once and validate holds offline, e.g. in JavaScript. Rolls and
+// holds are identified by roll ID, as in Action.HeldDiceID.
+type HoldTable struct {
+	// The rules the table was computed for, e.g. "standard".
+	Rules string `json:"rules"`
+	// Identifies the numbering of roll IDs, which is fixed.
+	RollTableChecksum string `json:"rollTableChecksum"`
+	// The dice of each roll, indexed by roll ID.
+	Rolls [][]int `json:"rolls"`
+	// The legal holds of each roll, indexed by roll ID, as pairs of the roll
+	// ID of the held dice and their score in points. Farkles have none.
+	Holds [][][2]int `json:"holds"`
+}
+
+func NewHoldTable() HoldTable {
+	t := HoldTable{
+		Rules:             CurrentRules().Fingerprint().String(),
+		RollTableChecksum: fmt.Sprintf("%016x", calcRollTableChecksum()),
+		Rolls:             make([][]int, len(rollsByID)),
+		Holds:             make([][][2]int, len(rollsByID)),
+	}
+	for id, roll := range rollsByID {
+		t.Rolls[id] = []int{}
+		for _, die := range roll.Dice() {
+			t.Rolls[id] = append(t.Rolls[id], int(die))
+		}
+
+		t.Holds[id] = [][2]int{}
+		for _, held := range rollIDToPotentialHolds[id] {
+			t.Holds[id] = append(t.Holds[id], [2]int{
+				int(GetRollID(held)),
+				incr * int(CalculateScore(held)),
+			})
+		}
+	}
+	return t
+}
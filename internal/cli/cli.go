@@ -0,0 +1,128 @@
+// Package cli runs the commands that are built both as subcommands of the
+// farkle command and as commands of their own, with the flags, config file,
+// logging, rules and databases they share.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/config"
+	"github.com/timpalpant/go-farkle/internal/glogslog"
+)
+
+// A subcommand of the farkle command, e.g. farkle solve, which is also built
+// as a command of its own, e.g. solve-farkle.
+type Command struct {
+	Name    string
+	Binary  string
+	Summary string
+	// Define the flags of the command in fs, parse them and run it, exiting
+	// with a non-zero status on errors.
+	Run func(fs *FlagSet)
+}
+
+// The flags of a command, which also include the glog flags, and -config.
+type FlagSet struct {
+	*flag.FlagSet
+	args     []string
+	sections []string
+}
+
+// Parse the arguments of the command and the config file named by -config,
+// and log with glog. Called by Run after the flags of the command are defined.
+func (fs *FlagSet) Parse() {
+	config.ParseFlagSet(fs.FlagSet, fs.args, fs.sections...)
+	farkle.SetLogger(glogslog.New())
+}
+
+// Run cmd as a command of its own with the arguments of the process.
+func Main(cmd Command) {
+	cmd.run(cmd.Binary, os.Args[1:])
+}
+
+// Run the subcommand of the farkle command named by the first argument of the
+// process with the remaining arguments, or print the subcommands.
+func Dispatch(cmds []Command) {
+	if len(os.Args) < 2 {
+		printCommands(os.Stderr, cmds)
+		os.Exit(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+	if name == "help" || name == "-help" || name == "--help" || name == "-h" {
+		if len(args) == 0 {
+			printCommands(os.Stdout, cmds)
+			return
+		}
+		name, args = args[0], []string{"-help"}
+	}
+	for _, cmd := range cmds {
+		if cmd.Name == name {
+			cmd.run("farkle "+cmd.Name, args)
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "farkle: unknown command %q\n\n", name)
+	printCommands(os.Stderr, cmds)
+	os.Exit(2)
+}
+
+func (cmd Command) run(name string, args []string) {
+	fs := &FlagSet{
+		FlagSet:  flag.NewFlagSet(name, flag.ExitOnError),
+		args:     args,
+		sections: []string{cmd.Name, cmd.Binary},
+	}
+	// glog registers its flags with the flag package.
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: %s [flags]\n\n%s\n\nFlags:\n", name, cmd.Summary)
+		fs.PrintDefaults()
+	}
+	cmd.Run(fs)
+}
+
+func printCommands(w io.Writer, cmds []Command) {
+	fmt.Fprintln(w, "Usage: farkle <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	width := 0
+	for _, cmd := range cmds {
+		width = max(width, len(cmd.Name))
+	}
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(w, "\nRun 'farkle help <command>' for the flags of a command.")
+	fmt.Fprintf(w, "Flags may also be set in a config file (-config or $%s).\n", config.PathEnv)
+}
+
+// Play, solve or analyze with the rules parsed by farkle.ParseRules from spec.
+func SetRules(spec string) error {
+	rules, err := farkle.ParseRules(spec)
+	if err != nil {
+		return err
+	}
+	return farkle.SetRules(rules)
+}
+
+// Open the solution database at path to read, and check that it was solved
+// with the rules in effect.
+func OpenDB(path string, numPlayers int) (*farkle.FileDB, error) {
+	db, err := farkle.OpenFileDBReadOnly(path, numPlayers)
+	if err != nil {
+		return nil, err
+	}
+	if err := farkle.CheckRules(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
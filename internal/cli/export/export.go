@@ -0,0 +1,180 @@
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/cli"
+)
+
+type Params struct {
+	NumPlayers   int
+	DBPath       string
+	OutputPrefix string
+	RowsPerFile  int
+	Gzip         bool
+	SkipGameOver bool
+	Rules        string
+}
+
+// farkle export, also built as export-db.
+var Command = cli.Command{
+	Name:    "export",
+	Binary:  "export-db",
+	Summary: "Export a solution database to CSV files",
+	Run:     run,
+}
+
+func run(fs *cli.FlagSet) {
+	var params Params
+	fs.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	fs.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	fs.StringVar(&params.OutputPrefix, "output", "2player",
+		"Prefix of the CSV files to write, which are numbered e.g. 2player-00000.csv")
+	fs.IntVar(&params.RowsPerFile, "rows_per_file", 10000000, "Maximum number of states in each CSV file")
+	fs.BoolVar(&params.Gzip, "gzip", false, "Compress the CSV files with gzip")
+	fs.BoolVar(&params.SkipGameOver, "skip_game_over", true,
+		"Skip states in which the game is over, whose values are not stored")
+	fs.StringVar(&params.Rules, "rules", "standard",
+		"Rules the database was solved with: standard, facebook, pocket-farkle or kingdom-come")
+	fs.Parse()
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	db, err := cli.OpenDB(params.DBPath, params.NumPlayers)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := export(db, params); err != nil {
+		glog.Errorf("Error exporting database: %v", err)
+		os.Exit(1)
+	}
+}
+
+func export(db farkle.DB, params Params) error {
+	var w *chunkWriter
+	numChunks := 0
+	numStates := farkle.NumGameStates(params.NumPlayers)
+	row := make([]string, 0, 3+2*params.NumPlayers)
+	for gsID := 0; gsID < numStates; gsID++ {
+		state := farkle.GameStateFromID(params.NumPlayers, gsID)
+		if params.SkipGameOver && state.IsGameOver() {
+			continue
+		}
+
+		if w == nil || w.rows >= params.RowsPerFile {
+			if err := w.Close(); err != nil {
+				return err
+			}
+
+			var err error
+			w, err = newChunkWriter(params, numChunks)
+			if err != nil {
+				return err
+			}
+			numChunks++
+		}
+
+		row = append(row[:0],
+			strconv.Itoa(gsID),
+			strconv.Itoa(int(state.NumDiceToRoll)),
+			strconv.Itoa(50*int(state.ScoreThisRound)))
+		for _, score := range state.PlayerScores[:params.NumPlayers] {
+			row = append(row, strconv.Itoa(50*int(score)))
+		}
+		pWin := db.Get(gsID)
+		for _, p := range pWin[:params.NumPlayers] {
+			row = append(row, strconv.FormatFloat(p, 'g', -1, 64))
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// Writes rows to a sequence of numbered CSV files, each with a header.
+type chunkWriter struct {
+	f    *os.File
+	zw   *gzip.Writer
+	bw   *bufio.Writer
+	w    *csv.Writer
+	rows int
+}
+
+func newChunkWriter(params Params, chunk int) (*chunkWriter, error) {
+	path := fmt.Sprintf("%s-%05d.csv", params.OutputPrefix, chunk)
+	if params.Gzip {
+		path += ".gz"
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Writing %s", path)
+
+	cw := &chunkWriter{f: f}
+	var out io.Writer = f
+	if params.Gzip {
+		cw.zw = gzip.NewWriter(f)
+		out = cw.zw
+	}
+	cw.bw = bufio.NewWriterSize(out, 4*1024*1024)
+	cw.w = csv.NewWriter(cw.bw)
+
+	header := []string{"id", "num_dice", "turn_score"}
+	for i := 0; i < params.NumPlayers; i++ {
+		header = append(header, fmt.Sprintf("score%d", i))
+	}
+	for i := 0; i < params.NumPlayers; i++ {
+		header = append(header, fmt.Sprintf("p%d", i))
+	}
+	if err := cw.w.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+func (w *chunkWriter) Write(row []string) error {
+	w.rows++
+	return w.w.Write(row)
+}
+
+func (w *chunkWriter) Close() error {
+	if w == nil {
+		return nil
+	}
+	defer w.f.Close()
+
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+	if err := w.bw.Flush(); err != nil {
+		return err
+	}
+	if w.zw != nil {
+		if err := w.zw.Close(); err != nil {
+			return err
+		}
+	}
+
+	return w.f.Close()
+}
@@ -0,0 +1,505 @@
+package play
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/farkledata"
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/internal/i18n"
+	"github.com/timpalpant/go-farkle/overlay"
+	"github.com/timpalpant/go-farkle/stats"
+)
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	Seed       int64
+	TUI        bool
+	ReplayPath string
+	LazyDepth  int
+	// A compact policy written by export-policy, to play with instead of DBPath.
+	PolicyPath string
+	MCTSBudget time.Duration
+	CacheGB    float64
+	// The database was solved with solve-farkle -score_buckets.
+	ScoreBuckets int
+	// Practice positions of this category instead of playing a game.
+	Practice     string
+	NumQuestions int
+	// Practice these positions, e.g. a puzzle pack from find-puzzles.
+	PuzzlesPath string
+	// Download the database from here if it does not exist.
+	DownloadURL    string
+	DownloadSHA256 string
+	Rules          string
+	// Record the result to this statistics database, under this name.
+	StatsPath string
+	Name      string
+	// Write the state of the game to this file for stream overlays.
+	OverlayPath string
+	// Describe rolls and actions in sentences, for screen readers.
+	VerboseDescriptions bool
+	// The language of messages, or empty for the user's locale.
+	Language string
+}
+
+// farkle play, also built as play-farkle.
+var Command = cli.Command{
+	Name:    "play",
+	Binary:  "play-farkle",
+	Summary: "Play against the optimal strategy, or practice decisions",
+	Run:     run,
+}
+
+func run(fs *cli.FlagSet) {
+	var params Params
+	fs.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	fs.StringVar(&params.DBPath, "db", "",
+		"Path to solution database (optional for 2 players with standard rules, which have a built-in policy)")
+	fs.Int64Var(&params.Seed, "seed", 12345, "Random seed")
+	fs.BoolVar(&params.TUI, "tui", false, "Play in a full-screen terminal UI")
+	fs.StringVar(&params.ReplayPath, "replay", "", "Record the game to this file (optional)")
+	fs.StringVar(&params.PolicyPath, "policy", "",
+		"Play with this compact policy from export-policy instead of -db (optional)")
+	fs.IntVar(&params.LazyDepth, "lazy_depth", 0,
+		"If > 0, play without a database by searching this many turns ahead (less accurate)")
+	fs.DurationVar(&params.MCTSBudget, "mcts_budget", 0,
+		"If > 0, play without a database using Monte Carlo tree search for this long per decision")
+	fs.StringVar(&params.DownloadURL, "download_url", "",
+		"If the -db database does not exist, download it from this URL (optional)")
+	fs.StringVar(&params.DownloadSHA256, "download_sha256", "",
+		"Expected SHA-256 of the database downloaded from -download_url (optional)")
+	fs.Float64Var(&params.CacheGB, "cache_gb", 0,
+		"Approximate memory budget in GiB for sort buffers and caches (0 for the defaults)")
+	fs.IntVar(&params.ScoreBuckets, "score_buckets", 0,
+		"Number of opponent score buckets the database was solved with (see solve-farkle -score_buckets)")
+	fs.StringVar(&params.Practice, "practice", "",
+		"Instead of playing a game, practice decisions of this kind: final, one-die or opening (optional)")
+	fs.IntVar(&params.NumQuestions, "num_questions", 10, "Number of positions to practice")
+	fs.StringVar(&params.PuzzlesPath, "puzzles", "",
+		"Instead of playing a game, practice positions from this puzzle pack (see find-puzzles) (optional)")
+	fs.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	fs.StringVar(&params.StatsPath, "stats", "",
+		"Record the result and your rating to this SQLite database (optional, see farkle-stats)")
+	fs.StringVar(&params.Name, "name", "you", "Your name in the -stats database")
+	fs.StringVar(&params.OverlayPath, "overlay", "",
+		"Write the scores, last roll and win probabilities to this JSON file after every event (optional)")
+	fs.BoolVar(&params.VerboseDescriptions, "verbose_descriptions", false,
+		"Describe rolls, the dice you may keep and actions in full sentences, for screen readers")
+	fs.StringVar(&params.Language, "lang", "",
+		"Language of messages: "+strings.Join(i18n.Languages(), ", ")+" (default from $LANG)")
+	fs.Parse()
+
+	if err := i18n.SetLanguage(params.Language); err != nil {
+		glog.Errorf("Invalid -lang: %v", err)
+		os.Exit(1)
+	}
+
+	if params.CacheGB > 0 {
+		farkle.SetMemoryBudget(int64(params.CacheGB * (1 << 30)))
+	}
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	var advisor farkle.Advisor
+	if params.MCTSBudget > 0 {
+		advisor = farkle.NewMCTS(params.MCTSBudget, rand.New(rand.NewSource(params.Seed)))
+	} else if params.PolicyPath != "" {
+		policy, err := readPolicy(params.PolicyPath, params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to read policy: %v", err)
+			os.Exit(1)
+		}
+		advisor = farkle.DBAdvisor{DB: policy}
+	} else if params.LazyDepth > 0 {
+		advisor = farkle.DBAdvisor{DB: farkle.NewLazyDB(params.NumPlayers, params.LazyDepth)}
+	} else if params.DBPath == "" {
+		if params.DownloadURL != "" {
+			glog.Errorf("-download_url needs -db, the path to download the database to")
+			os.Exit(1)
+		}
+		policy, err := builtinPolicy(params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to play without -db or -policy: %v", err)
+			os.Exit(1)
+		}
+		advisor = farkle.DBAdvisor{DB: policy}
+	} else {
+		if params.DownloadURL != "" {
+			err := farkledata.EnsureDB(context.Background(),
+				params.DownloadURL, params.DBPath, params.DownloadSHA256)
+			if err != nil {
+				glog.Errorf("Unable to download database: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		db, err := cli.OpenDB(params.DBPath, params.NumPlayers)
+		if err != nil {
+			glog.Errorf("Unable to initialize database: %v", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		advisor = farkle.DBAdvisor{DB: db}
+		if params.ScoreBuckets > 0 {
+			buckets, err := farkle.NewScoreBuckets(params.ScoreBuckets)
+			if err != nil {
+				glog.Errorf("Invalid -score_buckets: %v", err)
+				os.Exit(1)
+			}
+			advisor = farkle.DBAdvisor{DB: farkle.NewBucketedDB(db, buckets)}
+		}
+	}
+
+	var replay *farkle.ReplayWriter
+	if params.ReplayPath != "" {
+		f, err := os.Create(params.ReplayPath)
+		if err != nil {
+			glog.Errorf("Unable to create replay file: %v", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		replay = farkle.NewReplayWriter(f)
+	}
+
+	game := farkle.NewGame(params.NumPlayers, rand.New(rand.NewSource(params.Seed)))
+	game.AddObserver(farkle.ActionObserver(func(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+		recordAction(replay, state, roll, action)
+	}))
+	if params.StatsPath != "" {
+		store, err := stats.OpenStore(params.StatsPath)
+		if err != nil {
+			glog.Errorf("Unable to open statistics: %v", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+
+		// Seat names are translated, so the computer's players are identified
+		// by seat instead.
+		players := make([]stats.Player, params.NumPlayers)
+		players[0] = stats.Player{ID: "local:" + params.Name, Name: params.Name}
+		for seat := 1; seat < len(players); seat++ {
+			players[seat] = stats.Player{ID: fmt.Sprintf("cpu:%d", seat), Name: seatName(seat, params.NumPlayers)}
+		}
+		// Searching for the optimal move again would double the time to play.
+		statsAdvisor := advisor
+		if params.MCTSBudget > 0 {
+			statsAdvisor = nil
+		}
+		game.AddObserver(stats.NewRecorder(store, players, statsAdvisor))
+	}
+	if params.OverlayPath != "" {
+		names := make([]string, params.NumPlayers)
+		for seat := range names {
+			names[seat] = seatName(seat, params.NumPlayers)
+		}
+		game.AddObserver(overlay.NewFile(params.OverlayPath, names, advisor))
+	}
+
+	if params.Practice != "" || params.PuzzlesPath != "" {
+		positions, err := practicePositions(params)
+		if err != nil {
+			glog.Errorf("Unable to generate practice positions: %v", err)
+			os.Exit(1)
+		}
+		playPractice(advisor, positions)
+	} else if params.TUI {
+		if err := playGameTUI(advisor, game); err != nil {
+			glog.Errorf("Error running terminal UI: %v", err)
+			os.Exit(1)
+		}
+	} else {
+		playGame(advisor, game, params.VerboseDescriptions)
+	}
+}
+
+func practicePositions(params Params) ([]farkle.Position, error) {
+	rng := rand.New(rand.NewSource(params.Seed))
+	if params.PuzzlesPath != "" {
+		return loadPuzzles(params.PuzzlesPath, params.NumPlayers, params.NumQuestions, rng)
+	}
+
+	category, err := farkle.ParsePracticeCategory(params.Practice)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]farkle.Position, params.NumQuestions)
+	for i := range positions {
+		positions[i], err = farkle.RandomPosition(category, params.NumPlayers, rng)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return positions, nil
+}
+
+// A random sample of n positions from the given puzzle pack.
+func loadPuzzles(path string, numPlayers, n int, rng *rand.Rand) ([]farkle.Position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	positions, err := farkle.ReadPositions(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pos := range positions {
+		if int(pos.State.NumPlayers) != numPlayers {
+			return nil, fmt.Errorf("%s has %d-player positions, not %d-player",
+				path, pos.State.NumPlayers, numPlayers)
+		}
+	}
+
+	rng.Shuffle(len(positions), func(i, j int) {
+		positions[i], positions[j] = positions[j], positions[i]
+	})
+	return positions[:min(n, len(positions))], nil
+}
+
+// The optimal 2-player policy for the standard rules, used when neither -db
+// nor -policy is given. It was exported from an exact solution with:
+//
+//	bin/solve-farkle -exact -num_players 2 -db 2player.db
+//	bin/export-policy -db 2player.db -output internal/cli/play/policy/2player.turndb
+//
+//go:embed policy/2player.turndb
+var embeddedPolicy []byte
+
+// Read a compact policy written by export-policy, and check that it is for
+// the number of players and rules of the game.
+func readPolicy(path string, numPlayers int) (*farkle.TurnDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return checkPolicy(path, f, numPlayers)
+}
+
+// The policy built into the binary, checked as by readPolicy.
+func builtinPolicy(numPlayers int) (*farkle.TurnDB, error) {
+	return checkPolicy("the built-in policy", bytes.NewReader(embeddedPolicy), numPlayers)
+}
+
+func checkPolicy(name string, r io.Reader, numPlayers int) (*farkle.TurnDB, error) {
+	policy, err := farkle.ReadTurnDB(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if policy.NumPlayers() != numPlayers {
+		return nil, fmt.Errorf("%s is for %d players, not %d", name, policy.NumPlayers(), numPlayers)
+	}
+	if err := farkle.CheckRules(policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return policy, nil
+}
+
+func playGame(advisor farkle.Advisor, game *farkle.Game, verbose bool) {
+	pWinAtTurnStart := seatWinProbs(advisor, game)
+
+	for !game.IsOver() {
+		state := game.State()
+		roll, err := game.Roll()
+		if err != nil {
+			glog.Errorf("Error rolling: %v", err)
+			return
+		}
+		if verbose {
+			fmt.Printf("%s rolled %s.\n", seatName(game.CurrentPlayer(), game.NumPlayers()), farkle.DescribeDice(roll))
+		} else {
+			i18n.Printf("Player %d rolled: %s\n", game.CurrentPlayer(), roll)
+		}
+
+		var action farkle.Action
+		if farkle.IsFarkle(roll) {
+			if verbose {
+				fmt.Println(farkle.DescribeAction(state, roll, farkle.Action{}))
+			} else {
+				i18n.Println("...farkle!")
+			}
+		} else if game.CurrentPlayer() == 0 {
+			if verbose {
+				fmt.Println(farkle.DescribeHolds(roll))
+			}
+			held := promptUserForDiceToKeep(roll)
+			score := state.ScoreThisRound + farkle.CalculateScore(held)
+			continueRolling := true
+			if state.CurrentPlayerScore() > 0 || 50*int(score) >= farkle.CurrentRules().OpeningScore {
+				i18n.Printf("...score this round = %d\n", int(score)*50)
+				continueRolling = promptUserToContinue()
+			} else {
+				i18n.Printf("...score this round = %d\n", int(score)*50)
+				i18n.Printf("...you must continue rolling until you get at least %d\n",
+					farkle.CurrentRules().OpeningScore)
+			}
+			action = farkle.Action{
+				HeldDiceID:      farkle.GetRollID(held),
+				ContinueRolling: continueRolling,
+			}
+
+			optAction, pWinOpt := advisor.Recommend(state, roll)
+			pOpt := pWinOpt[0]
+			pAction := advisor.EvaluateAction(state, action)[0]
+			if pAction >= pOpt {
+				i18n.Printf("...selected action is optimal! (pWin = %f)\n", pAction)
+			} else if verbose {
+				fmt.Printf("Better was: %s That wins %.0f%% of the time, and yours %.0f%%.\n",
+					farkle.DescribeAction(state, roll, optAction), 100*pOpt, 100*pAction)
+			} else {
+				i18n.Printf("...optimal action was %s with pWin = %f\n",
+					optAction, pOpt)
+				i18n.Printf("...selected action has pWin = %f (%f)\n",
+					pAction, pAction-pOpt)
+			}
+		} else { // CP
+			i18n.Printf("...score this round = %d\n", int(state.ScoreThisRound)*50)
+			selected, pWin := advisor.Recommend(state, roll)
+			if verbose {
+				fmt.Printf("%s (Win probability %.0f%%.)\n", farkle.DescribeAction(state, roll, selected), 100*pWin[0])
+			} else {
+				i18n.Printf("...selected action %s (pWin = %f)\n", selected, pWin[0])
+			}
+			action = selected
+			fmt.Scanln()
+		}
+
+		if err := game.Apply(action); err != nil {
+			glog.Errorf("Illegal action: %v", err)
+			return
+		}
+		if !action.ContinueRolling {
+			scores := game.Scores()
+			i18n.Printf("Current scores: player = %d, others: %v\n", scores[0], scores[1:])
+			if !game.IsOver() {
+				pWin := seatWinProbs(advisor, game)
+				i18n.Printf("Win probability: %s\n", formatWinProbChange(pWinAtTurnStart, pWin))
+				pWinAtTurnStart = pWin
+			}
+			fmt.Println()
+		}
+	}
+
+	if game.Result().Winners[0] == 0 {
+		i18n.Println("You win!")
+	} else {
+		i18n.Println("You lose!")
+	}
+}
+
+// Each player's probability of winning, indexed by seat. The human is seat 0.
+func seatWinProbs(advisor farkle.Advisor, game *farkle.Game) []float64 {
+	pWin := advisor.WinProb(game.State())
+	result := make([]float64, game.NumPlayers())
+	for i := range result {
+		result[game.Seat(i)] = pWin[i]
+	}
+	return result
+}
+
+func seatName(seat, numPlayers int) string {
+	if seat == 0 {
+		return i18n.T("You")
+	} else if numPlayers == 2 {
+		return i18n.T("CPU")
+	}
+	return i18n.Sprintf("CPU %d", seat)
+}
+
+// Describe how each player's probability of winning changed,
+// e.g. "You: 52% → 47%, CPU: 48% → 53%".
+func formatWinProbChange(before, after []float64) string {
+	changes := make([]string, len(after))
+	for seat := range after {
+		changes[seat] = fmt.Sprintf("%s: %.0f%% → %.0f%%",
+			seatName(seat, len(after)), 100*before[seat], 100*after[seat])
+	}
+	return strings.Join(changes, ", ")
+}
+
+func recordAction(replay *farkle.ReplayWriter, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+	if replay == nil {
+		return
+	}
+
+	if err := replay.Record(state, roll, action); err != nil {
+		glog.Warningf("Unable to record replay: %v", err)
+	}
+}
+
+func promptUserForDiceToKeep(roll farkle.Roll) farkle.Roll {
+	var held farkle.Roll
+	for {
+		i18n.Printf("...enter dice to keep: ")
+		rdr := bufio.NewReader(os.Stdin)
+		toKeepStr, err := rdr.ReadString('\n')
+		if err != nil {
+			i18n.Printf("......unable to read dice: %v\n", err)
+			continue
+		}
+
+		held, err = farkle.ParseRoll(toKeepStr)
+		if err != nil {
+			i18n.Printf("......unable to parse dice: %v\n", err)
+			continue
+		}
+
+		if err := farkle.CheckHold(roll, held); err != nil {
+			fmt.Printf("......%v\n", err)
+			continue
+		}
+
+		i18n.Printf("...held %s\n", farkle.DescribeScore(held))
+		return held
+	}
+}
+
+var yesNoResponses = map[string]bool{
+	"Y":   true,
+	"N":   false,
+	"1":   true,
+	"0":   false,
+	"YES": true,
+	"NO":  false,
+	// German.
+	"J":    true,
+	"JA":   true,
+	"NEIN": false,
+}
+
+func promptUserToContinue() bool {
+	for {
+		i18n.Printf("...continue rolling (Y/N)? ")
+		var yesNoStr string
+		fmt.Scanln(&yesNoStr)
+
+		yesNoStr = strings.ToUpper(strings.TrimSpace(yesNoStr))
+		continueRolling, ok := yesNoResponses[yesNoStr]
+		if !ok {
+			i18n.Printf("......don't understand '%s'\n", yesNoStr)
+			continue
+		}
+
+		return continueRolling
+	}
+}
@@ -0,0 +1,25 @@
+package play
+
+import (
+	"math"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// The built-in policy is the 2-player solution of the standard rules, in
+// which the first player has a small advantage.
+func TestBuiltinPolicy(t *testing.T) {
+	policy, err := builtinPolicy(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pWin := farkle.CalculateWinProb(farkle.NewGameState(2), policy)
+	if pWin[0] < 0.5 || pWin[0] > 0.6 || math.Abs(pWin[0]+pWin[1]-1) > 1e-6 {
+		t.Errorf("win probabilities at the start of the game = %v", pWin[:2])
+	}
+
+	if _, err := builtinPolicy(3); err == nil {
+		t.Error("used the built-in policy for 3 players")
+	}
+}
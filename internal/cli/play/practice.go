@@ -0,0 +1,65 @@
+package play
+
+import (
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/i18n"
+)
+
+// Ask the user for the best action in each position, and score their answers
+// against the advisor, like flashcards.
+func playPractice(advisor farkle.Advisor, positions []farkle.Position) {
+	nOptimal := 0
+	totalLoss := 0.0
+	for i, pos := range positions {
+		state := pos.State
+		i18n.Printf("Question %d of %d\n", i+1, len(positions))
+		i18n.Printf("...your score = %d, opponents: %v\n",
+			50*int(state.PlayerScores[0]), opponentScores(state))
+		if int(state.NumPlayers) > 1 && 50*int(state.PlayerScores[1]) >= farkle.CurrentRules().TargetScore {
+			i18n.Println("...this is your last turn")
+		}
+		i18n.Printf("...score this round = %d, rolled: %s\n",
+			50*int(state.ScoreThisRound), pos.Roll)
+
+		held := promptUserForDiceToKeep(pos.Roll)
+		score := state.ScoreThisRound + farkle.CalculateScore(held)
+		continueRolling := true
+		if state.CurrentPlayerScore() > 0 || 50*int(score) >= farkle.CurrentRules().OpeningScore {
+			i18n.Printf("...score this round = %d\n", int(score)*50)
+			continueRolling = promptUserToContinue()
+		} else {
+			i18n.Printf("...score this round = %d\n", int(score)*50)
+			i18n.Printf("...you must continue rolling until you get at least %d\n",
+				farkle.CurrentRules().OpeningScore)
+		}
+		action := farkle.Action{
+			HeldDiceID:      farkle.GetRollID(held),
+			ContinueRolling: continueRolling,
+		}
+
+		optAction, pWinOpt := advisor.Recommend(state, pos.Roll)
+		pOpt := pWinOpt[0]
+		pAction := advisor.EvaluateAction(state, action)[0]
+		if pAction >= pOpt {
+			nOptimal++
+			i18n.Printf("...correct! (pWin = %.1f%%)\n\n", 100*pAction)
+		} else {
+			totalLoss += pOpt - pAction
+			i18n.Printf("...optimal action was %s with pWin = %.1f%%, yours has %.1f%% (%+.1f%%)\n\n",
+				optAction, 100*pOpt, 100*pAction, 100*(pAction-pOpt))
+		}
+	}
+
+	if len(positions) > 0 {
+		i18n.Printf("You chose the optimal action in %d of %d positions, losing %.2f%% win probability per position on average\n",
+			nOptimal, len(positions), 100*totalLoss/float64(len(positions)))
+	}
+}
+
+func opponentScores(state farkle.GameState) []int {
+	scores := make([]int, 0, state.NumPlayers-1)
+	for _, score := range state.PlayerScores[1:state.NumPlayers] {
+		scores = append(scores, 50*int(score))
+	}
+	return scores
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package play
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func makeRaw(fd int) (func(), error) {
+	return nil, fmt.Errorf("terminal UI is not supported on %s", runtime.GOOS)
+}
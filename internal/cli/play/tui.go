@@ -0,0 +1,326 @@
+package play
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/i18n"
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	clearScreen    = "\x1b[H\x1b[2J"
+	bold           = "\x1b[1m"
+	dim            = "\x1b[2m"
+	reset          = "\x1b[0m"
+
+	barWidth = 30
+)
+
+type key int
+
+const (
+	keyOther key = iota
+	keyLeft
+	keyRight
+	keySpace
+	keyEnter
+	keyQuit
+)
+
+var errQuit = errors.New("quit")
+
+// Full-screen terminal front-end, played against the optimal strategy.
+type tui struct {
+	advisor farkle.Advisor
+	game    *farkle.Game // The human is seat 0.
+	in      *bufio.Reader
+	out     *bufio.Writer
+
+	roll     farkle.Roll
+	dice     []uint8 // Dice of the current roll, in display order.
+	selected []bool
+	cursor   int
+	message  string
+
+	// Win probabilities of the last state drawn, since they may be slow to compute.
+	pWinState farkle.GameState
+	pWin      [4]float64
+}
+
+func playGameTUI(advisor farkle.Advisor, game *farkle.Game) error {
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	t := &tui{
+		advisor: advisor,
+		game:    game,
+		in:      bufio.NewReader(os.Stdin),
+		out:     bufio.NewWriter(os.Stdout),
+	}
+
+	t.out.WriteString(enterAltScreen + hideCursor)
+	defer func() {
+		t.out.WriteString(showCursor + exitAltScreen)
+		t.out.Flush()
+	}()
+
+	err = t.run()
+	if errors.Is(err, errQuit) {
+		return nil
+	}
+	return err
+}
+
+func (t *tui) run() error {
+	for !t.game.IsOver() {
+		roll, err := t.game.Roll()
+		if err != nil {
+			return err
+		}
+		t.roll = roll
+		t.dice = t.roll.Dice()
+		t.selected = make([]bool, len(t.dice))
+		t.cursor = 0
+
+		var action farkle.Action
+		if farkle.IsFarkle(t.roll) {
+			t.message = i18n.Sprintf("%s rolled a farkle! Press any key.", t.currentPlayerName())
+			if err := t.waitForKey(); err != nil {
+				return err
+			}
+		} else if t.game.CurrentPlayer() == 0 {
+			action, err = t.humanTurn()
+			if err != nil {
+				return err
+			}
+		} else {
+			var pWin [4]float64
+			action, pWin = t.advisor.Recommend(t.game.State(), t.roll)
+			t.message = i18n.Sprintf("%s selected %s (pWin = %.1f%%). Press any key.",
+				t.currentPlayerName(), action, 100*pWin[0])
+			if err := t.waitForKey(); err != nil {
+				return err
+			}
+		}
+
+		if err := t.game.Apply(action); err != nil {
+			return err
+		}
+	}
+
+	t.roll = farkle.Roll{}
+	t.dice = nil
+	if t.game.Result().Winners[0] == 0 {
+		t.message = i18n.T("You win! Press any key to exit.")
+	} else {
+		t.message = i18n.T("You lose! Press any key to exit.")
+	}
+	return t.waitForKey()
+}
+
+func (t *tui) humanTurn() (farkle.Action, error) {
+	for {
+		t.draw()
+		k, r, err := t.readKey()
+		if err != nil {
+			return farkle.Action{}, err
+		}
+
+		switch {
+		case k == keyQuit:
+			return farkle.Action{}, errQuit
+		case k == keyLeft:
+			t.cursor = (t.cursor + len(t.dice) - 1) % len(t.dice)
+		case k == keyRight:
+			t.cursor = (t.cursor + 1) % len(t.dice)
+		case k == keySpace:
+			t.selected[t.cursor] = !t.selected[t.cursor]
+		case r >= '1' && r <= '6':
+			t.toggleDie(uint8(r - '0'))
+		case r == 'h':
+			optAction, pWin := t.advisor.Recommend(t.game.State(), t.roll)
+			t.message = i18n.Sprintf("Hint: %s (pWin = %.1f%%)", optAction, 100*pWin[0])
+		case k == keyEnter || r == 'r' || r == 'b':
+			action, err := t.selectedAction(r != 'b')
+			if err != nil {
+				t.message = err.Error()
+				continue
+			}
+
+			t.message = t.describeAction(action)
+			return action, nil
+		}
+	}
+}
+
+// Select the next unselected die with the given value,
+// or deselect all of them if they are already selected.
+func (t *tui) toggleDie(die uint8) {
+	for i, d := range t.dice {
+		if d == die && !t.selected[i] {
+			t.selected[i] = true
+			t.cursor = i
+			return
+		}
+	}
+
+	for i, d := range t.dice {
+		if d == die {
+			t.selected[i] = false
+		}
+	}
+}
+
+func (t *tui) selectedAction(continueRolling bool) (farkle.Action, error) {
+	var held farkle.Roll
+	for i, die := range t.dice {
+		if t.selected[i] {
+			held[die]++
+		}
+	}
+
+	action := farkle.Action{
+		HeldDiceID:      farkle.GetRollID(held),
+		ContinueRolling: continueRolling,
+	}
+	if err := farkle.ValidateAction(t.game.State(), t.roll, action); err != nil {
+		return farkle.Action{}, err
+	}
+
+	return action, nil
+}
+
+// Compare the selected action to the optimal action.
+func (t *tui) describeAction(action farkle.Action) string {
+	state := t.game.State()
+	optAction, pWinOpt := t.advisor.Recommend(state, t.roll)
+	pOpt := pWinOpt[0]
+	pAction := t.advisor.EvaluateAction(state, action)[0]
+	if pAction >= pOpt {
+		return i18n.Sprintf("You selected the optimal action! (pWin = %.1f%%)", 100*pAction)
+	}
+
+	return i18n.Sprintf("Optimal action was %s with pWin = %.1f%%, yours has %.1f%% (%+.1f%%)",
+		optAction, 100*pOpt, 100*pAction, 100*(pAction-pOpt))
+}
+
+func (t *tui) waitForKey() error {
+	t.draw()
+	k, _, err := t.readKey()
+	if err != nil {
+		return err
+	} else if k == keyQuit {
+		return errQuit
+	}
+	return nil
+}
+
+func (t *tui) readKey() (key, rune, error) {
+	r, _, err := t.in.ReadRune()
+	if err != nil {
+		return keyOther, 0, err
+	}
+
+	switch r {
+	case 'q', 3: // Ctrl-C
+		return keyQuit, r, nil
+	case ' ':
+		return keySpace, r, nil
+	case '\r', '\n':
+		return keyEnter, r, nil
+	case 0x1b: // Escape sequence
+		if t.in.Buffered() < 2 {
+			return keyOther, r, nil
+		}
+		seq := make([]byte, 2)
+		t.in.Read(seq)
+		switch string(seq) {
+		case "[D":
+			return keyLeft, r, nil
+		case "[C":
+			return keyRight, r, nil
+		}
+	}
+
+	return keyOther, r, nil
+}
+
+// The name of the player at the given index of state.PlayerScores.
+func (t *tui) playerName(i int) string {
+	seat := t.game.Seat(i)
+	if seat == 0 {
+		return i18n.T("You")
+	}
+	return i18n.Sprintf("CPU %d", seat)
+}
+
+func (t *tui) currentPlayerName() string {
+	return t.playerName(0)
+}
+
+func (t *tui) draw() {
+	var sb strings.Builder
+	sb.WriteString(clearScreen)
+	sb.WriteString(bold + " FARKLE" + reset + "\r\n\r\n")
+
+	state := t.game.State()
+	if t.pWinState != state || t.pWin == [4]float64{} {
+		t.pWinState, t.pWin = state, t.advisor.WinProb(state)
+	}
+	pWin := t.pWin
+	numPlayers := t.game.NumPlayers()
+	sb.WriteString(fmt.Sprintf("   %-8s %6s   %s\r\n", i18n.T("Player"), i18n.T("Score"), i18n.T("Win probability")))
+	for seat := 0; seat < numPlayers; seat++ {
+		i := (seat - t.game.CurrentPlayer() + numPlayers) % numPlayers
+		marker := " "
+		if i == 0 {
+			marker = "▶"
+		}
+		nFilled := int(pWin[i]*barWidth + 0.5)
+		bar := strings.Repeat("█", nFilled) + dim + strings.Repeat("░", barWidth-nFilled) + reset
+		sb.WriteString(fmt.Sprintf(" %s %-8s %6d   %s %5.1f%%\r\n",
+			marker, t.playerName(i), 50*int(state.PlayerScores[i]), bar, 100*pWin[i]))
+	}
+
+	sb.WriteString(i18n.Sprintf("\r\n Turn score: %d    Dice to roll: %d\r\n\r\n",
+		50*int(state.ScoreThisRound), state.NumDiceToRoll))
+
+	if len(t.dice) > 0 {
+		sb.WriteString(i18n.T(" Roll: "))
+		for i, die := range t.dice {
+			if t.selected[i] {
+				sb.WriteString(bold + "[" + farkle.NewRoll(die).FormatAs(farkle.EmojiStyle) + "]" + reset)
+			} else {
+				sb.WriteString(" " + farkle.NewRoll(die).FormatAs(farkle.EmojiStyle) + " ")
+			}
+		}
+		sb.WriteString("\r\n       ")
+		for i, die := range t.dice {
+			if i == t.cursor && t.game.CurrentPlayer() == 0 {
+				sb.WriteString(fmt.Sprintf("^%d ", die))
+			} else {
+				sb.WriteString(fmt.Sprintf(" %d ", die))
+			}
+		}
+		sb.WriteString("\r\n")
+	}
+
+	sb.WriteString("\r\n " + t.message + "\r\n\r\n")
+	if t.game.CurrentPlayer() == 0 && len(t.dice) > 0 && !t.game.IsOver() {
+		sb.WriteString(dim + i18n.T(" ←/→ move  space/1-6 select  r/enter keep & roll  b keep & bank  h hint  q quit") + reset + "\r\n")
+	}
+
+	t.out.WriteString(sb.String())
+	t.out.Flush()
+}
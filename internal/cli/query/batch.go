@@ -0,0 +1,164 @@
+package query
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// The CSV columns of a position. Scores are in points, separated by spaces,
+// starting with the player to move, e.g. "4500 3200". The roll determines the
+// number of dice to roll.
+var csvColumns = []string{"scores", "turn_score", "roll"}
+
+// Evaluation of a position, as written to JSON lines.
+type evaluation struct {
+	farkle.Position
+	Best    *farkle.Action     `json:"best,omitempty"`
+	Actions []actionEvaluation `json:"actions"`
+}
+
+type actionEvaluation struct {
+	Action  farkle.Action `json:"action"`
+	Value   []float64     `json:"value"`
+	PFarkle float64       `json:"pFarkle"`
+}
+
+// Evaluate all of the positions in the file at inputPath, which holds JSON
+// lines (as written by find-puzzles) or CSV (with a header of csvColumns),
+// and write the evaluations to w in the same format.
+func evaluateFile(inputPath string, db farkle.DB, w io.Writer) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	isCSV := strings.EqualFold(filepath.Ext(inputPath), ".csv")
+	var positions []farkle.Position
+	if isCSV {
+		positions, err = readCSVPositions(f)
+	} else {
+		positions, err = farkle.ReadPositions(f)
+	}
+	if err != nil {
+		return err
+	}
+	for i, pos := range positions {
+		if int(pos.State.NumPlayers) != db.NumPlayers() {
+			return fmt.Errorf("position %d: expected %d players, got %d", i+1, db.NumPlayers(), pos.State.NumPlayers)
+		} else if pos.Roll.NumDice() != pos.State.NumDiceToRoll {
+			return fmt.Errorf("position %d: expected roll of %d dice, got %d",
+				i+1, pos.State.NumDiceToRoll, pos.Roll.NumDice())
+		}
+	}
+
+	results := farkle.EvaluatePositions(positions, db)
+	if isCSV {
+		return writeCSVEvaluations(w, positions, results)
+	}
+	return writeJSONEvaluations(w, positions, results)
+}
+
+func readCSVPositions(r io.Reader) ([]farkle.Position, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Join(header, ",") != strings.Join(csvColumns, ",") {
+		return nil, fmt.Errorf("expected CSV header %q, got %q",
+			strings.Join(csvColumns, ","), strings.Join(header, ","))
+	}
+
+	var result []farkle.Position
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return result, err
+		}
+
+		roll, err := farkle.ParseRoll(record[2])
+		if err != nil {
+			return result, fmt.Errorf("line %d: invalid roll: %w", line, err)
+		}
+		turnScore, err := strconv.Atoi(record[1])
+		if err != nil {
+			return result, fmt.Errorf("line %d: invalid turn score %q", line, record[1])
+		}
+		scores := strings.Join(strings.Fields(record[0]), ",")
+		state, err := parseState(scores, turnScore, int(roll.NumDice()), roll)
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		result = append(result, farkle.Position{State: state, Roll: roll})
+	}
+
+	return result, nil
+}
+
+// Write the input columns, followed by the best action and its value for the
+// player to move. The best action is empty for farkles.
+func writeCSVEvaluations(w io.Writer, positions []farkle.Position, results [][]farkle.ActionDetail) error {
+	cw := csv.NewWriter(w)
+	header := append(csvColumns[:len(csvColumns):len(csvColumns)], "best_held", "best_continue", "best_value")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i, pos := range positions {
+		scores := make([]string, pos.State.NumPlayers)
+		for j := range scores {
+			scores[j] = strconv.Itoa(50 * int(pos.State.PlayerScores[j]))
+		}
+		record := []string{
+			strings.Join(scores, " "),
+			strconv.Itoa(50 * int(pos.State.ScoreThisRound)),
+			pos.Roll.FormatAs(farkle.CompactStyle),
+			"", "", "",
+		}
+		if len(results[i]) > 0 {
+			best := results[i][0]
+			record[3] = best.Action.HeldDice().FormatAs(farkle.CompactStyle)
+			record[4] = strconv.FormatBool(best.Action.ContinueRolling)
+			record[5] = strconv.FormatFloat(best.Value[0], 'f', 6, 64)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONEvaluations(w io.Writer, positions []farkle.Position, results [][]farkle.ActionDetail) error {
+	enc := json.NewEncoder(w)
+	for i, pos := range positions {
+		eval := evaluation{Position: pos, Actions: []actionEvaluation{}}
+		for _, d := range results[i] {
+			eval.Actions = append(eval.Actions, actionEvaluation{
+				Action:  d.Action,
+				Value:   d.Value[:pos.State.NumPlayers],
+				PFarkle: d.PFarkle,
+			})
+		}
+		if len(results[i]) > 0 {
+			eval.Best = &results[i][0].Action
+		}
+		if err := enc.Encode(eval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package query
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/cli"
+)
+
+type Params struct {
+	DBPath    string
+	Scores    string
+	TurnScore int
+	NumDice   int
+	Roll      string
+	Rules     string
+
+	PositionsPath string
+	NumPlayers    int
+	OutputPath    string
+}
+
+// farkle query, also built as query-farkle.
+var Command = cli.Command{
+	Name:    "query",
+	Binary:  "query-farkle",
+	Summary: "Look up the win probability and best action of a position",
+	Run:     run,
+}
+
+func run(fs *cli.FlagSet) {
+	var params Params
+	fs.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database for the number of players in -scores")
+	fs.StringVar(&params.Scores, "scores", "0,0",
+		"Comma-separated scores of each player in points, starting with the player to move")
+	fs.IntVar(&params.TurnScore, "turn_score", 0, "Points won so far this turn")
+	fs.IntVar(&params.NumDice, "dice", 0, "Number of dice to roll (default: the number of dice in -roll, or all of the dice)")
+	fs.StringVar(&params.Roll, "roll", "", "Dice rolled, e.g. 1,3,3,4 (optional)")
+	fs.StringVar(&params.Rules, "rules", "standard",
+		"Rules the database was solved with: standard, facebook, pocket-farkle or kingdom-come")
+	fs.StringVar(&params.PositionsPath, "positions", "",
+		"Evaluate all of the positions in this .csv or .jsonl file instead of a single position (optional)")
+	fs.IntVar(&params.NumPlayers, "num_players", 2, "Number of players in the positions of -positions")
+	fs.StringVar(&params.OutputPath, "output", "", "Write the evaluations of -positions to this path (default: stdout)")
+	fs.Parse()
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	if params.PositionsPath != "" {
+		if err := evaluateBatch(params); err != nil {
+			glog.Errorf("Error evaluating positions: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var roll farkle.Roll
+	if params.Roll != "" {
+		var err error
+		if roll, err = farkle.ParseRoll(params.Roll); err != nil {
+			glog.Errorf("Invalid roll: %v", err)
+			os.Exit(1)
+		}
+	}
+	state, err := parseState(params.Scores, params.TurnScore, params.NumDice, roll)
+	if err != nil {
+		glog.Errorf("Invalid position: %v", err)
+		os.Exit(1)
+	}
+
+	db, err := cli.OpenDB(params.DBPath, int(state.NumPlayers))
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	meta := db.Metadata()
+
+	fmt.Printf("Position: %v\n", state)
+	if state.IsGameOver() {
+		fmt.Printf("Before rolling: %s\n", formatValue(meta, state, farkle.CalculateWinProb(state, db)))
+	} else {
+		// Solve the rest of the turn exactly from the values after it ends.
+		turn := farkle.SolveTurn(state, db)
+		fmt.Printf("Before rolling: %s\n", formatValue(meta, state, turn.Get(state)))
+		fmt.Printf("Farkles before banking: %.1f%%\n", 100*turn.PFarkle(state))
+	}
+	if moments, err := openMoments(params.DBPath, db); err == nil {
+		fmt.Printf("Final scores: %v\n", moments.Get(state))
+		moments.Mean.Close()
+		moments.Square.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		glog.Warningf("Unable to open moments of final scores: %v", err)
+	}
+	if params.Roll == "" {
+		return
+	}
+
+	fmt.Printf("Roll: %v\n", roll)
+	if farkle.IsFarkle(roll) {
+		fmt.Println("Farkle!")
+		return
+	}
+
+	details := farkle.SelectActionDetailed(state, roll, db)
+	fmt.Printf("Optimal action: %v\n\n", details[0].Action)
+	fmt.Printf("%-28s %-32s %10s %10s\n", "Action", "Value", "P(farkle)", "E[turn]")
+	for _, d := range details {
+		pFarkle := "-"
+		if d.Action.ContinueRolling {
+			pFarkle = fmt.Sprintf("%.1f%%", 100*d.PFarkle)
+		}
+		fmt.Printf("%-28v %-32s %10s %10.0f\n",
+			d.Action, formatValue(meta, state, d.Value), pFarkle, d.ExpectedTurnScore)
+	}
+}
+
+func evaluateBatch(params Params) error {
+	db, err := cli.OpenDB(params.DBPath, params.NumPlayers)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if params.OutputPath == "" {
+		w := bufio.NewWriter(os.Stdout)
+		defer w.Flush()
+		return evaluateFile(params.PositionsPath, db, w)
+	}
+
+	f, err := os.Create(params.OutputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := evaluateFile(params.PositionsPath, db, w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// The moments of the final scores computed by solve -moments alongside the
+// database, if any.
+func openMoments(path string, db farkle.DB) (*farkle.ScoreMoments, error) {
+	mean, err := cli.OpenDB(path+".mean", db.NumPlayers())
+	if err != nil {
+		return nil, err
+	}
+	square, err := cli.OpenDB(path+".square", db.NumPlayers())
+	if err != nil {
+		mean.Close()
+		return nil, err
+	}
+	moments, err := farkle.NewScoreMoments(db, mean, square)
+	if err != nil {
+		mean.Close()
+		square.Close()
+		return nil, err
+	}
+	return moments, nil
+}
+
+// Parse the position to query. Scores are in points.
+func parseState(scores string, turnScore, numDice int, roll farkle.Roll) (farkle.GameState, error) {
+	fields := strings.Split(scores, ",")
+	if len(fields) < 1 || len(fields) > 4 {
+		return farkle.GameState{}, fmt.Errorf("expected scores of 1 to 4 players, got %d", len(fields))
+	}
+	if numDice == 0 {
+		numDice = farkle.CurrentRules().TurnDice()
+		if roll.NumDice() > 0 {
+			numDice = int(roll.NumDice())
+		}
+	}
+	if numDice < 1 || numDice > farkle.CurrentRules().TurnDice() {
+		return farkle.GameState{}, fmt.Errorf("invalid number of dice: %d", numDice)
+	} else if roll.NumDice() > 0 && int(roll.NumDice()) != numDice {
+		return farkle.GameState{}, fmt.Errorf("expected roll of %d dice, got %d", numDice, roll.NumDice())
+	}
+
+	state := farkle.NewGameState(len(fields))
+	state.NumDiceToRoll = uint8(numDice)
+	var err error
+	if state.ScoreThisRound, err = farkle.ParseScore(turnScore); err != nil {
+		return farkle.GameState{}, err
+	}
+	for i, field := range fields {
+		points, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return farkle.GameState{}, fmt.Errorf("invalid score %q", field)
+		}
+		if state.PlayerScores[i], err = farkle.ParseScore(points); err != nil {
+			return farkle.GameState{}, err
+		}
+	}
+	return state, nil
+}
+
+// Format the value of each player, as stored in a database with the given metadata.
+func formatValue(meta farkle.Metadata, state farkle.GameState, value [4]float64) string {
+	if state.NumPlayers == 1 {
+		return fmt.Sprintf("%.2f turns", value[0])
+	}
+
+	parts := make([]string, state.NumPlayers)
+	for i := range parts {
+		if meta.Objective == farkle.WinProbability {
+			parts[i] = fmt.Sprintf("%.1f%%", 100*value[i])
+		} else {
+			parts[i] = fmt.Sprintf("%.0f", value[i])
+		}
+	}
+	return strings.Join(parts, " / ")
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+)
+
+// Call reload, e.g. to switch a server to a newer version of its database, on
+// SIGHUP, and on POST /reload at adminAddr if it is not empty. The admin
+// address should not be reachable by players, e.g. localhost:8081. Errors are
+// logged, and returned to the admin client. The mux of the admin address is
+// returned, or nil if there is none, to add more admin endpoints to.
+func HandleReload(reload func() error, adminAddr string) *http.ServeMux {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		for range sigCh {
+			if err := reload(); err != nil {
+				glog.Errorf("Unable to reload: %v", err)
+			}
+		}
+	}()
+
+	if adminAddr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /reload", func(w http.ResponseWriter, r *http.Request) {
+		if err := reload(); err != nil {
+			glog.Errorf("Unable to reload: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	go func() {
+		glog.Infof("Serving admin endpoints on %s", adminAddr)
+		if err := http.ListenAndServe(adminAddr, mux); err != nil {
+			glog.Errorf("Error serving admin endpoints: %v", err)
+		}
+	}()
+	return mux
+}
@@ -0,0 +1,221 @@
+package serve
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The API keys allowed to use the JSON API, with the rate limit and usage of
+// each, so that the API can be exposed publicly.
+type keyring struct {
+	path string
+
+	mx   sync.Mutex
+	keys map[string]*apiKey
+	// Shared by requests without a key, or nil if a key is required.
+	anonymous *apiKey
+}
+
+type apiKey struct {
+	limiter rateLimiter
+	usage   KeyUsage
+}
+
+// The use of the API with one key.
+type KeyUsage struct {
+	Name string `json:"name"`
+	// The number of requests served, and rejected for exceeding the limit.
+	Requests int64     `json:"requests"`
+	Limited  int64     `json:"limited"`
+	LastUsed time.Time `json:"lastUsed"`
+	// The limit, in requests per minute.
+	RequestsPerMinute float64 `json:"requestsPerMinute"`
+}
+
+// Read the keys from path. If anonymousRate > 0, requests without a key are
+// allowed up to that many requests per minute in total.
+func loadKeyring(path string, anonymousRate float64) (*keyring, error) {
+	k := &keyring{path: path}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	if anonymousRate > 0 {
+		k.anonymous = newAPIKey("anonymous", anonymousRate)
+	}
+	return k, nil
+}
+
+func newAPIKey(name string, perMinute float64) *apiKey {
+	return &apiKey{
+		limiter: newRateLimiter(perMinute),
+		usage:   KeyUsage{Name: name, RequestsPerMinute: perMinute},
+	}
+}
+
+// Read the keys file again, e.g. to add or revoke keys. The usage of keys
+// that remain is kept, as is their rate limit if it has not changed.
+func (k *keyring) reload() error {
+	keys, err := readAPIKeys(k.path)
+	if err != nil {
+		return err
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+	for token, key := range keys {
+		if old, ok := k.keys[token]; ok {
+			key.usage.Requests, key.usage.Limited, key.usage.LastUsed =
+				old.usage.Requests, old.usage.Limited, old.usage.LastUsed
+			if old.usage.RequestsPerMinute == key.usage.RequestsPerMinute {
+				key.limiter = old.limiter
+			}
+		}
+	}
+	k.keys = keys
+	return nil
+}
+
+// Read a file with one key per line: the key, its limit in requests per
+// minute and, optionally, a name for the usage reports, separated by spaces.
+// Blank lines and lines starting with # are ignored.
+func readAPIKeys(path string) (map[string]*apiKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := make(map[string]*apiKey)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected KEY REQUESTS_PER_MINUTE [NAME]", path, lineNum)
+		}
+		perMinute, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || perMinute <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid rate limit: %q", path, lineNum, fields[1])
+		}
+		name := fmt.Sprintf("line %d", lineNum)
+		if len(fields) == 3 {
+			name = fields[2]
+		}
+		if _, ok := result[fields[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate key", path, lineNum)
+		}
+		result[fields[0]] = newAPIKey(name, perMinute)
+	}
+	return result, scanner.Err()
+}
+
+// Require a key, given as "Authorization: Bearer KEY", for the handler, and
+// limit the rate of requests with each key.
+func (k *keyring) require(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		k.mx.Lock()
+		key := k.lookup(r)
+		if key == nil {
+			k.mx.Unlock()
+			http.Error(w, "missing or unknown API key", http.StatusUnauthorized)
+			return
+		}
+		wait := key.limiter.take(now)
+		if wait > 0 {
+			key.usage.Limited++
+		} else {
+			key.usage.Requests++
+			key.usage.LastUsed = now
+		}
+		k.mx.Unlock()
+
+		if wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// The usage of the key of the request, which does not count against its limit.
+func (k *keyring) handleUsage(w http.ResponseWriter, r *http.Request) {
+	k.mx.Lock()
+	key := k.lookup(r)
+	var usage KeyUsage
+	if key != nil {
+		usage = key.usage
+	}
+	k.mx.Unlock()
+
+	if key == nil {
+		http.Error(w, "missing or unknown API key", http.StatusUnauthorized)
+		return
+	}
+	writeResponse(w, usage)
+}
+
+// The key of the request, or nil if it has none and one is required or it is
+// unknown. Must be called with mx held.
+func (k *keyring) lookup(r *http.Request) *apiKey {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return k.anonymous
+	}
+	return k.keys[token]
+}
+
+// The usage of every key, for the admin address.
+func (k *keyring) handleAllUsage(w http.ResponseWriter, r *http.Request) {
+	k.mx.Lock()
+	result := make([]KeyUsage, 0, len(k.keys)+1)
+	for _, key := range k.keys {
+		result = append(result, key.usage)
+	}
+	if k.anonymous != nil {
+		result = append(result, k.anonymous.usage)
+	}
+	k.mx.Unlock()
+
+	slices.SortFunc(result, func(a, b KeyUsage) int { return strings.Compare(a.Name, b.Name) })
+	writeResponse(w, result)
+}
+
+// A token bucket that allows bursts of up to 10 seconds' worth of requests.
+type rateLimiter struct {
+	perSecond float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+}
+
+func newRateLimiter(perMinute float64) rateLimiter {
+	burst := max(1, perMinute/6)
+	return rateLimiter{perSecond: perMinute / 60, burst: burst, tokens: burst}
+}
+
+// Take a token for a request at the given time, returning 0 if there was one,
+// or else how long until there will be.
+func (l *rateLimiter) take(now time.Time) time.Duration {
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return time.Duration((1 - l.tokens) / l.perSecond * float64(time.Second))
+	}
+	l.tokens--
+	return 0
+}
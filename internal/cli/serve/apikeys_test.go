@@ -0,0 +1,178 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A token bucket with a burst of 10 seconds' worth of requests, refilled at
+// the rate limit.
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(60) // 1 per second, in bursts of up to 10.
+	now := time.Unix(1000, 0)
+	for i := 0; i < 10; i++ {
+		if wait := l.take(now); wait != 0 {
+			t.Fatalf("request %d of the burst must wait %v", i, wait)
+		}
+	}
+	if wait := l.take(now); wait != time.Second {
+		t.Errorf("request after the burst must wait %v, want 1s", wait)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if wait := l.take(now); wait != 500*time.Millisecond {
+		t.Errorf("request after 500ms must wait %v, want 500ms", wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if wait := l.take(now); wait != 0 {
+		t.Errorf("request after 1s must wait %v, want 0", wait)
+	}
+
+	// Idle time only refills the bucket up to the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 10; i++ {
+		if wait := l.take(now); wait != 0 {
+			t.Fatalf("request %d after an hour must wait %v", i, wait)
+		}
+	}
+	if wait := l.take(now); wait == 0 {
+		t.Error("more than a burst of requests were allowed after an hour")
+	}
+}
+
+// Limits below 6 per minute still allow one request at a time.
+func TestRateLimiterSlow(t *testing.T) {
+	l := newRateLimiter(1)
+	now := time.Unix(1000, 0)
+	if wait := l.take(now); wait != 0 {
+		t.Fatalf("first request must wait %v", wait)
+	}
+	if wait := l.take(now); wait != time.Minute {
+		t.Errorf("second request must wait %v, want 1m", wait)
+	}
+}
+
+func writeKeys(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeys(t, path, "# Comment\n\nk1 60 alice\nk2 0.5\n")
+	keys, err := readAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("read %d keys, want 2", len(keys))
+	}
+	if usage := keys["k1"].usage; usage.Name != "alice" || usage.RequestsPerMinute != 60 {
+		t.Errorf("k1 = %+v", usage)
+	}
+	if usage := keys["k2"].usage; usage.Name != "line 4" || usage.RequestsPerMinute != 0.5 {
+		t.Errorf("k2 = %+v", usage)
+	}
+
+	for _, content := range []string{"k1\n", "k1 0\n", "k1 -1\n", "k1 x\n", "k1 60 a b\n", "k1 60\nk1 30\n"} {
+		writeKeys(t, path, content)
+		if _, err := readAPIKeys(path); err == nil {
+			t.Errorf("read invalid keys %q", content)
+		}
+	}
+}
+
+// Send a request with the given key, if any, returning the response.
+func requestWithKey(handler http.HandlerFunc, key string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/api/configs", nil)
+	if key != "" {
+		r.Header.Set("Authorization", "Bearer "+key)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestKeyringRequire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeys(t, path, "k1 6 alice\n")
+	k, err := loadKeyring(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := k.require(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, key := range []string{"", "unknown"} {
+		if w := requestWithKey(handler, key); w.Code != http.StatusUnauthorized {
+			t.Errorf("request with key %q: status %d, want %d", key, w.Code, http.StatusUnauthorized)
+		}
+	}
+	// A burst of one request, then one every 10 seconds.
+	if w := requestWithKey(handler, "k1"); w.Code != http.StatusOK {
+		t.Fatalf("request with k1: status %d", w.Code)
+	}
+	w := requestWithKey(handler, "k1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit: status %d, want %d", w.Code, http.StatusTooManyRequests)
+	} else if retry := w.Header().Get("Retry-After"); retry != "10" {
+		t.Errorf("Retry-After = %q, want 10", retry)
+	}
+	if usage := k.keys["k1"].usage; usage.Requests != 1 || usage.Limited != 1 {
+		t.Errorf("usage of k1 = %+v, want 1 request and 1 limited", usage)
+	}
+
+	// Reloading keeps the usage and the limiter of keys whose limit is the
+	// same, and adds new keys.
+	writeKeys(t, path, "k1 6 alice\nk2 60 bob\n")
+	if err := k.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if w := requestWithKey(handler, "k1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("request with k1 after reload: status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if usage := k.keys["k1"].usage; usage.Requests != 1 || usage.Limited != 2 {
+		t.Errorf("usage of k1 after reload = %+v, want 1 request and 2 limited", usage)
+	}
+	if w := requestWithKey(handler, "k2"); w.Code != http.StatusOK {
+		t.Errorf("request with new key k2: status %d", w.Code)
+	}
+
+	// Revoked keys are rejected.
+	writeKeys(t, path, "k2 60 bob\n")
+	if err := k.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if w := requestWithKey(handler, "k1"); w.Code != http.StatusUnauthorized {
+		t.Errorf("request with revoked key: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// Requests without a key share the anonymous limit, if there is one.
+func TestKeyringAnonymous(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeys(t, path, "k1 60\n")
+	k, err := loadKeyring(path, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := k.require(func(w http.ResponseWriter, r *http.Request) {})
+
+	if w := requestWithKey(handler, ""); w.Code != http.StatusOK {
+		t.Fatalf("anonymous request: status %d", w.Code)
+	}
+	if w := requestWithKey(handler, ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("anonymous request over the limit: status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w := requestWithKey(handler, "k1"); w.Code != http.StatusOK {
+		t.Errorf("request with a key after the anonymous limit: status %d", w.Code)
+	}
+	if w := requestWithKey(handler, "unknown"); w.Code != http.StatusUnauthorized {
+		t.Errorf("request with an unknown key: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,152 @@
+package serve
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// A database served under a key, which requests choose with their config
+// field, e.g. to serve 2-, 3- and 4-player games from one server.
+type config struct {
+	numPlayers int
+	// The solution database, if not serving with Monte Carlo tree search,
+	// which is safe for concurrent use.
+	db farkle.DB
+	// Requests are served concurrently, and searches are not safe
+	// for concurrent use, so each request gets its own advisor.
+	newAdvisor func() farkle.Advisor
+	// The cache of recommended actions, if enabled.
+	cache *farkle.ActionCache
+	// Switch to a newer version of the database, if any.
+	reload func() error
+}
+
+// The key, path and number of players of a database to serve.
+type configSpec struct {
+	key        string
+	path       string
+	numPlayers int
+}
+
+// Parse comma-separated KEY=PATH:NUM_PLAYERS, e.g. 2p=2player.db:2,3p=3player.db:3.
+func parseConfigSpecs(spec string) ([]configSpec, error) {
+	var result []configSpec
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		key, rest, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected KEY=PATH:NUM_PLAYERS, got %q", field)
+		}
+		i := strings.LastIndex(rest, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("expected KEY=PATH:NUM_PLAYERS, got %q", field)
+		}
+		numPlayers, err := strconv.Atoi(rest[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid number of players: %w", field, err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate configuration: %q", key)
+		}
+		seen[key] = true
+		result = append(result, configSpec{key: key, path: rest[:i], numPlayers: numPlayers})
+	}
+	return result, nil
+}
+
+// Open the database of a configuration. The rules apply to the whole
+// process, so configurations cannot have rules of their own: a database
+// solved with other rules than those in effect is rejected.
+func openConfig(spec configSpec, cacheSize int) (*config, error) {
+	if err := checkConfigRules(spec); err != nil {
+		return nil, err
+	}
+	db, err := farkle.OpenReloadableDB(spec.path, spec.numPlayers)
+	if err != nil {
+		if spec.key != "" {
+			err = fmt.Errorf("configuration %s: %w", spec.key, err)
+		}
+		return nil, err
+	}
+
+	c := &config{
+		numPlayers: spec.numPlayers,
+		db:         farkle.ConcurrentDB(db),
+	}
+	c.newAdvisor = func() farkle.Advisor { return farkle.DBAdvisor{DB: c.db} }
+	if cacheSize > 0 {
+		c.cache = farkle.NewActionCache(c.db, cacheSize)
+		c.newAdvisor = func() farkle.Advisor { return c.cache }
+	}
+	c.reload = func() error {
+		if err := db.Reload(); err != nil {
+			return fmt.Errorf("%s: %w", spec.key, err)
+		}
+		if c.cache != nil {
+			c.cache.Clear()
+		}
+		return nil
+	}
+	return c, nil
+}
+
+// Check that the database of a configuration was solved with the rules in
+// effect, and if not, say how to serve it. Serving databases solved with
+// different rules from one server is not supported: run a server for each.
+func checkConfigRules(spec configSpec) error {
+	db, err := farkle.OpenFileDBReadOnly(spec.path, spec.numPlayers)
+	if err != nil {
+		return nil // Reported when the database is opened to serve.
+	}
+	solved := db.Metadata().Rules
+	db.Close()
+	current := farkle.CurrentRules().Fingerprint()
+	if solved == current {
+		return nil
+	}
+	hint := "solve it again with -rules"
+	if preset, ok := solved.Preset(); ok {
+		hint = fmt.Sprintf("serve it from a separate server with -rules %v", preset)
+	}
+	name := spec.path
+	if spec.key != "" {
+		name = "configuration " + spec.key
+	}
+	return fmt.Errorf("%s was solved with %v rules, not the %v rules of this server, "+
+		"and configurations cannot have rules of their own: %s", name, solved, current, hint)
+}
+
+func (c *config) close() {
+	if c.db != nil {
+		c.db.Close()
+	}
+}
+
+func (c *config) parseState(st State) (farkle.GameState, error) {
+	if len(st.Scores) != c.numPlayers {
+		return farkle.GameState{}, fmt.Errorf("expected %d player scores, got %d",
+			c.numPlayers, len(st.Scores))
+	}
+	if st.NumDice < 1 || st.NumDice > farkle.CurrentRules().TurnDice() {
+		return farkle.GameState{}, fmt.Errorf("invalid number of dice: %d", st.NumDice)
+	}
+
+	state := farkle.NewGameState(len(st.Scores))
+	state.NumDiceToRoll = uint8(st.NumDice)
+	turnScore, err := farkle.ParseScore(st.TurnScore)
+	if err != nil {
+		return farkle.GameState{}, err
+	}
+	state.ScoreThisRound = turnScore
+	for i, score := range st.Scores {
+		state.PlayerScores[i], err = farkle.ParseScore(score)
+		if err != nil {
+			return farkle.GameState{}, err
+		}
+	}
+
+	return state, nil
+}
@@ -0,0 +1,53 @@
+package serve
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/testutil"
+)
+
+// States may have at most as many dice as are rolled at the start of a turn.
+func TestParseState(t *testing.T) {
+	testutil.SetRules(t, "standard,dice=3")
+	c := &config{numPlayers: 2}
+	for numDice, valid := range map[int]bool{0: false, 1: true, 3: true, 4: false, 6: false} {
+		_, err := c.parseState(State{Scores: []int{0, 0}, NumDice: numDice})
+		if valid != (err == nil) {
+			t.Errorf("state with %d dice: error = %v, want valid %v", numDice, err, valid)
+		}
+	}
+}
+
+// Configurations cannot have rules of their own, so a database solved with
+// other rules than those in effect is rejected.
+func TestOpenConfigRules(t *testing.T) {
+	testutil.SetRules(t, "pocket-farkle")
+	path := filepath.Join(t.TempDir(), "1player.db")
+	db, err := farkle.NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := configSpec{key: "pocket", path: path, numPlayers: 1}
+	c, err := openConfig(spec, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.close()
+
+	testutil.SetRules(t, "standard")
+	c, err = openConfig(spec, 0)
+	if err == nil {
+		c.close()
+		t.Fatal("opened a Pocket Farkle database with the standard rules")
+	}
+	if want := "-rules pocket-farkle"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not suggest %s", err, want)
+	}
+}
@@ -0,0 +1,504 @@
+package serve
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/cli"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+type Params struct {
+	NumPlayers int
+	DBPath     string
+	Addr       string
+	MCTSBudget time.Duration
+	CacheSize  int
+	AdminAddr  string
+	Configs    string
+	Rules      string
+	// The file of API keys, if keys are required (see readAPIKeys).
+	APIKeys       string
+	AnonymousRate float64
+}
+
+// farkle serve, also built as farkle-web.
+var Command = cli.Command{
+	Name:    "serve",
+	Binary:  "farkle-web",
+	Summary: "Serve the browser game and the JSON recommendation API",
+	Run:     run,
+}
+
+func run(flags *cli.FlagSet) {
+	var params Params
+	flags.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	flags.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	flags.StringVar(&params.Addr, "addr", ":8080", "Address to serve on")
+	flags.DurationVar(&params.MCTSBudget, "mcts_budget", 0,
+		"If > 0, serve without a database using Monte Carlo tree search for this long per request")
+	flags.IntVar(&params.CacheSize, "cache_size", 100000,
+		"Number of recommended actions to cache for popular positions (0 to disable)")
+	flags.StringVar(&params.AdminAddr, "admin_addr", "",
+		"Address to serve POST /reload on, to switch to a newer version of -db and -api_keys, and GET /usage on (optional, SIGHUP also reloads)")
+	flags.StringVar(&params.Configs, "configs", "",
+		"Serve these databases instead of -db, chosen by the config field of requests: comma-separated KEY=PATH:NUM_PLAYERS, the first of which is the default; all must be solved with -rules (optional)")
+	flags.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	flags.StringVar(&params.APIKeys, "api_keys", "",
+		"File of API keys required by the JSON API, one KEY REQUESTS_PER_MINUTE [NAME] per line (optional)")
+	flags.Float64Var(&params.AnonymousRate, "anonymous_rate", 0,
+		"With -api_keys, the requests per minute shared by all requests without a key, e.g. from the browser game (0 to require a key)")
+	flags.Parse()
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	s := &server{configs: make(map[string]*config)}
+	if params.MCTSBudget > 0 {
+		s.configs[""] = &config{
+			numPlayers: params.NumPlayers,
+			newAdvisor: func() farkle.Advisor {
+				return farkle.NewMCTS(params.MCTSBudget, rand.New(rand.NewSource(time.Now().UnixNano())))
+			},
+		}
+	} else {
+		specs := []configSpec{{path: params.DBPath, numPlayers: params.NumPlayers}}
+		if params.Configs != "" {
+			var err error
+			if specs, err = parseConfigSpecs(params.Configs); err != nil {
+				glog.Errorf("Invalid -configs: %v", err)
+				os.Exit(1)
+			}
+			s.defaultKey = specs[0].key
+		}
+		for _, spec := range specs {
+			c, err := openConfig(spec, params.CacheSize)
+			if err != nil {
+				glog.Errorf("Unable to open database: %v", err)
+				os.Exit(1)
+			}
+			defer c.close()
+			s.configs[spec.key] = c
+		}
+	}
+	if params.APIKeys != "" {
+		var err error
+		if s.keys, err = loadKeyring(params.APIKeys, params.AnonymousRate); err != nil {
+			glog.Errorf("Unable to load API keys: %v", err)
+			os.Exit(1)
+		}
+	}
+	if admin := cli.HandleReload(s.reload, params.AdminAddr); admin != nil && s.keys != nil {
+		admin.HandleFunc("GET /usage", s.keys.handleAllUsage)
+	}
+
+	static, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		glog.Errorf("Unable to load static files: %v", err)
+		os.Exit(1)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(static)))
+	mux.HandleFunc("POST /api/recommend", s.limit(s.handleRecommend))
+	mux.HandleFunc("POST /api/apply", s.limit(s.handleApply))
+	mux.HandleFunc("POST /api/winprob", s.limit(s.handleWinProb))
+	mux.HandleFunc("GET /api/holds", s.handleHolds)
+	mux.HandleFunc("GET /api/cache", s.limit(s.handleCache))
+	mux.HandleFunc("GET /api/configs", s.limit(s.handleConfigs))
+	if s.keys != nil {
+		mux.HandleFunc("GET /api/usage", s.keys.handleUsage)
+	}
+
+	glog.Infof("Serving on %s", params.Addr)
+	if err := http.ListenAndServe(params.Addr, mux); err != nil {
+		glog.Errorf("Error serving: %v", err)
+		os.Exit(1)
+	}
+}
+
+// Game state as exchanged with clients. All scores are in points,
+// and the current player is always player 0.
+type State struct {
+	Scores    []int `json:"scores"`
+	TurnScore int   `json:"turnScore"`
+	NumDice   int   `json:"numDice"`
+}
+
+type Action struct {
+	Held     []int `json:"held"`
+	Continue bool  `json:"continue"`
+}
+
+type Hold struct {
+	Dice   []int `json:"dice"`
+	Points int   `json:"points"`
+}
+
+type RecommendRequest struct {
+	// The key of the configuration to use (see -configs), or empty for
+	// the default.
+	Config string `json:"config,omitempty"`
+	State  State  `json:"state"`
+	Roll   []int  `json:"roll"`
+	// Omit the details of every legal action, which are much more expensive
+	// than the recommendation, so that it may be served from the cache.
+	Brief bool `json:"brief,omitempty"`
+}
+
+type RecommendResponse struct {
+	Action     Action    `json:"action"`
+	PWin       []float64 `json:"pWin"`
+	IsFarkle   bool      `json:"isFarkle"`
+	LegalHolds []Hold    `json:"legalHolds"`
+	// Every legal action from best to worst, if serving from a database and
+	// the request is not brief.
+	Actions []ActionDetail `json:"actions,omitempty"`
+}
+
+type ActionDetail struct {
+	Action            Action    `json:"action"`
+	PWin              []float64 `json:"pWin"`
+	NumDiceLeft       int       `json:"numDiceLeft"`
+	PFarkle           float64   `json:"pFarkle"`
+	ExpectedTurnScore float64   `json:"expectedTurnScore"`
+}
+
+type ApplyRequest struct {
+	Config string `json:"config,omitempty"`
+	State  State  `json:"state"`
+	Roll   []int  `json:"roll"`
+	Action Action `json:"action"`
+}
+
+type ApplyResponse struct {
+	State    State     `json:"state"`
+	PWin     []float64 `json:"pWin"`
+	GameOver bool      `json:"gameOver"`
+}
+
+type WinProbRequest struct {
+	Config string `json:"config,omitempty"`
+	State  State  `json:"state"`
+}
+
+type WinProbResponse struct {
+	PWin []float64 `json:"pWin"`
+}
+
+type ConfigInfo struct {
+	Key        string `json:"key"`
+	NumPlayers int    `json:"numPlayers"`
+	// The objective and rules of the database, e.g. "win".
+	Metadata string `json:"metadata"`
+	Default  bool   `json:"default"`
+}
+
+type CacheResponse struct {
+	farkle.ActionCacheStats
+	HitRate float64 `json:"hitRate"`
+}
+
+type server struct {
+	// The configurations by key. Without -configs, there is one, with an
+	// empty key.
+	configs map[string]*config
+	// The key of the configuration of requests that do not name one.
+	defaultKey string
+	// The API keys, if required.
+	keys *keyring
+}
+
+// The configuration with the given key, or the default if it is empty.
+func (s *server) config(key string) (*config, error) {
+	if key == "" {
+		key = s.defaultKey
+	}
+	c, ok := s.configs[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown configuration: %q", key)
+	}
+	return c, nil
+}
+
+// Switch every configuration to the newer version of its database, and read
+// the API keys again.
+func (s *server) reload() error {
+	var errs []error
+	for _, c := range s.configs {
+		if c.reload != nil {
+			errs = append(errs, c.reload())
+		}
+	}
+	if s.keys != nil {
+		errs = append(errs, s.keys.reload())
+	}
+	return errors.Join(errs...)
+}
+
+// Require an API key for the handler, if keys are required. The table of
+// holds and the static files are always public.
+func (s *server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	if s.keys == nil {
+		return handler
+	}
+	return s.keys.require(handler)
+}
+
+func (s *server) handleRecommend(w http.ResponseWriter, r *http.Request) {
+	var req RecommendRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	c, err := s.config(req.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state, err := c.parseState(req.State)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	roll, err := parseRoll(req.Roll, state.NumDiceToRoll)
+	if err == nil && roll.NumDice() != state.NumDiceToRoll {
+		err = fmt.Errorf("expected roll of %d dice, got %d", state.NumDiceToRoll, roll.NumDice())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	advisor := c.newAdvisor()
+	var details []farkle.ActionDetail
+	if c.db != nil && !req.Brief {
+		// The recommendation and the details are read from the same version
+		// of the database, even if it is reloaded in between.
+		db, release := farkle.AcquireDB(c.db)
+		defer release()
+		advisor = farkle.DBAdvisor{DB: db}
+		details = farkle.SelectActionDetailed(state, roll, db)
+	}
+
+	action, pWin := advisor.Recommend(state, roll)
+	resp := RecommendResponse{
+		Action: Action{
+			Held:     formatRoll(action.HeldDice()),
+			Continue: action.ContinueRolling,
+		},
+		PWin:     pWin[:state.NumPlayers],
+		IsFarkle: farkle.IsFarkle(roll),
+	}
+	for _, hold := range farkle.LegalHolds(roll) {
+		resp.LegalHolds = append(resp.LegalHolds, Hold{
+			Dice:   formatRoll(hold),
+			Points: farkle.ScoreIncrement * int(farkle.CalculateScore(hold)),
+		})
+	}
+	for _, d := range details {
+		resp.Actions = append(resp.Actions, ActionDetail{
+			Action: Action{
+				Held:     formatRoll(d.Action.HeldDice()),
+				Continue: d.Action.ContinueRolling,
+			},
+			PWin:              d.Value[:state.NumPlayers],
+			NumDiceLeft:       d.NumDiceLeft,
+			PFarkle:           d.PFarkle,
+			ExpectedTurnScore: d.ExpectedTurnScore,
+		})
+	}
+
+	writeResponse(w, resp)
+}
+
+func (s *server) handleApply(w http.ResponseWriter, r *http.Request) {
+	var req ApplyRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	c, err := s.config(req.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state, err := c.parseState(req.State)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	roll, err := parseRoll(req.Roll, state.NumDiceToRoll)
+	if err == nil && roll.NumDice() != state.NumDiceToRoll {
+		err = fmt.Errorf("expected roll of %d dice, got %d", state.NumDiceToRoll, roll.NumDice())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var action farkle.Action
+	if !farkle.IsFarkle(roll) {
+		held, err := parseRoll(req.Action.Held, state.NumDiceToRoll)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		action = farkle.Action{
+			HeldDiceID:      farkle.GetRollID(held),
+			ContinueRolling: req.Action.Continue,
+		}
+	}
+	if err := farkle.ValidateAction(state, roll, action); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newState := farkle.ApplyAction(state, action)
+	pWin := c.newAdvisor().WinProb(newState)
+	writeResponse(w, ApplyResponse{
+		State:    formatState(newState),
+		PWin:     pWin[:newState.NumPlayers],
+		GameOver: newState.IsGameOver(),
+	})
+}
+
+func (s *server) handleWinProb(w http.ResponseWriter, r *http.Request) {
+	var req WinProbRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	c, err := s.config(req.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state, err := c.parseState(req.State)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pWin := c.newAdvisor().WinProb(state)
+	writeResponse(w, WinProbResponse{PWin: pWin[:state.NumPlayers]})
+}
+
+// Statistics about the cache of recommended actions, to tune -cache_size.
+// The configuration is chosen by the config query parameter.
+func (s *server) handleCache(w http.ResponseWriter, r *http.Request) {
+	c, err := s.config(r.URL.Query().Get("config"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if c.cache == nil {
+		http.Error(w, "the action cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	stats := c.cache.Stats()
+	writeResponse(w, CacheResponse{ActionCacheStats: stats, HitRate: stats.HitRate()})
+}
+
+// The configurations served, for clients to choose from.
+func (s *server) handleConfigs(w http.ResponseWriter, r *http.Request) {
+	result := make([]ConfigInfo, 0, len(s.configs))
+	for key, c := range s.configs {
+		info := ConfigInfo{Key: key, NumPlayers: c.numPlayers, Default: key == s.defaultKey}
+		if c.db != nil {
+			info.Metadata = c.db.Metadata().String()
+		}
+		result = append(result, info)
+	}
+	slices.SortFunc(result, func(a, b ConfigInfo) int { return strings.Compare(a.Key, b.Key) })
+	writeResponse(w, result)
+}
+
+// The legal holds of every roll, for clients to validate holds offline.
+func (s *server) handleHolds(w http.ResponseWriter, r *http.Request) {
+	// The table only changes with the rules, so clients may cache it.
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	writeResponse(w, farkle.NewHoldTable())
+}
+
+func formatState(state farkle.GameState) State {
+	scores := make([]int, state.NumPlayers)
+	for i := range scores {
+		scores[i] = farkle.ScoreIncrement * int(state.PlayerScores[i])
+	}
+
+	return State{
+		Scores:    scores,
+		TurnScore: farkle.ScoreIncrement * int(state.ScoreThisRound),
+		NumDice:   int(state.NumDiceToRoll),
+	}
+}
+
+func parseRoll(dice []int, maxDice uint8) (farkle.Roll, error) {
+	if len(dice) > int(maxDice) {
+		return farkle.Roll{}, fmt.Errorf("too many dice: %d > %d", len(dice), maxDice)
+	}
+
+	var roll farkle.Roll
+	for _, die := range dice {
+		if die < 1 || die > 6 {
+			return farkle.Roll{}, fmt.Errorf("not a valid die: %d", die)
+		}
+		roll[die]++
+	}
+	return roll, nil
+}
+
+func formatRoll(roll farkle.Roll) []int {
+	result := make([]int, 0, roll.NumDice())
+	for _, die := range roll.Dice() {
+		result = append(result, int(die))
+	}
+	return result
+}
+
+// The largest request body that is read. Requests are a game state and a
+// roll, so this is far more than any valid request.
+const maxRequestBytes = 64 << 10
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		status := http.StatusBadRequest
+		var syntaxErr *json.SyntaxError
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &syntaxErr) {
+			err = fmt.Errorf("invalid JSON at offset %d: %w", syntaxErr.Offset, err)
+		} else if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Warningf("Error writing response: %v", err)
+	}
+}
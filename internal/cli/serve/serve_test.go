@@ -0,0 +1,108 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"valid", `{"state": {"scores": [0, 0], "turnScore": 0, "numDice": 6}}`, http.StatusOK},
+		{"syntax error", `{"state": `, http.StatusBadRequest},
+		{"unknown field", `{"stat": {}}`, http.StatusBadRequest},
+		{"too large", `{"config": "` + strings.Repeat("x", maxRequestBytes) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/winprob", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			var req WinProbRequest
+			if ok := decodeRequest(w, r, &req); ok != (tc.want == http.StatusOK) || w.Code != tc.want {
+				t.Errorf("decodeRequest = %v with status %d, want status %d", ok, w.Code, tc.want)
+			}
+		})
+	}
+}
+
+func recommend(t *testing.T, s *server, req RecommendRequest) RecommendResponse {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.handleRecommend(w, httptest.NewRequest("POST", "/api/recommend", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp RecommendResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// Brief recommendations omit the details of every action, and are served
+// from the cache.
+func TestRecommendBrief(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "1player.db")
+	db, err := farkle.NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := openConfig(configSpec{path: path, numPlayers: 1}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	s := &server{configs: map[string]*config{"": c}}
+
+	req := RecommendRequest{State: State{Scores: []int{0}, NumDice: 6}, Roll: []int{1, 1, 5, 2, 3, 4}}
+	full := recommend(t, s, req)
+	roll := mustParseRoll(t, req.Roll)
+	if want := len(farkle.LegalActions(farkle.NewGameState(1), roll)); len(full.Actions) != want {
+		t.Errorf("listed %d actions, want %d", len(full.Actions), want)
+	}
+	for _, hold := range full.LegalHolds {
+		if want := farkle.ScoreIncrement * int(farkle.CalculateScore(mustParseRoll(t, hold.Dice))); hold.Points != want {
+			t.Errorf("hold %v scores %d points, want %d", hold.Dice, hold.Points, want)
+		}
+	}
+	if stats := c.cache.Stats(); stats.Hits+stats.Misses != 0 {
+		t.Errorf("detailed recommendation used the cache: %+v", stats)
+	}
+
+	req.Brief = true
+	for range 2 {
+		if brief := recommend(t, s, req); len(brief.Actions) != 0 {
+			t.Errorf("brief recommendation listed %d actions", len(brief.Actions))
+		}
+	}
+	if stats := c.cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("cache stats after two brief recommendations = %+v, want 1 hit and 1 miss", stats)
+	}
+}
+
+func mustParseRoll(t *testing.T, dice []int) farkle.Roll {
+	t.Helper()
+	roll, err := parseRoll(dice, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return roll
+}
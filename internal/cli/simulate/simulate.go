@@ -0,0 +1,416 @@
+package simulate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/cli"
+	"github.com/timpalpant/go-farkle/neural"
+)
+
+type Params struct {
+	Strategies    string
+	DBPath        string
+	NumGames      int
+	NumBootstraps int
+	Seed          int64
+	EventLogPath  string
+	ReplayDir     string
+	Rules         string
+	BestOf        int
+}
+
+// farkle simulate, also built as farkle-tournament.
+var Command = cli.Command{
+	Name:    "simulate",
+	Binary:  "farkle-tournament",
+	Summary: "Play strategies against each other and rate them",
+	Run:     run,
+}
+
+func run(fs *cli.FlagSet) {
+	var params Params
+	fs.StringVar(&params.Strategies, "strategies", "optimal,threshold:300,threshold:500,threshold:1000",
+		"Comma-separated strategies to compete: optimal[:DB_PATH], threshold:BANK_AT[:MIN_DICE], neural:POLICY_PATH, exec:COMMAND [ARGS...]")
+	fs.StringVar(&params.DBPath, "db", "2player.db", "Path to default 2-player solution database (for the optimal strategy)")
+	fs.IntVar(&params.NumGames, "num_games", 1000, "Number of games played between each pair of strategies")
+	fs.IntVar(&params.NumBootstraps, "num_bootstraps", 200, "Number of bootstrap resamples for rating error bars")
+	fs.Int64Var(&params.Seed, "seed", 12345, "Random seed")
+	fs.StringVar(&params.EventLogPath, "event_log", "",
+		"Write every decision in every game to this file as JSON lines, e.g. as training data (optional)")
+	fs.StringVar(&params.ReplayDir, "replay_dir", "",
+		"Record each game to DIR/I-vs-J-K.jsonl, for farkle-replay, where I and J are the positions of the strategies in -strategies and K numbers the games between them from 0; I is in seat 0 in even games (optional)")
+	fs.StringVar(&params.Rules, "rules", "standard",
+		"Rules to play by: standard, facebook, pocket-farkle or kingdom-come")
+	fs.IntVar(&params.BestOf, "best_of", 0,
+		"Play a single-elimination bracket of best-of-N matches, seeded in the order of -strategies, instead of a round robin")
+	fs.Parse()
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+	if params.BestOf > 0 && params.EventLogPath != "" {
+		glog.Errorf("-event_log is not supported with -best_of")
+		os.Exit(1)
+	}
+	if params.BestOf > 0 && params.ReplayDir != "" {
+		glog.Errorf("-replay_dir is not supported with -best_of")
+		os.Exit(1)
+	}
+	if params.ReplayDir != "" {
+		if err := os.MkdirAll(params.ReplayDir, 0755); err != nil {
+			glog.Errorf("Unable to create replay directory: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	dbs := make(map[string]farkle.DB)
+	openDB := func(path string) (farkle.DB, error) {
+		if path == "" {
+			path = params.DBPath
+		}
+		if db, ok := dbs[path]; ok {
+			return db, nil
+		}
+
+		db, err := cli.OpenDB(path, 2)
+		if err != nil {
+			return nil, err
+		}
+		dbs[path] = db
+		return db, nil
+	}
+
+	specs := strings.Split(params.Strategies, ",")
+	strategies := make([]farkle.Strategy, len(specs))
+	for i, spec := range specs {
+		var err error
+		strategies[i], err = parseStrategy(strings.TrimSpace(spec), openDB)
+		if err != nil {
+			glog.Errorf("Invalid strategy %q: %v", spec, err)
+			os.Exit(1)
+		}
+	}
+	var eventLog *farkle.EventLogWriter
+	if params.EventLogPath != "" {
+		f, err := os.Create(params.EventLogPath)
+		if err != nil {
+			glog.Errorf("Unable to create event log: %v", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w := bufio.NewWriterSize(f, 1024*1024)
+		defer w.Flush()
+
+		// Include the value of each action if the default database is available.
+		db, err := openDB("")
+		if err != nil {
+			glog.Warningf("Event log will not include action values: %v", err)
+		}
+		eventLog = farkle.NewEventLogWriter(w, db)
+	}
+
+	for _, db := range dbs {
+		defer db.Close()
+	}
+	for _, strategy := range strategies {
+		if closer, ok := strategy.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+
+	rng := rand.New(rand.NewSource(params.Seed))
+	if params.BestOf > 0 {
+		bracket := farkle.Bracket{Strategies: strategies, NumGames: params.BestOf}
+		result, err := bracket.Play(rng)
+		if err != nil {
+			glog.Errorf("Error playing bracket: %v", err)
+			os.Exit(1)
+		}
+		printBracket(specs, result)
+		return
+	}
+
+	results, err := playRoundRobin(strategies, params.NumGames, rng, eventLog, params.ReplayDir)
+	if err != nil {
+		glog.Errorf("Error playing tournament: %v", err)
+		os.Exit(1)
+	}
+
+	ratings := fitRatings(results)
+	stdErrs := bootstrapStdErrs(results, params.NumBootstraps, rng)
+	printRatings(specs, results, ratings, stdErrs)
+}
+
+func parseStrategy(spec string, openDB func(path string) (farkle.DB, error)) (farkle.Strategy, error) {
+	name, args, _ := strings.Cut(spec, ":")
+	switch name {
+	case "optimal":
+		db, err := openDB(args)
+		if err != nil {
+			return nil, err
+		}
+		return farkle.OptimalStrategy{DB: db}, nil
+	case "threshold":
+		return farkle.ParseThresholdStrategy(spec)
+	case "neural":
+		f, err := os.Open(args)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		net, err := neural.LoadMLP(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", args, err)
+		}
+		return neural.Strategy{Net: net}, nil
+	case "exec":
+		command := strings.Fields(args)
+		if len(command) == 0 {
+			return nil, fmt.Errorf("expected exec:COMMAND [ARGS...]")
+		}
+		return farkle.NewExternalStrategy(command[0], command[1:]...)
+	}
+
+	return nil, fmt.Errorf("unknown strategy: %s", name)
+}
+
+// Outcomes of the games played between each pair of strategies.
+// Scores[i][j] holds the score of strategy i in each game against j:
+// 1 for a win, 0.5 for a tie and 0 for a loss.
+type tournamentResults struct {
+	Scores [][][]float64
+}
+
+// Play numGames games between each pair of strategies, writing every decision
+// to eventLog and each game to a replay in replayDir, if given.
+func playRoundRobin(strategies []farkle.Strategy, numGames int, rng *rand.Rand,
+	eventLog *farkle.EventLogWriter, replayDir string) (tournamentResults, error) {
+	n := len(strategies)
+	results := tournamentResults{Scores: make([][][]float64, n)}
+	for i := range results.Scores {
+		results.Scores[i] = make([][]float64, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			glog.Infof("Playing %d games: %v vs %v", numGames, strategies[i], strategies[j])
+			for k := 0; k < numGames; k++ {
+				// Alternate seats, since the first player has an advantage.
+				players := []farkle.Strategy{strategies[i], strategies[j]}
+				seatOfI := 0
+				if k%2 == 1 {
+					players[0], players[1] = players[1], players[0]
+					seatOfI = 1
+				}
+
+				var replayPath string
+				if replayDir != "" {
+					replayPath = filepath.Join(replayDir, fmt.Sprintf("%d-vs-%d-%d.jsonl", i, j, k))
+				}
+				result, err := playGame(players, rng, eventLog, replayPath)
+				if err != nil {
+					return results, err
+				}
+				if eventLog != nil {
+					if err := eventLog.FinishGame(result); err != nil {
+						return results, err
+					}
+				}
+
+				score := 0.0
+				for _, winner := range result.Winners {
+					if winner == seatOfI {
+						score = 1.0 / float64(len(result.Winners))
+					}
+				}
+				results.Scores[i][j] = append(results.Scores[i][j], score)
+				results.Scores[j][i] = append(results.Scores[j][i], 1-score)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Play a game, recording every decision to eventLog if it is not nil, and the
+// game to a replay at replayPath if it is not empty.
+func playGame(players []farkle.Strategy, rng *rand.Rand, eventLog *farkle.EventLogWriter, replayPath string) (farkle.GameResult, error) {
+	var replay *farkle.ReplayWriter
+	var f *os.File
+	var w *bufio.Writer
+	if replayPath != "" {
+		var err error
+		if f, err = os.Create(replayPath); err != nil {
+			return farkle.GameResult{}, err
+		}
+		defer f.Close()
+		w = bufio.NewWriter(f)
+		replay = farkle.NewReplayWriter(w)
+	}
+
+	result, err := farkle.PlayGameObserved(players, rng, func(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+		if eventLog != nil {
+			eventLog.Observe(seat, state, roll, action)
+		}
+		if replay != nil {
+			replay.Observe(seat, state, roll, action)
+		}
+	})
+	if err != nil || replay == nil {
+		return result, err
+	}
+	if err := replay.Err(); err != nil {
+		return result, err
+	}
+	if err := w.Flush(); err != nil {
+		return result, err
+	}
+	return result, f.Close()
+}
+
+// Fit Elo ratings to the results by maximum likelihood under the
+// Bradley-Terry model, using the minorization-maximization algorithm.
+// Ratings are centered on 1500.
+func fitRatings(results tournamentResults) []float64 {
+	n := len(results.Scores)
+	wins := make([]float64, n)
+	games := make([][]float64, n)
+	for i := range games {
+		games[i] = make([]float64, n)
+		for j, scores := range results.Scores[i] {
+			if i == j {
+				continue
+			}
+			// One virtual tie against each opponent keeps the ratings
+			// finite when a strategy wins or loses every game.
+			wins[i] += 0.5
+			games[i][j] = 1
+			for _, score := range scores {
+				wins[i] += score
+				games[i][j]++
+			}
+		}
+	}
+
+	strength := make([]float64, n)
+	for i := range strength {
+		strength[i] = 1
+	}
+	for iter := 0; iter < 1000; iter++ {
+		next := make([]float64, n)
+		for i := range next {
+			denom := 0.0
+			for j := range next {
+				if i != j {
+					denom += games[i][j] / (strength[i] + strength[j])
+				}
+			}
+			next[i] = wins[i] / denom
+		}
+
+		// Normalize to a geometric mean of 1.
+		logMean := 0.0
+		for _, s := range next {
+			logMean += math.Log(s)
+		}
+		logMean /= float64(n)
+		for i := range next {
+			next[i] /= math.Exp(logMean)
+		}
+		strength = next
+	}
+
+	ratings := make([]float64, n)
+	for i, s := range strength {
+		ratings[i] = 1500 + 400*math.Log10(s)
+	}
+	return ratings
+}
+
+// Estimate the standard error of each rating by refitting
+// ratings to resampled game outcomes.
+func bootstrapStdErrs(results tournamentResults, numBootstraps int, rng *rand.Rand) []float64 {
+	n := len(results.Scores)
+	sum := make([]float64, n)
+	sumSq := make([]float64, n)
+	for b := 0; b < numBootstraps; b++ {
+		resampled := tournamentResults{Scores: make([][][]float64, n)}
+		for i := range resampled.Scores {
+			resampled.Scores[i] = make([][]float64, n)
+		}
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				scores := results.Scores[i][j]
+				for range scores {
+					score := scores[rng.Intn(len(scores))]
+					resampled.Scores[i][j] = append(resampled.Scores[i][j], score)
+					resampled.Scores[j][i] = append(resampled.Scores[j][i], 1-score)
+				}
+			}
+		}
+
+		for i, rating := range fitRatings(resampled) {
+			sum[i] += rating
+			sumSq[i] += rating * rating
+		}
+	}
+
+	result := make([]float64, n)
+	if numBootstraps < 2 {
+		return result
+	}
+	for i := range result {
+		mean := sum[i] / float64(numBootstraps)
+		variance := (sumSq[i] - float64(numBootstraps)*mean*mean) / float64(numBootstraps-1)
+		result[i] = math.Sqrt(max(0, variance))
+	}
+	return result
+}
+
+func printRatings(names []string, results tournamentResults, ratings, stdErrs []float64) {
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return ratings[order[a]] > ratings[order[b]]
+	})
+
+	fmt.Printf("%-4s %-24s %16s %8s\n", "Rank", "Strategy", "Elo (95% CI)", "Score")
+	for rank, i := range order {
+		total, numGames := 0.0, 0
+		for _, scores := range results.Scores[i] {
+			for _, score := range scores {
+				total += score
+				numGames++
+			}
+		}
+
+		fmt.Printf("%-4d %-24s %7.0f ± %-6.0f %7.1f%%\n",
+			rank+1, names[i], ratings[i], 1.96*stdErrs[i], 100*total/float64(numGames))
+	}
+}
+
+func printBracket(names []string, result farkle.BracketResult) {
+	for i, round := range result.Rounds {
+		fmt.Printf("Round %d\n", i+1)
+		for _, m := range round {
+			a, b := m.Players[0], m.Players[1]
+			fmt.Printf("  %-24s %4.1f - %-4.1f %-24s (%d games, %d - %d points)\n",
+				names[a], m.Result.Wins[0], m.Result.Wins[1], names[b],
+				len(m.Result.Games), m.Result.TotalScores[0], m.Result.TotalScores[1])
+		}
+	}
+	fmt.Printf("Champion: %s\n", names[result.Champion])
+}
@@ -0,0 +1,534 @@
+package solve
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/cli"
+)
+
+type Params struct {
+	NumPlayers     int
+	GameStatesPath string
+	DBPath         string
+	CheckpointPath string
+	NumIter        int
+	Objective      string
+	RiskAversion   float64
+	Opponent       string
+	RemoteDB       string
+	DeltaDir       string
+	DeltaEpsilon   float64
+	Batched        bool
+	PinWorkers     bool
+	CacheGB        float64
+	ScoreBuckets   int
+	EndgamePoints  int
+	Prefetch       bool
+	HugePages      bool
+	Rules          string
+	Estimate       bool
+	FromDB         string
+	FromTarget     int
+	SweepEpsilon   float64
+	Exact          bool
+	Moments        bool
+}
+
+// farkle solve, also built as solve-farkle.
+var Command = cli.Command{
+	Name:    "solve",
+	Binary:  "solve-farkle",
+	Summary: "Solve the game for optimal play by value iteration",
+	Run:     run,
+}
+
+func run(fs *cli.FlagSet) {
+	var params Params
+	fs.IntVar(&params.NumPlayers, "num_players", 2, "Number of players")
+	fs.StringVar(&params.GameStatesPath, "games", "2player.games",
+		"Path to sorted game states, which depend on -num_players and -rules")
+	fs.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	fs.StringVar(&params.CheckpointPath, "chkpnt", "2player.chkpnt", "Path to checkpoint file")
+	fs.IntVar(&params.NumIter, "num_iter", 10, "Number of value iteration cycles")
+	fs.StringVar(&params.Objective, "objective", "win", "Objective to optimize: win (probability), margin (expected final score margin) or risk (risk-adjusted margin)")
+	fs.Float64Var(&params.RiskAversion, "risk_aversion", 0, "Risk aversion per 1000 points for -objective risk: > 0 is conservative, < 0 is aggressive")
+	fs.StringVar(&params.Opponent, "opponent", "",
+		"Solve for the best response to opponents playing this strategy, e.g. threshold:500 (optional)")
+	fs.StringVar(&params.RemoteDB, "remote_db", "",
+		"Address of a database served by serve-db to use instead of -db (optional)")
+	fs.StringVar(&params.DeltaDir, "delta_dir", "",
+		"Directory to save the states that changed in each value iteration cycle to (optional)")
+	fs.Float64Var(&params.DeltaEpsilon, "delta_epsilon", 1e-9,
+		"Only save states whose value changed by more than this in each cycle")
+	fs.BoolVar(&params.Exact, "exact", false,
+		"Solve exactly in a single pass over the scores, instead of -num_iter value iteration cycles")
+	fs.Float64Var(&params.SweepEpsilon, "sweep_epsilon", 0,
+		"If > 0, after the first cycle only update states that lead to a state whose value changed by more than this in the previous cycle")
+	fs.BoolVar(&params.Batched, "batched", false,
+		"Update the states at each depth with the same dice to roll and score this round in batches sorted by ID, for streaming database access")
+	fs.BoolVar(&params.PinWorkers, "pin_workers", false,
+		"Pin each worker to its own CPU and a contiguous range of states (Linux only)")
+	fs.Float64Var(&params.CacheGB, "cache_gb", 0,
+		"Approximate memory budget in GiB for sort buffers and caches (0 for the defaults)")
+	fs.IntVar(&params.ScoreBuckets, "score_buckets", 0,
+		"If > 0, solve an abstracted game in which opponent scores are grouped into this many buckets (approximate)")
+	fs.IntVar(&params.EndgamePoints, "endgame_points", 0,
+		"If > 0, keep states in which the players' scores add up to at least this many points in memory")
+	fs.BoolVar(&params.Prefetch, "prefetch", false,
+		"Read the pages of the database needed at each depth ahead of time, if it does not fit in memory")
+	fs.BoolVar(&params.HugePages, "huge_pages", false,
+		"Back the database with transparent huge pages, if supported (Linux only)")
+	fs.StringVar(&params.Rules, "rules", "standard",
+		"Rules to solve: standard, facebook, pocket-farkle or kingdom-come")
+	fs.BoolVar(&params.Estimate, "estimate", false,
+		"Instead of solving, estimate the number of states, file sizes and time the solve needs")
+	fs.StringVar(&params.FromDB, "from_db", "",
+		"Database solved with the same rules except for -from_target, to seed a new -db from and solve only the states that differ (optional)")
+	fs.IntVar(&params.FromTarget, "from_target", 10000, "Score to win of -from_db")
+	fs.BoolVar(&params.Moments, "moments", false,
+		"Instead of solving, compute the mean and variance of each player's final score with the strategy solved in -db, into -db.mean and -db.square")
+	fs.Parse()
+
+	if params.CacheGB > 0 {
+		farkle.SetMemoryBudget(int64(params.CacheGB * (1 << 30)))
+	}
+
+	go http.ListenAndServe(":6069", nil)
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	if params.Estimate {
+		if params.NumPlayers < 1 || params.NumPlayers > 4 {
+			glog.Errorf("Expected 1 to 4 players, got %d", params.NumPlayers)
+			os.Exit(1)
+		}
+		estimate := farkle.EstimateSolve(params.NumPlayers, 3*time.Second, rand.New(rand.NewSource(1)))
+		printEstimate(estimate, params)
+		return
+	}
+
+	initialState := farkle.NewGameState(params.NumPlayers)
+	glog.Infof("Initial state: %v", initialState)
+
+	if params.Moments {
+		if err := solveMoments(params, initialState); err != nil {
+			glog.Errorf("Error computing moments: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	objective, err := farkle.ParseObjective(params.Objective)
+	if err != nil {
+		glog.Errorf("Invalid objective: %v", err)
+		os.Exit(1)
+	}
+
+	meta := farkle.Metadata{Objective: objective, Rules: farkle.CurrentRules().Fingerprint()}
+	if objective == farkle.RiskAdjustedMargin {
+		meta.RiskAversion = params.RiskAversion
+	}
+	_, statErr := os.Stat(params.DBPath)
+	resuming := params.RemoteDB == "" && statErr == nil
+	db, err := openDB(params, meta)
+	if err != nil {
+		glog.Errorf("Unable to open database: %v", err)
+		os.Exit(1)
+	}
+	if coverageDB, ok := db.(farkle.CoverageDB); ok && resuming {
+		if coverage, err := coverageDB.Coverage(); err == nil {
+			glog.Infof("Resuming %s: %v", params.DBPath, coverage)
+		}
+	}
+
+	if params.Exact && (params.Opponent != "" || params.ScoreBuckets > 0 || params.FromDB != "" || params.SweepEpsilon > 0) {
+		glog.Errorf("-exact cannot be used with -opponent, -score_buckets, -from_db or -sweep_epsilon")
+		os.Exit(1)
+	}
+	if _, err := os.Stat(params.GameStatesPath); err != nil && !params.Exact {
+		glog.Infof("Enumerating and sorting game states by depth")
+		gamesIter := farkle.SortedGameStates(params.NumPlayers, filepath.Dir(params.GameStatesPath))
+		if err := farkle.SaveGameStates(gamesIter, params.GameStatesPath); err != nil {
+			glog.Errorf("Error sorting game state: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var retarget *farkle.Retarget
+	if params.FromDB != "" {
+		if params.Opponent != "" || params.ScoreBuckets > 0 {
+			glog.Errorf("-from_db cannot be used with -opponent or -score_buckets")
+			os.Exit(1)
+		}
+		retarget, err = openRetarget(db, params, resuming)
+		if err != nil {
+			glog.Errorf("Unable to seed database from %s: %v", params.FromDB, err)
+			os.Exit(1)
+		}
+	}
+
+	if params.HugePages {
+		if fileDB, ok := db.(*farkle.FileDB); !ok {
+			glog.Warning("-huge_pages only applies to local databases")
+		} else if err := fileDB.UseHugePages(); err != nil {
+			glog.Warningf("Using regular pages: %v", err)
+		}
+	}
+
+	var tieredDB *farkle.TieredDB
+	if params.EndgamePoints > 0 {
+		glog.Infof("Loading states with at least %d total points into memory", params.EndgamePoints)
+		tieredDB = farkle.NewTieredDB(db, params.EndgamePoints/50)
+		db = tieredDB
+	}
+
+	var deltaDB *farkle.DeltaDB
+	if params.DeltaDir != "" {
+		if err := os.MkdirAll(params.DeltaDir, 0755); err != nil {
+			glog.Errorf("Unable to create delta directory: %v", err)
+			os.Exit(1)
+		}
+		deltaDB = farkle.NewDeltaDB(db, params.DeltaEpsilon)
+		db = deltaDB
+	}
+
+	var residualDB *farkle.ResidualDB
+	if params.SweepEpsilon > 0 {
+		if params.Opponent != "" || params.ScoreBuckets > 0 {
+			glog.Errorf("-sweep_epsilon cannot be used with -opponent or -score_buckets")
+			os.Exit(1)
+		}
+		residualDB = farkle.NewResidualDB(db, params.SweepEpsilon)
+		db = residualDB
+	}
+
+	var buckets farkle.ScoreBuckets
+	if params.ScoreBuckets > 0 {
+		if params.Opponent != "" {
+			glog.Errorf("-score_buckets cannot be used with -opponent")
+			os.Exit(1)
+		}
+		buckets, err = farkle.NewScoreBuckets(params.ScoreBuckets)
+		if err != nil {
+			glog.Errorf("Invalid -score_buckets: %v", err)
+			os.Exit(1)
+		}
+		db = farkle.NewBucketedDB(db, buckets)
+	}
+
+	var br *farkle.BestResponse
+	if params.Opponent != "" {
+		br, err = openBestResponse(db, params, meta)
+		if err != nil {
+			glog.Errorf("Unable to initialize best response: %v", err)
+			os.Exit(1)
+		}
+		defer func() {
+			for _, table := range br.Tables[1:] {
+				table.Close()
+			}
+		}()
+	}
+
+	var pd *farkle.PlayerDice
+	if farkle.CurrentRules().HasPlayerDice() {
+		if params.Opponent != "" || params.ScoreBuckets > 0 || params.Exact || params.FromDB != "" || params.SweepEpsilon > 0 {
+			glog.Errorf("Rules with player dice cannot be solved with -opponent, -score_buckets, -exact, -from_db or -sweep_epsilon")
+			os.Exit(1)
+		}
+		pd, err = openPlayerDice(db, params, meta)
+		if err != nil {
+			glog.Errorf("Unable to initialize player dice: %v", err)
+			os.Exit(1)
+		}
+		defer func() {
+			for _, table := range pd.Tables[1:] {
+				table.Close()
+			}
+		}()
+	}
+
+	opts := farkle.UpdateOptions{
+		CheckpointPath: params.CheckpointPath,
+		Batched:        params.Batched,
+		PinWorkers:     params.PinWorkers,
+		Prefetch:       params.Prefetch,
+	}
+	if params.Exact {
+		glog.Infof("Solving exactly in a single pass")
+		logStats(farkle.SolveExact(db, params.CheckpointPath))
+		if tieredDB != nil {
+			tieredDB.Flush()
+		}
+		saveDelta(deltaDB, params, 0)
+		logValue(db.Get(initialState.ID()), params.NumPlayers, meta)
+	} else {
+		for i := 0; i < params.NumIter; i++ {
+			glog.Infof("Starting value iteration cycle %d", i)
+			gamesIter, err := farkle.IterGameStates(params.NumPlayers, params.GameStatesPath)
+			if err != nil {
+				glog.Errorf("Error loading sorted game states: %v", err)
+				os.Exit(1)
+			}
+			if params.ScoreBuckets > 0 {
+				gamesIter = buckets.AbstractGameStates(gamesIter)
+			}
+			if retarget != nil {
+				gamesIter = retarget.AffectedStates(gamesIter)
+			}
+			if br != nil {
+				stats, err := br.UpdateAllWithOptions(gamesIter, opts)
+				if err != nil {
+					glog.Errorf("Unable to solve the best response: %v", err)
+					os.Exit(1)
+				}
+				logStats(stats)
+				if tieredDB != nil {
+					tieredDB.Flush()
+				}
+				saveDelta(deltaDB, params, i)
+				for seat := 0; seat < params.NumPlayers; seat++ {
+					k := (params.NumPlayers - seat) % params.NumPlayers
+					glog.Infof("Best response value in seat %d: %v", seat, br.HeroValue(initialState, k))
+				}
+				continue
+			}
+			if pd != nil {
+				logStats(pd.UpdateAllWithOptions(gamesIter, opts))
+				if tieredDB != nil {
+					tieredDB.Flush()
+				}
+				saveDelta(deltaDB, params, i)
+				logValue(pd.Value(initialState, 0), params.NumPlayers, meta)
+				continue
+			}
+
+			logStats(farkle.UpdateAllWithOptions(db, gamesIter, opts))
+			if tieredDB != nil {
+				tieredDB.Flush()
+			}
+			saveDelta(deltaDB, params, i)
+			if residualDB != nil {
+				residualDB.NextCycle()
+			}
+			logValue(db.Get(initialState.ID()), params.NumPlayers, meta)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		glog.Errorf("Error closing database: %v", err)
+		os.Exit(1)
+	}
+}
+
+func logValue(winProb [4]float64, numPlayers int, meta farkle.Metadata) {
+	if numPlayers == 1 {
+		glog.Infof("Expected number of turns: %v", winProb[0])
+	} else if meta.Objective != farkle.WinProbability {
+		glog.Infof("Expected %v: %v", meta, winProb)
+	} else {
+		glog.Infof("Probability of winning: %v", winProb)
+	}
+}
+
+func printEstimate(e farkle.SolveEstimate, params Params) {
+	cycle := e.CycleTime()
+	fmt.Printf("%d-player game with %s rules\n", e.NumPlayers, params.Rules)
+	fmt.Printf("  %-21s %d (%d before the end of the game)\n", "States:", e.NumStates, e.NumOpenStates)
+	fmt.Printf("  %-21s %s\n", "Database (-db):", formatBytes(e.DBBytes))
+	fmt.Printf("  %-21s up to %s\n", "Game states (-games):", formatBytes(e.GameStatesBytes))
+	fmt.Printf("  %-21s up to %s while sorting game states\n", "Scratch space:", formatBytes(e.ScratchBytes))
+	fmt.Printf("  %-21s %.0f states/s per CPU, with %d CPU(s)\n", "Speed:", e.StatesPerSecondPerCPU, e.NumCPU)
+	fmt.Printf("  %-21s %v per cycle, %v for %d cycles (-num_iter)\n", "Time:",
+		formatDuration(cycle), formatDuration(time.Duration(params.NumIter)*cycle), params.NumIter)
+	fmt.Println("The time assumes the database fits in memory. Paging it from disk is much slower.")
+}
+
+func formatBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	size := float64(n)
+	i := 0
+	for ; size >= 1024 && i < len(units)-1; i++ {
+		size /= 1024
+	}
+	return fmt.Sprintf("%.1f %s", size, units[i])
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%.1f days", d.Hours()/24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%.1f hours", d.Hours())
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+// Log how long each depth took and how much the values changed. Once the
+// maximum change is small enough, further value iteration cycles are unnecessary.
+func logStats(stats farkle.UpdateStats) {
+	for _, depth := range stats.Depths {
+		glog.V(1).Infof("Depth %d: %d states in %v (%.0f states/s), mean change = %g, max change = %g",
+			depth.Depth, depth.NumStates, depth.Elapsed, depth.StatesPerSecond(),
+			depth.MeanChange, depth.MaxChange)
+	}
+
+	total := stats.Total()
+	glog.Infof("Updated %d states at %d depths in %v (%.0f states/s), mean change = %g, max change = %g",
+		total.NumStates, len(stats.Depths), total.Elapsed, total.StatesPerSecond(),
+		total.MeanChange, total.MaxChange)
+}
+
+func saveDelta(db *farkle.DeltaDB, params Params, cycle int) {
+	if db == nil {
+		return
+	}
+
+	path := filepath.Join(params.DeltaDir, fmt.Sprintf("delta-%03d.bin", cycle))
+	glog.Infof("Saving %d changed states to %s", db.Len(), path)
+	if err := db.SaveDelta(path); err != nil {
+		glog.Warningf("Unable to save delta: %v", err)
+	}
+}
+
+func openDB(params Params, meta farkle.Metadata) (farkle.DB, error) {
+	if params.RemoteDB == "" {
+		return farkle.NewFileDBWithMetadata(params.DBPath, params.NumPlayers, meta)
+	}
+
+	db, err := farkle.DialRemoteDB(params.RemoteDB)
+	if err != nil {
+		return nil, err
+	}
+	if db.NumPlayers() != params.NumPlayers || db.Metadata() != meta {
+		db.Close()
+		return nil, fmt.Errorf("%s is a %d-player %v database, not %d-player %v",
+			params.RemoteDB, db.NumPlayers(), db.Metadata(), params.NumPlayers, meta)
+	}
+	return db, nil
+}
+
+// The states of the game to the old target are translated to the new one, so
+// only the states that have no counterpart are solved in each cycle. A new
+// database is seeded with the translated values; a resumed one already has them.
+func openRetarget(db farkle.DB, params Params, resuming bool) (*farkle.Retarget, error) {
+	src, err := farkle.OpenFileDBReadOnly(params.FromDB, params.NumPlayers)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	if src.Metadata().Objective != db.Metadata().Objective {
+		return nil, fmt.Errorf("solved for %v, not %v", src.Metadata(), db.Metadata())
+	}
+	retarget, err := farkle.NewRetarget(src, params.FromTarget)
+	if err != nil {
+		return nil, err
+	}
+	if !resuming {
+		glog.Infof("Seeding %s from %s", params.DBPath, params.FromDB)
+		states, err := farkle.IterGameStates(params.NumPlayers, params.GameStatesPath)
+		if err != nil {
+			return nil, err
+		}
+		numAffected := retarget.Seed(db, states)
+		glog.Infof("%d states must be solved again", numAffected)
+	}
+	return retarget, nil
+}
+
+// The best response is stored with the table for the hero to move in the
+// main database, and the table for each other seat alongside it.
+func openBestResponse(db farkle.DB, params Params, meta farkle.Metadata) (*farkle.BestResponse, error) {
+	opponent, err := farkle.ParseThresholdStrategy(params.Opponent)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []farkle.DB{db}
+	for k := 1; k < params.NumPlayers; k++ {
+		path := fmt.Sprintf("%s.seat%d", params.DBPath, k)
+		table, err := farkle.NewFileDBWithMetadata(path, params.NumPlayers, meta)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return farkle.NewBestResponse(tables, opponent)
+}
+
+// The moments are computed in tables alongside the database of the strategy,
+// over the game states it was solved with, and are checkpointed separately.
+func solveMoments(params Params, initialState farkle.GameState) error {
+	if _, err := os.Stat(params.GameStatesPath); err != nil {
+		return fmt.Errorf("game states must be sorted by solving -db first: %w", err)
+	}
+	policy, err := cli.OpenDB(params.DBPath, params.NumPlayers)
+	if err != nil {
+		return err
+	}
+	defer policy.Close()
+
+	var tables []farkle.DB
+	for _, suffix := range []string{".mean", ".square"} {
+		table, err := farkle.NewFileDBWithMetadata(params.DBPath+suffix, params.NumPlayers, policy.Metadata())
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+	moments, err := farkle.NewScoreMoments(policy, tables[0], tables[1])
+	if err != nil {
+		return err
+	}
+
+	opts := farkle.UpdateOptions{
+		CheckpointPath: params.CheckpointPath + ".moments",
+		PinWorkers:     params.PinWorkers,
+		Prefetch:       params.Prefetch,
+	}
+	for i := 0; i < params.NumIter; i++ {
+		glog.Infof("Starting moments cycle %d", i)
+		gamesIter, err := farkle.IterGameStates(params.NumPlayers, params.GameStatesPath)
+		if err != nil {
+			return err
+		}
+		logStats(moments.UpdateAllWithOptions(gamesIter, opts))
+		glog.Infof("Final scores: %v", moments.Get(initialState))
+	}
+
+	for _, table := range tables {
+		if err := table.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// With player dice, the table for the first player to move is the main
+// database, and the table for each other player is alongside it.
+func openPlayerDice(db farkle.DB, params Params, meta farkle.Metadata) (*farkle.PlayerDice, error) {
+	tables := []farkle.DB{db}
+	for k := 1; k < params.NumPlayers; k++ {
+		path := fmt.Sprintf("%s.player%d", params.DBPath, k+1)
+		table, err := farkle.NewFileDBWithMetadata(path, params.NumPlayers, meta)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return farkle.NewPlayerDice(tables)
+}
@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/timpalpant/go-farkle"
+	"github.com/timpalpant/go-farkle/internal/cli"
+)
+
+type Params struct {
+	DBPath     string
+	NumPlayers int
+	Rules      string
+}
+
+// farkle verify, also built as verify-db.
+var Command = cli.Command{
+	Name:    "verify",
+	Binary:  "verify-db",
+	Summary: "Check the integrity of a solution database",
+	Run:     run,
+}
+
+func run(fs *cli.FlagSet) {
+	var params Params
+	fs.StringVar(&params.DBPath, "db", "2player.db", "Path to solution database")
+	fs.IntVar(&params.NumPlayers, "num_players", 2, "Number of players, to count the solved states")
+	fs.StringVar(&params.Rules, "rules", "standard",
+		"Rules the database was solved with, to count the solved states: standard, facebook, pocket-farkle or kingdom-come")
+	fs.Parse()
+
+	if err := cli.SetRules(params.Rules); err != nil {
+		glog.Errorf("Invalid rules: %v", err)
+		os.Exit(1)
+	}
+
+	if err := farkle.VerifyDB(params.DBPath); err != nil {
+		glog.Errorf("Verification failed: %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s is OK\n", params.DBPath)
+
+	db, err := cli.OpenDB(params.DBPath, params.NumPlayers)
+	if err != nil {
+		glog.Warningf("Unable to count solved states: %v", err)
+		return
+	}
+	defer db.Close()
+	coverage, err := db.Coverage()
+	if err != nil {
+		glog.Infof("Unable to count solved states: %v", err)
+		return
+	}
+	fmt.Println(coverage)
+}
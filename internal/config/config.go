@@ -0,0 +1,150 @@
+// Package config sets the flags of the commands from a config file, so that
+// settings such as the rules, database paths and solver tuning need not be
+// repeated on every command line.
+//
+// The file is TOML, with one `flag = value` per setting, where value is a
+// string, number or boolean that is given to the flag as on the command line.
+// Settings before the first [command] table apply to every command that has
+// the flag, and settings in a table only to that command:
+//
+//	rules = "facebook"
+//	num_players = 2
+//
+//	[solve-farkle]
+//	db = "2player.db"
+//	cache_gb = 16
+//
+//	[farkle-server]
+//	addr = ":8080"
+//
+// Flags given on the command line override the file.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// The environment variable with the path to the config file to use by
+// default.
+const PathEnv = "FARKLE_CONFIG"
+
+// A value from the config file, in the given section if not shared.
+type setting struct {
+	section string
+	name    string
+	value   string
+}
+
+// Parse the command line into the flags of flag.CommandLine, then set the
+// flags that were not given on it from the config file named by -config
+// or $FARKLE_CONFIG, if any. Exits with status 2 if the file is invalid, like
+// flag.Parse does for invalid flags.
+func Parse(command string) {
+	ParseFlagSet(flag.CommandLine, os.Args[1:], command)
+}
+
+// Like Parse, for the flags in fs and the settings in any of the given
+// sections, e.g. those of a subcommand and of its own command.
+func ParseFlagSet(fs *flag.FlagSet, args []string, sections ...string) {
+	path := fs.String("config", os.Getenv(PathEnv),
+		"Path to a config file with default values of flags (optional)")
+	fs.Parse(args)
+	if *path == "" {
+		return
+	}
+	if err := Load(fs, *path, sections...); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		os.Exit(2)
+	}
+}
+
+// Set the flags in fs that have not been set from the shared settings of the
+// config file at path and from those in its sections for the command, the
+// first of which names it in errors.
+func Load(fs *flag.FlagSet, path string, sections ...string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	settings, err := parse(string(data), sections)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, s := range settings {
+		if given[s.name] {
+			continue
+		}
+		if fs.Lookup(s.name) == nil {
+			if s.section == "" {
+				continue
+			}
+			return fmt.Errorf("%s: [%s] %s: %s has no flag -%s", path, s.section, s.name, sections[0], s.name)
+		}
+		if err := fs.Set(s.name, s.value); err != nil {
+			return fmt.Errorf("%s: invalid value %q for -%s: %v", path, s.value, s.name, err)
+		}
+	}
+	return nil
+}
+
+// The settings that apply to a command with the given sections, in the order
+// they appear, so that those in its sections override the shared ones.
+func parse(data string, sections []string) ([]setting, error) {
+	var tables map[string]any
+	md, err := toml.Decode(data, &tables)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []setting
+	for _, key := range md.Keys() {
+		if md.Type(key...) == "Hash" {
+			if len(key) > 1 {
+				return nil, fmt.Errorf("[%s]: tables may not be nested", key)
+			}
+			continue
+		}
+
+		section, name := "", key[0]
+		value := tables[name]
+		if len(key) == 2 {
+			section, name = key[0], key[1]
+			value = tables[section].(map[string]any)[name]
+		} else if len(key) > 2 {
+			return nil, fmt.Errorf("%s: expected flag = value", key)
+		}
+		if section != "" && !slices.Contains(sections, section) {
+			continue
+		}
+		str, err := formatValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		result = append(result, setting{section, name, str})
+	}
+	return result, nil
+}
+
+// The value as it would be given on the command line.
+func formatValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("expected a string, number or boolean, got %T", value)
+}
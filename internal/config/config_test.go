@@ -0,0 +1,82 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `# Shared settings.
+rules = "facebook"   # A quoted string.
+num_players = 2
+db = "shared.db"
+addr = ":8080"
+
+[solve-farkle]
+db = "solve # 1.db"
+pin_workers = true # A boolean.
+cache_gb = 1.5
+
+[farkle-server]
+store = "games.sqlite"
+`
+
+// Settings in the command's section override the shared ones, settings the
+// command has no flag for are ignored unless they are in its section, and
+// flags given on the command line override the file.
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "farkle.toml")
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("solve-farkle", flag.ContinueOnError)
+	rules := fs.String("rules", "standard", "")
+	numPlayers := fs.Int("num_players", 1, "")
+	db := fs.String("db", "", "")
+	pinWorkers := fs.Bool("pin_workers", false, "")
+	cacheGB := fs.Float64("cache_gb", 0, "")
+	if err := fs.Parse([]string{"-num_players", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(fs, path, "solve-farkle", "solve"); err != nil {
+		t.Fatal(err)
+	}
+	if *rules != "facebook" || *numPlayers != 3 || *db != "solve # 1.db" || !*pinWorkers || *cacheGB != 1.5 {
+		t.Errorf("rules=%q num_players=%d db=%q pin_workers=%v cache_gb=%v",
+			*rules, *numPlayers, *db, *pinWorkers, *cacheGB)
+	}
+
+	// farkle-server has no -store flag here.
+	fs = flag.NewFlagSet("farkle-server", flag.ContinueOnError)
+	fs.String("addr", "", "")
+	if err := Load(fs, path, "farkle-server"); err == nil {
+		t.Error("set -store, which farkle-server does not have")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, config := range []string{
+		"[solve-farkle\n",
+		"[]\n",
+		"db\n",
+		"= 1\n",
+		"db =\n",
+		`db = "unterminated` + "\n",
+		`db = "a" b` + "\n",
+		"db = bare.db\n",
+		"db = [1, 2]\n",
+		"[solve-farkle.nested]\ndb = \"a\"\n",
+	} {
+		path := filepath.Join(t.TempDir(), "farkle.toml")
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fs := flag.NewFlagSet("solve-farkle", flag.ContinueOnError)
+		fs.String("db", "", "")
+		if err := Load(fs, path, "solve-farkle"); err == nil {
+			t.Errorf("loaded invalid config %q", config)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package glogslog logs the structured records of the library with glog, so
+// that the commands keep their -v, -logtostderr, etc. flags.
+package glogslog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// The number of stack frames between the caller of the slog.Logger and glog.
+const callDepth = 3
+
+type handler struct {
+	attrs  []slog.Attr
+	groups []string
+}
+
+// A logger that writes to glog. Debug records are logged only with -v=1
+// or more.
+func New() *slog.Logger {
+	return slog.New(&handler{})
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	if level < slog.LevelInfo {
+		return bool(glog.V(1))
+	}
+	return true
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&sb, "", a)
+	}
+	prefix := strings.Join(h.groups, ".")
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&sb, prefix, a)
+		return true
+	})
+
+	msg := sb.String()
+	switch {
+	case r.Level >= slog.LevelError:
+		glog.ErrorDepth(callDepth, msg)
+	case r.Level >= slog.LevelWarn:
+		glog.WarningDepth(callDepth, msg)
+	default:
+		glog.InfoDepth(callDepth, msg)
+	}
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := strings.Join(h.groups, ".")
+	result := &handler{groups: h.groups}
+	result.attrs = append(result.attrs, h.attrs...)
+	for _, a := range attrs {
+		if prefix != "" {
+			a.Key = prefix + "." + a.Key
+		}
+		result.attrs = append(result.attrs, a)
+	}
+	return result
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &handler{attrs: h.attrs, groups: groups}
+}
+
+func writeAttr(sb *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeAttr(sb, key, ga)
+		}
+		return
+	}
+	fmt.Fprintf(sb, " %s=%v", key, a.Value.Any())
+}
@@ -0,0 +1,51 @@
+package i18n
+
+// German, in which Farkle is also known as Zehntausend.
+var german = map[string]string{
+	// play-farkle
+	"Player %d rolled: %s\n":                                   "Spieler %d würfelt: %s\n",
+	"...farkle!":                                               "...Farkle!",
+	"...enter dice to keep: ":                                  "...Würfel zum Behalten eingeben: ",
+	"......unable to read dice: %v\n":                          "......Würfel konnten nicht gelesen werden: %v\n",
+	"......unable to parse dice: %v\n":                         "......Würfel nicht erkannt: %v\n",
+	"...held %s\n":                                             "...behalten: %s\n",
+	"...continue rolling (Y/N)? ":                              "...weiterwürfeln (J/N)? ",
+	"......don't understand '%s'\n":                            "......'%s' nicht verstanden\n",
+	"...score this round = %d\n":                               "...Punkte in dieser Runde = %d\n",
+	"...you must continue rolling until you get at least %d\n": "...du musst weiterwürfeln, bis du mindestens %d hast\n",
+	"...selected action is optimal! (pWin = %f)\n":             "...gewählter Zug ist optimal! (Gewinnchance = %f)\n",
+	"...optimal action was %s with pWin = %f\n":                "...optimal war %s mit Gewinnchance = %f\n",
+	"...selected action has pWin = %f (%f)\n":                  "...gewählter Zug hat Gewinnchance = %f (%f)\n",
+	"...selected action %s (pWin = %f)\n":                      "...gewählter Zug %s (Gewinnchance = %f)\n",
+	"Current scores: player = %d, others: %v\n":                "Punktestand: Spieler = %d, andere: %v\n",
+	"Win probability: %s\n":                                    "Gewinnchance: %s\n",
+	"You win!":                                                 "Du gewinnst!",
+	"You lose!":                                                "Du verlierst!",
+	"You":                                                      "Du",
+	"CPU":                                                      "CPU",
+	"CPU %d":                                                   "CPU %d",
+
+	// play-farkle -practice
+	"Question %d of %d\n":                    "Frage %d von %d\n",
+	"...your score = %d, opponents: %v\n":    "...deine Punkte = %d, Gegner: %v\n",
+	"...this is your last turn":              "...das ist dein letzter Zug",
+	"...score this round = %d, rolled: %s\n": "...Punkte in dieser Runde = %d, gewürfelt: %s\n",
+	"...correct! (pWin = %.1f%%)\n\n":        "...richtig! (Gewinnchance = %.1f%%)\n\n",
+	"...optimal action was %s with pWin = %.1f%%, yours has %.1f%% (%+.1f%%)\n\n":                                 "...optimal war %s mit Gewinnchance = %.1f%%, deiner hat %.1f%% (%+.1f%%)\n\n",
+	"You chose the optimal action in %d of %d positions, losing %.2f%% win probability per position on average\n": "Du hast in %d von %d Stellungen optimal gespielt und im Schnitt %.2f%% Gewinnchance pro Stellung verloren\n",
+
+	// play-farkle -tui
+	"%s rolled a farkle! Press any key.":                                   "%s: Farkle! Beliebige Taste drücken.",
+	"%s selected %s (pWin = %.1f%%). Press any key.":                       "%s wählt %s (Gewinnchance = %.1f%%). Beliebige Taste drücken.",
+	"You win! Press any key to exit.":                                      "Du gewinnst! Beliebige Taste zum Beenden drücken.",
+	"You lose! Press any key to exit.":                                     "Du verlierst! Beliebige Taste zum Beenden drücken.",
+	"Hint: %s (pWin = %.1f%%)":                                             "Tipp: %s (Gewinnchance = %.1f%%)",
+	"You selected the optimal action! (pWin = %.1f%%)":                     "Du hast den optimalen Zug gewählt! (Gewinnchance = %.1f%%)",
+	"Optimal action was %s with pWin = %.1f%%, yours has %.1f%% (%+.1f%%)": "Optimal war %s mit Gewinnchance = %.1f%%, deiner hat %.1f%% (%+.1f%%)",
+	"Player":          "Spieler",
+	"Score":           "Punkte",
+	"Win probability": "Gewinnchance",
+	"\r\n Turn score: %d    Dice to roll: %d\r\n\r\n": "\r\n Punkte in dieser Runde: %d    Würfel: %d\r\n\r\n",
+	" Roll: ": " Wurf: ",
+	" ←/→ move  space/1-6 select  r/enter keep & roll  b keep & bank  h hint  q quit": " ←/→ bewegen  Leertaste/1-6 wählen  r/Enter behalten & würfeln  b behalten & schreiben  h Tipp  q beenden",
+}
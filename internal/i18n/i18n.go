@@ -0,0 +1,88 @@
+// Package i18n translates the messages that commands show to players.
+//
+// Messages are identified by their English text, which is a format string for
+// the fmt package, as in gettext. Each language has a catalog that maps them
+// to translations with the same verbs in the same order. Messages missing from
+// the catalog of the current language are shown in English, so catalogs may
+// be incomplete, and new messages only need to be wrapped in T or Sprintf.
+package i18n
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The translations of the messages into each language other than English,
+// by ISO 639-1 code.
+var catalogs = map[string]map[string]string{
+	"de": german,
+}
+
+// The current language, or nil for English.
+var catalog map[string]string
+
+// The languages messages can be shown in, including "en".
+func Languages() []string {
+	return append([]string{"en"}, slices.Sorted(maps.Keys(catalogs))...)
+}
+
+// Show messages in the given language, e.g. "de". If lang is empty, the
+// language is taken from the environment ($LC_ALL, $LC_MESSAGES or $LANG,
+// e.g. "de_DE.UTF-8"), falling back to English if it is not supported.
+func SetLanguage(lang string) error {
+	if lang == "" {
+		lang = envLanguage()
+		if _, ok := catalogs[lang]; !ok {
+			lang = "en"
+		}
+	}
+
+	if lang == "en" {
+		catalog = nil
+		return nil
+	}
+	c, ok := catalogs[lang]
+	if !ok {
+		return fmt.Errorf("unsupported language %q, expected one of %v", lang, Languages())
+	}
+	catalog = c
+	return nil
+}
+
+// The language of the user's locale, e.g. "de" for "de_DE.UTF-8".
+func envLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			lang, _, _ := strings.Cut(locale, "_")
+			lang, _, _ = strings.Cut(lang, ".")
+			return strings.ToLower(lang)
+		}
+	}
+	return ""
+}
+
+// The translation of the given message into the current language.
+func T(msg string) string {
+	if translation, ok := catalog[msg]; ok {
+		return translation
+	}
+	return msg
+}
+
+// As fmt.Sprintf, with the format translated into the current language.
+func Sprintf(format string, a ...any) string {
+	return fmt.Sprintf(T(format), a...)
+}
+
+// As fmt.Printf, with the format translated into the current language.
+func Printf(format string, a ...any) {
+	fmt.Printf(T(format), a...)
+}
+
+// As fmt.Println, with the message translated into the current language.
+func Println(msg string) {
+	fmt.Println(T(msg))
+}
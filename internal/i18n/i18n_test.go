@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Matches the verbs of a format string, including flags, width and precision.
+var verbRegexp = regexp.MustCompile(`%[-+# 0]*(\d+|\*)?(\.(\d+|\*)?)?[a-zA-Z%]`)
+
+// Translations take the same arguments as the message they translate, and end
+// the line where it does, so that they can be formatted the same way.
+func TestCatalogVerbs(t *testing.T) {
+	for lang, c := range catalogs {
+		for msg, translation := range c {
+			want := verbRegexp.FindAllString(msg, -1)
+			if got := verbRegexp.FindAllString(translation, -1); !slices.Equal(got, want) {
+				t.Errorf("%s translation of %q has verbs %q, want %q", lang, msg, got, want)
+			}
+			if strings.HasSuffix(translation, "\n") != strings.HasSuffix(msg, "\n") {
+				t.Errorf("%s translation of %q does not end the line where it does: %q", lang, msg, translation)
+			}
+		}
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	t.Cleanup(func() { catalog = nil })
+	const msg = "You win!"
+
+	testCases := []struct {
+		name   string
+		lang   string
+		locale string
+		want   string
+	}{
+		{"English", "en", "de_DE.UTF-8", msg},
+		{"German", "de", "", "Du gewinnst!"},
+		{"German locale", "", "de_DE.UTF-8", "Du gewinnst!"},
+		{"unsupported locale", "", "xx_XX", msg},
+		{"no locale", "", "", msg},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", "")
+			t.Setenv("LC_MESSAGES", "")
+			t.Setenv("LANG", tc.locale)
+			if err := SetLanguage(tc.lang); err != nil {
+				t.Fatal(err)
+			}
+			if got := T(msg); got != tc.want {
+				t.Errorf("T(%q) = %q, want %q", msg, got, tc.want)
+			}
+		})
+	}
+
+	if err := SetLanguage("xx"); err == nil {
+		t.Error("no error for an unsupported language")
+	}
+}
+
+// Messages that have no translation are shown in English.
+func TestMissingTranslation(t *testing.T) {
+	t.Cleanup(func() { catalog = nil })
+	if err := SetLanguage("de"); err != nil {
+		t.Fatal(err)
+	}
+	const format = "not translated: %d"
+	if got, want := Sprintf(format, 42), "not translated: 42"; got != want {
+		t.Errorf("Sprintf(%q) = %q, want %q", format, got, want)
+	}
+}
@@ -0,0 +1,25 @@
+// Package testutil holds helpers shared by the tests of other packages.
+package testutil
+
+import (
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Set the rules of the given spec (see farkle.ParseRules) for the rest of the
+// test. The tests of package farkle itself cannot import this package, and
+// use their own copy, setTestRules.
+func SetRules(t testing.TB, spec string) farkle.Rules {
+	t.Helper()
+	saved := farkle.CurrentRules()
+	t.Cleanup(func() { farkle.SetRules(saved) })
+	rules, err := farkle.ParseRules(spec)
+	if err == nil {
+		err = farkle.SetRules(rules)
+	}
+	if err != nil {
+		t.Fatalf("Invalid rules %q: %v", spec, err)
+	}
+	return rules
+}
@@ -0,0 +1,116 @@
+package farkle
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Rolls are encoded in JSON as the list of dice, in ascending order.
+func (r Roll) MarshalJSON() ([]byte, error) {
+	dice := make([]int, 0, r.NumDice())
+	for _, die := range r.Dice() {
+		dice = append(dice, int(die))
+	}
+	return json.Marshal(dice)
+}
+
+func (r *Roll) UnmarshalJSON(data []byte) error {
+	var dice []int
+	if err := json.Unmarshal(data, &dice); err != nil {
+		return err
+	}
+	if len(dice) > MaxNumDice {
+		return fmt.Errorf("cannot create Roll with %d > max %d dice", len(dice), MaxNumDice)
+	}
+
+	var roll Roll
+	for _, die := range dice {
+		if die < 1 || die > numSides {
+			return fmt.Errorf("cannot create Roll with die = %d", die)
+		}
+		roll[die]++
+	}
+
+	*r = roll
+	return nil
+}
+
+type jsonAction struct {
+	Held     Roll `json:"held"`
+	Continue bool `json:"continue"`
+}
+
+// Actions are encoded in JSON by their held dice rather than roll ID.
+func (a Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAction{
+		Held:     a.HeldDice(),
+		Continue: a.ContinueRolling,
+	})
+}
+
+func (a *Action) UnmarshalJSON(data []byte) error {
+	var ja jsonAction
+	if err := json.Unmarshal(data, &ja); err != nil {
+		return err
+	}
+
+	id, ok := rollToID[ja.Held]
+	if !ok {
+		return fmt.Errorf("no roll ID for: %v", ja.Held)
+	}
+
+	*a = Action{
+		HeldDiceID:      id,
+		ContinueRolling: ja.Continue,
+	}
+	return nil
+}
+
+type jsonGameState struct {
+	Scores    []int `json:"scores"`
+	TurnScore int   `json:"turnScore"`
+	NumDice   int   `json:"numDice"`
+}
+
+// Game states are encoded in JSON with all scores in points.
+// As in GameState, the current player is always first.
+func (gs GameState) MarshalJSON() ([]byte, error) {
+	scores := make([]int, gs.NumPlayers)
+	for i := range scores {
+		scores[i] = incr * int(gs.PlayerScores[i])
+	}
+
+	return json.Marshal(jsonGameState{
+		Scores:    scores,
+		TurnScore: incr * int(gs.ScoreThisRound),
+		NumDice:   int(gs.NumDiceToRoll),
+	})
+}
+
+func (gs *GameState) UnmarshalJSON(data []byte) error {
+	var jgs jsonGameState
+	if err := json.Unmarshal(data, &jgs); err != nil {
+		return err
+	}
+	if len(jgs.Scores) < 1 || len(jgs.Scores) > maxNumPlayers {
+		return fmt.Errorf("invalid number of players: %d", len(jgs.Scores))
+	}
+	if jgs.NumDice < 1 || jgs.NumDice > MaxNumDice {
+		return fmt.Errorf("invalid number of dice: %d", jgs.NumDice)
+	}
+
+	state := NewGameState(len(jgs.Scores))
+	state.NumDiceToRoll = uint8(jgs.NumDice)
+	var err error
+	if state.ScoreThisRound, err = ParseScore(jgs.TurnScore); err != nil {
+		return err
+	}
+	for i, score := range jgs.Scores {
+		if state.PlayerScores[i], err = ParseScore(score); err != nil {
+			return err
+		}
+	}
+
+	*gs = state
+	return nil
+}
@@ -0,0 +1,139 @@
+package farkle
+
+import "math"
+
+const (
+	// Approximate mean and standard deviation of the points scored
+	// per turn with good play, used to estimate win probabilities.
+	meanPointsPerTurn   = 515
+	stdDevPointsPerTurn = 600
+	// LazyDB forgets all memoized values once it holds this many,
+	// unless limited by SetMemoryBudget.
+	maxLazyDBSize = 4000000
+)
+
+// DB that computes values on demand, without a pre-built database, by
+// searching a limited number of turns ahead. Beyond that, win probabilities
+// are estimated from the scores of each player. Values are memoized.
+// It only supports the WinProbability objective, and is not safe for
+// concurrent use.
+type LazyDB struct {
+	numPlayers int
+	// Number of turns to search ahead (including the current turn).
+	depth  int
+	values map[int]lazyValue
+}
+
+type lazyValue struct {
+	pWin  [maxNumPlayers]float64
+	depth int
+}
+
+func NewLazyDB(numPlayers, depth int) *LazyDB {
+	return &LazyDB{
+		numPlayers: numPlayers,
+		depth:      depth,
+		values:     make(map[int]lazyValue),
+	}
+}
+
+func (db *LazyDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+// Values are estimated with the rules in effect.
+func (db *LazyDB) Metadata() Metadata {
+	return Metadata{Objective: WinProbability, Rules: rulesFingerprint}
+}
+
+// Store an exact value for the given state, overriding any estimate.
+func (db *LazyDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	db.values[gsID] = lazyValue{pWin: pWin, depth: math.MaxInt}
+}
+
+func (db *LazyDB) Get(gsID int) [maxNumPlayers]float64 {
+	return db.value(GameStateFromID(db.numPlayers, gsID), db.depth)
+}
+
+func (db *LazyDB) Close() error {
+	return nil
+}
+
+// The value of the given state, searching the given number of turns ahead.
+func (db *LazyDB) value(state GameState, depth int) [maxNumPlayers]float64 {
+	if state.IsGameOver() {
+		return calcEndGameValue(state, db.Metadata())
+	}
+	if depth <= 0 {
+		return estimateWinProb(state)
+	}
+
+	gsID := state.ID()
+	if v, ok := db.values[gsID]; ok && v.depth >= depth {
+		return v.pWin
+	}
+
+	// States within this turn are searched to the same depth,
+	// and states after the current player banks to one less.
+	thisTurn := lazyDBView{db, depth}
+	nextTurn := lazyDBView{db, depth - 1}
+	var pWin [maxNumPlayers]float64
+	for _, wRoll := range allRolls[state.NumDiceToRoll] {
+		_, pSubgame := selectAction(state, wRoll.ID, thisTurn, nextTurn)
+		mixInto(&pWin, wRoll.Prob, &pSubgame)
+	}
+
+	if len(db.values) >= maxMemoSize() {
+		clear(db.values)
+	}
+	db.values[gsID] = lazyValue{pWin: pWin, depth: depth}
+	return pWin
+}
+
+// A LazyDB that searches a fixed number of turns ahead.
+type lazyDBView struct {
+	*LazyDB
+	depth int
+}
+
+func (v lazyDBView) Get(gsID int) [maxNumPlayers]float64 {
+	return v.value(GameStateFromID(v.numPlayers, gsID), v.depth)
+}
+
+// Estimate the win probability of each player at the start of a turn,
+// by approximating the number of turns each player needs to reach the
+// score to win as normally distributed.
+func estimateWinProb(state GameState) [maxNumPlayers]float64 {
+	n := int(state.NumPlayers)
+	var meanTurns, varTurns [maxNumPlayers]float64
+	for i, score := range state.PlayerScores[:n] {
+		deficit := max(0, incr*float64(int(scoreToWin)-int(score)))
+		meanTurns[i] = deficit / meanPointsPerTurn
+		varTurns[i] = 1.0/12 + deficit*stdDevPointsPerTurn*stdDevPointsPerTurn/
+			(meanPointsPerTurn*meanPointsPerTurn*meanPointsPerTurn)
+	}
+
+	// The probability of each player finishing before each other player,
+	// with earlier players in the turn order finishing first on the same turn.
+	var pWin [maxNumPlayers]float64
+	total := 0.0
+	for i := 0; i < n; i++ {
+		pWin[i] = 1
+		for j := 0; j < n; j++ {
+			if i != j {
+				lead := meanTurns[j] - meanTurns[i] + float64(j-i)/float64(n)
+				pWin[i] *= normalCDF(lead / math.Sqrt(varTurns[i]+varTurns[j]))
+			}
+		}
+		total += pWin[i]
+	}
+
+	for i := range pWin[:n] {
+		pWin[i] /= total
+	}
+	return pWin
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
@@ -0,0 +1,24 @@
+package farkle
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// Log progress and problems of this package and its subpackages to l, e.g.
+// to control their level and format in an application that embeds them.
+// Detailed progress, such as of each solver iteration, is logged at
+// slog.LevelDebug. Until this is called, slog.Default() is used.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// The logger set with SetLogger, or slog.Default().
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
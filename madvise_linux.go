@@ -0,0 +1,9 @@
+package farkle
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	pageAdviceFlags[adviseHugePage] = unix.MADV_HUGEPAGE
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package farkle
+
+import (
+	"errors"
+	"os"
+)
+
+func madvise(b []byte, advice pageAdvice) error {
+	return errors.ErrUnsupported
+}
+
+func pageSize() int {
+	return os.Getpagesize()
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package farkle
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+var pageAdviceFlags = map[pageAdvice]int{
+	adviseNormal:     unix.MADV_NORMAL,
+	adviseSequential: unix.MADV_SEQUENTIAL,
+	adviseWillNeed:   unix.MADV_WILLNEED,
+}
+
+// Advise the kernel how a page-aligned region of a mapping created by mmapFile
+// will be accessed.
+func madvise(b []byte, advice pageAdvice) error {
+	flag, ok := pageAdviceFlags[advice]
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return unix.Madvise(b, flag)
+}
+
+func pageSize() int {
+	return unix.Getpagesize()
+}
@@ -0,0 +1,84 @@
+package farkle
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+// Solver progress is only logged with -v.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+	os.Exit(m.Run())
+}
+
+// Set the rules of the given spec (see ParseRules) for the rest of the test.
+// The same as testutil.SetRules, which this package cannot import.
+func setTestRules(t testing.TB, spec string) Rules {
+	t.Helper()
+	saved := CurrentRules()
+	t.Cleanup(func() { SetRules(saved) })
+	rules, err := ParseRules(spec)
+	if err == nil {
+		err = SetRules(rules)
+	}
+	if err != nil {
+		t.Fatalf("Invalid rules %q: %v", spec, err)
+	}
+	return rules
+}
+
+// DB with reproducible values, for tests and benchmarks that need a database
+// without solving the game. Each value is a pseudo-random function of the
+// state's ID and the seed, so that different states have different optimal
+// actions, unless uniform, in which case every player has the same chance of
+// winning every state and reads are effectively free. Puts are ignored.
+type fakeDB struct {
+	numPlayers int
+	seed       uint64
+	uniform    bool
+	// If nil, the win probability with the current rules.
+	meta *Metadata
+}
+
+func (db fakeDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db fakeDB) Metadata() Metadata {
+	if db.meta == nil {
+		return Metadata{Objective: WinProbability, Rules: rulesFingerprint}
+	}
+	return *db.meta
+}
+
+func (db fakeDB) Put(gsID int, pWin [maxNumPlayers]float64) {}
+
+func (db fakeDB) Get(gsID int) [maxNumPlayers]float64 {
+	var result [maxNumPlayers]float64
+	if db.uniform {
+		for i := range db.numPlayers {
+			result[i] = 1 / float64(db.numPlayers)
+		}
+		return result
+	}
+
+	x := uint64(gsID) ^ db.seed*0xd1b54a32d192ed03
+	for i := range db.numPlayers {
+		// splitmix64
+		x += 0x9e3779b97f4a7c15
+		z := (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		result[i] = float64((z^(z>>31))>>11) / (1 << 53)
+	}
+	return result
+}
+
+func (db fakeDB) Close() error {
+	return nil
+}
@@ -0,0 +1,212 @@
+package farkle
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+// A match of up to NumGames games between the same players, won by the player
+// who wins the most games. Going first is a significant advantage, so the
+// first player rotates from game to game: player i goes first in games
+// i, i+n, i+2n, ... Ties in the number of games won are broken by the total
+// points scored over all games, and if they are still tied, extra games are
+// played until one player is ahead.
+type Match struct {
+	// The strategy of each player.
+	Strategies []Strategy
+	// The maximum number of games to play. The match ends as soon as the
+	// leader cannot be caught.
+	NumGames int
+	// Notified of the events of every game, with seats as in that game.
+	Observers []Observer
+}
+
+// Result of a completed match.
+type MatchResult struct {
+	// The result of each game, with scores and winners indexed by player
+	// rather than seat.
+	Games []GameResult
+	// The number of games won by each player, sharing tied games.
+	Wins []float64
+	// The total points scored by each player over all games.
+	TotalScores []int
+	// The player who won the match.
+	Winner int
+}
+
+func (m Match) Play(rng *rand.Rand) (MatchResult, error) {
+	n := len(m.Strategies)
+	if n < 2 || n > maxNumPlayers {
+		return MatchResult{}, fmt.Errorf("matches require 2 to %d players, got %d", maxNumPlayers, n)
+	} else if m.NumGames < 1 {
+		return MatchResult{}, fmt.Errorf("matches require at least 1 game, got %d", m.NumGames)
+	}
+
+	result := MatchResult{
+		Wins:        make([]float64, n),
+		TotalScores: make([]int, n),
+	}
+	for k := 0; ; k++ {
+		if winner, ok := result.leader(m.NumGames - k); ok {
+			result.Winner = winner
+			return result, nil
+		}
+
+		game, err := m.playGame(k, rng)
+		if err != nil {
+			return result, fmt.Errorf("game %d: %w", k+1, err)
+		}
+		result.Games = append(result.Games, game)
+		for _, player := range game.Winners {
+			result.Wins[player] += 1 / float64(len(game.Winners))
+		}
+		for player, score := range game.Scores {
+			result.TotalScores[player] += score
+		}
+	}
+}
+
+// Play the kth game of the match, with player k%n in seat 0.
+func (m Match) playGame(k int, rng *rand.Rand) (GameResult, error) {
+	n := len(m.Strategies)
+	first := k % n
+	seated := make([]Strategy, n)
+	for seat := range seated {
+		seated[seat] = m.Strategies[(first+seat)%n]
+	}
+
+	game := NewGame(n, rng)
+	for _, o := range m.Observers {
+		game.AddObserver(o)
+	}
+	for !game.IsOver() {
+		seat := game.CurrentPlayer()
+		roll, err := game.Roll()
+		if err != nil {
+			return GameResult{}, err
+		}
+		action, err := seated[seat].SelectAction(game.State(), roll)
+		if err != nil {
+			return GameResult{}, fmt.Errorf("player %d: %w", (first+seat)%n, err)
+		}
+		if err := game.Apply(action); err != nil {
+			return GameResult{}, fmt.Errorf("player %d: illegal action: %w", (first+seat)%n, err)
+		}
+	}
+
+	// Index the result by player.
+	bySeat := game.Result()
+	result := GameResult{
+		Scores:   make([]int, n),
+		NumTurns: bySeat.NumTurns,
+	}
+	for seat, score := range bySeat.Scores {
+		result.Scores[(first+seat)%n] = score
+	}
+	for _, seat := range bySeat.Winners {
+		result.Winners = append(result.Winners, (first+seat)%n)
+	}
+	slices.Sort(result.Winners)
+	return result, nil
+}
+
+// The winner of the match, if it is decided with the given number of games
+// left to play in the regular match.
+func (r MatchResult) leader(gamesLeft int) (int, bool) {
+	best := 0
+	for player := range r.Wins {
+		if r.Wins[player] > r.Wins[best] ||
+			(r.Wins[player] == r.Wins[best] && r.TotalScores[player] > r.TotalScores[best]) {
+			best = player
+		}
+	}
+
+	for player := range r.Wins {
+		if player == best {
+			continue
+		}
+		if gamesLeft > 0 {
+			if r.Wins[player]+float64(gamesLeft) >= r.Wins[best] {
+				return 0, false
+			}
+		} else if r.Wins[player] == r.Wins[best] && r.TotalScores[player] == r.TotalScores[best] {
+			return 0, false
+		}
+	}
+	return best, true
+}
+
+// A single-elimination tournament between 2-player matches.
+type Bracket struct {
+	// The strategy of each entrant, in order of seed.
+	Strategies []Strategy
+	// The maximum number of games in each match.
+	NumGames int
+}
+
+// A match played in a bracket, between entrants identified by their index in
+// Bracket.Strategies.
+type BracketMatch struct {
+	Players [2]int
+	Result  MatchResult
+}
+
+// The winner of the match, as an index into Bracket.Strategies.
+func (m BracketMatch) Winner() int {
+	return m.Players[m.Result.Winner]
+}
+
+// Result of a completed bracket.
+type BracketResult struct {
+	// The matches played in each round.
+	Rounds [][]BracketMatch
+	// The entrant who won the final.
+	Champion int
+}
+
+// Play the bracket. In each round, the highest remaining seed plays the
+// lowest, the second highest plays the second lowest, and so on. If an odd
+// number of entrants remain, the highest seed advances without playing.
+func (b Bracket) Play(rng *rand.Rand) (BracketResult, error) {
+	if len(b.Strategies) < 2 {
+		return BracketResult{}, fmt.Errorf("brackets require at least 2 entrants, got %d", len(b.Strategies))
+	}
+
+	entrants := make([]int, len(b.Strategies))
+	for i := range entrants {
+		entrants[i] = i
+	}
+
+	var result BracketResult
+	for len(entrants) > 1 {
+		var round []BracketMatch
+		var advancing []int
+		if len(entrants)%2 == 1 {
+			advancing = append(advancing, entrants[0])
+			entrants = entrants[1:]
+		}
+
+		for i := 0; i < len(entrants)/2; i++ {
+			players := [2]int{entrants[i], entrants[len(entrants)-1-i]}
+			match := Match{
+				Strategies: []Strategy{b.Strategies[players[0]], b.Strategies[players[1]]},
+				NumGames:   b.NumGames,
+			}
+			matchResult, err := match.Play(rng)
+			if err != nil {
+				return result, fmt.Errorf("%v vs %v: %w", match.Strategies[0], match.Strategies[1], err)
+			}
+			round = append(round, BracketMatch{Players: players, Result: matchResult})
+		}
+		result.Rounds = append(result.Rounds, round)
+		for _, m := range round {
+			advancing = append(advancing, m.Winner())
+		}
+		slices.Sort(advancing)
+		entrants = advancing
+	}
+
+	result.Champion = entrants[0]
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package farkle
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Records the player who selected the last action.
+type recordingStrategy struct {
+	ThresholdStrategy
+	player int
+	last   *int
+}
+
+func (s recordingStrategy) SelectAction(state GameState, roll Roll) (Action, error) {
+	*s.last = s.player
+	return s.ThresholdStrategy.SelectAction(state, roll)
+}
+
+// Records the player in each seat, and the result by seat, of every game.
+type seatObserver struct {
+	NopObserver
+	last    *int
+	seats   []map[int]int
+	results []GameResult
+}
+
+func (o *seatObserver) OnAction(seat int, state GameState, roll Roll, action Action) {
+	if len(o.seats) == len(o.results) {
+		o.seats = append(o.seats, make(map[int]int))
+	}
+	o.seats[len(o.seats)-1][seat] = *o.last
+}
+
+func (o *seatObserver) OnGameOver(result GameResult) {
+	o.results = append(o.results, result)
+}
+
+// The first player rotates from game to game, and results are indexed by
+// player rather than seat.
+func TestMatch(t *testing.T) {
+	var last int
+	observer := &seatObserver{last: &last}
+	m := Match{NumGames: 7, Observers: []Observer{observer}}
+	for player, bankAt := range []int{300, 500, 1000} {
+		m.Strategies = append(m.Strategies, recordingStrategy{ThresholdStrategy{BankAt: bankAt}, player, &last})
+	}
+	result, err := m.Play(rand.New(rand.NewSource(benchSeed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(observer.results) != len(result.Games) || len(observer.seats) != len(result.Games) {
+		t.Fatalf("observed %d games, want %d", len(observer.results), len(result.Games))
+	}
+	wins, totals := make([]float64, 3), make([]int, 3)
+	for k, game := range result.Games {
+		for seat, player := range observer.seats[k] {
+			if want := (k + seat) % 3; player != want {
+				t.Errorf("game %d: player %d in seat %d, want player %d", k+1, player, seat, want)
+			}
+		}
+		for seat, score := range observer.results[k].Scores {
+			if got := game.Scores[(k+seat)%3]; got != score {
+				t.Errorf("game %d: score of the player in seat %d = %d, want %d", k+1, seat, got, score)
+			}
+		}
+		for _, player := range game.Winners {
+			wins[player] += 1 / float64(len(game.Winners))
+		}
+		for player, score := range game.Scores {
+			totals[player] += score
+		}
+	}
+	if !slices.Equal(wins, result.Wins) || !slices.Equal(totals, result.TotalScores) {
+		t.Errorf("wins %v and scores %v, want %v and %v", result.Wins, result.TotalScores, wins, totals)
+	}
+	if result.Wins[result.Winner] != slices.Max(result.Wins) {
+		t.Errorf("player %d won the match with %v wins", result.Winner, result.Wins)
+	}
+
+	if _, err := (Match{Strategies: m.Strategies[:1], NumGames: 1}).Play(rand.New(rand.NewSource(benchSeed))); err == nil {
+		t.Error("played a match with one player")
+	}
+	if _, err := (Match{Strategies: m.Strategies}).Play(rand.New(rand.NewSource(benchSeed))); err == nil {
+		t.Error("played a match of no games")
+	}
+}
+
+// The match ends as soon as the leader cannot be caught, and ties are broken
+// by total score, or else by more games.
+func TestMatchLeader(t *testing.T) {
+	testCases := []struct {
+		name      string
+		wins      []float64
+		scores    []int
+		gamesLeft int
+		winner    int
+		decided   bool
+	}{
+		{"cannot be caught", []float64{2, 0}, []int{0, 0}, 1, 0, true},
+		{"can be caught", []float64{2, 1}, []int{0, 0}, 1, 0, false},
+		{"can tie", []float64{0, 1, 0.5}, []int{0, 0, 0}, 1, 0, false},
+		{"more wins", []float64{1, 2}, []int{20000, 10000}, 0, 1, true},
+		{"tie broken by score", []float64{1.5, 1.5}, []int{20000, 20500}, 0, 1, true},
+		{"tie", []float64{1, 1}, []int{20000, 20000}, 0, 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := MatchResult{Wins: tc.wins, TotalScores: tc.scores}
+			winner, decided := r.leader(tc.gamesLeft)
+			if decided != tc.decided || (decided && winner != tc.winner) {
+				t.Errorf("leader = %d, %v, want %d, %v", winner, decided, tc.winner, tc.decided)
+			}
+		})
+	}
+}
+
+// The highest seed plays the lowest in each round, and advances without
+// playing when the number of entrants is odd.
+func TestBracket(t *testing.T) {
+	b := Bracket{NumGames: 3}
+	for _, bankAt := range []int{300, 350, 400, 450, 500} {
+		b.Strategies = append(b.Strategies, ThresholdStrategy{BankAt: bankAt})
+	}
+	result, err := b.Play(rand.New(rand.NewSource(benchSeed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Rounds) != 3 {
+		t.Fatalf("%d rounds, want 3: %+v", len(result.Rounds), result.Rounds)
+	}
+	var firstRound [][2]int
+	for _, m := range result.Rounds[0] {
+		firstRound = append(firstRound, m.Players)
+	}
+	if want := [][2]int{{1, 4}, {2, 3}}; !slices.Equal(firstRound, want) {
+		t.Errorf("first round = %v, want %v", firstRound, want)
+	}
+	for i, round := range result.Rounds {
+		for _, m := range round {
+			if w := m.Winner(); w != m.Players[0] && w != m.Players[1] {
+				t.Errorf("round %d: %d won the match between %v", i+1, w, m.Players)
+			}
+		}
+	}
+	if final := result.Rounds[2]; len(final) != 1 || final[0].Winner() != result.Champion {
+		t.Errorf("champion %d, but the final was %+v", result.Champion, final)
+	}
+
+	if _, err := (Bracket{Strategies: b.Strategies[:1], NumGames: 1}).Play(rand.New(rand.NewSource(benchSeed))); err == nil {
+		t.Error("played a bracket with one entrant")
+	}
+}
@@ -0,0 +1,310 @@
+package farkle
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Recommends actions and estimates win probabilities. Values are relative
+// to the current player, as returned by SelectAction and CalculateWinProb.
+type Advisor interface {
+	// The recommended action in response to a roll, and its value.
+	Recommend(state GameState, roll Roll) (Action, [maxNumPlayers]float64)
+	// The value of taking the given action.
+	EvaluateAction(state GameState, action Action) [maxNumPlayers]float64
+	// The value of the given state.
+	WinProb(state GameState) [maxNumPlayers]float64
+}
+
+// Advisor that looks up exact values in a solved database. Each call reads
+// the values of one version of a ReloadableDB (see AcquireDB).
+type DBAdvisor struct {
+	DB DB
+}
+
+func (a DBAdvisor) Recommend(state GameState, roll Roll) (Action, [maxNumPlayers]float64) {
+	db, release := AcquireDB(a.DB)
+	defer release()
+	return SelectAction(state, GetRollID(roll), db)
+}
+
+func (a DBAdvisor) EvaluateAction(state GameState, action Action) [maxNumPlayers]float64 {
+	db, release := AcquireDB(a.DB)
+	defer release()
+	return EvaluateAction(state, action, db)
+}
+
+func (a DBAdvisor) WinProb(state GameState) [maxNumPlayers]float64 {
+	db, release := AcquireDB(a.DB)
+	defer release()
+	return CalculateWinProb(state, db)
+}
+
+// Monte Carlo tree search, for when there is no solved database.
+// Each decision is searched for a fixed amount of time, playing out
+// games with a simple rollout strategy to estimate win probabilities.
+// It is not safe for concurrent use.
+type MCTS struct {
+	// Time spent searching for each decision or estimate.
+	Budget time.Duration
+	// Exploration constant of the UCB1 selection rule.
+	Exploration float64
+	// Strategy played by all players beyond the search tree.
+	Rollout Strategy
+
+	rng *rand.Rand
+}
+
+func NewMCTS(budget time.Duration, rng *rand.Rand) *MCTS {
+	return &MCTS{
+		Budget:      budget,
+		Exploration: 0.7,
+		Rollout:     ThresholdStrategy{BankAt: 350, MinDiceToContinue: 3},
+		rng:         rng,
+	}
+}
+
+// Search statistics of a decision: a state and roll.
+type mctsNode struct {
+	actions []Action
+	visits  []int
+	// Total value of each action to the player making the decision.
+	values   []float64
+	nVisited int
+	// Index of the action chosen by the rollout strategy.
+	rollout int
+}
+
+type mctsKey struct {
+	gsID   int
+	rollID uint16
+}
+
+// Search for the best action in response to the given roll,
+// and its estimated value.
+func (m *MCTS) Recommend(state GameState, roll Roll) (Action, [maxNumPlayers]float64) {
+	if IsFarkle(roll) {
+		return Action{}, m.WinProb(ApplyAction(state, Action{}))
+	}
+
+	tree := make(map[mctsKey]*mctsNode)
+	var totals [][maxNumPlayers]float64
+	m.search(func() {
+		// Keep track of the value of each root action, for every player.
+		result, rootAction := m.iterate(tree, state, &roll)
+		root := tree[mctsKey{state.ID(), GetRollID(roll)}]
+		if totals == nil {
+			totals = make([][maxNumPlayers]float64, len(root.actions))
+		}
+		for i := range result[:state.NumPlayers] {
+			totals[rootAction][i] += result[i]
+		}
+	})
+
+	root := tree[mctsKey{state.ID(), GetRollID(roll)}]
+	best := 0
+	for i, visits := range root.visits {
+		if visits > root.visits[best] {
+			best = i
+		}
+	}
+
+	var pWin [maxNumPlayers]float64
+	for i := range pWin[:state.NumPlayers] {
+		pWin[i] = totals[best][i] / float64(root.visits[best])
+	}
+	return root.actions[best], pWin
+}
+
+// Estimate the value of taking the given action.
+func (m *MCTS) EvaluateAction(state GameState, action Action) [maxNumPlayers]float64 {
+	newState := ApplyAction(state, action)
+	pWin := m.WinProb(newState)
+	if !action.ContinueRolling {
+		pWin = unrotate(pWin, state.NumPlayers)
+	}
+	return pWin
+}
+
+// Estimate the value of the given state, searching with a new roll each iteration.
+func (m *MCTS) WinProb(state GameState) [maxNumPlayers]float64 {
+	if state.IsGameOver() {
+		return calcEndGameValue(state, Metadata{Objective: WinProbability})
+	}
+
+	tree := make(map[mctsKey]*mctsNode)
+	var total [maxNumPlayers]float64
+	n := 0
+	m.search(func() {
+		result, _ := m.iterate(tree, state, nil)
+		for i := range result[:state.NumPlayers] {
+			total[i] += result[i]
+		}
+		n++
+	})
+
+	for i := range total[:state.NumPlayers] {
+		total[i] /= float64(n)
+	}
+	return total
+}
+
+func (m *MCTS) SelectAction(state GameState, roll Roll) (Action, error) {
+	action, _ := m.Recommend(state, roll)
+	return action, nil
+}
+
+func (m *MCTS) String() string {
+	return fmt.Sprintf("mcts:%v", m.Budget)
+}
+
+// Run iterations until the time budget is used up (and at least once).
+func (m *MCTS) search(iterate func()) {
+	deadline := time.Now().Add(m.Budget)
+	for i := 0; i == 0 || i%16 != 0 || time.Now().Before(deadline); i++ {
+		iterate()
+	}
+}
+
+// Run one iteration of the search from the given state and roll (or a random
+// roll if nil), returning the result of the game relative to the current player
+// in state, and the index of the action chosen at the root.
+func (m *MCTS) iterate(tree map[mctsKey]*mctsNode, state GameState, roll *Roll) ([maxNumPlayers]float64, int) {
+	type step struct {
+		node   *mctsNode
+		action int
+		seat   int // Of the player making the decision, relative to the root.
+	}
+
+	var path []step
+	numPlayers := int(state.NumPlayers)
+	seat := 0
+	expanded := false
+	for !state.IsGameOver() && !expanded {
+		var r Roll
+		var rollID uint16
+		if roll != nil && len(path) == 0 {
+			r, rollID = *roll, GetRollID(*roll)
+		} else {
+			r, rollID = SampleRoll(int(state.NumDiceToRoll), m.rng)
+		}
+
+		var action Action
+		if len(rollIDToPotentialHolds[rollID]) > 0 { // Not a farkle.
+			key := mctsKey{state.ID(), rollID}
+			node, ok := tree[key]
+			if !ok {
+				node = m.newNode(state, r)
+				tree[key] = node
+				expanded = true
+			}
+
+			i := node.selectAction(m.Exploration)
+			path = append(path, step{node, i, seat})
+			action = node.actions[i]
+		}
+
+		state = ApplyAction(state, action)
+		if !action.ContinueRolling {
+			seat = (seat + 1) % numPlayers
+		}
+	}
+
+	// Play out the rest of the game, then propagate the result back up the tree.
+	var final GameState
+	final, seat = m.playOut(state, seat)
+	endValue := calcEndGameValue(final, Metadata{Objective: WinProbability})
+	var result [maxNumPlayers]float64 // Relative to the root player.
+	for i := 0; i < numPlayers; i++ {
+		result[(seat+i)%numPlayers] = endValue[i]
+	}
+
+	for _, s := range path {
+		s.node.nVisited++
+		s.node.visits[s.action]++
+		s.node.values[s.action] += result[s.seat]
+	}
+
+	rootAction := -1
+	if len(path) > 0 {
+		rootAction = path[0].action
+	}
+	return result, rootAction
+}
+
+// Play the game to the end with the rollout strategy, returning the final state
+// and the seat of its current player relative to the root.
+func (m *MCTS) playOut(state GameState, seat int) (GameState, int) {
+	for !state.IsGameOver() {
+		roll, _ := SampleRoll(int(state.NumDiceToRoll), m.rng)
+		action, err := m.Rollout.SelectAction(state, roll)
+		if err != nil {
+			panic(fmt.Errorf("rollout strategy %v: %w", m.Rollout, err))
+		}
+		if state.ScoreThisRound == math.MaxUint8 && action.ContinueRolling {
+			action.ContinueRolling = false
+		}
+
+		state = ApplyAction(state, action)
+		if !action.ContinueRolling {
+			seat = (seat + 1) % int(state.NumPlayers)
+		}
+	}
+
+	return state, seat
+}
+
+func (m *MCTS) newNode(state GameState, roll Roll) *mctsNode {
+	actions := legalActions(nil, state, GetRollID(roll))
+	node := &mctsNode{
+		actions: actions,
+		visits:  make([]int, len(actions)),
+		values:  make([]float64, len(actions)),
+	}
+
+	if rollout, err := m.Rollout.SelectAction(state, roll); err == nil {
+		node.rollout = max(slices.Index(actions, rollout), 0)
+	}
+	return node
+}
+
+// Select the action to explore with UCB1, trying each action once first.
+// Rarely visited nodes play the rollout action instead: most rolls are only
+// seen a few times, and exploring poor actions there would bias the search
+// against whichever player is to move.
+func (n *mctsNode) selectAction(exploration float64) int {
+	if n.nVisited < len(n.actions) {
+		return n.rollout
+	}
+
+	best, bestScore := 0, math.Inf(-1)
+	logN := math.Log(float64(n.nVisited))
+	for i, visits := range n.visits {
+		if visits == 0 {
+			return i
+		}
+
+		score := n.values[i]/float64(visits) + exploration*math.Sqrt(logN/float64(visits))
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// All legal actions in response to a (non-farkle) roll, appended to result.
+func legalActions(result []Action, state GameState, rollID uint16) []Action {
+	effects := rollIDToActionEffects[rollID]
+	parts := newStateIDParts(state)
+	for i, action := range rollIDToPotentialActions[rollID] {
+		// After the score this round overflows, continuing to roll is taken
+		// as banking, which is already an action.
+		if taken, _, allowed := parts.takeAction(action, effects[i]); allowed && taken == action {
+			result = append(result, action)
+		}
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package farkle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// DB that stores results in memory.
+// Only states that have been Put are stored, so it is suitable for
+// solving small subsets of the game tree (see GameStatesFrom).
+type InMemoryDB struct {
+	numPlayers int
+	meta       Metadata
+	values     map[int][maxNumPlayers]float64
+}
+
+// Create a database for the WinProbability objective with the rules in effect.
+func NewInMemoryDB(numPlayers int) *InMemoryDB {
+	return NewInMemoryDBWithMetadata(numPlayers, Metadata{Objective: WinProbability, Rules: rulesFingerprint})
+}
+
+func NewInMemoryDBWithMetadata(numPlayers int, meta Metadata) *InMemoryDB {
+	return &InMemoryDB{
+		numPlayers: numPlayers,
+		meta:       meta,
+		values:     make(map[int][maxNumPlayers]float64),
+	}
+}
+
+func (db *InMemoryDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db *InMemoryDB) Metadata() Metadata {
+	return db.meta
+}
+
+// The number of states that have been stored.
+func (db *InMemoryDB) Len() int {
+	return len(db.values)
+}
+
+func (db *InMemoryDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	db.values[gsID] = pWin
+}
+
+// Retrieve a stored result for the given game state. States that have not been
+// stored have the same initial value they would have in a new FileDB.
+func (db *InMemoryDB) Get(gsID int) [maxNumPlayers]float64 {
+	if pWin, ok := db.values[gsID]; ok {
+		return pWin
+	}
+
+	return InitialValue(db.numPlayers, gsID, db.meta)
+}
+
+func (db *InMemoryDB) Close() error {
+	return nil
+}
+
+// Write all values in the FileDB format, including states that have not
+// been stored, so that the result can be opened with NewFileDB.
+func (db *InMemoryDB) WriteTo(w io.Writer) (int64, error) {
+	return writeFileDB(w, db)
+}
+
+// Replace the contents of the database with a database in the FileDB format,
+// e.g. written by WriteTo. The metadata is also read from the database.
+// Only states whose values differ from their initial value are stored.
+func (db *InMemoryDB) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: bufio.NewReaderSize(r, 4*1024*1024)}
+	header, meta, version, err := readHeader(cr, db.numPlayers)
+	if err != nil {
+		return cr.n, err
+	}
+
+	h := sha256.New()
+	values := make(map[int][maxNumPlayers]float64)
+	buf := make([]byte, 8*db.numPlayers)
+	for gsID := 0; gsID < calcNumDistinctStates(db.numPlayers); gsID++ {
+		if _, err := io.ReadFull(cr, buf); err != nil {
+			return cr.n, err
+		}
+		h.Write(buf)
+
+		var pWin [maxNumPlayers]float64
+		if version == dbSparseFormatVersion {
+			pWin = decodeSparseValue(buf, db.numPlayers, gsID, meta)
+		} else {
+			for i := range pWin[:db.numPlayers] {
+				pWin[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:]))
+			}
+		}
+		if pWin != InitialValue(db.numPlayers, gsID, meta) {
+			values[gsID] = pWin
+		}
+	}
+
+	flags := binary.LittleEndian.Uint32(header[dbFlagsOffset:])
+	if flags&dbFlagChecksum != 0 &&
+		!bytes.Equal(h.Sum(nil), header[dbChecksumOffset:dbChecksumOffset+sha256.Size]) {
+		return cr.n, fmt.Errorf("database is corrupt: checksum does not match")
+	}
+
+	db.meta = meta
+	db.values = values
+	return cr.n, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,53 @@
+package farkle
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+const (
+	// Default size of the buffer for sorting game states, beyond which
+	// they are spilled to disk.
+	defaultSortBufferSize = 16 * 1024 * 1024
+	// Minimum sort buffer size supported by extsort.
+	minSortBufferSize = 64 * 1024
+	// Approximate size of each memoized value in LazyDB and TurnDB,
+	// including the overhead of the map.
+	memoEntrySize = 96
+)
+
+// The memory budget set by SetMemoryBudget, in bytes, or 0 for the defaults.
+var memoryBudget atomic.Int64
+
+// Limit the memory used by the solver's in-memory structures to
+// approximately the given number of bytes: half for the buffer used to sort
+// game states by depth, beyond which they are spilled to disk, and a quarter
+// for the memoized values of each LazyDB and TurnDB. It also sets a soft limit
+// on the Go heap, so that the garbage collector works harder as the budget is
+// approached. Structures proportional to the number of game states, such as
+// SparseDB, are not limited. Use FileDB, which is paged by the OS, to solve
+// games that do not fit in memory. A budget of 0 restores the defaults.
+func SetMemoryBudget(bytes int64) {
+	memoryBudget.Store(bytes)
+	if bytes > 0 {
+		debug.SetMemoryLimit(bytes)
+	}
+}
+
+// The size of the buffer to use for sorting game states, in bytes.
+func sortBufferSize() int {
+	budget := memoryBudget.Load()
+	if budget <= 0 {
+		return defaultSortBufferSize
+	}
+	return max(int(budget/2), minSortBufferSize)
+}
+
+// The maximum number of memoized values to hold in a LazyDB or TurnDB.
+func maxMemoSize() int {
+	budget := memoryBudget.Load()
+	if budget <= 0 {
+		return maxLazyDBSize
+	}
+	return max(int(budget/4/memoEntrySize), 1)
+}
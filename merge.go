@@ -0,0 +1,93 @@
+package farkle
+
+import (
+	"fmt"
+)
+
+// How MergeDBs combines the values of states in two databases.
+type MergePolicy int
+
+const (
+	// Take the value in src of every state that has been solved in src,
+	// i.e. whose value differs from its initial value. This combines shards
+	// that each solved a different subset of the states, or recovers the
+	// solved states from a partially-updated database.
+	MergeSolved MergePolicy = iota
+	// Average the values of states that have been solved in both databases,
+	// and otherwise take the solved value from either.
+	MergeAverage
+	// Take the value in src of every state.
+	MergeOverwrite
+)
+
+var mergePolicyNames = map[MergePolicy]string{
+	MergeSolved:    "solved",
+	MergeAverage:   "average",
+	MergeOverwrite: "overwrite",
+}
+
+func (p MergePolicy) String() string {
+	if name, ok := mergePolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("MergePolicy(%d)", int(p))
+}
+
+func ParseMergePolicy(name string) (MergePolicy, error) {
+	for p, pName := range mergePolicyNames {
+		if pName == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown merge policy: %q", name)
+}
+
+// Merge the values of all states in src into dst according to the given
+// policy. Both databases must be for the same number of players and objective.
+// Returns the number of states whose value in dst was changed.
+func MergeDBs(dst, src DB, policy MergePolicy) (int, error) {
+	numPlayers := dst.NumPlayers()
+	if src.NumPlayers() != numPlayers {
+		return 0, fmt.Errorf("cannot merge %d-player database into %d-player database",
+			src.NumPlayers(), numPlayers)
+	}
+	meta := dst.Metadata()
+	if src.Metadata() != meta {
+		return 0, fmt.Errorf("cannot merge %v database into %v database",
+			src.Metadata(), meta)
+	}
+	if _, ok := mergePolicyNames[policy]; !ok {
+		return 0, fmt.Errorf("unknown merge policy: %v", policy)
+	}
+
+	nChanged := 0
+	unsolved := unsolvedValue(numPlayers, meta)
+	for gsID := 0; gsID < calcNumDistinctStates(numPlayers); gsID++ {
+		if gsID%100000000 == 0 {
+			Logger().Info("Merged game states", "count", gsID)
+		}
+		if GameStateFromID(numPlayers, gsID).IsGameOver() {
+			continue
+		}
+
+		srcValue := src.Get(gsID)
+		if policy != MergeOverwrite && srcValue == unsolved {
+			continue
+		}
+
+		dstValue := dst.Get(gsID)
+		value := srcValue
+		if policy == MergeAverage && dstValue != unsolved {
+			for i := range value[:numPlayers] {
+				value[i] = (dstValue[i] + srcValue[i]) / 2
+			}
+		}
+
+		if value != dstValue {
+			dst.Put(gsID, value)
+			nChanged++
+		}
+	}
+
+	return nChanged, nil
+}
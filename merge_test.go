@@ -0,0 +1,72 @@
+package farkle
+
+import (
+	"testing"
+)
+
+// Each policy takes the solved values of src, or averages them, and the
+// states that have not been solved in src keep their values in dst unless
+// they are overwritten.
+func TestMergeDBs(t *testing.T) {
+	var a, b, c GameState
+	for i, state := range []*GameState{&a, &b, &c} {
+		*state = NewGameState(1)
+		state.ScoreThisRound = uint8(i + 1)
+	}
+	unsolved := unsolvedValue(1, NewInMemoryDB(1).Metadata())
+
+	testCases := []struct {
+		policy      MergePolicy
+		want        map[GameState]float64
+		wantChanged int
+	}{
+		// a and b are solved in src, and b and c in dst.
+		{MergeSolved, map[GameState]float64{a: 2, b: 4, c: 6}, 2},
+		{MergeAverage, map[GameState]float64{a: 2, b: 3.5, c: 6}, 2},
+		{MergeOverwrite, map[GameState]float64{a: 2, b: 4, c: unsolved[0]}, 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			src, dst := NewInMemoryDB(1), NewInMemoryDB(1)
+			src.Put(a.ID(), [maxNumPlayers]float64{2})
+			src.Put(b.ID(), [maxNumPlayers]float64{4})
+			dst.Put(b.ID(), [maxNumPlayers]float64{3})
+			dst.Put(c.ID(), [maxNumPlayers]float64{6})
+
+			nChanged, err := MergeDBs(dst, src, tc.policy)
+			if err != nil {
+				t.Fatal(err)
+			} else if nChanged != tc.wantChanged {
+				t.Errorf("changed %d states, want %d", nChanged, tc.wantChanged)
+			}
+			for state, want := range tc.want {
+				if got := dst.Get(state.ID())[0]; got != want {
+					t.Errorf("value of %v = %v, want %v", state, got, want)
+				}
+			}
+
+			policy, err := ParseMergePolicy(tc.policy.String())
+			if err != nil || policy != tc.policy {
+				t.Errorf("ParseMergePolicy(%q) = %v, %v", tc.policy, policy, err)
+			}
+		})
+	}
+}
+
+// Databases for other games or objectives are not merged.
+func TestMergeDBsIncompatible(t *testing.T) {
+	dst := NewInMemoryDB(1)
+	if _, err := MergeDBs(dst, NewInMemoryDB(2), MergeSolved); err == nil {
+		t.Error("merged a 2-player database into a 1-player database")
+	}
+	margin := NewInMemoryDBWithMetadata(1, Metadata{Objective: ScoreMargin, Rules: rulesFingerprint})
+	if _, err := MergeDBs(dst, margin, MergeSolved); err == nil {
+		t.Error("merged databases with different objectives")
+	}
+	if _, err := MergeDBs(dst, NewInMemoryDB(1), MergePolicy(-1)); err == nil {
+		t.Error("merged with an unknown policy")
+	}
+	if _, err := ParseMergePolicy("max"); err == nil {
+		t.Error("parsed an unknown merge policy")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package farkle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Memory-mapped files are only supported on unix. On other platforms
+// (e.g. js/wasm) the rest of the package remains usable with InMemoryDB.
+func mmapFile(f *os.File, size int, writable bool) ([]byte, error) {
+	return nil, fmt.Errorf("memory-mapped files are not supported on %s: %w",
+		runtime.GOOS, errors.ErrUnsupported)
+}
+
+func munmapFile(mmap []byte, writable bool) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package farkle
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Map the first size bytes of f into memory, shared with the underlying file.
+func mmapFile(f *os.File, size int, writable bool) ([]byte, error) {
+	flags := unix.MAP_SHARED
+	prot := unix.PROT_READ
+	if writable {
+		prot |= unix.PROT_WRITE
+	}
+	return unix.Mmap(int(f.Fd()), 0, size, prot, flags)
+}
+
+// Flush a writable mapping created by mmapFile to disk and unmap it.
+func munmapFile(mmap []byte, writable bool) error {
+	if writable {
+		if err := unix.Msync(mmap, unix.MS_SYNC); err != nil {
+			return err
+		}
+	}
+
+	return unix.Munmap(mmap)
+}
@@ -0,0 +1,114 @@
+package farkle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// Element types of an mmapArray.
+type mmapElem interface {
+	~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// Fixed-size array of integers stored in a memory-mapped temporary file, so
+// that arrays larger than memory can be paged in and out by the OS. Elements
+// are stored in native byte order, so the files are not portable. On platforms
+// without memory-mapped files, the array is held in memory instead.
+type mmapArray[T mmapElem] struct {
+	f    *os.File
+	mmap []byte
+	data []T
+}
+
+// Create an array of n zero elements backed by the file at the given path,
+// which is overwritten.
+func newMmapArray[T mmapElem](path string, n int) (*mmapArray[T], error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &mmapArray[T]{f: f}
+	if err := a.resize(n); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Map the first n elements of the file into memory.
+func (a *mmapArray[T]) resize(n int) error {
+	var zero T
+	size := n * int(unsafe.Sizeof(zero))
+	if err := a.f.Truncate(int64(size)); err != nil {
+		return err
+	}
+	if size == 0 {
+		a.mmap, a.data = nil, nil
+		return nil
+	}
+
+	mmap, err := mmapFile(a.f, size, true)
+	if errors.Is(err, errors.ErrUnsupported) {
+		data := make([]T, n)
+		copy(data, a.data)
+		a.data = data
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	a.mmap = mmap
+	a.data = unsafe.Slice((*T)(unsafe.Pointer(&mmap[0])), n)
+	return nil
+}
+
+func (a *mmapArray[T]) Len() int {
+	return len(a.data)
+}
+
+func (a *mmapArray[T]) Get(i int) T {
+	a.checkIndex(i)
+	return a.data[i]
+}
+
+func (a *mmapArray[T]) Set(i int, value T) {
+	a.checkIndex(i)
+	a.data[i] = value
+}
+
+func (a *mmapArray[T]) checkIndex(i int) {
+	if i < 0 || i >= len(a.data) {
+		panic(fmt.Errorf("index %d out of range for array of length %d", i, len(a.data)))
+	}
+}
+
+// Grow the array to hold n elements. New elements are zero.
+func (a *mmapArray[T]) Grow(n int) error {
+	if n <= len(a.data) {
+		return nil
+	}
+
+	if a.mmap != nil {
+		if err := munmapFile(a.mmap, true); err != nil {
+			return err
+		}
+		a.mmap, a.data = nil, nil
+	}
+	return a.resize(n)
+}
+
+func (a *mmapArray[T]) Close() error {
+	defer a.f.Close()
+
+	if a.mmap != nil {
+		if err := munmapFile(a.mmap, true); err != nil {
+			return err
+		}
+	}
+	a.mmap, a.data = nil, nil
+
+	return a.f.Close()
+}
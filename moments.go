@@ -0,0 +1,133 @@
+package farkle
+
+import (
+	"fmt"
+	"iter"
+	"math"
+)
+
+// The mean and variance of the final score of each player, in points, when
+// all players play optimally with respect to the values in Policy, e.g. to
+// tell a 51% chance of winning by a wide margin from one that is decided by a
+// few points. Mean and Square are tables of the same states as Policy, with
+// the expected final score and the expected square of the final score of each
+// player. As in other databases, values are relative to the player to move.
+type ScoreMoments struct {
+	Policy DB
+	Mean   DB
+	Square DB
+}
+
+func NewScoreMoments(policy, mean, square DB) (*ScoreMoments, error) {
+	for _, db := range []DB{mean, square} {
+		if db.NumPlayers() != policy.NumPlayers() {
+			return nil, fmt.Errorf("expected %d-player tables, got %d players",
+				policy.NumPlayers(), db.NumPlayers())
+		}
+		if db.Metadata() != policy.Metadata() {
+			return nil, fmt.Errorf("tables are for a %v strategy, not %v",
+				db.Metadata(), policy.Metadata())
+		}
+	}
+
+	return &ScoreMoments{
+		Policy: policy,
+		Mean:   mean,
+		Square: square,
+	}, nil
+}
+
+// Recalculate the moments of all states in the given iterator, with the
+// values of Policy held fixed.
+func (m *ScoreMoments) UpdateAll(states iter.Seq2[uint64, GameState], chkpntPath string) UpdateStats {
+	return m.UpdateAllWithOptions(states, UpdateOptions{CheckpointPath: chkpntPath})
+}
+
+// As UpdateAll, with the given options.
+func (m *ScoreMoments) UpdateAllWithOptions(states iter.Seq2[uint64, GameState], opts UpdateOptions) UpdateStats {
+	tables := []valueTable{
+		{
+			db: m.Mean,
+			value: func(state GameState) [maxNumPlayers]float64 {
+				return m.calcStateValue(state, m.Mean, 1)
+			},
+		},
+		{
+			db: m.Square,
+			value: func(state GameState) [maxNumPlayers]float64 {
+				return m.calcStateValue(state, m.Square, 2)
+			},
+		},
+	}
+
+	return updateTables(tables, states, opts)
+}
+
+// The expected value of the moment with the given power of the final scores,
+// from the table of that moment, after each roll, when the player to move
+// takes the optimal action of Policy. The tables are initialized with the
+// values of the objective of Policy, so the moments at the end of the game are
+// calculated rather than looked up.
+func (m *ScoreMoments) calcStateValue(state GameState, table DB, power int) [maxNumPlayers]float64 {
+	var result [maxNumPlayers]float64
+	for _, wRoll := range allRolls[state.NumDiceToRoll] {
+		action, _ := SelectAction(state, wRoll.ID, m.Policy)
+		next := ApplyAction(state, action)
+		var value [maxNumPlayers]float64
+		if next.IsGameOver() {
+			value = calcEndGameMoment(next, power)
+		} else {
+			value = table.Get(next.ID())
+		}
+		if len(rollIDToPotentialActions[wRoll.ID]) == 0 || !action.ContinueRolling {
+			value = unrotate(value, state.NumPlayers)
+		}
+		mixInto(&result, wRoll.Prob, &value)
+	}
+
+	return result
+}
+
+// The final score of each player raised to the given power.
+func calcEndGameMoment(state GameState, power int) [maxNumPlayers]float64 {
+	var result [maxNumPlayers]float64
+	for player, score := range state.PlayerScores[:state.NumPlayers] {
+		result[player] = math.Pow(incr*float64(score), float64(power))
+	}
+	return result
+}
+
+// The distribution of the final score of a player, in points.
+type ScoreDistribution struct {
+	Mean     float64
+	Variance float64
+}
+
+func (d ScoreDistribution) StdDev() float64 {
+	return math.Sqrt(d.Variance)
+}
+
+func (d ScoreDistribution) String() string {
+	return fmt.Sprintf("%.0f ± %.0f", d.Mean, d.StdDev())
+}
+
+// The distribution of the final score of each player in the given state,
+// relative to the player to move.
+func (m *ScoreMoments) Get(state GameState) []ScoreDistribution {
+	var mean, square [maxNumPlayers]float64
+	if state.IsGameOver() {
+		mean, square = calcEndGameMoment(state, 1), calcEndGameMoment(state, 2)
+	} else {
+		mean, square = m.Mean.Get(state.ID()), m.Square.Get(state.ID())
+	}
+
+	result := make([]ScoreDistribution, state.NumPlayers)
+	for i := range result {
+		// Rounding may make the variance slightly negative.
+		result[i] = ScoreDistribution{
+			Mean:     mean[i],
+			Variance: max(square[i]-mean[i]*mean[i], 0),
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package farkle
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// The moments of the final scores agree with those of games played with the
+// optimal strategy.
+func TestScoreMoments(t *testing.T) {
+	if testing.Short() {
+		t.Skip("evaluates a two-player game")
+	}
+	setTestRules(t, "pocket-farkle,target=50,dice=2")
+	states := miniatureGameStates(t, 2)
+	policy := NewInMemoryDB(2)
+	SolveExact(policy, "")
+	if _, err := NewScoreMoments(policy, NewInMemoryDB(1), NewInMemoryDB(2)); err == nil {
+		t.Error("created moments with tables for another number of players")
+	}
+	moments, err := NewScoreMoments(policy, NewInMemoryDB(2), NewInMemoryDB(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		if i == 1000 {
+			t.Fatal("moments did not converge")
+		}
+		// The moments are in points, and points squared.
+		if moments.UpdateAll(depthStates(states), "").Total().MaxChange < 1e-2 {
+			break
+		}
+	}
+	got := moments.Get(NewGameState(2))
+
+	const numGames = 20000
+	rng := rand.New(rand.NewSource(benchSeed))
+	strategies := []Strategy{OptimalStrategy{DB: policy}, OptimalStrategy{DB: policy}}
+	var sum, sumSquares [2]float64
+	for range numGames {
+		result, err := PlayGame(strategies, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, score := range result.Scores {
+			sum[i] += float64(score)
+			sumSquares[i] += float64(score) * float64(score)
+		}
+	}
+	for i, dist := range got {
+		mean := sum[i] / numGames
+		variance := sumSquares[i]/numGames - mean*mean
+		// Within five standard errors of the mean.
+		if stdErr := math.Sqrt(dist.Variance / numGames); math.Abs(mean-dist.Mean) > 5*stdErr {
+			t.Errorf("player %d: mean final score %v, want %v ± %v", i+1, mean, dist.Mean, 5*stdErr)
+		}
+		if math.Abs(variance-dist.Variance) > 0.1*dist.Variance {
+			t.Errorf("player %d: variance of the final score %v, want %v", i+1, variance, dist.Variance)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package neural
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// The features of an action, and its value for the player to move.
+type Example struct {
+	Features []float64
+	Value    float64
+}
+
+// One example for every legal action in the given events, which must have
+// been logged with a database so that they include the value of each action.
+func ExamplesFromEvents(events []farkle.GameEvent) ([]Example, error) {
+	var result []Example
+	for i, event := range events {
+		if len(event.ActionValues) != len(event.Actions) {
+			return nil, fmt.Errorf("event %d has no action values: the event log must be written with a database", i)
+		}
+
+		for j, action := range event.Actions {
+			result = append(result, Example{
+				Features: Features(event.State, action),
+				Value:    event.ActionValues[j],
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// Write examples as CSV, with one column per feature and then the value,
+// e.g. to train a larger model with other tools.
+func WriteCSV(w io.Writer, examples []Example) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, NumFeatures+1)
+	for i := 0; i < NumFeatures; i++ {
+		header = append(header, fmt.Sprintf("f%d", i))
+	}
+	if err := cw.Write(append(header, "value")); err != nil {
+		return err
+	}
+
+	row := make([]string, 0, NumFeatures+1)
+	for _, example := range examples {
+		row = row[:0]
+		for _, x := range example.Features {
+			row = append(row, strconv.FormatFloat(x, 'g', -1, 64))
+		}
+		row = append(row, strconv.FormatFloat(example.Value, 'g', -1, 64))
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,54 @@
+// Package neural distills the optimal policy into a small neural network,
+// which needs much less memory than a solved database. The network estimates
+// the value of each legal action for the player to move, from features of the
+// state and action, and is trained on the action values in an event log
+// (see farkle.EventLogWriter).
+package neural
+
+import (
+	"github.com/timpalpant/go-farkle"
+)
+
+// The score that triggers the final round, in the units of farkle.GameState.
+func winningScore() float64 {
+	return float64(farkle.CurrentRules().TargetScore) / 50
+}
+
+// The number of features returned by Features.
+const NumFeatures = 10
+
+// Features of taking the given action in the given state, scaled to be of order 1.
+func Features(state farkle.GameState, action farkle.Action) []float64 {
+	myScore := float64(state.PlayerScores[0])
+	maxOpponent, sumOpponents := 0.0, 0.0
+	for _, score := range state.PlayerScores[1:state.NumPlayers] {
+		maxOpponent = max(maxOpponent, float64(score))
+		sumOpponents += float64(score)
+	}
+	meanOpponent := sumOpponents / max(float64(state.NumPlayers-1), 1)
+
+	// The state after holding the dice, before deciding whether to continue.
+	held := farkle.ApplyAction(state, farkle.Action{HeldDiceID: action.HeldDiceID, ContinueRolling: true})
+	turnScore := float64(held.ScoreThisRound)
+	target := winningScore()
+
+	return []float64{
+		myScore / target,
+		maxOpponent / target,
+		meanOpponent / target,
+		turnScore / target,
+		boolFeature(action.ContinueRolling),
+		float64(held.NumDiceToRoll) / farkle.MaxNumDice,
+		boolFeature(myScore > 0),
+		boolFeature(maxOpponent >= target),
+		(myScore + turnScore - maxOpponent) / target,
+		float64(farkle.CalculateScore(action.HeldDice())) / target,
+	}
+}
+
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package neural
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// Multi-layer perceptron with tanh hidden layers and a sigmoid output,
+// so that it predicts a probability.
+type MLP struct {
+	Layers []Layer `json:"layers"`
+}
+
+// A fully-connected layer: output[i] = sum_j Weights[i][j]*input[j] + Biases[i].
+type Layer struct {
+	Weights [][]float64 `json:"weights"`
+	Biases  []float64   `json:"biases"`
+}
+
+// A network with the given number of inputs, hidden units in each hidden
+// layer, and a single output, with randomly initialized weights.
+func NewMLP(numInputs int, hidden []int, rng *rand.Rand) *MLP {
+	sizes := append(append([]int{numInputs}, hidden...), 1)
+	net := &MLP{Layers: make([]Layer, len(sizes)-1)}
+	for l := range net.Layers {
+		// Xavier initialization.
+		scale := math.Sqrt(6 / float64(sizes[l]+sizes[l+1]))
+		layer := Layer{
+			Weights: make([][]float64, sizes[l+1]),
+			Biases:  make([]float64, sizes[l+1]),
+		}
+		for i := range layer.Weights {
+			layer.Weights[i] = make([]float64, sizes[l])
+			for j := range layer.Weights[i] {
+				layer.Weights[i][j] = scale * (2*rng.Float64() - 1)
+			}
+		}
+		net.Layers[l] = layer
+	}
+	return net
+}
+
+// Predict the output for the given input.
+func (net *MLP) Predict(x []float64) float64 {
+	activations := net.forward(x)
+	return activations[len(activations)-1][0]
+}
+
+// The activations of each layer, starting with the input.
+func (net *MLP) forward(x []float64) [][]float64 {
+	activations := [][]float64{x}
+	for l, layer := range net.Layers {
+		out := make([]float64, len(layer.Biases))
+		for i, w := range layer.Weights {
+			sum := layer.Biases[i]
+			for j, xj := range x {
+				sum += w[j] * xj
+			}
+			if l == len(net.Layers)-1 {
+				out[i] = 1 / (1 + math.Exp(-sum))
+			} else {
+				out[i] = math.Tanh(sum)
+			}
+		}
+		activations = append(activations, out)
+		x = out
+	}
+	return activations
+}
+
+// Take one step of stochastic gradient descent on the squared error for the
+// given example. Returns the squared error before the step.
+func (net *MLP) step(x []float64, target, learningRate float64) float64 {
+	activations := net.forward(x)
+	output := activations[len(activations)-1][0]
+	err := output - target
+
+	// Gradient of the loss with respect to the pre-activation of each unit.
+	delta := []float64{err * output * (1 - output)}
+	for l := len(net.Layers) - 1; l >= 0; l-- {
+		layer := net.Layers[l]
+		input := activations[l]
+		var prevDelta []float64
+		if l > 0 {
+			prevDelta = make([]float64, len(input))
+			for i, w := range layer.Weights {
+				for j := range w {
+					prevDelta[j] += w[j] * delta[i]
+				}
+			}
+			for j, a := range input {
+				prevDelta[j] *= 1 - a*a // tanh'
+			}
+		}
+
+		for i, w := range layer.Weights {
+			for j := range w {
+				w[j] -= learningRate * delta[i] * input[j]
+			}
+			layer.Biases[i] -= learningRate * delta[i]
+		}
+		delta = prevDelta
+	}
+
+	return err * err
+}
+
+// Train the network on the given examples with stochastic gradient descent,
+// for the given number of passes over the data. Returns the mean squared
+// error of each pass.
+func (net *MLP) Train(examples []Example, epochs int, learningRate float64, rng *rand.Rand) []float64 {
+	order := rng.Perm(len(examples))
+	losses := make([]float64, epochs)
+	for epoch := range losses {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		total := 0.0
+		for _, i := range order {
+			total += net.step(examples[i].Features, examples[i].Value, learningRate)
+		}
+		losses[epoch] = total / float64(len(examples))
+	}
+	return losses
+}
+
+// The mean squared error of the network on the given examples.
+func (net *MLP) Loss(examples []Example) float64 {
+	total := 0.0
+	for _, example := range examples {
+		err := net.Predict(example.Features) - example.Value
+		total += err * err
+	}
+	return total / float64(len(examples))
+}
+
+// Save the network as JSON, to be loaded with LoadMLP.
+func (net *MLP) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(net)
+}
+
+// Load a network saved with MLP.Save.
+func LoadMLP(r io.Reader) (*MLP, error) {
+	var net MLP
+	if err := json.NewDecoder(r).Decode(&net); err != nil {
+		return nil, err
+	}
+
+	numInputs := NumFeatures
+	for l, layer := range net.Layers {
+		if len(layer.Weights) != len(layer.Biases) {
+			return nil, fmt.Errorf("layer %d has %d weight rows but %d biases",
+				l, len(layer.Weights), len(layer.Biases))
+		}
+		for _, w := range layer.Weights {
+			if len(w) != numInputs {
+				return nil, fmt.Errorf("layer %d expects %d inputs, got %d", l, numInputs, len(w))
+			}
+		}
+		numInputs = len(layer.Biases)
+	}
+	if len(net.Layers) == 0 || numInputs != 1 {
+		return nil, fmt.Errorf("network must have a single output")
+	}
+
+	return &net, nil
+}
@@ -0,0 +1,148 @@
+package neural
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func cloneMLP(t *testing.T, net *MLP) *MLP {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := net.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var clone MLP
+	if err := json.Unmarshal(buf.Bytes(), &clone); err != nil {
+		t.Fatal(err)
+	}
+	return &clone
+}
+
+// Each step moves every parameter along the gradient of half the squared
+// error, as estimated by finite differences.
+func TestStepGradient(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	net := NewMLP(3, []int{4, 2}, rng)
+	for _, layer := range net.Layers {
+		for i := range layer.Biases {
+			layer.Biases[i] = rng.Float64() - 0.5
+		}
+	}
+	x := []float64{0.3, -0.7, 1.2}
+	const target = 0.2
+
+	stepped := cloneMLP(t, net)
+	stepped.step(x, target, 1)
+	loss := func() float64 {
+		err := net.Predict(x) - target
+		return err * err / 2
+	}
+	const h = 1e-6
+	check := func(name string, param *float64, after float64) {
+		orig := *param
+		*param = orig + h
+		up := loss()
+		*param = orig - h
+		down := loss()
+		*param = orig
+		want := (up - down) / (2 * h)
+		if got := orig - after; math.Abs(got-want) > 1e-8 {
+			t.Errorf("gradient of %s = %v, want %v", name, got, want)
+		}
+	}
+	for l, layer := range net.Layers {
+		for i, w := range layer.Weights {
+			for j := range w {
+				check(fmt.Sprintf("layer %d weight [%d][%d]", l, i, j), &w[j], stepped.Layers[l].Weights[i][j])
+			}
+			check(fmt.Sprintf("layer %d bias %d", l, i), &layer.Biases[i], stepped.Layers[l].Biases[i])
+		}
+	}
+}
+
+// The network learns a simple function of its inputs.
+func TestTrain(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var examples []Example
+	for range 200 {
+		x := []float64{rng.Float64(), rng.Float64()}
+		value := 0.0
+		if x[0] > x[1] {
+			value = 1
+		}
+		examples = append(examples, Example{Features: x, Value: value})
+	}
+
+	net := NewMLP(2, []int{8}, rng)
+	before := net.Loss(examples)
+	losses := net.Train(examples, 100, 0.1, rng)
+	if len(losses) != 100 {
+		t.Fatalf("Train returned %d losses, want 100", len(losses))
+	}
+	after := net.Loss(examples)
+	if after > before/4 || after > 0.05 {
+		t.Errorf("loss = %v after training, %v before", after, before)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	net := NewMLP(NumFeatures, []int{5, 3}, rng)
+	var buf bytes.Buffer
+	if err := net.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadMLP(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := make([]float64, NumFeatures)
+	for i := range x {
+		x[i] = rng.Float64()
+	}
+	if got, want := loaded.Predict(x), net.Predict(x); got != want {
+		t.Errorf("loaded network predicts %v, want %v", got, want)
+	}
+}
+
+// Networks that cannot be applied to Features are rejected.
+func TestLoadMLPInvalid(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	twoOutputs := NewMLP(NumFeatures, []int{3}, rng)
+	last := &twoOutputs.Layers[len(twoOutputs.Layers)-1]
+	last.Weights = append(last.Weights, last.Weights[0])
+	last.Biases = append(last.Biases, 0)
+	missingBias := NewMLP(NumFeatures, []int{3}, rng)
+	missingBias.Layers[0].Biases = missingBias.Layers[0].Biases[1:]
+
+	testCases := []struct {
+		name string
+		net  *MLP
+	}{
+		{"wrong number of inputs", NewMLP(NumFeatures+1, []int{3}, rng)},
+		{"two outputs", twoOutputs},
+		{"missing bias", missingBias},
+		{"no layers", &MLP{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.net.Save(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMLP(&buf); err == nil {
+				t.Error("loaded an invalid network")
+			}
+		})
+	}
+
+	if _, err := LoadMLP(strings.NewReader("not json")); err == nil {
+		t.Error("loaded a network that is not JSON")
+	}
+}
@@ -0,0 +1,26 @@
+package neural
+
+import (
+	"github.com/timpalpant/go-farkle"
+)
+
+// Plays the legal action with the highest value predicted by a network.
+type Strategy struct {
+	Net *MLP
+}
+
+func (s Strategy) SelectAction(state farkle.GameState, roll farkle.Roll) (farkle.Action, error) {
+	var best farkle.Action
+	bestValue := -1.0
+	for _, action := range farkle.LegalActions(state, roll) {
+		if value := s.Net.Predict(Features(state, action)); value > bestValue {
+			best, bestValue = action, value
+		}
+	}
+
+	return best, nil
+}
+
+func (s Strategy) String() string {
+	return "neural"
+}
@@ -0,0 +1,77 @@
+package neural
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/rand"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+func TestFeatures(t *testing.T) {
+	state := farkle.NewGameState(3)
+	roll := farkle.NewRoll(1, 1, 5, 2, 3, 4)
+	for _, action := range farkle.LegalActions(state, roll) {
+		if got := len(Features(state, action)); got != NumFeatures {
+			t.Errorf("%d features for %v, want %d", got, action, NumFeatures)
+		}
+	}
+}
+
+// An untrained network still plays legal actions in every game.
+func TestStrategyPlaysLegalActions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	strategies := []farkle.Strategy{
+		Strategy{Net: NewMLP(NumFeatures, []int{4}, rng)},
+		farkle.ThresholdStrategy{BankAt: 300},
+	}
+	for range 10 {
+		if _, err := farkle.PlayGame(strategies, rng); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExamplesFromEvents(t *testing.T) {
+	state := farkle.NewGameState(2)
+	roll := farkle.NewRoll(1, 1, 5, 2, 3, 4)
+	actions := farkle.LegalActions(state, roll)
+	event := farkle.GameEvent{State: state, Roll: roll, Actions: actions, Action: actions[0]}
+	if _, err := ExamplesFromEvents([]farkle.GameEvent{event}); err == nil {
+		t.Error("made examples from events without action values")
+	}
+
+	event.ActionValues = make([]float64, len(actions))
+	for i := range actions {
+		event.ActionValues[i] = float64(i) / float64(len(actions))
+	}
+	// Farkles have no actions, so they make no examples.
+	farkled := farkle.GameEvent{State: state, Roll: farkle.NewRoll(2, 2, 3, 3, 4, 6)}
+	examples, err := ExamplesFromEvents([]farkle.GameEvent{event, farkled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != len(actions) {
+		t.Fatalf("%d examples, want %d", len(examples), len(actions))
+	}
+	for i, example := range examples {
+		if example.Value != event.ActionValues[i] {
+			t.Errorf("value of example %d = %v, want %v", i, example.Value, event.ActionValues[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, examples); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(examples)+1 {
+		t.Errorf("CSV has %d rows, want a header and %d examples", len(rows), len(examples))
+	} else if rows[0][0] != "f0" || rows[0][NumFeatures] != "value" {
+		t.Errorf("CSV header = %v", rows[0])
+	}
+}
@@ -0,0 +1,137 @@
+package farkle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The quantity each player seeks to maximize. The database stores
+// the expected value of this quantity for each player in each state.
+type Objective int
+
+const (
+	// Maximize the probability of winning the game.
+	WinProbability Objective = iota
+	// Maximize the expected final score margin, in points: the player's
+	// final score minus the average final score of the other players.
+	ScoreMargin
+	// Maximize the expected utility of the final score margin, for an
+	// exponential utility function with the given risk aversion.
+	RiskAdjustedMargin
+)
+
+var objectiveNames = map[Objective]string{
+	WinProbability:     "win",
+	ScoreMargin:        "margin",
+	RiskAdjustedMargin: "risk",
+}
+
+func (o Objective) String() string {
+	if name, ok := objectiveNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Objective(%d)", int(o))
+}
+
+func ParseObjective(name string) (Objective, error) {
+	for o, oName := range objectiveNames {
+		if oName == name {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown objective: %q", name)
+}
+
+// Description of the values stored in a database.
+type Metadata struct {
+	Objective Objective
+	// For RiskAdjustedMargin, the coefficient of absolute risk aversion
+	// per 1000 points. Positive values are conservative, preferring a
+	// certain margin to a gamble with the same expected margin, and negative
+	// values are aggressive. Zero is equivalent to ScoreMargin.
+	RiskAversion float64
+	// The rules the values were solved with.
+	Rules RulesFingerprint
+}
+
+func (m Metadata) String() string {
+	result := m.Objective.String()
+	if m.Objective == RiskAdjustedMargin {
+		result = fmt.Sprintf("%v(%g)", m.Objective, m.RiskAversion)
+	}
+	if m.Rules != DefaultRules.Fingerprint() {
+		result += "/" + m.Rules.String()
+	}
+	return result
+}
+
+// Metadata is stored in files as 20 bytes: the objective (16 bits), 16 zero
+// bits, the risk aversion and the rules fingerprint.
+const metadataSize = 20
+
+func encodeMetadata(b []byte, meta Metadata) {
+	binary.LittleEndian.PutUint16(b, uint16(meta.Objective))
+	binary.LittleEndian.PutUint16(b[2:], 0)
+	binary.LittleEndian.PutUint64(b[4:], math.Float64bits(meta.RiskAversion))
+	binary.LittleEndian.PutUint64(b[12:], uint64(meta.Rules))
+}
+
+func decodeMetadata(b []byte) (Metadata, error) {
+	meta := Metadata{
+		Objective:    Objective(binary.LittleEndian.Uint16(b)),
+		RiskAversion: math.Float64frombits(binary.LittleEndian.Uint64(b[4:])),
+		Rules:        RulesFingerprint(binary.LittleEndian.Uint64(b[12:])),
+	}
+	if _, ok := objectiveNames[meta.Objective]; !ok {
+		return Metadata{}, fmt.Errorf("unknown objective: %d", int(meta.Objective))
+	}
+	return meta, nil
+}
+
+// The value of each player at the end of the game.
+func calcEndGameValue(state GameState, meta Metadata) [maxNumPlayers]float64 {
+	if state.NumPlayers == 1 {
+		// Solitaire: there are no turns remaining.
+		return [maxNumPlayers]float64{}
+	}
+
+	switch meta.Objective {
+	case WinProbability:
+		return calcEndGameWinProb(state)
+	case ScoreMargin:
+		return calcEndGameMargin(state)
+	case RiskAdjustedMargin:
+		result := calcEndGameMargin(state)
+		for i := range result[:state.NumPlayers] {
+			result[i] = marginUtility(result[i], meta.RiskAversion)
+		}
+		return result
+	}
+	panic(fmt.Errorf("unknown objective: %v", meta.Objective))
+}
+
+func calcEndGameMargin(state GameState) [maxNumPlayers]float64 {
+	total := 0.0
+	for _, score := range state.PlayerScores[:state.NumPlayers] {
+		total += incr * float64(score)
+	}
+
+	var result [maxNumPlayers]float64
+	numOthers := float64(state.NumPlayers - 1)
+	for player, score := range state.PlayerScores[:state.NumPlayers] {
+		points := incr * float64(score)
+		result[player] = points - (total-points)/numOthers
+	}
+	return result
+}
+
+// Exponential utility of a score margin, scaled so that small margins
+// have approximately the same utility as in points.
+func marginUtility(margin, riskAversion float64) float64 {
+	if riskAversion == 0 {
+		return margin
+	}
+	a := riskAversion / 1000
+	return -math.Expm1(-a*margin) / a
+}
@@ -0,0 +1,210 @@
+// Package overlay writes the state of a game to a JSON file after every
+// event, for streamers to display in an overlay, e.g. an OBS browser source
+// that polls the file. The schema is stable: fields are only added, and
+// Version is incremented if any are changed or removed.
+//
+// For example:
+//
+//	{
+//	  "version": 1,
+//	  "event": "bank",
+//	  "players": [
+//	    {"name": "You", "score": 1050, "winProb": 0.586},
+//	    {"name": "CPU", "score": 0, "winProb": 0.414}
+//	  ],
+//	  "currentPlayer": 1,
+//	  "turnScore": 0,
+//	  "lastRoll": [1, 1, 1, 1, 2, 5],
+//	  "held": [1, 1, 1, 1, 5],
+//	  "gameOver": false,
+//	  "winners": [],
+//	  "updatedAt": "2024-05-01T20:15:02Z"
+//	}
+//
+// Players are ordered by seat, and currentPlayer is the seat of the player
+// to move. Win probabilities are null without an advisor.
+package overlay
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// The version of the schema.
+const Version = 1
+
+// The contents of the file.
+type State struct {
+	Version int `json:"version"`
+	// The last event: "start", "roll", "action", "farkle", "bank" or "gameOver".
+	Event         string   `json:"event"`
+	Players       []Player `json:"players"`
+	CurrentPlayer int      `json:"currentPlayer"`
+	// Points scored by the current player this turn, so far.
+	TurnScore int `json:"turnScore"`
+	// The last roll, and the dice the player held from it, if any.
+	LastRoll  []int     `json:"lastRoll"`
+	Held      []int     `json:"held"`
+	GameOver  bool      `json:"gameOver"`
+	Winners   []int     `json:"winners"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Player struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	// The probability that the player wins, if known.
+	WinProb *float64 `json:"winProb"`
+}
+
+// Observes a farkle.Game, writing its state to a file after every event.
+type File struct {
+	path string
+	// Used to compute win probabilities, if not nil.
+	advisor farkle.Advisor
+
+	state State
+	// The state of the game after the last action, and the seat to move.
+	next     farkle.GameState
+	nextSeat int
+}
+
+// Write the state of a game between the named players, ordered by seat, to
+// the given path. The file is written immediately, before the first roll.
+func NewFile(path string, players []string, advisor farkle.Advisor) *File {
+	f := &File{
+		path:    path,
+		advisor: advisor,
+		state: State{
+			Version:  Version,
+			Event:    "start",
+			Players:  make([]Player, len(players)),
+			LastRoll: []int{},
+			Held:     []int{},
+			Winners:  []int{},
+		},
+		next: farkle.NewGameState(len(players)),
+	}
+	for i, name := range players {
+		f.state.Players[i].Name = name
+	}
+	f.setWinProbs(0, func() [4]float64 { return advisor.WinProb(f.next) })
+	f.write()
+	return f
+}
+
+func (f *File) OnRoll(seat int, state farkle.GameState, roll farkle.Roll) {
+	f.state.Event = "roll"
+	f.state.CurrentPlayer = seat
+	f.state.TurnScore = 50 * int(state.ScoreThisRound)
+	f.state.LastRoll = dice(roll)
+	f.state.Held = []int{}
+	if !farkle.IsFarkle(roll) {
+		f.setWinProbs(seat, func() [4]float64 {
+			_, pWin := f.advisor.Recommend(state, roll)
+			return pWin
+		})
+	}
+	f.write()
+}
+
+func (f *File) OnAction(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+	f.next = farkle.ApplyAction(state, action)
+	f.nextSeat = seat
+	if !action.ContinueRolling {
+		f.nextSeat = (seat + 1) % len(f.state.Players)
+	}
+	if farkle.IsFarkle(roll) {
+		return // Written by OnFarkle.
+	}
+
+	f.state.Event = "action"
+	f.state.TurnScore = 50 * (int(state.ScoreThisRound) + int(farkle.CalculateScore(action.HeldDice())))
+	f.state.Held = dice(action.HeldDice())
+	f.setWinProbs(seat, func() [4]float64 { return f.advisor.EvaluateAction(state, action) })
+	f.write()
+}
+
+func (f *File) OnFarkle(seat int, state farkle.GameState) {
+	f.endTurn("farkle")
+}
+
+func (f *File) OnBank(seat, points, total int) {
+	f.state.Players[seat].Score = total
+	f.endTurn("bank")
+}
+
+func (f *File) endTurn(event string) {
+	if f.next.IsGameOver() {
+		return // Written by OnGameOver.
+	}
+	f.state.Event = event
+	f.state.CurrentPlayer = f.nextSeat
+	f.state.TurnScore = 0
+	f.setWinProbs(f.nextSeat, func() [4]float64 { return f.advisor.WinProb(f.next) })
+	f.write()
+}
+
+func (f *File) OnGameOver(result farkle.GameResult) {
+	f.state.Event = "gameOver"
+	f.state.GameOver = true
+	f.state.TurnScore = 0
+	f.state.Winners = result.Winners
+	for seat, score := range result.Scores {
+		f.state.Players[seat].Score = score
+		if f.advisor != nil {
+			pWin := 0.0
+			for _, winner := range result.Winners {
+				if winner == seat {
+					pWin = 1 / float64(len(result.Winners))
+				}
+			}
+			f.state.Players[seat].WinProb = &pWin
+		}
+	}
+	f.write()
+}
+
+// Set each player's win probability from the values of pWin, which are
+// relative to the player in the given seat.
+func (f *File) setWinProbs(seat int, pWin func() [4]float64) {
+	if f.advisor == nil {
+		return
+	}
+	values := pWin()
+	n := len(f.state.Players)
+	for i := 0; i < n; i++ {
+		p := values[i]
+		f.state.Players[(seat+i)%n].WinProb = &p
+	}
+}
+
+// Replace the file, so that readers never see it partially written.
+func (f *File) write() {
+	f.state.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		farkle.Logger().Warn("Unable to encode overlay", "err", err)
+		return
+	}
+
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		farkle.Logger().Warn("Unable to write overlay", "err", err)
+		return
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		farkle.Logger().Warn("Unable to write overlay", "err", err)
+	}
+}
+
+func dice(roll farkle.Roll) []int {
+	result := []int{}
+	for _, die := range roll.Dice() {
+		result = append(result, int(die))
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package overlay
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+// Reads the overlay file after every event of the game, and checks it
+// against the event.
+type checker struct {
+	t          *testing.T
+	path       string
+	hasAdvisor bool
+	scores     []int
+	numEvents  int
+}
+
+func (c *checker) read() State {
+	c.t.Helper()
+	c.numEvents++
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		c.t.Fatal(err)
+	}
+	if state.Version != Version {
+		c.t.Errorf("version = %d, want %d", state.Version, Version)
+	}
+
+	total := 0.0
+	for _, player := range state.Players {
+		if (player.WinProb != nil) != c.hasAdvisor {
+			c.t.Errorf("%s: win probability %v, with an advisor: %v", state.Event, player.WinProb, c.hasAdvisor)
+		} else if player.WinProb != nil {
+			total += *player.WinProb
+		}
+	}
+	if c.hasAdvisor && math.Abs(total-1) > 1e-9 {
+		c.t.Errorf("%s: win probabilities add up to %v", state.Event, total)
+	}
+	return state
+}
+
+func (c *checker) OnRoll(seat int, state farkle.GameState, roll farkle.Roll) {
+	got := c.read()
+	if got.Event != "roll" || got.CurrentPlayer != seat || got.TurnScore != 50*int(state.ScoreThisRound) ||
+		len(got.LastRoll) != int(state.NumDiceToRoll) || len(got.Held) != 0 {
+		c.t.Errorf("after rolling %v in %v: %+v", roll, state, got)
+	}
+}
+
+func (c *checker) OnAction(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+	if farkle.IsFarkle(roll) {
+		return
+	}
+	got := c.read()
+	if got.Event != "action" || got.CurrentPlayer != seat || len(got.Held) != len(action.HeldDice().Dice()) {
+		c.t.Errorf("after %v in %v: %+v", action, state, got)
+	}
+}
+
+func (c *checker) OnFarkle(seat int, state farkle.GameState) {
+	c.checkTurnEnd("farkle", seat)
+}
+
+func (c *checker) OnBank(seat, points, total int) {
+	c.scores[seat] = total
+	c.checkTurnEnd("bank", seat)
+}
+
+func (c *checker) checkTurnEnd(event string, seat int) {
+	got := c.read()
+	if got.GameOver {
+		return // Written by OnGameOver.
+	}
+	next := (seat + 1) % len(c.scores)
+	if got.Event != event || got.CurrentPlayer != next || got.TurnScore != 0 {
+		c.t.Errorf("after seat %d's %s: %+v", seat, event, got)
+	}
+	for i, player := range got.Players {
+		if player.Score != c.scores[i] {
+			c.t.Errorf("after seat %d's %s: scores %+v, want %v", seat, event, got.Players, c.scores)
+			break
+		}
+	}
+}
+
+func (c *checker) OnGameOver(result farkle.GameResult) {
+	got := c.read()
+	if got.Event != "gameOver" || !got.GameOver || len(got.Winners) != len(result.Winners) {
+		c.t.Errorf("after the game: %+v, want winners %v", got, result.Winners)
+	}
+	for i, player := range got.Players {
+		if player.Score != result.Scores[i] {
+			c.t.Errorf("after the game: scores %+v, want %v", got.Players, result.Scores)
+			break
+		}
+	}
+}
+
+// The file holds the state of the game after every event, with win
+// probabilities if there is an advisor.
+func TestFile(t *testing.T) {
+	for _, advisor := range []farkle.Advisor{nil, farkle.DBAdvisor{DB: farkle.NewInMemoryDB(2)}} {
+		path := filepath.Join(t.TempDir(), "overlay.json")
+		c := &checker{t: t, path: path, hasAdvisor: advisor != nil, scores: make([]int, 2)}
+		f := NewFile(path, []string{"You", "CPU"}, advisor)
+		if got := c.read(); got.Event != "start" || len(got.Players) != 2 || got.Players[1].Name != "CPU" {
+			t.Errorf("before the game: %+v", got)
+		}
+
+		game := farkle.NewGame(2, rand.New(rand.NewSource(1)))
+		game.AddObserver(f)
+		game.AddObserver(c)
+		strategy := farkle.ThresholdStrategy{BankAt: 1000}
+		for !game.IsOver() {
+			roll, err := game.Roll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			action, err := strategy.SelectAction(game.State(), roll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := game.Apply(action); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.numEvents < 10 {
+			t.Errorf("checked the file after %d events", c.numEvents)
+		}
+	}
+}
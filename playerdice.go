@@ -0,0 +1,92 @@
+package farkle
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Values of optimal play when players have different dice (see
+// Rules.PlayerDieWeights), e.g. one of them cheats with loaded dice. Each
+// player plays optimally knowing everyone's dice, so the values are an
+// equilibrium of the asymmetric game. As in BestResponse, game states do not
+// record which player is which, so there is a table of values for each player:
+// Tables[k] holds the values of states in which player k, in order of play
+// from the first player, is to move. As in other databases, values are
+// relative to the player to move.
+type PlayerDice struct {
+	Tables []DB
+}
+
+func NewPlayerDice(tables []DB) (*PlayerDice, error) {
+	if len(tables) < 2 {
+		return nil, fmt.Errorf("player dice require at least 2 players, got %d", len(tables))
+	}
+	for _, db := range tables {
+		if db.NumPlayers() != len(tables) {
+			return nil, fmt.Errorf("expected one table per player, got %d for %d players",
+				len(tables), db.NumPlayers())
+		}
+		if db.Metadata() != tables[0].Metadata() {
+			return nil, fmt.Errorf("tables have different objectives: %v and %v",
+				db.Metadata(), tables[0].Metadata())
+		}
+	}
+	for player := len(tables); player < maxNumPlayers; player++ {
+		if rules.PlayerDieWeights[player] != (DieWeights{}) {
+			return nil, fmt.Errorf("rules give dice to player %d of a %d-player game",
+				player+1, len(tables))
+		}
+	}
+
+	return &PlayerDice{Tables: tables}, nil
+}
+
+// Recalculate the value of all states in the given iterator, in all tables.
+func (pd *PlayerDice) UpdateAll(states iter.Seq2[uint64, GameState], chkpntPath string) UpdateStats {
+	return pd.UpdateAllWithOptions(states, UpdateOptions{CheckpointPath: chkpntPath})
+}
+
+// As UpdateAll, with the given options.
+func (pd *PlayerDice) UpdateAllWithOptions(states iter.Seq2[uint64, GameState], opts UpdateOptions) UpdateStats {
+	tables := make([]valueTable, len(pd.Tables))
+	for k, db := range pd.Tables {
+		tables[k] = valueTable{
+			db: db,
+			value: func(state GameState) [maxNumPlayers]float64 {
+				return pd.calcStateValue(state, k)
+			},
+		}
+	}
+
+	return updateTables(tables, states, opts)
+}
+
+// The value of the given state, in which player k is to move and rolls
+// their own dice.
+func (pd *PlayerDice) calcStateValue(state GameState, k int) [maxNumPlayers]float64 {
+	db := pd.Tables[k]
+	nextDB := pd.Tables[(k+1)%len(pd.Tables)]
+	probs := playerRollProbs[k][state.NumDiceToRoll]
+
+	var pWin [maxNumPlayers]float64
+	for i, wRoll := range allRolls[state.NumDiceToRoll] {
+		_, pSubgame := selectAction(state, wRoll.ID, db, nextDB)
+		mixInto(&pWin, probs[i], &pSubgame)
+	}
+
+	return pWin
+}
+
+// The value of the given state, in which player k is to move, relative to
+// that player.
+func (pd *PlayerDice) Value(state GameState, k int) [maxNumPlayers]float64 {
+	if state.IsGameOver() {
+		return calcEndGameValue(state, pd.Tables[0].Metadata())
+	}
+	return pd.Tables[k].Get(state.ID())
+}
+
+// Select the optimal action for player k after the given roll.
+func (pd *PlayerDice) SelectAction(state GameState, k int, roll Roll) (Action, [maxNumPlayers]float64) {
+	return selectAction(state, GetRollID(roll), pd.Tables[k], pd.Tables[(k+1)%len(pd.Tables)])
+}
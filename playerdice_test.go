@@ -0,0 +1,64 @@
+package farkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Solve the game with the player dice of the rules by value iteration, to
+// the given tolerance, starting from the values of the given database.
+func solvePlayerDice(t *testing.T, states []depthState, initial DB, tolerance float64) *PlayerDice {
+	t.Helper()
+	tables := []DB{NewInMemoryDB(2), NewInMemoryDB(2)}
+	for _, ds := range states {
+		for _, db := range tables {
+			db.Put(ds.state.ID(), initial.Get(ds.state.ID()))
+		}
+	}
+	pd, err := NewPlayerDice(tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 1000 {
+		if pd.UpdateAll(depthStates(states), "").Total().MaxChange < tolerance {
+			return pd
+		}
+	}
+	t.Fatal("value iteration did not converge")
+	return nil
+}
+
+// Players with the same dice play the symmetric game, whichever dice they
+// are, and a player with loaded dice wins more often.
+func TestPlayerDice(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves two-player games")
+	}
+	setTestRules(t, "pocket-farkle,target=50,dice=2,weights=2:1:1:1:1:1")
+	states := miniatureGameStates(t, 2)
+	symmetric := NewInMemoryDB(2)
+	SolveExact(symmetric, "")
+
+	setTestRules(t, "pocket-farkle,target=50,dice=2,player1_weights=2:1:1:1:1:1,player2_weights=2:1:1:1:1:1")
+	pd := solvePlayerDice(t, states, symmetric, 1e-11)
+	for k, db := range pd.Tables {
+		checkValues(t, fmt.Sprintf("equal player dice, player %d to move", k+1), states, db, symmetric, 1e-9)
+	}
+
+	setTestRules(t, "pocket-farkle,target=50,dice=2")
+	initialState := NewGameState(2)
+	fair := NewInMemoryDB(2)
+	SolveExact(fair, "")
+
+	setTestRules(t, "pocket-farkle,target=50,dice=2,player1_weights=3:1:1:1:1:1")
+	pd = solvePlayerDice(t, states, fair, 1e-4)
+	fairValue := fair.Get(initialState.ID())
+	for k := range 2 {
+		// The value of the first player, whose dice are loaded, when player k is to move.
+		got := pd.Value(initialState, k)[k]
+		if got <= fairValue[k] {
+			t.Errorf("player 1 with loaded dice has value %v when player %d is to move, want more than %v",
+				got, k+1, fairValue[k])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package farkle
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Kinds of decisions to practice, see RandomPosition.
+type PracticeCategory int
+
+const (
+	// The next player has reached the score to win, so this is the last turn.
+	FinalTurnPractice PracticeCategory = iota
+	// One of the legal actions leaves a single die to roll.
+	OneDiePractice
+	// The current player has not yet opened.
+	OpeningPractice
+)
+
+var practiceCategoryNames = map[PracticeCategory]string{
+	FinalTurnPractice: "final",
+	OneDiePractice:    "one-die",
+	OpeningPractice:   "opening",
+}
+
+func (c PracticeCategory) String() string {
+	if name, ok := practiceCategoryNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("PracticeCategory(%d)", int(c))
+}
+
+func ParsePracticeCategory(name string) (PracticeCategory, error) {
+	for c, cName := range practiceCategoryNames {
+		if cName == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown practice category: %q", name)
+}
+
+// A decision: the state of the game and the roll to respond to.
+type Position struct {
+	State GameState `json:"state"`
+	Roll  Roll      `json:"roll"`
+}
+
+// A random position in the given category with more than one legal action.
+func RandomPosition(category PracticeCategory, numPlayers int, rng *rand.Rand) (Position, error) {
+	if numPlayers < 2 || numPlayers > maxNumPlayers {
+		return Position{}, fmt.Errorf("practice positions require 2 to %d players, got %d",
+			maxNumPlayers, numPlayers)
+	}
+	if _, ok := practiceCategoryNames[category]; !ok {
+		return Position{}, fmt.Errorf("unknown practice category: %v", category)
+	}
+	if category == OpeningPractice && openingScore == 0 {
+		return Position{}, fmt.Errorf("there is no opening score in these rules")
+	}
+
+	for {
+		state := NewGameState(numPlayers)
+		for i := range state.PlayerScores[:numPlayers] {
+			state.PlayerScores[i] = randomOpenScore(rng)
+		}
+		state.NumDiceToRoll = uint8(1 + rng.Intn(int(turnNumDice)))
+		state.ScoreThisRound = uint8(rng.Intn(30))
+
+		switch category {
+		case FinalTurnPractice:
+			state.PlayerScores[1] = uint8(min(int(scoreToWin)+rng.Intn(40), math.MaxUint8))
+			for i := 2; i < numPlayers; i++ {
+				state.PlayerScores[i] = min(state.PlayerScores[i], state.PlayerScores[1])
+			}
+			// Up to a little more than needed to take the lead.
+			deficit := int(state.PlayerScores[1]) - int(state.PlayerScores[0])
+			state.ScoreThisRound = uint8(rng.Intn(deficit + 10))
+		case OneDiePractice:
+			state.NumDiceToRoll = uint8(2 + rng.Intn(int(turnNumDice)-1))
+		case OpeningPractice:
+			state.PlayerScores[0] = 0
+			state.ScoreThisRound = uint8(rng.Intn(int(openingScore)))
+		}
+
+		roll := rollDice(int(state.NumDiceToRoll), rng.Intn)
+		actions := LegalActions(state, roll)
+		if len(actions) < 2 {
+			continue
+		}
+		if category == OneDiePractice && !leavesOneDie(state, actions) {
+			continue
+		}
+
+		return Position{State: state, Roll: roll}, nil
+	}
+}
+
+// A random score of a player who has opened.
+func randomOpenScore(rng *rand.Rand) uint8 {
+	return openingScore + uint8(rng.Intn(int(scoreToWin-openingScore)))
+}
+
+// Whether any of the given actions holds all but one of the dice.
+func leavesOneDie(state GameState, actions []Action) bool {
+	for _, action := range actions {
+		if action.HeldDice().NumDice() == state.NumDiceToRoll-1 {
+			return true
+		}
+	}
+	return false
+}
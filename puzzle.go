@@ -0,0 +1,129 @@
+package farkle
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A position in which the best action is much better than any other,
+// yet counter-intuitive. Puzzles are stored as JSON lines, and can be
+// read as positions with ReadPositions.
+type Puzzle struct {
+	Position
+	// The best action, and its value for the current player.
+	Best      Action  `json:"best"`
+	BestValue float64 `json:"bestValue"`
+	// The difference in value between the best and second-best actions.
+	Margin float64 `json:"margin"`
+	// Why the best action is counter-intuitive.
+	Reason string `json:"reason"`
+}
+
+// The puzzle in the given position, if the best action is counter-intuitive
+// and better than every other action by at least minMargin.
+func FindPuzzle(pos Position, db DB, minMargin float64) (Puzzle, bool) {
+	actions := LegalActions(pos.State, pos.Roll)
+	if len(actions) < 2 {
+		return Puzzle{}, false
+	}
+
+	var best Action
+	bestValue, secondValue := -1.0, -1.0
+	for _, action := range actions {
+		value := EvaluateAction(pos.State, action, db)[0]
+		if value > bestValue {
+			best, bestValue, secondValue = action, value, bestValue
+		} else if value > secondValue {
+			secondValue = value
+		}
+	}
+	if bestValue-secondValue < minMargin {
+		return Puzzle{}, false
+	}
+
+	reason, ok := counterIntuitiveReason(pos, best, actions)
+	if !ok {
+		return Puzzle{}, false
+	}
+
+	return Puzzle{
+		Position:  pos,
+		Best:      best,
+		BestValue: bestValue,
+		Margin:    bestValue - secondValue,
+		Reason:    reason,
+	}, true
+}
+
+// Why most players would not choose the given action, if they would not.
+func counterIntuitiveReason(pos Position, action Action, actions []Action) (string, bool) {
+	held := action.HeldDice()
+	numDiceLeft := pos.State.NumDiceToRoll - held.NumDice()
+	maxScore := uint8(0)
+	for _, a := range actions {
+		maxScore = max(maxScore, CalculateScore(a.HeldDice()))
+	}
+
+	switch {
+	case action.ContinueRolling && numDiceLeft == 1 && !mustContinue(pos.State, held):
+		return "continue with one die", true
+	case CalculateScore(held) < maxScore:
+		return "leave scoring dice", true
+	case !action.ContinueRolling && numDiceLeft >= 4 && !winsFinalTurn(pos.State, held):
+		return fmt.Sprintf("stop with %d dice", numDiceLeft), true
+	}
+	return "", false
+}
+
+// Whether stopping after holding the given dice takes the lead on the last turn,
+// which is obviously right.
+func winsFinalTurn(state GameState, held Roll) bool {
+	return state.PlayerScores[1] >= scoreToWin && totalAfterHold(state, held) >= int(state.HighestScore())
+}
+
+// Whether the current player cannot (or obviously should not) stop after
+// holding the given dice: they have not opened, or would lose on the last turn.
+func mustContinue(state GameState, held Roll) bool {
+	turnScore := int(state.ScoreThisRound) + int(CalculateScore(held))
+	if state.PlayerScores[0] == 0 && turnScore < int(openingScore) {
+		return true
+	}
+	return state.PlayerScores[1] >= scoreToWin && totalAfterHold(state, held) < int(state.HighestScore())
+}
+
+// The current player's score if they stop after holding the given dice.
+func totalAfterHold(state GameState, held Roll) int {
+	return int(state.PlayerScores[0]) + int(state.ScoreThisRound) + int(CalculateScore(held))
+}
+
+// Write puzzles as JSON lines.
+func WritePuzzles(w io.Writer, puzzles []Puzzle) error {
+	enc := json.NewEncoder(w)
+	for _, puzzle := range puzzles {
+		if err := enc.Encode(puzzle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read positions stored as JSON lines, e.g. a puzzle pack written by WritePuzzles.
+func ReadPositions(r io.Reader) ([]Position, error) {
+	var result []Position
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var pos Position
+		if err := dec.Decode(&pos); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return result, err
+		}
+
+		result = append(result, pos)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package farkle
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// A database file opened read-only that can be replaced by a newer version,
+// e.g. after more value iteration cycles, while it is in use. Replace the file
+// by renaming the new one over it, rather than copying into it, and then call
+// Reload: the file is opened again, and the old version is closed once
+// nothing reads it. Safe for concurrent use.
+//
+// Each Get reads the current version, so a computation that reads many
+// values, such as SelectAction, may read some from each version if the
+// database is reloaded while it runs. To read them all from one, use the
+// database returned by Acquire (or AcquireDB). DBAdvisor, ActionCache and
+// OptimalStrategy do so for each call.
+type ReloadableDB struct {
+	path       string
+	numPlayers int
+
+	mx      sync.RWMutex
+	current *reloadableVersion
+}
+
+// A version of the file, which is closed once it has been replaced and
+// released by everything that acquired it.
+type reloadableVersion struct {
+	db *FileDB
+	// One reference while it is the current version, and one for each
+	// Acquire that has not been released.
+	refs atomic.Int64
+}
+
+func (v *reloadableVersion) release() error {
+	if v.refs.Add(-1) == 0 {
+		return v.db.Close()
+	}
+	return nil
+}
+
+func OpenReloadableDB(path string, numPlayers int) (*ReloadableDB, error) {
+	db, err := openReloadableFile(path, numPlayers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReloadableDB{
+		path:       path,
+		numPlayers: numPlayers,
+		current:    &reloadableVersion{db: db},
+	}
+	result.current.refs.Store(1)
+	return result, nil
+}
+
+func openReloadableFile(path string, numPlayers int) (*FileDB, error) {
+	db, err := OpenFileDBReadOnly(path, numPlayers)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckRules(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Open the file again and switch to it. The new version must have the same
+// metadata, or else the old version is kept and an error returned.
+func (db *ReloadableDB) Reload() error {
+	newDB, err := openReloadableFile(db.path, db.numPlayers)
+	if err != nil {
+		return err
+	}
+	if newDB.Metadata() != db.Metadata() {
+		newDB.Close()
+		return fmt.Errorf("%s is now a %v database, not %v", db.path, newDB.Metadata(), db.Metadata())
+	}
+
+	version := &reloadableVersion{db: newDB}
+	version.refs.Store(1)
+	db.mx.Lock()
+	oldVersion := db.current
+	db.current = version
+	db.mx.Unlock()
+
+	Logger().Info("Reloaded database", "path", db.path)
+	return oldVersion.release()
+}
+
+// The current version of the database, which is not closed by Reload or
+// Close until release is called, so that its values do not change.
+func (db *ReloadableDB) Acquire() (DB, func()) {
+	db.mx.RLock()
+	version := db.current
+	version.refs.Add(1)
+	db.mx.RUnlock()
+
+	return version.db, func() {
+		if err := version.release(); err != nil {
+			Logger().Warn("Unable to close old version of database", "path", db.path, "err", err)
+		}
+	}
+}
+
+// Databases whose values may change between reads, which can be acquired in
+// a state in which they do not.
+type acquirableDB interface {
+	Acquire() (DB, func())
+}
+
+// A view of db whose values do not change until release is called: the
+// current version of a ReloadableDB, or db itself.
+func AcquireDB(db DB) (DB, func()) {
+	if a, ok := db.(acquirableDB); ok {
+		return a.Acquire()
+	}
+	return db, func() {}
+}
+
+func (db *ReloadableDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db *ReloadableDB) Metadata() Metadata {
+	db.mx.RLock()
+	defer db.mx.RUnlock()
+	return db.current.db.Metadata()
+}
+
+func (db *ReloadableDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	panic(fmt.Errorf("put into read-only database %s", db.path))
+}
+
+func (db *ReloadableDB) Get(gsID int) [maxNumPlayers]float64 {
+	db.mx.RLock()
+	defer db.mx.RUnlock()
+	return db.current.db.Get(gsID)
+}
+
+// Close the database, once everything that acquired it has released it.
+func (db *ReloadableDB) Close() error {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+	return db.current.release()
+}
+
+func (db *ReloadableDB) safeForConcurrentUse() bool {
+	return true
+}
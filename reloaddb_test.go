@@ -0,0 +1,67 @@
+package farkle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A database acquired before a reload keeps the values of its version, which
+// is closed once it is released, while Get reads the new version.
+func TestReloadableDBAcquire(t *testing.T) {
+	setTestRules(t, "standard")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "1player.db")
+	writeTestFileDB(t, path, 1.5)
+
+	db, err := OpenReloadableDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	oldVersion := db.current
+	acquired, release := AcquireDB(db)
+
+	newPath := filepath.Join(dir, "new.db")
+	writeTestFileDB(t, newPath, 2.5)
+	if err := os.Rename(newPath, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := acquired.Get(1); got[0] != 1.5 {
+		t.Errorf("acquired value = %v, want 1.5", got[0])
+	}
+	if got := db.Get(1); got[0] != 2.5 {
+		t.Errorf("value after reload = %v, want 2.5", got[0])
+	}
+	if refs := oldVersion.refs.Load(); refs != 1 {
+		t.Errorf("old version has %d references before release, want 1", refs)
+	}
+	release()
+	if refs := oldVersion.refs.Load(); refs != 0 {
+		t.Errorf("old version has %d references after release, want 0", refs)
+	}
+
+	// Databases that are not reloadable are their own view.
+	memDB := NewInMemoryDB(1)
+	if got, release := AcquireDB(memDB); got != DB(memDB) {
+		t.Errorf("AcquireDB(%T) = %T", memDB, got)
+	} else {
+		release()
+	}
+}
+
+func writeTestFileDB(t *testing.T, path string, value float64) {
+	t.Helper()
+	db, err := NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put(1, [maxNumPlayers]float64{value})
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,385 @@
+package farkle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+)
+
+// Protocol spoken between RemoteDB and ServeDB. All integers are little-endian.
+//
+// On connecting, the client sends remoteDBMagic and remoteDBProtocolVersion
+// (uint32), and the server replies with the number of players (uint32) and the
+// metadata (see metadataSize) of its database. Then the client sends requests:
+//
+//	'G' gsID (uint64)                         -> numPlayers values (float64)
+//	'P' n (uint32) n*(gsID, numPlayers values) -> 'K'
+//
+// Batches of puts may hold at most remotePutBatchSize states.
+const (
+	remoteDBMagic           = "FARKLERM"
+	remoteDBProtocolVersion = 2
+
+	remoteGet byte = 'G'
+	remotePut byte = 'P'
+	remoteAck byte = 'K'
+
+	// RemoteDB sends puts to the server in batches of this many states.
+	remotePutBatchSize = 4096
+)
+
+// DB that stores results in a database on another machine, served by ServeDB,
+// so that solvers on several machines can share one database. Puts are sent to
+// the server in batches, or on Close. Values read from the server are cached
+// until the next batch is sent, so they may not reflect the latest puts from
+// other clients, which only slows the convergence of value iteration.
+// It is safe for concurrent use, but any errors communicating with the server panic.
+type RemoteDB struct {
+	numPlayers int
+	meta       Metadata
+
+	mx      sync.Mutex
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	pending map[int][maxNumPlayers]float64
+	cache   map[int][maxNumPlayers]float64
+}
+
+// Connect to the database served at the given address.
+func DialRemoteDB(addr string) (*RemoteDB, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &RemoteDB{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		w:       bufio.NewWriter(conn),
+		pending: make(map[int][maxNumPlayers]float64),
+		cache:   make(map[int][maxNumPlayers]float64),
+	}
+	if err := db.handshake(); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+	if err := CheckRules(db); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+
+	return db, nil
+}
+
+func (db *RemoteDB) handshake() error {
+	buf := make([]byte, 4+metadataSize)
+	copy(buf, remoteDBMagic)
+	binary.LittleEndian.PutUint32(buf[8:], remoteDBProtocolVersion)
+	if _, err := db.w.Write(buf[:12]); err != nil {
+		return err
+	}
+	if err := db.w.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(db.r, buf); err != nil {
+		return err
+	}
+	db.numPlayers = int(binary.LittleEndian.Uint32(buf))
+	meta, err := decodeMetadata(buf[4 : 4+metadataSize])
+	if err != nil {
+		return err
+	}
+	db.meta = meta
+	if db.numPlayers < 1 || db.numPlayers > maxNumPlayers {
+		return fmt.Errorf("invalid number of players: %d", db.numPlayers)
+	}
+	return nil
+}
+
+func (db *RemoteDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db *RemoteDB) safeForConcurrentUse() bool {
+	return true
+}
+
+func (db *RemoteDB) Metadata() Metadata {
+	return db.meta
+}
+
+func (db *RemoteDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+
+	db.pending[gsID] = pWin
+	if len(db.pending) >= remotePutBatchSize {
+		if err := db.flush(); err != nil {
+			panic(fmt.Errorf("error writing to remote database: %w", err))
+		}
+	}
+}
+
+func (db *RemoteDB) Get(gsID int) [maxNumPlayers]float64 {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+
+	if pWin, ok := db.pending[gsID]; ok {
+		return pWin
+	} else if pWin, ok := db.cache[gsID]; ok {
+		return pWin
+	}
+
+	pWin, err := db.get(gsID)
+	if err != nil {
+		panic(fmt.Errorf("error reading from remote database: %w", err))
+	}
+	db.cache[gsID] = pWin
+	return pWin
+}
+
+func (db *RemoteDB) get(gsID int) ([maxNumPlayers]float64, error) {
+	var result [maxNumPlayers]float64
+	buf := make([]byte, 9)
+	buf[0] = remoteGet
+	binary.LittleEndian.PutUint64(buf[1:], uint64(gsID))
+	if _, err := db.w.Write(buf); err != nil {
+		return result, err
+	}
+	if err := db.w.Flush(); err != nil {
+		return result, err
+	}
+
+	for i := range result[:db.numPlayers] {
+		if _, err := io.ReadFull(db.r, buf[:8]); err != nil {
+			return result, err
+		}
+		result[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+	}
+	return result, nil
+}
+
+// Read the values of the given states that are not cached, sending all of
+// the requests before reading any of the replies.
+func (db *RemoteDB) prefetch(gsIDs []int) {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+
+	var missing []int
+	for _, gsID := range gsIDs {
+		_, pending := db.pending[gsID]
+		_, cached := db.cache[gsID]
+		if !pending && !cached {
+			missing = append(missing, gsID)
+		}
+	}
+
+	if err := db.getAll(missing); err != nil {
+		panic(fmt.Errorf("error reading from remote database: %w", err))
+	}
+}
+
+// Read the values of the given states into the cache.
+func (db *RemoteDB) getAll(gsIDs []int) error {
+	buf := make([]byte, 9)
+	buf[0] = remoteGet
+	for _, gsID := range gsIDs {
+		binary.LittleEndian.PutUint64(buf[1:], uint64(gsID))
+		if _, err := db.w.Write(buf); err != nil {
+			return err
+		}
+	}
+	if err := db.w.Flush(); err != nil {
+		return err
+	}
+
+	for _, gsID := range gsIDs {
+		var pWin [maxNumPlayers]float64
+		for i := range pWin[:db.numPlayers] {
+			if _, err := io.ReadFull(db.r, buf[:8]); err != nil {
+				return err
+			}
+			pWin[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+		}
+		db.cache[gsID] = pWin
+	}
+	return nil
+}
+
+// Send all pending puts to the server, and wait for it to store them.
+func (db *RemoteDB) flush() error {
+	if len(db.pending) == 0 {
+		return nil
+	}
+
+	buf := make([]byte, 8)
+	if err := db.w.WriteByte(remotePut); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(buf, uint32(len(db.pending)))
+	if _, err := db.w.Write(buf[:4]); err != nil {
+		return err
+	}
+	for gsID, pWin := range db.pending {
+		binary.LittleEndian.PutUint64(buf, uint64(gsID))
+		if _, err := db.w.Write(buf); err != nil {
+			return err
+		}
+		if _, err := db.w.Write(encodeValue(pWin[:db.numPlayers])); err != nil {
+			return err
+		}
+	}
+	if err := db.w.Flush(); err != nil {
+		return err
+	}
+
+	if ack, err := db.r.ReadByte(); err != nil {
+		return err
+	} else if ack != remoteAck {
+		return fmt.Errorf("unexpected reply from server: %q", ack)
+	}
+
+	clear(db.pending)
+	clear(db.cache)
+	return nil
+}
+
+func (db *RemoteDB) Close() error {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+	defer db.conn.Close()
+
+	if err := db.flush(); err != nil {
+		return err
+	}
+
+	return db.conn.Close()
+}
+
+// Serve the given database to RemoteDB clients connecting to l,
+// until l is closed.
+func ServeDB(l net.Listener, db DB) error {
+	var mx sync.RWMutex
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := serveRemoteDB(conn, db, &mx); err != nil && !errors.Is(err, io.EOF) {
+				Logger().Warn("Error serving remote database", "addr", conn.RemoteAddr(), "err", err)
+			}
+		}()
+	}
+}
+
+func serveRemoteDB(conn net.Conn, db DB, mx *sync.RWMutex) error {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	numPlayers := db.NumPlayers()
+
+	buf := make([]byte, 4+metadataSize)
+	if _, err := io.ReadFull(r, buf[:12]); err != nil {
+		return err
+	}
+	if string(buf[:8]) != remoteDBMagic {
+		return fmt.Errorf("not a farkle database client")
+	}
+	if version := binary.LittleEndian.Uint32(buf[8:]); version != remoteDBProtocolVersion {
+		return fmt.Errorf("unsupported protocol version: %d", version)
+	}
+
+	meta := db.Metadata()
+	binary.LittleEndian.PutUint32(buf, uint32(numPlayers))
+	encodeMetadata(buf[4:4+metadataSize], meta)
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	numStates := calcNumDistinctStates(numPlayers)
+	readID := func() (int, error) {
+		if _, err := io.ReadFull(r, buf[:8]); err != nil {
+			return 0, err
+		}
+		gsID := binary.LittleEndian.Uint64(buf)
+		if gsID >= uint64(numStates) {
+			return 0, fmt.Errorf("invalid game state: %d", gsID)
+		}
+		return int(gsID), nil
+	}
+
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case remoteGet:
+			gsID, err := readID()
+			if err != nil {
+				return err
+			}
+
+			mx.RLock()
+			pWin := db.Get(gsID)
+			mx.RUnlock()
+			if _, err := w.Write(encodeValue(pWin[:numPlayers])); err != nil {
+				return err
+			}
+		case remotePut:
+			if _, err := io.ReadFull(r, buf[:4]); err != nil {
+				return err
+			}
+			n := int(binary.LittleEndian.Uint32(buf))
+			if n > remotePutBatchSize {
+				return fmt.Errorf("batch of %d puts is larger than %d", n, remotePutBatchSize)
+			}
+			ids := make([]int, n)
+			values := make([][maxNumPlayers]float64, n)
+			for i := range ids {
+				if ids[i], err = readID(); err != nil {
+					return err
+				}
+				for j := range values[i][:numPlayers] {
+					if _, err := io.ReadFull(r, buf[:8]); err != nil {
+						return err
+					}
+					values[i][j] = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+				}
+			}
+
+			mx.Lock()
+			for i, gsID := range ids {
+				db.Put(gsID, values[i])
+			}
+			mx.Unlock()
+			if err := w.WriteByte(remoteAck); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown request: %q", op)
+		}
+
+		// Only flush once there are no more pipelined requests to answer.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
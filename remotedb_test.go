@@ -0,0 +1,43 @@
+package farkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// Batches of puts larger than any client sends are rejected before the server
+// allocates room for them.
+func TestServeDBRejectsLargeBatch(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go ServeDB(l, NewInMemoryDB(1))
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	hello := binary.LittleEndian.AppendUint32([]byte(remoteDBMagic), remoteDBProtocolVersion)
+	if _, err := conn.Write(hello); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, 4+metadataSize)); err != nil {
+		t.Fatal(err)
+	}
+	put := binary.LittleEndian.AppendUint32([]byte{remotePut}, 1<<32-1)
+	if _, err := conn.Write(put); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("server replied to a batch of %d puts: %v", uint32(1<<32-1), err)
+	}
+}
@@ -0,0 +1,88 @@
+package farkle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// A single roll in a recorded game, and the action taken in response.
+// Replays are stored as JSON lines, with one ReplayEvent per roll.
+type ReplayEvent struct {
+	// Turn number, starting from 0, incremented each time a player banks or farkles.
+	Turn int `json:"turn"`
+	// Seat of the player who rolled. Seat 0 is the player who went first.
+	Seat int `json:"seat"`
+	// Game state before the roll, from the point of view of the current player.
+	State  GameState `json:"state"`
+	Roll   Roll      `json:"roll"`
+	Action Action    `json:"action"`
+	// Game state after applying the action.
+	Result GameState `json:"result"`
+}
+
+// Records a game as it is played.
+type ReplayWriter struct {
+	enc  *json.Encoder
+	turn int
+	seat int
+	// The first error from Observe.
+	err error
+}
+
+func NewReplayWriter(w io.Writer) *ReplayWriter {
+	return &ReplayWriter{enc: json.NewEncoder(w)}
+}
+
+// Record a roll and the action taken in response to it.
+func (rw *ReplayWriter) Record(state GameState, roll Roll, action Action) error {
+	event := ReplayEvent{
+		Turn:   rw.turn,
+		Seat:   rw.seat,
+		State:  state,
+		Roll:   roll,
+		Action: action,
+		Result: ApplyAction(state, action),
+	}
+	if err := rw.enc.Encode(event); err != nil {
+		return err
+	}
+
+	if !action.ContinueRolling {
+		rw.turn++
+		rw.seat = (rw.seat + 1) % int(state.NumPlayers)
+	}
+	return nil
+}
+
+// Record a roll and the action taken in response to it, as Record. This is a
+// GameObserver, e.g. to record a game played with PlayGameObserved. The first
+// error is returned by Err, and later events are not recorded.
+func (rw *ReplayWriter) Observe(seat int, state GameState, roll Roll, action Action) {
+	if rw.err == nil {
+		rw.err = rw.Record(state, roll, action)
+	}
+}
+
+// The first error from Observe, if any.
+func (rw *ReplayWriter) Err() error {
+	return rw.err
+}
+
+// Read all events of a recorded game.
+func ReadReplay(r io.Reader) ([]ReplayEvent, error) {
+	var result []ReplayEvent
+	dec := json.NewDecoder(r)
+	for {
+		var event ReplayEvent
+		if err := dec.Decode(&event); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return result, err
+		}
+
+		result = append(result, event)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package farkle
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// A game recorded with ReplayWriter.Observe is read back by ReadReplay with
+// every roll, and the turns and seats follow the game.
+func TestReplayObserve(t *testing.T) {
+	setTestRules(t, "standard")
+	strategies := []Strategy{ThresholdStrategy{BankAt: 300}, ThresholdStrategy{BankAt: 1000}}
+	rng := rand.New(rand.NewSource(benchSeed))
+	for range 10 {
+		var buf bytes.Buffer
+		replay := NewReplayWriter(&buf)
+		numRolls := 0
+		result, err := PlayGameObserved(strategies, rng, func(seat int, state GameState, roll Roll, action Action) {
+			numRolls++
+			replay.Observe(seat, state, roll, action)
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if err := replay.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := ReadReplay(&buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(events) != numRolls {
+			t.Fatalf("%d events, want %d", len(events), numRolls)
+		}
+		for i, event := range events {
+			if event.Result != ApplyAction(event.State, event.Action) {
+				t.Fatalf("event %d: result %v, want %v", i, event.Result, ApplyAction(event.State, event.Action))
+			} else if event.Seat != event.Turn%len(strategies) {
+				t.Fatalf("event %d: seat %d in turn %d", i, event.Seat, event.Turn)
+			} else if i > 0 && event.State != events[i-1].Result {
+				t.Fatalf("event %d: state %v, want %v", i, event.State, events[i-1].Result)
+			}
+		}
+		if last := events[len(events)-1]; last.Turn+1 != result.NumTurns {
+			t.Fatalf("%d turns recorded, want %d", last.Turn+1, result.NumTurns)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package farkle
+
+import (
+	"math"
+)
+
+// DB that records the states whose value changes by more than epsilon when
+// they are Put into the underlying database, and treats the states none of
+// whose children changed since they were last updated as decided, so that
+// value iteration skips them (prioritized sweeping). After the first few
+// cycles, only the states near the ones still converging change, so later
+// cycles update far fewer states. The changes to each state are accumulated
+// until they add up to more than epsilon, so a state that changes by a little
+// in every cycle still leads to its parents being updated, and every value
+// converges to within about epsilon of its fixed point. This takes 4 bytes
+// per state, in addition to the underlying database.
+//
+// Call NextCycle after each value iteration cycle. States are only skipped
+// from the second cycle on, since the changes before the first are unknown.
+type ResidualDB struct {
+	DB
+	epsilon float64
+	// The states that changed in this cycle and in the previous one, which
+	// includes the changes to the states reached by farkling (or banking)
+	// after states that lead to them were updated.
+	changed     *bitMask
+	prevChanged *bitMask
+	// The total change to each state since it was last recorded as changed.
+	residual []float32
+}
+
+func NewResidualDB(db DB, epsilon float64) *ResidualDB {
+	n := calcNumDistinctStates(db.NumPlayers())
+	return &ResidualDB{
+		DB:       db,
+		epsilon:  epsilon,
+		changed:  newBitMask(n),
+		residual: make([]float32, n),
+	}
+}
+
+func (db *ResidualDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	prev := db.DB.Get(gsID)
+	change := 0.0
+	for i := range pWin[:db.NumPlayers()] {
+		change = max(change, math.Abs(pWin[i]-prev[i]))
+	}
+	if residual := float64(db.residual[gsID]) + change; residual > db.epsilon {
+		db.changed.Set(gsID)
+		db.residual[gsID] = 0
+	} else {
+		db.residual[gsID] = float32(residual)
+	}
+
+	db.DB.Put(gsID, pWin)
+}
+
+// Whether the value of the given state is final in the underlying database,
+// or none of the states that it leads to have changed since the last cycle.
+func (db *ResidualDB) IsDecided(gsID int) bool {
+	if decided, ok := db.DB.(decidedDB); ok && decided.IsDecided(gsID) {
+		return true
+	}
+	if db.prevChanged == nil {
+		return false
+	}
+
+	return !db.leadsToChange(GameStateFromID(db.NumPlayers(), gsID))
+}
+
+// Whether any of the states that the given state leads to after one roll,
+// as enumerated by recursiveEnumerateStates, have changed.
+func (db *ResidualDB) leadsToChange(state GameState) bool {
+	parts := newStateIDParts(state)
+	for _, wRoll := range allRolls[state.NumDiceToRoll] {
+		potentialActions := rollIDToPotentialActions[wRoll.ID]
+		effects := rollIDToActionEffects[wRoll.ID]
+		for i, action := range potentialActions {
+			if _, newStateID, allowed := parts.takeAction(action, effects[i]); allowed && db.hasChanged(newStateID) {
+				return true
+			}
+		}
+
+		if len(potentialActions) == 0 && db.hasChanged(ApplyAction(state, Action{}).ID()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *ResidualDB) hasChanged(gsID int) bool {
+	return db.changed.IsSet(gsID) || db.prevChanged.IsSet(gsID)
+}
+
+// Start the next value iteration cycle.
+func (db *ResidualDB) NextCycle() {
+	db.prevChanged = db.changed
+	db.changed = newBitMask(calcNumDistinctStates(db.NumPlayers()))
+}
+
+func (db *ResidualDB) prefetch(gsIDs []int) {
+	if p, ok := db.DB.(prefetchDB); ok {
+		p.prefetch(gsIDs)
+	}
+}
@@ -0,0 +1,32 @@
+package farkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Value iteration with prioritized sweeping converges to the same values as
+// value iteration that updates every state in every cycle, within epsilon,
+// even though most states change by less than epsilon in each cycle.
+func TestResidualDB(t *testing.T) {
+	setTestRules(t, "standard,target=300,dice=3,opening=100")
+	states := miniatureGameStates(t, 1)
+	want := NewInMemoryDB(1)
+	solveByValueIteration(t, want, states, UpdateOptions{}, 1e-14)
+
+	for _, epsilon := range []float64{1e-3, 1e-6} {
+		t.Run(fmt.Sprint(epsilon), func(t *testing.T) {
+			db := NewResidualDB(NewInMemoryDB(1), epsilon)
+			numUpdated := updateStates(db, states, UpdateOptions{}).Total().NumStates
+			db.NextCycle()
+			for cycle := 1; numUpdated > 0; cycle++ {
+				if cycle == 1000 {
+					t.Fatalf("%d states were still updated after %d cycles", numUpdated, cycle)
+				}
+				numUpdated = updateStates(db, states, UpdateOptions{}).Total().NumStates
+				db.NextCycle()
+			}
+			checkValues(t, "prioritized sweeping", states, db, want, epsilon)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package farkle
+
+import (
+	"fmt"
+	"iter"
+	"math"
+)
+
+// A change of the score to win from a database solved before to the rules in
+// effect, which are otherwise the same. Most states of the game to the new
+// target are states of the game to the old target with every score shifted by
+// the difference, and have the same value, so only the others need to be
+// solved again. Those are the states with an unopened player, whose score
+// would no longer be zero, and states whose scores would be shifted past the
+// cap of 12,750 points or below zero.
+//
+// Shifted values are exact, except in the rare games that reach the cap from
+// a shifted state. A full solve of the seeded database removes those errors,
+// in fewer cycles than from scratch.
+type Retarget struct {
+	src DB
+	// The old target minus the new target, in units of incr.
+	shift int
+}
+
+// Prepare to solve the game with the rules in effect from src, which was
+// solved with the same rules and objective, except for a target of oldTarget.
+func NewRetarget(src DB, oldTarget int) (*Retarget, error) {
+	if oldTarget <= 0 || oldTarget%incr != 0 || oldTarget > incr*math.MaxUint8 {
+		return nil, fmt.Errorf("invalid target: %d", oldTarget)
+	}
+	oldRules := CurrentRules()
+	oldRules.TargetScore = oldTarget
+	if solved := src.Metadata().Rules; solved != oldRules.Fingerprint() {
+		return nil, fmt.Errorf("database was solved with %v rules, not the rules in effect with a target of %d (%v)",
+			solved, oldTarget, oldRules.Fingerprint())
+	}
+
+	return &Retarget{src: src, shift: oldTarget/incr - int(scoreToWin)}, nil
+}
+
+// The state of the game to the old target with the same value as state, or
+// false if there is none and the state must be solved again.
+func (r *Retarget) Translate(state GameState) (GameState, bool) {
+	if r.shift == 0 {
+		return state, true
+	}
+
+	for i, score := range state.PlayerScores[:state.NumPlayers] {
+		shifted := int(score) + r.shift
+		if openingScore > 0 && (score == 0 || shifted < int(openingScore)) {
+			// The player has opened in only one of the games, and scores
+			// below the opening score are unreachable, so are not solved.
+			return state, false
+		} else if shifted < 0 || max(int(score), shifted) >= math.MaxUint8 {
+			return state, false // The score is capped in one of the games.
+		}
+		state.PlayerScores[i] = uint8(shifted)
+	}
+
+	// Banking the turn score must not reach the cap in either game.
+	oldTotal := int(state.PlayerScores[0]) + int(state.ScoreThisRound)
+	if max(oldTotal, oldTotal-r.shift) > math.MaxUint8 {
+		return state, false
+	}
+	return state, true
+}
+
+// Store the values from the old database of the given states, e.g. the sorted
+// game states, that have one, in db. The others keep their initial value.
+// Returns the number of states that must be solved again.
+func (r *Retarget) Seed(db DB, states iter.Seq2[uint64, GameState]) int {
+	n, numAffected := 0, 0
+	for _, state := range states {
+		if n%100000000 == 0 {
+			Logger().Info("Seeded game states", "count", n)
+		}
+		n++
+
+		old, ok := r.Translate(state)
+		if !ok {
+			numAffected++
+			continue
+		}
+		db.Put(state.ID(), r.src.Get(old.ID()))
+	}
+	return numAffected
+}
+
+// The states that must be solved again, of the given states, e.g. the sorted
+// game states, to solve a database seeded with Seed.
+func (r *Retarget) AffectedStates(states iter.Seq2[uint64, GameState]) iter.Seq2[uint64, GameState] {
+	return func(yield func(uint64, GameState) bool) {
+		for depth, state := range states {
+			if _, ok := r.Translate(state); ok {
+				continue
+			}
+			if !yield(depth, state) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package farkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Seeding the game to a new target from the game to the old one, and solving
+// only the affected states, gives the values of solving the new game.
+func TestRetarget(t *testing.T) {
+	setTestRules(t, "standard,target=300,dice=3,opening=100")
+	old := NewInMemoryDB(1)
+	solveByValueIteration(t, old, miniatureGameStates(t, 1), UpdateOptions{}, 1e-14)
+
+	for _, target := range []int{200, 300, 400} {
+		t.Run(fmt.Sprint(target), func(t *testing.T) {
+			setTestRules(t, fmt.Sprintf("standard,target=%d,dice=3,opening=100", target))
+			states := miniatureGameStates(t, 1)
+			want := NewInMemoryDB(1)
+			solveByValueIteration(t, want, states, UpdateOptions{}, 1e-14)
+
+			if _, err := NewRetarget(old, 250); err == nil {
+				t.Error("retargeted a database from the wrong target")
+			}
+			r, err := NewRetarget(old, 300)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := NewInMemoryDB(1)
+			numAffected := r.Seed(db, depthStates(states))
+			var affected []depthState
+			for depth, state := range r.AffectedStates(depthStates(states)) {
+				affected = append(affected, depthState{depth, state})
+			}
+			if len(affected) != numAffected {
+				t.Errorf("%d affected states, but Seed found %d", len(affected), numAffected)
+			}
+			if target == 300 && numAffected != 0 {
+				t.Errorf("%d states are affected without a change of target", numAffected)
+			} else if target != 300 && (numAffected == 0 || numAffected == len(states)) {
+				t.Errorf("%d of %d states are affected", numAffected, len(states))
+			}
+
+			solveByValueIteration(t, db, affected, UpdateOptions{}, 1e-14)
+			checkValues(t, "retargeted", states, db, want, 1e-9)
+		})
+	}
+}
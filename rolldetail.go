@@ -0,0 +1,68 @@
+package farkle
+
+import (
+	"fmt"
+)
+
+// A roll with the value of each die in the order it was rolled, for user
+// interfaces that show individual dice, e.g. to animate them, and keep track
+// of which physical dice the player holds.
+type RollDetailed struct {
+	// The value of each die, in the order rolled.
+	Dice []int `json:"dice"`
+}
+
+// Roll the given number of dice, using intn as the source of randomness, in
+// the same way as rollDice.
+func rollDiceDetailed(numDice int, intn func(int) int) RollDetailed {
+	dice := make([]int, numDice)
+	for i := range dice {
+		dice[i] = rollDie(intn)
+	}
+	return RollDetailed{Dice: dice}
+}
+
+// The dice rolled, regardless of order.
+func (r RollDetailed) Roll() Roll {
+	var roll Roll
+	for _, die := range r.Dice {
+		roll[die]++
+	}
+	return roll
+}
+
+// The positions in Dice of the given held dice, taking the first of the dice
+// with each value, in ascending order. Returns an error if the dice were not
+// all rolled.
+func (r RollDetailed) Positions(held Roll) ([]int, error) {
+	var result []int
+	remaining := held
+	for i, die := range r.Dice {
+		if remaining[die] > 0 {
+			remaining[die]--
+			result = append(result, i)
+		}
+	}
+	if remaining.NumDice() > 0 {
+		return nil, fmt.Errorf("%v were not rolled", remaining)
+	}
+	return result, nil
+}
+
+// The held dice at the given positions in Dice, e.g. the dice a player
+// selected in a user interface. Returns an error if a position is out of
+// range or repeated.
+func (r RollDetailed) Held(positions []int) (Roll, error) {
+	var held Roll
+	seen := make([]bool, len(r.Dice))
+	for _, i := range positions {
+		if i < 0 || i >= len(r.Dice) {
+			return Roll{}, fmt.Errorf("no die at position %d of %d", i, len(r.Dice))
+		} else if seen[i] {
+			return Roll{}, fmt.Errorf("die at position %d held twice", i)
+		}
+		seen[i] = true
+		held[r.Dice[i]]++
+	}
+	return held, nil
+}
@@ -0,0 +1,87 @@
+package farkle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How to format a roll as a string with Roll.FormatAs.
+type RollStyle int
+
+const (
+	// The dice in ascending order, e.g. "1155".
+	CompactStyle RollStyle = iota
+	// The number of each die in words, e.g. "two 1s, two 5s".
+	VerboseStyle
+	// Unicode die faces, e.g. "⚀⚀⚄⚄".
+	EmojiStyle
+)
+
+var dieEmoji = [...]rune{'⚀', '⚁', '⚂', '⚃', '⚄', '⚅'}
+
+var countWords = [...]string{"no", "one", "two", "three", "four", "five", "six"}
+
+// Parse a roll from the dice it contains, as digits or Unicode die faces in any
+// order, e.g. "1155", "1 1 5 5", "[1,1,5,5]" or "⚀⚀⚄⚄". Spaces, commas and
+// brackets are ignored, so the output of every RollStyle except VerboseStyle
+// can be parsed.
+func ParseRoll(s string) (Roll, error) {
+	var roll Roll
+	numDice := 0
+	for _, c := range s {
+		var die uint8
+		switch {
+		case c >= '1' && c <= '6':
+			die = uint8(c - '0')
+		case c >= dieEmoji[0] && c <= dieEmoji[numSides-1]:
+			die = uint8(c-dieEmoji[0]) + 1
+		case strings.ContainsRune(" \t\r\n,[]()", c):
+			continue
+		default:
+			return Roll{}, fmt.Errorf("not a valid die: '%c'", c)
+		}
+
+		numDice++
+		if numDice > MaxNumDice {
+			return Roll{}, fmt.Errorf("too many dice: %q has more than %d", s, MaxNumDice)
+		}
+		roll[die]++
+	}
+
+	return roll, nil
+}
+
+// Format the dice in this roll in the given style.
+func (r Roll) FormatAs(style RollStyle) string {
+	var sb strings.Builder
+	switch style {
+	case CompactStyle:
+		for _, die := range r.Dice() {
+			sb.WriteByte('0' + die)
+		}
+	case EmojiStyle:
+		for _, die := range r.Dice() {
+			sb.WriteRune(dieEmoji[die-1])
+		}
+	case VerboseStyle:
+		if r.NumDice() == 0 {
+			return "no dice"
+		}
+		for die, count := range r {
+			if count == 0 {
+				continue
+			}
+			if sb.Len() > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%s %d", countWords[count], die)
+			if count > 1 {
+				sb.WriteByte('s')
+			}
+		}
+	default:
+		return r.String()
+	}
+
+	return sb.String()
+}
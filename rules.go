@@ -0,0 +1,529 @@
+package farkle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Which sets of scoring dice may be held from a roll.
+type HoldRule int
+
+const (
+	// The held dice must be a union of tricks, each of which takes all of
+	// the matching dice: e.g. from four 2s, all four must be held.
+	StrictHolds HoldRule = iota
+	// Any of the rolled dice may be held, as long as every held die scores:
+	// e.g. from four 2s, three may be held and the fourth rolled again.
+	LenientHolds
+)
+
+var holdRuleNames = map[HoldRule]string{
+	StrictHolds:  "strict",
+	LenientHolds: "lenient",
+}
+
+func (r HoldRule) String() string {
+	if name, ok := holdRuleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("HoldRule(%d)", int(r))
+}
+
+func ParseHoldRule(name string) (HoldRule, error) {
+	for r, rName := range holdRuleNames {
+		if rName == name {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown hold rule: %q", name)
+}
+
+// How four, five and six of a kind score.
+type MultiplesRule int
+
+const (
+	// Four, five and six of a kind score a flat 1000, 2000 and 3000.
+	FlatMultiples MultiplesRule = iota
+	// Each die beyond three of a kind doubles the score of the three of a
+	// kind: e.g. four 4s score 800, five 4s 1600 and six 4s 3200.
+	DoublingMultiples
+)
+
+var multiplesRuleNames = map[MultiplesRule]string{
+	FlatMultiples:     "flat",
+	DoublingMultiples: "doubling",
+}
+
+func (r MultiplesRule) String() string {
+	if name, ok := multiplesRuleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("MultiplesRule(%d)", int(r))
+}
+
+func ParseMultiplesRule(name string) (MultiplesRule, error) {
+	for r, rName := range multiplesRuleNames {
+		if rName == name {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown multiples rule: %q", name)
+}
+
+// Variations in the rules of the game. Scores are in points,
+// and must be multiples of 50.
+type Rules struct {
+	Holds     HoldRule
+	Multiples MultiplesRule
+	// Whether 1-2-3-4-5 scores 500 and 2-3-4-5-6 scores 750.
+	PartialStraights bool
+	// Whether three pairs, four of a kind plus a pair and two triplets score.
+	SixDiceCombos bool
+	// The score of three 1s.
+	ThreeOnesScore int
+	// The score a player must bank in one turn to get on the board,
+	// or 0 if there is no minimum.
+	OpeningScore int
+	// The score that triggers the final round.
+	TargetScore int
+	// The number of dice rolled at the start of each turn, or 0 for
+	// MaxNumDice. Fewer dice make miniature games that solve quickly, e.g.
+	// to test the solver or to experiment with new rules.
+	NumDice int
+	// The weights of the faces of the dice, for loaded dice such as the
+	// badge dice of Kingdom Come: Deliverance. All of the dice are loaded the
+	// same way. All zero (or all equal) for fair dice.
+	DieWeights DieWeights
+	// The weights of the faces of the dice of each player, in order of play,
+	// for players whose dice differ from DieWeights, e.g. a cheater. All zero
+	// for players with DieWeights. Games in which any player has their own
+	// dice are solved with PlayerDice.
+	PlayerDieWeights [maxNumPlayers]DieWeights
+}
+
+// The number of dice rolled at the start of each turn.
+func (r Rules) TurnDice() int {
+	if r.NumDice == 0 {
+		return MaxNumDice
+	}
+	return r.NumDice
+}
+
+// The dice of the given player, in order of play.
+func (r Rules) PlayerDice(player int) DieWeights {
+	if w := r.PlayerDieWeights[player]; w != (DieWeights{}) {
+		return w
+	}
+	return r.DieWeights
+}
+
+// Whether any player has their own dice.
+func (r Rules) HasPlayerDice() bool {
+	return r.PlayerDieWeights != [maxNumPlayers]DieWeights{}
+}
+
+// The relative weight of each face of a die, from 1 to 6.
+type DieWeights [numSides]float64
+
+// Whether the dice are fair, i.e. every face is equally likely.
+func (w DieWeights) Fair() bool {
+	for _, x := range w[1:] {
+		if x != w[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// The probability of rolling each face of a die, from 1 to 6.
+func (w DieWeights) Probs() [numSides]float64 {
+	var result [numSides]float64
+	if w.Fair() {
+		for i := range result {
+			result[i] = 1.0 / numSides
+		}
+		return result
+	}
+
+	total := 0.0
+	for _, x := range w {
+		total += x
+	}
+	for i, x := range w {
+		result[i] = x / total
+	}
+	return result
+}
+
+func (w DieWeights) validate() error {
+	for face, x := range w {
+		if x < 0 || math.IsInf(x, 0) || math.IsNaN(x) {
+			return fmt.Errorf("weight of face %d must be finite and non-negative, got %v", face+1, x)
+		}
+	}
+	return nil
+}
+
+// The rules of the game as originally implemented.
+var DefaultRules = Rules{
+	Holds:          StrictHolds,
+	Multiples:      FlatMultiples,
+	SixDiceCombos:  true,
+	ThreeOnesScore: 300,
+	OpeningScore:   500,
+	TargetScore:    10000,
+}
+
+// The rules in effect, see SetRules.
+var rules = DefaultRules
+
+// Play and solve games with the given rules, instead of DefaultRules. The
+// rules apply to the whole package, so this must be called before any games
+// are played or solved, and not concurrently with them. Databases solved with
+// one set of rules are not valid for any other.
+func SetRules(r Rules) error {
+	if _, ok := holdRuleNames[r.Holds]; !ok {
+		return fmt.Errorf("unknown hold rule: %v", r.Holds)
+	}
+	if _, ok := multiplesRuleNames[r.Multiples]; !ok {
+		return fmt.Errorf("unknown multiples rule: %v", r.Multiples)
+	}
+	// Six 1s must fit in a turn score, even when doubling.
+	if r.ThreeOnesScore <= 0 || r.ThreeOnesScore > 1500 || r.ThreeOnesScore%incr != 0 {
+		return fmt.Errorf("score of three 1s must be a multiple of %d up to 1500, got %d",
+			incr, r.ThreeOnesScore)
+	}
+	// Players' scores must be able to exceed the target score.
+	if r.TargetScore <= 0 || r.TargetScore > incr*(math.MaxUint8-1) || r.TargetScore%incr != 0 {
+		return fmt.Errorf("target score must be a multiple of %d up to %d, got %d",
+			incr, incr*(math.MaxUint8-1), r.TargetScore)
+	}
+	if r.OpeningScore < 0 || r.OpeningScore >= r.TargetScore || r.OpeningScore%incr != 0 {
+		return fmt.Errorf("opening score must be a multiple of %d below the target score, got %d",
+			incr, r.OpeningScore)
+	}
+	if r.NumDice != 0 && (r.NumDice < 2 || r.NumDice > MaxNumDice) {
+		return fmt.Errorf("number of dice must be from 2 to %d, got %d", MaxNumDice, r.NumDice)
+	}
+	if err := r.DieWeights.validate(); err != nil {
+		return err
+	}
+	for player, w := range r.PlayerDieWeights {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("dice of player %d: %w", player+1, err)
+		}
+	}
+
+	rules = r
+	rulesFingerprint = r.Fingerprint()
+	scoreToWin = uint8(r.TargetScore / incr)
+	openingScore = uint8(r.OpeningScore / incr)
+	turnNumDice = uint8(r.TurnDice())
+	setFaceProbs(r)
+	rollIDToPotentialHolds = calcPotentialHolds()
+	scoreCache = calcScoreCache()
+	rollIDToPotentialActions = calcPotentialActions()
+	rollIDToActionEffects = calcActionEffects()
+	return nil
+}
+
+// The rules in effect, see SetRules.
+func CurrentRules() Rules {
+	return rules
+}
+
+// A named set of rules.
+type Preset int
+
+const (
+	// DefaultRules.
+	StandardPreset Preset = iota
+	// As played on Facebook: any scoring dice may be held, three 1s score
+	// 1000 and there is no opening score.
+	FacebookPreset
+	// As in the Pocket Farkle travel game: any scoring dice may be held and
+	// there is no opening score.
+	PocketFarklePreset
+	// As in the dice game of Kingdom Come: Deliverance: any scoring dice may
+	// be held, three 1s score 1000, four, five and six of a kind double,
+	// partial straights score, six-dice combinations other than the straight
+	// do not, there is no opening score, and the target is 4000.
+	KingdomComePreset
+)
+
+var presetNames = map[Preset]string{
+	StandardPreset:     "standard",
+	FacebookPreset:     "facebook",
+	PocketFarklePreset: "pocket-farkle",
+	KingdomComePreset:  "kingdom-come",
+}
+
+var presetRules = map[Preset]Rules{
+	StandardPreset: DefaultRules,
+	FacebookPreset: {
+		Holds:          LenientHolds,
+		Multiples:      FlatMultiples,
+		SixDiceCombos:  true,
+		ThreeOnesScore: 1000,
+		TargetScore:    10000,
+	},
+	PocketFarklePreset: {
+		Holds:          LenientHolds,
+		Multiples:      FlatMultiples,
+		SixDiceCombos:  true,
+		ThreeOnesScore: 300,
+		TargetScore:    10000,
+	},
+	KingdomComePreset: {
+		Holds:            LenientHolds,
+		Multiples:        DoublingMultiples,
+		PartialStraights: true,
+		ThreeOnesScore:   1000,
+		TargetScore:      4000,
+	},
+}
+
+func (p Preset) String() string {
+	if name, ok := presetNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Preset(%d)", int(p))
+}
+
+func ParsePreset(name string) (Preset, error) {
+	for p, pName := range presetNames {
+		if pName == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown rules: %q", name)
+}
+
+// Parse rules given as the name of a preset, optionally followed by
+// comma-separated overrides of its rules, e.g. "standard,opening=0,target=2000".
+// The rules that may be overridden are holds (strict or lenient), multiples
+// (flat or doubling), partial_straights and six_dice_combos (true or false),
+// three_ones, opening and target (in points), dice, weights (the weight of
+// each face from 1 to 6, separated by colons, e.g. weights=2:1:1:1:1:1), and
+// the weights of the dice of one player, numbered from 1 in order of play,
+// e.g. player2_weights=2:1:1:1:1:1.
+func ParseRules(spec string) (Rules, error) {
+	name, overrides, _ := strings.Cut(spec, ",")
+	preset, err := ParsePreset(name)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	r := preset.Rules()
+	for _, override := range strings.Split(overrides, ",") {
+		if override == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(override, "=")
+		if !ok {
+			return Rules{}, fmt.Errorf("expected RULE=VALUE, got %q", override)
+		}
+
+		switch key {
+		case "holds":
+			r.Holds, err = ParseHoldRule(value)
+		case "multiples":
+			r.Multiples, err = ParseMultiplesRule(value)
+		case "partial_straights":
+			r.PartialStraights, err = strconv.ParseBool(value)
+		case "six_dice_combos":
+			r.SixDiceCombos, err = strconv.ParseBool(value)
+		case "three_ones":
+			r.ThreeOnesScore, err = strconv.Atoi(value)
+		case "opening":
+			r.OpeningScore, err = strconv.Atoi(value)
+		case "target":
+			r.TargetScore, err = strconv.Atoi(value)
+		case "dice":
+			r.NumDice, err = strconv.Atoi(value)
+		case "weights":
+			r.DieWeights, err = parseDieWeights(value)
+		default:
+			player, ok := parsePlayerWeightsKey(key)
+			if !ok {
+				err = fmt.Errorf("unknown rule: %q", key)
+			} else if player < 1 || player > maxNumPlayers {
+				err = fmt.Errorf("player must be from 1 to %d, got %d", maxNumPlayers, player)
+			} else {
+				r.PlayerDieWeights[player-1], err = parseDieWeights(value)
+			}
+		}
+		if err != nil {
+			return Rules{}, fmt.Errorf("%s: %w", override, err)
+		}
+	}
+	return r, nil
+}
+
+// The player numbered in a rule such as player2_weights, if it is one.
+func parsePlayerWeightsKey(key string) (int, bool) {
+	digits, hasPrefix := strings.CutPrefix(key, "player")
+	digits, hasSuffix := strings.CutSuffix(digits, "_weights")
+	player, err := strconv.Atoi(digits)
+	return player, hasPrefix && hasSuffix && err == nil
+}
+
+// Parse the weight of each face of the dice, separated by colons.
+func parseDieWeights(value string) (DieWeights, error) {
+	var result DieWeights
+	fields := strings.Split(value, ":")
+	if len(fields) != numSides {
+		return result, fmt.Errorf("expected %d weights, got %d", numSides, len(fields))
+	}
+	for i, field := range fields {
+		w, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return result, err
+		}
+		result[i] = w
+	}
+	return result, nil
+}
+
+// The rules of the preset.
+func (p Preset) Rules() Rules {
+	return presetRules[p]
+}
+
+// Play and solve games with the rules of the given preset, see SetRules.
+func SetPreset(p Preset) error {
+	if _, ok := presetRules[p]; !ok {
+		return fmt.Errorf("unknown rules: %v", p)
+	}
+	return SetRules(p.Rules())
+}
+
+// The preset of the rules in effect, if they are a preset.
+func CurrentPreset() (Preset, bool) {
+	return rulesFingerprint.Preset()
+}
+
+// A hash of a set of rules, which is stored in databases (see Metadata) to
+// detect databases solved with other rules. The standard rules have
+// fingerprint 0, so that databases written before the rules were
+// configurable have the standard rules.
+type RulesFingerprint uint64
+
+// The fingerprint of the rules in effect.
+var rulesFingerprint RulesFingerprint
+
+func (r Rules) Fingerprint() RulesFingerprint {
+	return hashRules(r) ^ hashRules(DefaultRules)
+}
+
+func hashRules(r Rules) RulesFingerprint {
+	h := fnv.New64a()
+	h.Write(encodeRules(r))
+	return RulesFingerprint(h.Sum64())
+}
+
+// The version of the encoding of rules by encodeRules. Changing the encoding
+// changes the fingerprint of every set of rules, so databases solved before
+// would no longer match their rules.
+const rulesEncodingVersion = 1
+
+// Encode the rules that affect the values of states, for hashing: the version
+// of the encoding, then each rule in the order of the fields of Rules, in
+// little-endian byte order. Equivalent rules encode the same way: the number
+// of dice is that of TurnDice, the dice are encoded by the probability of each
+// face, or by a single zero byte if they are fair, and players without their
+// own dice are encoded with DieWeights.
+func encodeRules(r Rules) []byte {
+	b := []byte{rulesEncodingVersion, byte(r.Holds), byte(r.Multiples)}
+	for _, rule := range []bool{r.PartialStraights, r.SixDiceCombos} {
+		if rule {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	}
+	for _, score := range []int{r.ThreeOnesScore, r.OpeningScore, r.TargetScore} {
+		b = binary.LittleEndian.AppendUint32(b, uint32(score))
+	}
+	b = append(b, byte(r.TurnDice()))
+
+	appendWeights := func(w DieWeights) {
+		if w.Fair() {
+			b = append(b, 0)
+			return
+		}
+		b = append(b, 1)
+		for _, p := range w.Probs() {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p))
+		}
+	}
+	appendWeights(r.DieWeights)
+	for _, w := range r.PlayerDieWeights {
+		if w == (DieWeights{}) {
+			w = r.DieWeights
+		}
+		appendWeights(w)
+	}
+	return b
+}
+
+// The preset with this fingerprint, if any.
+func (f RulesFingerprint) Preset() (Preset, bool) {
+	for p, r := range presetRules {
+		if r.Fingerprint() == f {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func (f RulesFingerprint) String() string {
+	if p, ok := f.Preset(); ok {
+		return p.String()
+	}
+	return fmt.Sprintf("custom(%016x)", uint64(f))
+}
+
+// Check that the database was solved with the rules in effect.
+func CheckRules(db DB) error {
+	if solved := db.Metadata().Rules; solved != rulesFingerprint {
+		return fmt.Errorf("database was solved with %v rules, not %v", solved, rulesFingerprint)
+	}
+	return nil
+}
+
+// The distinct sets of dice that may be held from the given roll under
+// LenientHolds, in order of roll ID: every non-empty subset of the dice in
+// which all of the dice score.
+func lenientHolds(roll Roll) []Roll {
+	var result []Roll
+	var held Roll
+	var visit func(die int)
+	visit = func(die int) {
+		if die > numSides {
+			if held.NumDice() > 0 {
+				if _, _, leftovers := ExplainScore(held); leftovers.NumDice() == 0 {
+					result = append(result, held)
+				}
+			}
+			return
+		}
+
+		for count := uint8(0); count <= roll[die]; count++ {
+			held[die] = count
+			visit(die + 1)
+		}
+		held[die] = 0
+	}
+	visit(1)
+
+	slices.SortFunc(result, func(a, b Roll) int {
+		return int(rollToID[a]) - int(rollToID[b])
+	})
+	return result
+}
@@ -0,0 +1,79 @@
+package farkle
+
+import (
+	"testing"
+)
+
+// The fingerprints of the presets. They change only if the encoding of the
+// rules does, which must also change rulesEncodingVersion.
+var presetFingerprints = map[Preset]RulesFingerprint{
+	StandardPreset:     0,
+	FacebookPreset:     0xe40459c8543ca290,
+	PocketFarklePreset: 0xa2d6d1d07ed8dcea,
+	KingdomComePreset:  0xd23b9da3cf0f2d59,
+}
+
+func TestPresetFingerprints(t *testing.T) {
+	for p, want := range presetFingerprints {
+		if got := p.Rules().Fingerprint(); got != want {
+			t.Errorf("fingerprint of %v = %#x, want %#x", p, uint64(got), uint64(want))
+		}
+		if got, ok := want.Preset(); !ok || got != p {
+			t.Errorf("preset of %#x = %v, want %v", uint64(want), got, p)
+		}
+	}
+}
+
+// Rules that play the same have the same fingerprint, and changing any rule
+// changes it.
+func TestRulesFingerprint(t *testing.T) {
+	for _, equal := range [][]string{
+		{"standard", "standard,dice=6"},
+		{"standard", "standard,weights=1:1:1:1:1:1", "standard,weights=3:3:3:3:3:3"},
+		{"standard,weights=2:1:1:1:1:1", "standard,weights=4:2:2:2:2:2"},
+		{"standard", "standard,player2_weights=1:1:1:1:1:1"},
+		{"standard,weights=2:1:1:1:1:1", "standard,weights=2:1:1:1:1:1,player2_weights=2:1:1:1:1:1"},
+	} {
+		want := parseTestRules(t, equal[0]).Fingerprint()
+		for _, spec := range equal[1:] {
+			if got := parseTestRules(t, spec).Fingerprint(); got != want {
+				t.Errorf("fingerprint of %s = %v, want %v as for %s", spec, got, want, equal[0])
+			}
+		}
+	}
+
+	seen := make(map[RulesFingerprint]string)
+	for _, spec := range []string{
+		"standard",
+		"standard,holds=lenient",
+		"standard,multiples=doubling",
+		"standard,partial_straights=true",
+		"standard,six_dice_combos=false",
+		"standard,three_ones=1000",
+		"standard,opening=0",
+		"standard,target=2000",
+		"standard,dice=5",
+		"standard,weights=2:1:1:1:1:1",
+		"standard,weights=1:2:1:1:1:1",
+		"standard,player1_weights=2:1:1:1:1:1",
+		"standard,player2_weights=2:1:1:1:1:1",
+		"facebook",
+		"pocket-farkle",
+		"kingdom-come",
+	} {
+		f := parseTestRules(t, spec).Fingerprint()
+		if other, ok := seen[f]; ok {
+			t.Errorf("%s and %s have the same fingerprint %v", spec, other, f)
+		}
+		seen[f] = spec
+	}
+}
+
+func parseTestRules(t *testing.T, spec string) Rules {
+	t.Helper()
+	r, err := ParseRules(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
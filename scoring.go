@@ -1,8 +1,26 @@
 package farkle
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
 const numScoreBits = 8
-const incr = 50
-const scoreToWin = 10000 / incr
+
+// Scores are multiples of ScoreIncrement points, and are counted in units of
+// it by GameState and CalculateScore.
+const ScoreIncrement = 50
+const incr = ScoreIncrement
+
+// The score that triggers the final round, see Rules.TargetScore.
+var scoreToWin = uint8(DefaultRules.TargetScore / incr)
+
+// Minimum score that must be banked to get on the board, see Rules.OpeningScore.
+var openingScore = uint8(DefaultRules.OpeningScore / incr)
+
+// Number of dice rolled at the start of each turn, see Rules.NumDice.
+var turnNumDice = uint8(DefaultRules.TurnDice())
 
 type TrickType int
 
@@ -22,6 +40,9 @@ const (
 	ThreePairs
 	FourOfAKindPlusPair
 	TwoTriplets
+	// Partial straights, which only score with Rules.PartialStraights.
+	LowStraight
+	HighStraight
 )
 
 var trickScores = map[TrickType]uint8{
@@ -40,6 +61,35 @@ var trickScores = map[TrickType]uint8{
 	ThreePairs:          1500 / incr,
 	FourOfAKindPlusPair: 1500 / incr,
 	TwoTriplets:         2500 / incr,
+	LowStraight:         500 / incr,
+	HighStraight:        750 / incr,
+}
+
+var trickNames = map[TrickType]string{
+	Single1:             "Single 1",
+	Single5:             "Single 5",
+	Three1s:             "Three 1s",
+	Three2s:             "Three 2s",
+	Three3s:             "Three 3s",
+	Three4s:             "Three 4s",
+	Three5s:             "Three 5s",
+	Three6s:             "Three 6s",
+	FourOfAKind:         "Four of a kind",
+	FiveOfAKind:         "Five of a kind",
+	SixOfAKind:          "Six of a kind",
+	Straight:            "Straight",
+	ThreePairs:          "Three pairs",
+	FourOfAKindPlusPair: "Four of a kind plus a pair",
+	TwoTriplets:         "Two triplets",
+	LowStraight:         "Straight 1-5",
+	HighStraight:        "Straight 2-6",
+}
+
+func (t TrickType) String() string {
+	if name, ok := trickNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TrickType(%d)", int(t))
 }
 
 var threeOfAKind = map[int]TrickType{
@@ -51,6 +101,12 @@ var threeOfAKind = map[int]TrickType{
 	6: Three6s,
 }
 
+// The dice of each partial straight.
+var partialStraights = map[TrickType]Roll{
+	LowStraight:  NewRoll(1, 2, 3, 4, 5),
+	HighStraight: NewRoll(2, 3, 4, 5, 6),
+}
+
 var singles = map[int]TrickType{
 	1: Single1,
 	5: Single5,
@@ -61,99 +117,117 @@ type Trick struct {
 	Dice Roll
 }
 
-func (t Trick) Score() uint8 {
-	return trickScores[t.Type]
+// For DoublingMultiples, the multiple of the three of a kind that each
+// larger set of a kind scores.
+var doublingFactors = map[TrickType]uint8{
+	FourOfAKind: 2,
+	FiveOfAKind: 4,
+	SixOfAKind:  8,
 }
 
-func remainingTricks(roll Roll, trick Trick) [][]Trick {
-	result := [][]Trick{{trick}}
-	remainingDice := SubtractRolls(roll, trick.Dice)
-	for _, addlTricks := range enumeratePossibleTricks(remainingDice) {
-		result = append(result, append([]Trick{trick}, addlTricks...))
+func (t Trick) Score() uint8 {
+	if t.Type == Three1s {
+		return uint8(rules.ThreeOnesScore / incr)
 	}
-	return result
+	if rules.Multiples == DoublingMultiples {
+		if factor, ok := doublingFactors[t.Type]; ok {
+			die := slices.IndexFunc(t.Dice[:], func(count uint8) bool { return count > 0 })
+			triple := Trick{Type: threeOfAKind[die], Dice: RepeatedRoll(uint8(die), 3)}
+			return factor * triple.Score()
+		}
+	}
+	return trickScores[t.Type]
 }
 
+// All sets of tricks that may be scored from the given roll.
 func enumeratePossibleTricks(roll Roll) [][]Trick {
 	var result [][]Trick
+	visitTrickSets(roll, nil, func(tricks []Trick) {
+		result = append(result, slices.Clone(tricks))
+	})
+	return result
+}
+
+// Call f with each set of tricks that may be scored from the given roll,
+// appended to tricks, in the order of enumeratePossibleTricks. The slice
+// passed to f is reused, so f must copy it to keep it. Passing a scratch
+// slice with capacity for MaxNumDice tricks avoids allocating.
+func visitTrickSets(roll Roll, tricks []Trick, f func([]Trick)) {
+	visit := func(trick Trick) {
+		tricks := append(tricks, trick)
+		f(tricks)
+		visitTrickSets(SubtractRolls(roll, trick.Dice), tricks, f)
+	}
+
 	for die, count := range roll {
 		if count >= 1 && (die == 1 || die == 5) {
-			trick := Trick{
+			visit(Trick{
 				Type: singles[die],
 				Dice: NewRoll(uint8(die)),
-			}
-
-			result = append(result, remainingTricks(roll, trick)...)
+			})
 		}
 
 		if count >= 3 {
-			trick := Trick{
+			visit(Trick{
 				Type: threeOfAKind[die],
 				Dice: RepeatedRoll(uint8(die), count),
-			}
-
-			result = append(result, remainingTricks(roll, trick)...)
+			})
 		}
 
 		if count >= 4 {
-			trick := Trick{
+			visit(Trick{
 				Type: FourOfAKind,
 				Dice: RepeatedRoll(uint8(die), count),
-			}
-
-			result = append(result, remainingTricks(roll, trick)...)
+			})
 		}
 
 		if count >= 5 {
-			trick := Trick{
+			visit(Trick{
 				Type: FiveOfAKind,
 				Dice: RepeatedRoll(uint8(die), count),
-			}
-
-			result = append(result, remainingTricks(roll, trick)...)
+			})
 		}
 
 		if count >= 6 {
-			trick := Trick{
+			f(append(tricks, Trick{
 				Type: SixOfAKind,
 				Dice: roll,
-			}
+			}))
+		}
+	}
 
-			result = append(result, []Trick{trick})
+	if rules.PartialStraights {
+		for _, t := range [...]TrickType{LowStraight, HighStraight} {
+			if dice := partialStraights[t]; containsRoll(roll, dice) {
+				visit(Trick{
+					Type: t,
+					Dice: dice,
+				})
+			}
 		}
 	}
 
 	if isStraight(roll) {
-		trick := Trick{
+		f(append(tricks, Trick{
 			Type: Straight,
 			Dice: roll,
-		}
-
-		result = append(result, []Trick{trick})
-	} else if isThreePairs(roll) {
-		trick := Trick{
+		}))
+	} else if rules.SixDiceCombos && isThreePairs(roll) {
+		f(append(tricks, Trick{
 			Type: ThreePairs,
 			Dice: roll,
-		}
-
-		result = append(result, []Trick{trick})
-	} else if isFourOfAKindPlusPair(roll) {
-		trick := Trick{
+		}))
+	} else if rules.SixDiceCombos && isFourOfAKindPlusPair(roll) {
+		f(append(tricks, Trick{
 			Type: FourOfAKindPlusPair,
 			Dice: roll,
-		}
-
-		result = append(result, []Trick{trick})
-	} else if isTwoTriplets(roll) {
-		trick := Trick{
+		}))
+	} else if rules.SixDiceCombos && isTwoTriplets(roll) {
+		f(append(tricks, Trick{
 			Type: TwoTriplets,
 			Dice: roll,
-		}
-
-		result = append(result, []Trick{trick})
+		}))
 	}
-
-	return result
 }
 
 func isStraight(roll Roll) bool {
@@ -166,6 +240,17 @@ func isStraight(roll Roll) bool {
 	return true
 }
 
+// Whether all of the dice in sub are also in roll.
+func containsRoll(roll, sub Roll) bool {
+	for die, count := range sub {
+		if roll[die] < count {
+			return false
+		}
+	}
+
+	return true
+}
+
 func isThreePairs(roll Roll) bool {
 	numPairs := 0
 	for _, count := range roll {
@@ -203,71 +288,178 @@ func isTwoTriplets(roll Roll) bool {
 	return numTriplets >= 2
 }
 
+// The best score for the given dice. Looked up in a table for rolls of up
+// to MaxNumDice dice, so it does not allocate.
 func CalculateScore(held Roll) uint8 {
+	if rollID, ok := rollToID[held]; ok {
+		return scoreCache[rollID]
+	}
+	return calcScore(held)
+}
+
+func calcScore(held Roll) uint8 {
+	var scratch [MaxNumDice]Trick
 	result := uint8(0)
-	for _, tricks := range enumeratePossibleTricks(held) {
+	visitTrickSets(held, scratch[:0], func(tricks []Trick) {
 		score := uint8(0)
 		for _, trick := range tricks {
 			score += trick.Score()
 		}
 
 		result = max(result, score)
-	}
+	})
 
 	return result
 }
 
+// The distinct sets of dice that may be held from the given roll.
 func potentialHolds(roll Roll) []Roll {
-	trickSets := enumeratePossibleTricks(roll)
-	result := make([]Roll, 0, len(trickSets))
-	for _, tricks := range trickSets {
-		allRolls := make([]Roll, len(tricks))
-		for i := range allRolls {
-			allRolls[i] = tricks[i].Dice
+	var scratch [MaxNumDice]Trick
+	var result []Roll
+	visitTrickSets(roll, scratch[:0], func(tricks []Trick) {
+		var held Roll
+		for _, trick := range tricks {
+			held = CombineRolls(held, trick.Dice)
 		}
 
-		roll := CombineRolls(allRolls...)
-		result = append(result, roll)
-	}
+		// The same dice may be reached by choosing tricks in a different order.
+		if !slices.Contains(result, held) {
+			result = append(result, held)
+		}
+	})
 
 	return result
 }
 
-var rollIDToPotentialHolds = func() [][]Roll {
+// For each roll ID, the distinct sets of dice that may be held under the rules in effect.
+var rollIDToPotentialHolds = calcPotentialHolds()
+
+func calcPotentialHolds() [][]Roll {
 	var result [][]Roll
 	for _, rolls := range allRolls {
 		for _, weightedRoll := range rolls {
-			result = append(result, potentialHolds(weightedRoll.Roll))
+			if rules.Holds == LenientHolds {
+				result = append(result, lenientHolds(weightedRoll.Roll))
+			} else {
+				result = append(result, potentialHolds(weightedRoll.Roll))
+			}
 		}
 	}
 	return result
-}()
+}
 
 func IsFarkle(roll Roll) bool {
 	rollID := rollToID[roll]
 	return len(rollIDToPotentialHolds[rollID]) == 0
 }
 
-func IsValidHold(roll, held Roll) bool {
+// The distinct sets of dice that may legally be held from the given roll.
+// A farkle has no legal holds.
+func LegalHolds(roll Roll) []Roll {
 	rollID := GetRollID(roll)
-	potentialHolds := rollIDToPotentialHolds[rollID]
-	potentialHoldsSet := make(map[Roll]struct{}, len(potentialHolds))
-	for _, hold := range potentialHolds {
-		potentialHoldsSet[hold] = struct{}{}
+	return slices.Clone(rollIDToPotentialHolds[rollID])
+}
+
+// The tricks that make up the best score for the given held dice.
+// If there are multiple ways to reach the best score, the one that
+// uses the most dice, and then the fewest tricks, is returned.
+func ScoreBreakdown(held Roll) []Trick {
+	var scratch [MaxNumDice]Trick
+	var result []Trick
+	bestScore := uint8(0)
+	bestNumDice := uint8(0)
+	visitTrickSets(held, scratch[:0], func(tricks []Trick) {
+		score := uint8(0)
+		numDice := uint8(0)
+		for _, trick := range tricks {
+			score += trick.Score()
+			numDice += trick.Dice.NumDice()
+		}
+
+		if score > bestScore ||
+			(score == bestScore && numDice > bestNumDice) ||
+			(score == bestScore && numDice == bestNumDice && len(tricks) < len(result)) {
+			result = append(result[:0], tricks...)
+			bestScore = score
+			bestNumDice = numDice
+		}
+	})
+
+	return result
+}
+
+// The best score for the given held dice in points, the tricks that make it up
+// (as in ScoreBreakdown), and any held dice that are not part of those tricks.
+// Dice can only be held if there are no leftovers.
+func ExplainScore(held Roll) (total int, tricks []Trick, leftovers Roll) {
+	tricks = ScoreBreakdown(held)
+	leftovers = held
+	for _, trick := range tricks {
+		total += incr * int(trick.Score())
+		leftovers = SubtractRolls(leftovers, trick.Dice)
 	}
+	return total, tricks, leftovers
+}
 
-	_, ok := potentialHoldsSet[held]
-	return ok
+// Describe how the score for the given held dice is made up,
+// e.g. "350 = Three 3s + Single 5".
+func DescribeScore(held Roll) string {
+	total, tricks, leftovers := ExplainScore(held)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d", total)
+	for i, trick := range tricks {
+		if i == 0 {
+			sb.WriteString(" = ")
+		} else {
+			sb.WriteString(" + ")
+		}
+		sb.WriteString(trick.Type.String())
+	}
+	if leftovers.NumDice() > 0 {
+		fmt.Fprintf(&sb, " (%v do not score)", leftovers)
+	}
+	return sb.String()
 }
 
-// For each set of held dice, the total score.
-var scoreCache = func() []uint8 {
-	result := make([]uint8, nDistinctRolls)
-	for _, holds := range rollIDToPotentialHolds {
-		for _, hold := range holds {
-			rollID := rollToID[hold]
-			result[rollID] = CalculateScore(hold)
+// Check whether the given dice can be held from the roll,
+// with an explanation of why not, e.g. for displaying to players.
+func CheckHold(roll, held Roll) error {
+	if held.NumDice() == 0 {
+		return fmt.Errorf("must hold at least one scoring die")
+	}
+
+	var notRolled Roll
+	for die, count := range held {
+		if count > roll[die] {
+			notRolled[die] = count - roll[die]
 		}
 	}
+	if notRolled.NumDice() > 0 {
+		return fmt.Errorf("can't hold %v, only rolled %v", notRolled, roll)
+	}
+
+	if _, _, leftovers := ExplainScore(held); leftovers.NumDice() > 0 {
+		return fmt.Errorf("can't hold %v, they do not score", leftovers)
+	}
+
+	if !IsValidHold(roll, held) {
+		// Only possible with StrictHolds, since all of the held dice score.
+		return fmt.Errorf("can't hold %v from %v, must hold all of the dice in each trick", held, roll)
+	}
+	return nil
+}
+
+func IsValidHold(roll, held Roll) bool {
+	return slices.Contains(rollIDToPotentialHolds[GetRollID(roll)], held)
+}
+
+// For each roll ID, the best score of the dice, as in CalculateScore.
+var scoreCache = calcScoreCache()
+
+func calcScoreCache() []uint8 {
+	result := make([]uint8, nDistinctRolls)
+	for rollID, roll := range rollsByID {
+		result[rollID] = calcScore(roll)
+	}
 	return result
-}()
+}
@@ -0,0 +1,196 @@
+package farkle
+
+import (
+	"fmt"
+	"testing"
+)
+
+// The rules to check invariants under: every preset, and custom rules that
+// combine the options they do not cover.
+var invariantRules = func() []Rules {
+	var result []Rules
+	for p := StandardPreset; p <= KingdomComePreset; p++ {
+		result = append(result, p.Rules())
+	}
+	custom := DefaultRules
+	custom.Multiples = DoublingMultiples
+	custom.PartialStraights = true
+	custom.NumDice = 3
+	return append(result, custom)
+}()
+
+// Check invariants of the scoring engine that everything else depends on, for
+// every roll of up to MaxNumDice dice under each of invariantRules.
+func TestScoringInvariants(t *testing.T) {
+	for _, r := range invariantRules {
+		setRulesIfChanged(t, r)
+		for numDice := 1; numDice <= MaxNumDice; numDice++ {
+			for _, wRoll := range PossibleRolls(numDice) {
+				roll := wRoll.Roll
+				if err := safely(func() error { return checkRollInvariants(roll) }); err != nil {
+					t.Errorf("%v rules, roll %v: %v", r.Fingerprint(), roll, err)
+				}
+			}
+		}
+	}
+}
+
+func FuzzScore(f *testing.F) {
+	addRollSeeds(f)
+	f.Fuzz(func(t *testing.T, which uint8, dice []byte) {
+		setRulesIfChanged(t, invariantRules[int(which)%len(invariantRules)])
+		roll := rollFromBytes(dice)
+		if err := checkScore(roll); err != nil {
+			t.Errorf("roll %v: %v", roll, err)
+		}
+	})
+}
+
+func FuzzLegalHolds(f *testing.F) {
+	addRollSeeds(f)
+	f.Fuzz(func(t *testing.T, which uint8, dice []byte) {
+		setRulesIfChanged(t, invariantRules[int(which)%len(invariantRules)])
+		roll := rollFromBytes(dice)
+		if err := checkLegalHolds(roll); err != nil {
+			t.Errorf("roll %v: %v", roll, err)
+		}
+	})
+}
+
+// ParseRoll must never panic, and rolls it parses must have at most
+// MaxNumDice dice and survive formatting and parsing again.
+func FuzzParseRoll(f *testing.F) {
+	for _, s := range []string{"", "1,5,5", "[1 2 3 4 5 6]", "⚀⚄", "1111111", "x", "-1", "0"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if err := checkParseRoll(s); err != nil {
+			t.Errorf("ParseRoll(%q): %v", s, err)
+		}
+	})
+}
+
+func addRollSeeds(f *testing.F) {
+	for which := range invariantRules {
+		for _, dice := range [][]byte{{0}, {0, 4}, {0, 0, 0}, {1, 1, 1, 2, 2, 2}, {0, 1, 2, 3, 4, 5}, {1, 2, 3}} {
+			f.Add(uint8(which), dice)
+		}
+	}
+}
+
+// A roll of up to MaxNumDice dice, with one die per byte.
+func rollFromBytes(dice []byte) Roll {
+	var roll Roll
+	for _, b := range dice[:min(len(dice), MaxNumDice)] {
+		roll[1+int(b)%numSides]++
+	}
+	return roll
+}
+
+// Set the rules for the rest of the test, unless they are already in effect,
+// since computing the tables of new rules would dominate fuzzing.
+func setRulesIfChanged(t testing.TB, r Rules) {
+	t.Helper()
+	if CurrentRules() == r {
+		return
+	}
+	saved := CurrentRules()
+	t.Cleanup(func() { SetRules(saved) })
+	if err := SetRules(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkRollInvariants(roll Roll) error {
+	if err := checkScore(roll); err != nil {
+		return err
+	} else if err := checkLegalHolds(roll); err != nil {
+		return err
+	}
+	return checkParseRoll(roll.FormatAs(CompactStyle))
+}
+
+// Every set of tricks scores and uses only dice that were rolled, the table of
+// scores agrees with scoring the tricks, a farkle scores nothing, and adding a
+// die never lowers the score.
+func checkScore(roll Roll) error {
+	for _, tricks := range enumeratePossibleTricks(roll) {
+		var dice Roll
+		for _, trick := range tricks {
+			if trick.Score() == 0 {
+				return fmt.Errorf("trick %v does not score", trick.Type)
+			}
+			dice = CombineRolls(dice, trick.Dice)
+		}
+		if !containsRoll(roll, dice) {
+			return fmt.Errorf("tricks use %v, which were not rolled", dice)
+		}
+	}
+
+	score := CalculateScore(roll)
+	if score != calcScore(roll) {
+		return fmt.Errorf("scores %d, but its tricks score %d", incr*int(score), incr*int(calcScore(roll)))
+	} else if IsFarkle(roll) && score != 0 {
+		return fmt.Errorf("farkle scores %d", incr*int(score))
+	}
+	if roll.NumDice() < MaxNumDice {
+		for die := uint8(1); die <= numSides; die++ {
+			more := CombineRolls(roll, NewRoll(die))
+			if CalculateScore(more) < score {
+				return fmt.Errorf("adding a %d lowers the score from %d to %d",
+					die, incr*int(score), incr*int(CalculateScore(more)))
+			}
+		}
+	}
+	return nil
+}
+
+// A roll is a farkle exactly when it has no legal holds, and every legal hold
+// is a non-empty subset of the roll that scores and is accepted by CheckHold.
+func checkLegalHolds(roll Roll) error {
+	holds := LegalHolds(roll)
+	if IsFarkle(roll) != (len(holds) == 0) {
+		return fmt.Errorf("IsFarkle = %v with %d legal holds", IsFarkle(roll), len(holds))
+	}
+	for _, held := range holds {
+		if held.NumDice() == 0 || !containsRoll(roll, held) {
+			return fmt.Errorf("legal hold %v is not a non-empty subset of the roll", held)
+		} else if CalculateScore(held) == 0 {
+			return fmt.Errorf("legal hold %v does not score", held)
+		} else if !IsValidHold(roll, held) {
+			return fmt.Errorf("legal hold %v is not valid", held)
+		} else if err := CheckHold(roll, held); err != nil {
+			return fmt.Errorf("legal hold %v: %w", held, err)
+		}
+	}
+	return nil
+}
+
+func checkParseRoll(s string) error {
+	roll, err := ParseRoll(s)
+	if err != nil {
+		return nil
+	}
+	if roll.NumDice() > MaxNumDice || roll[0] != 0 {
+		return fmt.Errorf("parsed invalid roll %v", roll)
+	}
+	for _, style := range []RollStyle{CompactStyle, EmojiStyle} {
+		formatted := roll.FormatAs(style)
+		if again, err := ParseRoll(formatted); err != nil {
+			return fmt.Errorf("unable to parse %q: %w", formatted, err)
+		} else if again != roll {
+			return fmt.Errorf("%q parsed as %v, not %v", formatted, again, roll)
+		}
+	}
+	return nil
+}
+
+// Call check, returning a panic as an error.
+func safely(check func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return check()
+}
@@ -0,0 +1,50 @@
+package farkle
+
+import "math"
+
+// In single-player (solitaire) games the objective is to reach the score
+// to win in as few turns as possible. Instead of win probabilities, the
+// database stores the expected number of turns remaining in each state
+// (in the first entry), which players seek to minimize.
+
+// Find the action that minimizes the expected number of turns remaining
+// in a single-player game.
+func selectSolitaireAction(state GameState, rollID uint16, db DB) (Action, [maxNumPlayers]float64) {
+	bestTurns := [maxNumPlayers]float64{math.Inf(1)}
+	var bestAction Action
+	potentialActions := rollIDToPotentialActions[rollID]
+	effects := rollIDToActionEffects[rollID]
+	parts := newStateIDParts(state)
+	for i, action := range potentialActions {
+		action, newStateID, allowed := parts.takeAction(action, effects[i])
+		if !allowed {
+			continue
+		}
+
+		turns := db.Get(newStateID)
+		if !action.ContinueRolling {
+			turns[0]++
+		}
+		if turns[0] < bestTurns[0] {
+			bestTurns = turns
+			bestAction = action
+		}
+	}
+
+	if len(potentialActions) == 0 {
+		bestTurns = evaluateSolitaireAction(state, bestAction, db)
+	}
+
+	return bestAction, bestTurns
+}
+
+// The expected number of turns remaining after taking the given action,
+// including the current turn if it ends.
+func evaluateSolitaireAction(state GameState, action Action, db DB) [maxNumPlayers]float64 {
+	newState := ApplyAction(state, action)
+	turns := db.Get(newState.ID())
+	if !action.ContinueRolling {
+		turns[0]++
+	}
+	return turns
+}
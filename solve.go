@@ -0,0 +1,57 @@
+package farkle
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Maximum number of value iteration cycles in SolveFrom.
+	maxSolveFromIter = 100
+	// SolveFrom stops once the value of the initial state changes by less than this.
+	solveFromTolerance = 1e-9
+)
+
+// Solve just the part of the game tree that is reachable from the given state,
+// storing the value of each reachable state in db. Value iteration continues
+// until the value of the given state converges. This is much faster than a full
+// solve for positions late in the game, and can be used with an InMemoryDB.
+func SolveFrom(state GameState, db DB) error {
+	if state.IsGameOver() {
+		return nil
+	}
+
+	workDir, err := os.MkdirTemp("", "farkle-solve-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	statesPath := filepath.Join(workDir, "states")
+	if err := SaveGameStates(SortedGameStatesFrom(state, workDir), statesPath); err != nil {
+		return err
+	}
+
+	lastValue := CalculateWinProb(state, db)
+	for i := 0; i < maxSolveFromIter; i++ {
+		states, err := IterGameStates(int(state.NumPlayers), statesPath)
+		if err != nil {
+			return err
+		}
+		UpdateAll(db, states, "")
+
+		value := CalculateWinProb(state, db)
+		maxChange := 0.0
+		for j := range value[:state.NumPlayers] {
+			maxChange = max(maxChange, math.Abs(value[j]-lastValue[j]))
+		}
+		Logger().Debug("Solved iteration", "iteration", i, "value", value[:state.NumPlayers], "change", maxChange)
+		if maxChange < solveFromTolerance {
+			break
+		}
+		lastValue = value
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package farkle
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Miniature games small enough to solve in a test, with the value of the
+// start of the game worked out by hand where it is practical.
+var miniatureGames = []struct {
+	rules      string
+	numPlayers int
+	// The value of the initial state, if known.
+	want []float64
+}{
+	// Any roll of two dice that is not a farkle reaches the target, which
+	// happens with probability 20/36: 36/20 turns.
+	{"pocket-farkle,target=50,dice=2", 1, []float64{9.0 / 5}},
+	// From 0, 12/36 of rolls reach 100 at once. A 5 and a die that does not
+	// score (8/36) is worth rolling the last die for 100 (1/3) rather than
+	// banking 50 and needing 36/20 more turns. So V = 1 + 16/27 V = 27/11.
+	{"pocket-farkle,target=100,dice=2", 1, []float64{27.0 / 11}},
+	{"pocket-farkle,target=300,dice=3", 1, nil},
+	// After a player reaches the target, the others have one more turn.
+	{"pocket-farkle,target=50,dice=2", 2, nil},
+	{"standard,target=150,dice=2,opening=0", 2, nil},
+}
+
+// Every way of solving the miniature games gives the same values: the exact
+// solver, SolveFrom, and value iteration with each way of updating the states
+// at each depth. Where the value of the start of the game is known, they
+// agree with it. Value iteration takes tens of cycles to converge in the
+// two-player games, so there it is only checked that the exact solution is a
+// fixed point of each way of updating the states.
+func TestMiniatureGames(t *testing.T) {
+	for _, game := range miniatureGames {
+		t.Run(fmt.Sprintf("%s/%dp", game.rules, game.numPlayers), func(t *testing.T) {
+			if testing.Short() && game.numPlayers > 1 {
+				t.Skip("solves a two-player game")
+			}
+			setTestRules(t, game.rules)
+			states := miniatureGameStates(t, game.numPlayers)
+			initialState := NewGameState(game.numPlayers)
+
+			exact := NewInMemoryDB(game.numPlayers)
+			SolveExact(exact, "")
+			if game.want != nil {
+				checkValue(t, "SolveExact", initialState, exact, game.want, 1e-12)
+			}
+
+			// Solving a two-player game from the start would take as long as
+			// value iteration, so from the last turn of the game.
+			fromState := initialState
+			if game.numPlayers > 1 {
+				fromState.PlayerScores[0] = scoreToWin - 1
+				fromState.PlayerScores[game.numPlayers-1] = scoreToWin + 1
+			}
+			solved := NewInMemoryDB(game.numPlayers)
+			if err := SolveFrom(fromState, solved); err != nil {
+				t.Fatal(err)
+			}
+			want := exact.Get(fromState.ID())
+			checkValue(t, "SolveFrom", fromState, solved, want[:game.numPlayers], 1e-8)
+
+			for name, opts := range updateOptionsToTest {
+				db := NewInMemoryDB(game.numPlayers)
+				if game.numPlayers == 1 {
+					solveByValueIteration(t, db, states, opts, 1e-14)
+					checkValues(t, name, states, db, exact, 1e-10)
+					continue
+				}
+
+				for _, ds := range states {
+					db.Put(ds.state.ID(), exact.Get(ds.state.ID()))
+				}
+				stats := updateStates(db, states, opts)
+				if change := stats.Total().MaxChange; change > 1e-12 {
+					t.Errorf("%s: the exact solution changed by %g in a cycle", name, change)
+				}
+			}
+		})
+	}
+}
+
+// States at the same depth never depend on each other, so the order in which
+// they are updated does not change the values after each cycle at all.
+func TestUpdateOrder(t *testing.T) {
+	setTestRules(t, "pocket-farkle,target=50,dice=2")
+	states := miniatureGameStates(t, 2)
+	shuffled := slices.Clone(states)
+	rng := rand.New(rand.NewSource(benchSeed))
+	for start := 0; start < len(shuffled); {
+		end := start
+		for end < len(shuffled) && shuffled[end].depth == shuffled[start].depth {
+			end++
+		}
+		rng.Shuffle(end-start, func(i, j int) {
+			shuffled[start+i], shuffled[start+j] = shuffled[start+j], shuffled[start+i]
+		})
+		start = end
+	}
+
+	// One cycle is enough to show that updates do not depend on their order,
+	// so short runs do not repeat it from the values of the first.
+	numCycles := 2
+	if testing.Short() {
+		numCycles = 1
+	}
+	for name, opts := range updateOptionsToTest {
+		db, shuffledDB := NewInMemoryDB(2), NewInMemoryDB(2)
+		for range numCycles {
+			updateStates(db, states, opts)
+			updateStates(shuffledDB, shuffled, opts)
+		}
+		for _, ds := range states {
+			if got, want := shuffledDB.Get(ds.state.ID()), db.Get(ds.state.ID()); got != want {
+				t.Fatalf("%s: value of %v = %v updated in another order, want %v",
+					name, ds.state, got[:2], want[:2])
+			}
+		}
+	}
+}
+
+// Each way of updating the states at each depth in value iteration.
+var updateOptionsToTest = map[string]UpdateOptions{
+	"concurrent": {},
+	"pinned":     {PinWorkers: true},
+	"prefetch":   {Prefetch: true},
+	"batched":    {Batched: true},
+}
+
+// The states of a game reachable from the start, sorted by depth.
+func miniatureGameStates(t *testing.T, numPlayers int) []depthState {
+	t.Helper()
+	var result []depthState
+	for depth, state := range SortedGameStates(numPlayers, t.TempDir()) {
+		result = append(result, depthState{depth, state})
+	}
+	return result
+}
+
+// Run value iteration until the values change by less than tolerance.
+func solveByValueIteration(t *testing.T, db DB, states []depthState, opts UpdateOptions, tolerance float64) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if updateStates(db, states, opts).Total().MaxChange < tolerance {
+			return
+		}
+	}
+	t.Fatalf("value iteration did not converge")
+}
+
+// Run one cycle of value iteration.
+func updateStates(db DB, states []depthState, opts UpdateOptions) UpdateStats {
+	return UpdateAllWithOptions(db, func(yield func(uint64, GameState) bool) {
+		for _, ds := range states {
+			if !yield(ds.depth, ds.state) {
+				return
+			}
+		}
+	}, opts)
+}
+
+func checkValue(t *testing.T, name string, state GameState, db DB, want []float64, tolerance float64) {
+	t.Helper()
+	got := CalculateWinProb(state, db)
+	for i, v := range want {
+		if math.Abs(got[i]-v) > tolerance {
+			t.Errorf("%s: value of %v = %v, want %v", name, state, got[:state.NumPlayers], want)
+			return
+		}
+	}
+}
+
+// The values of all of the given states agree.
+func checkValues(t *testing.T, name string, states []depthState, db, want DB, tolerance float64) {
+	t.Helper()
+	for _, ds := range states {
+		got, want := db.Get(ds.state.ID()), want.Get(ds.state.ID())
+		for i := range ds.state.NumPlayers {
+			if math.Abs(got[i]-want[i]) > tolerance {
+				t.Errorf("%s: value of %v = %v, want %v", name, ds.state,
+					got[:ds.state.NumPlayers], want[:ds.state.NumPlayers])
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package farkle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+)
+
+// SparseDB files begin with a header of sparseDBHeaderSize bytes:
+// sparseDBMagic, the format version and the number of players (uint32 each),
+// epsilon and the metadata. They are followed by the bit masks of the decided
+// states and of each bit of their winners (see SparseDB), as little-endian
+// uint64 words, and then the number of uncertain states and the ID and values
+// of each of them.
+const (
+	sparseDBMagic         = "FARKLESP"
+	sparseDBFormatVersion = 2
+	sparseDBHeaderSize    = 24 + metadataSize
+)
+
+// DB of win probabilities that only stores the states whose outcome is
+// uncertain. End game states are calculated when needed, and states in which
+// one player wins with probability within epsilon of 1 take a bit, plus a bit
+// per bit of the number of the player who won (1 in two-player games, and 2
+// with more players). Since these states cannot meaningfully change,
+// UpdateAll skips them.
+type SparseDB struct {
+	numPlayers int
+	epsilon    float64
+	meta       Metadata
+	decided    *bitMask
+	// Bit i of the player who won each decided state.
+	winner []*bitMask
+	values *sparseTable
+}
+
+// Create an empty SparseDB for the rules in effect.
+func NewSparseDB(numPlayers int, epsilon float64) (*SparseDB, error) {
+	return newSparseDB(numPlayers, epsilon, Metadata{Objective: WinProbability, Rules: rulesFingerprint})
+}
+
+func newSparseDB(numPlayers int, epsilon float64, meta Metadata) (*SparseDB, error) {
+	if numPlayers < 2 {
+		return nil, fmt.Errorf("sparse databases require at least 2 players")
+	}
+	if meta.Objective != WinProbability {
+		return nil, fmt.Errorf("sparse databases hold win probabilities, not %v", meta)
+	}
+
+	numStates := calcNumDistinctStates(numPlayers)
+	winner := make([]*bitMask, bits.Len(uint(numPlayers-1)))
+	for i := range winner {
+		winner[i] = newBitMask(numStates)
+	}
+	return &SparseDB{
+		numPlayers: numPlayers,
+		epsilon:    epsilon,
+		meta:       meta,
+		decided:    newBitMask(numStates),
+		winner:     winner,
+		values:     newSparseTable(numPlayers),
+	}, nil
+}
+
+// Copy all values from the given (solved) database into a new SparseDB.
+// The database must have been solved with the rules in effect, which decide
+// the states in which the game is over.
+func NewSparseDBFrom(db DB, epsilon float64) (*SparseDB, error) {
+	if err := CheckRules(db); err != nil {
+		return nil, err
+	}
+
+	result, err := newSparseDB(db.NumPlayers(), epsilon, db.Metadata())
+	if err != nil {
+		return nil, err
+	}
+
+	for gsID := range calcNumDistinctStates(db.NumPlayers()) {
+		result.Put(gsID, db.Get(gsID))
+		if gsID%100000000 == 0 {
+			Logger().Info("Copied game states", "count", gsID)
+		}
+	}
+
+	return result, nil
+}
+
+func (db *SparseDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db *SparseDB) Metadata() Metadata {
+	return db.meta
+}
+
+// The number of states whose value is stored.
+func (db *SparseDB) Len() int {
+	return db.values.len()
+}
+
+// Whether one player is certain to win from the given state.
+func (db *SparseDB) IsDecided(gsID int) bool {
+	return db.decided.IsSet(gsID) || GameStateFromID(db.numPlayers, gsID).IsGameOver()
+}
+
+func (db *SparseDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	if GameStateFromID(db.numPlayers, gsID).IsGameOver() {
+		return
+	}
+
+	if winner := db.certainWinner(pWin); winner >= 0 {
+		db.setWinner(gsID, winner)
+		db.values.delete(gsID)
+	} else {
+		db.decided.Clear(gsID)
+		db.values.put(gsID, pWin[:db.numPlayers])
+	}
+}
+
+func (db *SparseDB) setWinner(gsID, winner int) {
+	db.decided.Set(gsID)
+	for i, bm := range db.winner {
+		if winner&(1<<i) != 0 {
+			bm.Set(gsID)
+		} else {
+			bm.Clear(gsID)
+		}
+	}
+}
+
+// The player who won the given decided state.
+func (db *SparseDB) getWinner(gsID int) int {
+	winner := 0
+	for i, bm := range db.winner {
+		if bm.IsSet(gsID) {
+			winner |= 1 << i
+		}
+	}
+	return winner
+}
+
+// The player who wins with probability within epsilon of 1, or -1 if none.
+func (db *SparseDB) certainWinner(pWin [maxNumPlayers]float64) int {
+	winner := -1
+	for i, p := range pWin[:db.numPlayers] {
+		if p >= 1-db.epsilon {
+			winner = i
+		} else if p > db.epsilon {
+			return -1
+		}
+	}
+	return winner
+}
+
+func (db *SparseDB) Get(gsID int) [maxNumPlayers]float64 {
+	var result [maxNumPlayers]float64
+	if db.decided.IsSet(gsID) {
+		result[db.getWinner(gsID)] = 1
+		return result
+	}
+
+	if pWin, ok := db.values.get(gsID); ok {
+		copy(result[:], pWin)
+		return result
+	}
+
+	return InitialValue(db.numPlayers, gsID, db.meta)
+}
+
+func (db *SparseDB) Close() error {
+	return nil
+}
+
+// Save the database to a file, which can be read with LoadSparseDB.
+func (db *SparseDB) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriterSize(f, 4*1024*1024)
+
+	header := make([]byte, sparseDBHeaderSize)
+	copy(header, sparseDBMagic)
+	binary.LittleEndian.PutUint32(header[8:], sparseDBFormatVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(db.numPlayers))
+	binary.LittleEndian.PutUint64(header[16:], math.Float64bits(db.epsilon))
+	encodeMetadata(header[24:], db.meta)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	numWords := (calcNumDistinctStates(db.numPlayers) + 63) / 64
+	for _, bm := range append([]*bitMask{db.decided}, db.winner...) {
+		if err := bm.writeWords(w, numWords); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(db.values.len()))
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	for _, gsID := range db.values.sortedKeys() {
+		pWin, _ := db.values.get(gsID)
+		binary.LittleEndian.PutUint64(buf, uint64(gsID))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		if _, err := w.Write(encodeValue(pWin)); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// Load a database saved with SparseDB.Save. As with other databases, use
+// CheckRules to check that it was solved with the rules in effect.
+func LoadSparseDB(path string) (*SparseDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 4*1024*1024)
+
+	header := make([]byte, sparseDBHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if string(header[:8]) != sparseDBMagic {
+		return nil, fmt.Errorf("%s is not a sparse farkle database", path)
+	}
+	if version := binary.LittleEndian.Uint32(header[8:]); version != sparseDBFormatVersion {
+		return nil, fmt.Errorf("%s: unsupported database version: %d", path, version)
+	}
+	meta, err := decodeMetadata(header[24:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	numPlayers := int(binary.LittleEndian.Uint32(header[12:]))
+	if numPlayers < 2 || numPlayers > maxNumPlayers {
+		return nil, fmt.Errorf("%s: invalid number of players: %d", path, numPlayers)
+	}
+	epsilon := math.Float64frombits(binary.LittleEndian.Uint64(header[16:]))
+	db, err := newSparseDB(numPlayers, epsilon, meta)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	numWords := (calcNumDistinctStates(numPlayers) + 63) / 64
+	for _, bm := range append([]*bitMask{db.decided}, db.winner...) {
+		if err := bm.readWords(r, numWords); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	// Unless every winner is a player, check that they are.
+	if 1<<len(db.winner) != numPlayers {
+		for gsID := range calcNumDistinctStates(numPlayers) {
+			if db.decided.IsSet(gsID) && db.getWinner(gsID) >= numPlayers {
+				return nil, fmt.Errorf("%s: invalid winner of game state %d: %d", path, gsID, db.getWinner(gsID))
+			}
+		}
+	}
+
+	buf := make([]byte, 8*(1+numPlayers))
+	if _, err := io.ReadFull(r, buf[:8]); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	n := binary.LittleEndian.Uint64(buf)
+	for i := uint64(0); i < n; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		gsID := int(binary.LittleEndian.Uint64(buf))
+		if gsID < 0 || gsID >= calcNumDistinctStates(numPlayers) {
+			return nil, fmt.Errorf("%s: invalid game state: %d", path, gsID)
+		}
+
+		var pWin [maxNumPlayers]float64
+		for j := range pWin[:numPlayers] {
+			pWin[j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*(j+1):]))
+		}
+		db.values.put(gsID, pWin[:numPlayers])
+	}
+
+	return db, nil
+}
@@ -0,0 +1,149 @@
+package farkle
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// The table agrees with a map through a long sequence of puts and deletes of
+// few enough keys that their probe sequences collide.
+func TestSparseTable(t *testing.T) {
+	table := newSparseTable(2)
+	want := make(map[int][2]float64)
+	rng := rand.New(rand.NewSource(benchSeed))
+	for i := 0; i < 200000; i++ {
+		gsID := rng.Intn(1000)
+		if rng.Intn(3) == 0 {
+			table.delete(gsID)
+			delete(want, gsID)
+		} else {
+			v := [2]float64{rng.Float64(), rng.Float64()}
+			table.put(gsID, v[:])
+			want[gsID] = v
+		}
+
+		if i%1000 == 0 {
+			checkSparseTable(t, table, want)
+		}
+	}
+	checkSparseTable(t, table, want)
+}
+
+func checkSparseTable(t *testing.T, table *sparseTable, want map[int][2]float64) {
+	t.Helper()
+	if table.len() != len(want) {
+		t.Fatalf("table has %d keys, want %d", table.len(), len(want))
+	}
+	for gsID := range 1000 {
+		got, ok := table.get(gsID)
+		v, wantOK := want[gsID]
+		if ok != wantOK || (ok && !slices.Equal(got, v[:])) {
+			t.Fatalf("value of %d = %v (%v), want %v (%v)", gsID, got, ok, v, wantOK)
+		}
+	}
+	keys := table.sortedKeys()
+	if len(keys) != len(want) || !slices.IsSorted(keys) {
+		t.Fatalf("sorted keys = %v", keys)
+	}
+}
+
+// The winners of decided states take as many bits as the number of the last
+// player.
+func TestSparseDBWinner(t *testing.T) {
+	db := &SparseDB{numPlayers: 4, decided: newBitMask(100), winner: []*bitMask{newBitMask(100), newBitMask(100)}}
+	for gsID := range 100 {
+		db.setWinner(gsID, gsID%4)
+	}
+	for gsID := range 100 {
+		if !db.decided.IsSet(gsID) {
+			t.Errorf("state %d is not decided", gsID)
+		}
+		if got := db.getWinner(gsID); got != gsID%4 {
+			t.Errorf("winner of state %d = %d, want %d", gsID, got, gsID%4)
+		}
+	}
+}
+
+// Values are kept and saved with the rules they were solved with, so that
+// a database solved with other rules fails CheckRules.
+func TestSparseDB(t *testing.T) {
+	setTestRules(t, "pocket-farkle")
+	db, err := NewSparseDB(2, 1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Objective: WinProbability, Rules: rulesFingerprint}
+	checkMetadata(t, "NewSparseDB", db.Metadata(), want)
+
+	values := make(map[int][maxNumPlayers]float64)
+	rng := rand.New(rand.NewSource(benchSeed))
+	for len(values) < 1000 {
+		state := randomState(rng)
+		state.NumPlayers = 2
+		var pWin [maxNumPlayers]float64
+		switch rng.Intn(3) {
+		case 0:
+			pWin[0] = 1
+		case 1:
+			pWin[1] = 1 - 1e-12
+		default:
+			pWin[0] = rng.Float64()
+			pWin[1] = 1 - pWin[0]
+		}
+		if !state.IsGameOver() {
+			// Put twice, to replace a decided or uncertain value.
+			db.Put(state.ID(), [maxNumPlayers]float64{0.5, 0.5})
+			db.Put(state.ID(), pWin)
+			values[state.ID()] = pWin
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "test.sparse")
+	if err := db.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSparseDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata(t, "LoadSparseDB", loaded.Metadata(), want)
+	if err := CheckRules(loaded); err != nil {
+		t.Error(err)
+	}
+	for _, db := range []*SparseDB{db, loaded} {
+		if db.Len() > len(values)/2 {
+			t.Errorf("%d of %d values are stored", db.Len(), len(values))
+		}
+		for gsID, pWin := range values {
+			got := db.Get(gsID)
+			decided := db.certainWinner(pWin) >= 0
+			if decided {
+				pWin[0], pWin[1] = math.Round(pWin[0]), math.Round(pWin[1])
+			}
+			if got != pWin || db.IsDecided(gsID) != decided {
+				t.Fatalf("value of %v = %v (decided: %v), want %v", GameStateFromID(2, gsID),
+					got[:2], db.IsDecided(gsID), pWin[:2])
+			}
+		}
+	}
+
+	setTestRules(t, "standard")
+	if err := CheckRules(loaded); err == nil {
+		t.Error("CheckRules passed a sparse database solved with Pocket Farkle rules")
+	}
+}
+
+func TestNewSparseDBFrom(t *testing.T) {
+	setTestRules(t, "pocket-farkle")
+	for _, meta := range []Metadata{
+		{Objective: WinProbability, Rules: DefaultRules.Fingerprint()},
+		{Objective: ScoreMargin, Rules: rulesFingerprint},
+	} {
+		if _, err := NewSparseDBFrom(fakeDB{numPlayers: 2, meta: &meta}, 1e-9); err == nil {
+			t.Errorf("copied a database of %v into a sparse database", meta)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package farkle
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// The smallest number of slots in a sparseTable.
+const minSparseTableSize = 16
+
+// Open-addressed hash table from game state IDs to the values of the players,
+// with linear probing. Keys are stored as gsID+1, so that zero marks an empty
+// slot, and the values of the key in slot i are values[i*stride:(i+1)*stride].
+// The number of slots is a power of two, and the table grows when it is 3/4
+// full. It takes 8*(1+stride) bytes per slot, with values only for the
+// players in the game.
+type sparseTable struct {
+	stride int
+	shift  uint // 64 - log2(len(keys))
+	keys   []uint64
+	values []float64
+	n      int
+}
+
+func newSparseTable(stride int) *sparseTable {
+	t := &sparseTable{stride: stride}
+	t.resize(minSparseTableSize)
+	return t
+}
+
+// The number of keys in the table.
+func (t *sparseTable) len() int {
+	return t.n
+}
+
+// The values of the given game state, if it is in the table. The result
+// aliases the table, and is only valid until it is next modified.
+func (t *sparseTable) get(gsID int) ([]float64, bool) {
+	i, ok := t.find(gsID)
+	if !ok {
+		return nil, false
+	}
+	return t.values[i*t.stride : (i+1)*t.stride], true
+}
+
+func (t *sparseTable) put(gsID int, values []float64) {
+	i, ok := t.find(gsID)
+	if !ok {
+		if 4*(t.n+1) > 3*len(t.keys) {
+			t.resize(2 * len(t.keys))
+			i, _ = t.find(gsID)
+		}
+		t.keys[i] = uint64(gsID) + 1
+		t.n++
+	}
+	copy(t.values[i*t.stride:(i+1)*t.stride], values)
+}
+
+func (t *sparseTable) delete(gsID int) {
+	i, ok := t.find(gsID)
+	if !ok {
+		return
+	}
+
+	// Shift back the keys after i that would no longer be found past the
+	// empty slot, so that no tombstones are needed.
+	mask := len(t.keys) - 1
+	for j := (i + 1) & mask; t.keys[j] != 0; j = (j + 1) & mask {
+		home := t.home(t.keys[j])
+		if (j-home)&mask >= (j-i)&mask {
+			t.keys[i] = t.keys[j]
+			copy(t.values[i*t.stride:(i+1)*t.stride], t.values[j*t.stride:(j+1)*t.stride])
+			i = j
+		}
+	}
+	t.keys[i] = 0
+	t.n--
+}
+
+// The game states in the table, in increasing order.
+func (t *sparseTable) sortedKeys() []int {
+	result := make([]int, 0, t.n)
+	for _, key := range t.keys {
+		if key != 0 {
+			result = append(result, int(key-1))
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// The slot of the given game state, or the empty slot where it would be put.
+func (t *sparseTable) find(gsID int) (int, bool) {
+	key := uint64(gsID) + 1
+	mask := len(t.keys) - 1
+	for i := t.home(key); ; i = (i + 1) & mask {
+		switch t.keys[i] {
+		case key:
+			return i, true
+		case 0:
+			return i, false
+		}
+	}
+}
+
+// The first slot probed for the given key (Fibonacci hashing).
+func (t *sparseTable) home(key uint64) int {
+	return int((key * 0x9e3779b97f4a7c15) >> t.shift)
+}
+
+func (t *sparseTable) resize(size int) {
+	keys, values := t.keys, t.values
+	t.keys = make([]uint64, size)
+	t.values = make([]float64, size*t.stride)
+	t.shift = uint(64 - bits.TrailingZeros(uint(size)))
+
+	for j, key := range keys {
+		if key == 0 {
+			continue
+		}
+		i, _ := t.find(int(key - 1))
+		t.keys[i] = key
+		copy(t.values[i*t.stride:(i+1)*t.stride], values[j*t.stride:(j+1)*t.stride])
+	}
+}
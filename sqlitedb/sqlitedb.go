@@ -0,0 +1,232 @@
+// Package sqlitedb stores solutions in SQLite databases. This is much slower
+// than farkle.FileDB for solving, but the values can be queried with any
+// SQLite client, e.g. to slice the solution or join it with other data.
+//
+// Values are stored in the states table, with one row per state:
+//
+//	CREATE TABLE states (id INTEGER PRIMARY KEY, p0 REAL, p1 REAL, ...)
+//
+// where id is the farkle.GameState ID and pN is the value for player N,
+// relative to the current player. The state_values view also decodes each ID
+// into the number of dice to roll, the score this turn, and each player's
+// score (in points). The metadata table holds the number of players, the
+// objective and the rules fingerprint of the values.
+package sqlitedb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/timpalpant/go-farkle"
+	_ "modernc.org/sqlite"
+)
+
+// Puts are written in transactions of this many states.
+const batchSize = 10000
+
+// Must match farkle.GameState.ID.
+const numScoreBits = 8
+
+// DB that stores results in a SQLite database.
+// Puts are buffered until there are enough for a transaction, or Close.
+type SQLiteDB struct {
+	numPlayers int
+	meta       farkle.Metadata
+	db         *sql.DB
+	get        *sql.Stmt
+	put        string
+
+	pending map[int][4]float64
+}
+
+// Open the database at the given path, or create a new database for the
+// WinProbability objective with the rules in effect if it does not exist.
+// Existing databases may have any objective and rules.
+func NewSQLiteDB(path string, numPlayers int) (*SQLiteDB, error) {
+	meta := farkle.Metadata{Objective: farkle.WinProbability, Rules: farkle.CurrentRules().Fingerprint()}
+	return open(path, numPlayers, meta, false)
+}
+
+// Open the database at the given path, or create a new database with the
+// given metadata if it does not exist. Existing databases must have been
+// created with the same metadata.
+func NewSQLiteDBWithMetadata(path string, numPlayers int, meta farkle.Metadata) (*SQLiteDB, error) {
+	return open(path, numPlayers, meta, true)
+}
+
+func open(path string, numPlayers int, meta farkle.Metadata, requireMeta bool) (*SQLiteDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SQLiteDB{
+		numPlayers: numPlayers,
+		db:         db,
+		pending:    make(map[int][4]float64),
+	}
+	if err := result.init(meta, requireMeta); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return result, nil
+}
+
+// Create the tables if they do not exist, and check the metadata if they do.
+func (db *SQLiteDB) init(meta farkle.Metadata, requireMeta bool) error {
+	if _, err := db.db.Exec(`CREATE TABLE IF NOT EXISTS metadata (
+		num_players INTEGER NOT NULL,
+		objective TEXT NOT NULL,
+		risk_aversion REAL NOT NULL,
+		rules INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+
+	var numPlayers int
+	var objective string
+	var rules int64
+	var storedMeta farkle.Metadata
+	err := db.db.QueryRow(`SELECT num_players, objective, risk_aversion, rules FROM metadata`).
+		Scan(&numPlayers, &objective, &storedMeta.RiskAversion, &rules)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The fingerprint is stored with the same bits, as SQLite integers are signed.
+		if _, err := db.db.Exec(`INSERT INTO metadata VALUES (?, ?, ?, ?)`,
+			db.numPlayers, meta.Objective.String(), meta.RiskAversion, int64(meta.Rules)); err != nil {
+			return err
+		}
+		numPlayers, storedMeta = db.numPlayers, meta
+	} else if err != nil {
+		return err
+	} else if storedMeta.Objective, err = farkle.ParseObjective(objective); err != nil {
+		return err
+	} else {
+		storedMeta.Rules = farkle.RulesFingerprint(rules)
+	}
+
+	if numPlayers != db.numPlayers {
+		return fmt.Errorf("database is for %d players, not %d", numPlayers, db.numPlayers)
+	}
+	if requireMeta && storedMeta != meta {
+		return fmt.Errorf("database was solved for %v, not %v", storedMeta, meta)
+	}
+	db.meta = storedMeta
+
+	columns := make([]string, db.numPlayers)
+	decoded := []string{
+		fmt.Sprintf("(id >> %d) + 1 AS num_dice", (db.numPlayers+1)*numScoreBits),
+		"(id & 255) * 50 AS turn_score",
+	}
+	for i := range columns {
+		columns[i] = fmt.Sprintf("p%d", i)
+		decoded = append(decoded, fmt.Sprintf("((id >> %d) & 255) * 50 AS score%d",
+			(db.numPlayers-i)*numScoreBits, i))
+	}
+
+	if _, err := db.db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS states (id INTEGER PRIMARY KEY, %s REAL NOT NULL)`,
+		strings.Join(columns, " REAL NOT NULL, "))); err != nil {
+		return err
+	}
+	if _, err := db.db.Exec(fmt.Sprintf(
+		`CREATE VIEW IF NOT EXISTS state_values AS SELECT id, %s, %s FROM states`,
+		strings.Join(decoded, ", "), strings.Join(columns, ", "))); err != nil {
+		return err
+	}
+
+	db.get, err = db.db.Prepare(fmt.Sprintf(
+		`SELECT %s FROM states WHERE id = ?`, strings.Join(columns, ", ")))
+	if err != nil {
+		return err
+	}
+	db.put = fmt.Sprintf(`INSERT OR REPLACE INTO states (id, %s) VALUES (?%s)`,
+		strings.Join(columns, ", "), strings.Repeat(", ?", db.numPlayers))
+	return nil
+}
+
+func (db *SQLiteDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db *SQLiteDB) Metadata() farkle.Metadata {
+	return db.meta
+}
+
+func (db *SQLiteDB) Put(gsID int, pWin [4]float64) {
+	db.pending[gsID] = pWin
+	if len(db.pending) >= batchSize {
+		if err := db.flush(); err != nil {
+			panic(fmt.Errorf("error writing to sqlite database: %w", err))
+		}
+	}
+}
+
+// Retrieve a stored result for the given game state. States that have not been
+// stored have the same initial value they would have in a new FileDB.
+func (db *SQLiteDB) Get(gsID int) [4]float64 {
+	if pWin, ok := db.pending[gsID]; ok {
+		return pWin
+	}
+
+	var pWin [4]float64
+	dest := make([]any, db.numPlayers)
+	for i := range dest {
+		dest[i] = &pWin[i]
+	}
+	err := db.get.QueryRow(gsID).Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return farkle.InitialValue(db.numPlayers, gsID, db.meta)
+	} else if err != nil {
+		panic(fmt.Errorf("error reading from sqlite database: %w", err))
+	}
+
+	return pWin
+}
+
+// Write all pending puts to the database in a single transaction.
+func (db *SQLiteDB) flush() error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(db.put)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]any, 1+db.numPlayers)
+	for gsID, pWin := range db.pending {
+		args[0] = gsID
+		for i := range pWin[:db.numPlayers] {
+			args[i+1] = pWin[i]
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	clear(db.pending)
+	return nil
+}
+
+func (db *SQLiteDB) Close() error {
+	defer db.db.Close()
+
+	if err := db.flush(); err != nil {
+		return err
+	}
+	if err := db.get.Close(); err != nil {
+		return err
+	}
+
+	return db.db.Close()
+}
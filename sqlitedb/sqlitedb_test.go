@@ -0,0 +1,121 @@
+package sqlitedb
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+func testState() farkle.GameState {
+	state := farkle.NewGameState(2)
+	state.NumDiceToRoll = 4
+	state.ScoreThisRound = 3
+	state.PlayerScores[0] = 20
+	state.PlayerScores[1] = 45
+	return state
+}
+
+// Values are read back before and after they are written to the database,
+// and states that were never stored have their initial value.
+func TestSQLiteDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	db, err := NewSQLiteDB(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := testState()
+	value := [4]float64{0.75, 0.25}
+	db.Put(state.ID(), value)
+	if got := db.Get(state.ID()); got != value {
+		t.Errorf("pending value = %v, want %v", got, value)
+	}
+	// More than one transaction of other states.
+	for i := range batchSize {
+		db.Put(farkle.NewGameState(2).ID()+i, [4]float64{0.5, 0.5})
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewSQLiteDB(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := db.Get(state.ID()); got != value {
+		t.Errorf("value after reopening = %v, want %v", got, value)
+	}
+	other := testState()
+	other.ScoreThisRound++
+	if got, want := db.Get(other.ID()), farkle.InitialValue(2, other.ID(), db.Metadata()); got != want {
+		t.Errorf("value of a state that was never stored = %v, want %v", got, want)
+	}
+
+	var numDice, turnScore, score0, score1 int
+	var p0 float64
+	if err := db.db.QueryRow(`SELECT num_dice, turn_score, score0, score1, p0 FROM state_values WHERE id = ?`,
+		state.ID()).Scan(&numDice, &turnScore, &score0, &score1, &p0); err != nil {
+		t.Fatal(err)
+	}
+	if numDice != 4 || turnScore != 150 || score0 != 1000 || score1 != 2250 || p0 != 0.75 {
+		t.Errorf("state_values of %v = %d dice, %d this turn, scores %d and %d, value %v",
+			state, numDice, turnScore, score0, score1, p0)
+	}
+	var numRows int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM states`).Scan(&numRows); err != nil {
+		t.Fatal(err)
+	} else if numRows != batchSize+1 {
+		t.Errorf("%d states were written, want %d", numRows, batchSize+1)
+	}
+}
+
+// Databases are reopened with the metadata they were created with, and
+// are not opened for another number of players or other metadata.
+func TestSQLiteDBMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	meta := farkle.Metadata{
+		Objective:    farkle.RiskAdjustedMargin,
+		RiskAversion: 0.5,
+		Rules:        farkle.StandardPreset.Rules().Fingerprint() ^ 1<<63,
+	}
+	db, err := NewSQLiteDBWithMetadata(path, 2, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewSQLiteDBWithMetadata(path, 2, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Metadata(); got != meta {
+		t.Errorf("metadata = %v, want %v", got, meta)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = NewSQLiteDB(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Metadata(); got != meta {
+		t.Errorf("metadata opened without requiring it = %v, want %v", got, meta)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	other := meta
+	other.RiskAversion = 1
+	if db, err := NewSQLiteDBWithMetadata(path, 2, other); err == nil {
+		db.Close()
+		t.Error("opened a database with other metadata")
+	}
+	if db, err := NewSQLiteDB(path, 3); err == nil {
+		db.Close()
+		t.Error("opened a 2-player database for 3 players")
+	}
+}
@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"github.com/timpalpant/go-farkle"
+)
+
+// Observes a farkle.Game, and records the statistics of its players to a
+// store when it is over.
+type Recorder struct {
+	farkle.NopObserver
+
+	store   *Store
+	players []Player
+	// Used to compute the win probability given up by each move, if not nil.
+	advisor farkle.Advisor
+	stats   []GameStats
+}
+
+// Record a game between the given players, ordered by seat. If advisor is not
+// nil, every move is compared to its recommendation.
+func NewRecorder(store *Store, players []Player, advisor farkle.Advisor) *Recorder {
+	return &Recorder{
+		store:   store,
+		players: players,
+		advisor: advisor,
+		stats:   make([]GameStats, len(players)),
+	}
+}
+
+// The statistics of each player so far, by seat.
+func (r *Recorder) Stats() []GameStats {
+	return r.stats
+}
+
+func (r *Recorder) OnRoll(seat int, state farkle.GameState, roll farkle.Roll) {
+	r.stats[seat].Rolls++
+}
+
+func (r *Recorder) OnAction(seat int, state farkle.GameState, roll farkle.Roll, action farkle.Action) {
+	if farkle.IsFarkle(roll) {
+		return // Not a decision.
+	}
+
+	st := &r.stats[seat]
+	st.Moves++
+	if r.advisor != nil {
+		_, pWinOpt := r.advisor.Recommend(state, roll)
+		pWinAction := r.advisor.EvaluateAction(state, action)
+		st.AnalyzedMoves++
+		st.TotalLoss += max(0, pWinOpt[0]-pWinAction[0])
+	}
+}
+
+func (r *Recorder) OnFarkle(seat int, state farkle.GameState) {
+	r.stats[seat].Farkles++
+}
+
+func (r *Recorder) OnGameOver(result farkle.GameResult) {
+	ratings, err := r.store.RecordGame(r.players, result, r.stats)
+	if err != nil {
+		farkle.Logger().Warn("Unable to record statistics", "err", err)
+		return
+	}
+	farkle.Logger().Debug("New ratings", "players", r.players, "ratings", ratings)
+}
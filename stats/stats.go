@@ -0,0 +1,282 @@
+// Package stats records the results of players' games in a SQLite database,
+// with how well they played each move and an Elo rating that is updated after
+// every game, to rank players on a leaderboard.
+//
+// Players are identified by an ID that is stable across games, such as an
+// account or a bot's strategy, rather than by the name they play under, and
+// each game is stored in the results table, with one row per player:
+//
+//	players (id, name, rating, games, wins, moves, analyzed_moves, total_loss, rolls, farkles)
+//	results (game, played_at, player, name, seat, score, won, moves, analyzed_moves,
+//	         total_loss, rolls, farkles, rating)
+//
+// where name is the last name the player played under, and total_loss is the
+// sum of the win probability given up by the analyzed moves, compared to
+// optimal play.
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/timpalpant/go-farkle"
+	_ "modernc.org/sqlite"
+)
+
+// The rating of new players.
+const InitialRating = 1500
+
+// The maximum change in rating from a game against a single opponent. In
+// games with more players, each pair of players is rated as a game between
+// them, with the change divided among the opponents.
+const kFactor = 32
+
+// The version of the tables, in SQLite's user_version. Databases with other
+// versions, e.g. from before players had IDs, are not read.
+const schemaVersion = 1
+
+// A player in a game. Ratings are kept by ID, which identifies the player
+// across games, while the name is only displayed.
+type Player struct {
+	ID   string
+	Name string
+}
+
+// The play of one player in a game.
+type GameStats struct {
+	// Number of decisions made, excluding farkles.
+	Moves int
+	// Number of moves that were compared to optimal play, and the total win
+	// probability they gave up.
+	AnalyzedMoves int
+	TotalLoss     float64
+	Rolls         int
+	Farkles       int
+}
+
+// A player's statistics over all of their games.
+type PlayerStats struct {
+	Player
+	Rating float64
+	Games  int
+	Wins   int
+	GameStats
+}
+
+// Average win probability given up per analyzed move.
+func (s GameStats) AverageLoss() float64 {
+	if s.AnalyzedMoves == 0 {
+		return 0
+	}
+	return s.TotalLoss / float64(s.AnalyzedMoves)
+}
+
+// Fraction of rolls that were farkles.
+func (s GameStats) FarkleRate() float64 {
+	if s.Rolls == 0 {
+		return 0
+	}
+	return float64(s.Farkles) / float64(s.Rolls)
+}
+
+// Stores statistics in a SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open the store at the given path, creating it if it does not exist.
+func OpenStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows only one writer at a time.
+	db.SetMaxOpenConns(1)
+
+	s := &Store{db: db}
+	if err := s.init(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *Store) init() error {
+	var version, numTables int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&numTables); err != nil {
+		return err
+	}
+	if numTables > 0 && version != schemaVersion {
+		return fmt.Errorf("statistics of version %d are not supported, expected version %d", version, schemaVersion)
+	}
+
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS players (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		rating REAL NOT NULL,
+		games INTEGER NOT NULL,
+		wins INTEGER NOT NULL,
+		moves INTEGER NOT NULL,
+		analyzed_moves INTEGER NOT NULL,
+		total_loss REAL NOT NULL,
+		rolls INTEGER NOT NULL,
+		farkles INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS results (
+		game INTEGER NOT NULL,
+		played_at INTEGER NOT NULL,
+		player TEXT NOT NULL REFERENCES players (id),
+		name TEXT NOT NULL,
+		seat INTEGER NOT NULL,
+		score INTEGER NOT NULL,
+		won INTEGER NOT NULL,
+		moves INTEGER NOT NULL,
+		analyzed_moves INTEGER NOT NULL,
+		total_loss REAL NOT NULL,
+		rolls INTEGER NOT NULL,
+		farkles INTEGER NOT NULL,
+		rating REAL NOT NULL,
+		PRIMARY KEY (game, seat))`); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion))
+	return err
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record a finished game between the given players, ordered by seat, and
+// update their ratings. Returns each player's new rating. Each player may only
+// be in one seat, since a game against oneself is not rated.
+func (s *Store) RecordGame(players []Player, result farkle.GameResult, stats []GameStats) ([]float64, error) {
+	if len(players) != len(result.Scores) || len(stats) != len(result.Scores) {
+		return nil, fmt.Errorf("expected %d players, got %d players and %d stats",
+			len(result.Scores), len(players), len(stats))
+	}
+	for i, p := range players {
+		if p.ID == "" {
+			return nil, fmt.Errorf("player in seat %d has no ID", i)
+		}
+		if j := slices.IndexFunc(players[:i], func(other Player) bool { return other.ID == p.ID }); j >= 0 {
+			return nil, fmt.Errorf("player %q is in seats %d and %d", p.ID, j, i)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ratings := make([]float64, len(players))
+	for i, p := range players {
+		err := tx.QueryRow(`SELECT rating FROM players WHERE id = ?`, p.ID).Scan(&ratings[i])
+		if err == sql.ErrNoRows {
+			ratings[i] = InitialRating
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	// Players who forfeit lose to everyone else.
+	scores := slices.Clone(result.Scores)
+	for _, seat := range result.Forfeited {
+		scores[seat] = -1
+	}
+	ratings = updateRatings(ratings, scores)
+
+	var game int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(game), 0) + 1 FROM results`).Scan(&game); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for seat, p := range players {
+		won := isWinner(result, seat)
+		st := stats[seat]
+		if _, err := tx.Exec(`INSERT INTO players VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name,
+				rating = excluded.rating,
+				games = games + 1,
+				wins = wins + excluded.wins,
+				moves = moves + excluded.moves,
+				analyzed_moves = analyzed_moves + excluded.analyzed_moves,
+				total_loss = total_loss + excluded.total_loss,
+				rolls = rolls + excluded.rolls,
+				farkles = farkles + excluded.farkles`,
+			p.ID, p.Name, ratings[seat], won, st.Moves, st.AnalyzedMoves, st.TotalLoss, st.Rolls, st.Farkles); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`INSERT INTO results VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			game, now, p.ID, p.Name, seat, result.Scores[seat], won,
+			st.Moves, st.AnalyzedMoves, st.TotalLoss, st.Rolls, st.Farkles, ratings[seat]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ratings, tx.Commit()
+}
+
+// The players who have played at least minGames games, by decreasing rating.
+func (s *Store) Leaderboard(minGames int) ([]PlayerStats, error) {
+	rows, err := s.db.Query(`SELECT id, name, rating, games, wins,
+		moves, analyzed_moves, total_loss, rolls, farkles
+		FROM players WHERE games >= ? ORDER BY rating DESC`, minGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []PlayerStats
+	for rows.Next() {
+		var p PlayerStats
+		if err := rows.Scan(&p.ID, &p.Name, &p.Rating, &p.Games, &p.Wins,
+			&p.Moves, &p.AnalyzedMoves, &p.TotalLoss, &p.Rolls, &p.Farkles); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+func isWinner(result farkle.GameResult, seat int) bool {
+	for _, winner := range result.Winners {
+		if winner == seat {
+			return true
+		}
+	}
+	return false
+}
+
+// The new Elo ratings of players with the given final scores. Each pair of
+// players is rated as a game won by the one with the higher score.
+func updateRatings(ratings []float64, scores []int) []float64 {
+	n := len(ratings)
+	k := kFactor / float64(n-1)
+	result := make([]float64, n)
+	copy(result, ratings)
+	for i := range ratings {
+		for j := range ratings {
+			if i == j {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, (ratings[j]-ratings[i])/400))
+			actual := 0.5
+			if scores[i] > scores[j] {
+				actual = 1
+			} else if scores[i] < scores[j] {
+				actual = 0
+			}
+			result[i] += k * (actual - expected)
+		}
+	}
+	return result
+}
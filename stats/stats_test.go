@@ -0,0 +1,162 @@
+package stats
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/timpalpant/go-farkle"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := OpenStore(filepath.Join(t.TempDir(), "stats.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestUpdateRatings(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ratings []float64
+		scores  []int
+		want    []float64
+	}{
+		{"win between equals", []float64{1500, 1500}, []int{10000, 5000}, []float64{1516, 1484}},
+		{"tie between equals", []float64{1500, 1500}, []int{10000, 10000}, []float64{1500, 1500}},
+		// Expected to win 10:1, so the winner gains only 1/11 of the K-factor.
+		{"expected win", []float64{1900, 1500}, []int{10000, 5000},
+			[]float64{1900 + 32./11, 1500 - 32./11}},
+		{"upset", []float64{1500, 1900}, []int{10000, 5000},
+			[]float64{1500 + 320./11, 1900 - 320./11}},
+		// Each pair is rated with half the K-factor.
+		{"three players", []float64{1500, 1500, 1500}, []int{10000, 5000, 0},
+			[]float64{1516, 1500, 1484}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := updateRatings(tc.ratings, tc.scores)
+			total := 0.0
+			for i := range got {
+				if math.Abs(got[i]-tc.want[i]) > 1e-9 {
+					t.Errorf("rating of player %d = %v, want %v", i, got[i], tc.want[i])
+				}
+				total += got[i] - tc.ratings[i]
+			}
+			if math.Abs(total) > 1e-9 {
+				t.Errorf("ratings changed by %v in total, want 0", total)
+			}
+		})
+	}
+}
+
+func leaderboardByID(t *testing.T, store *Store) map[string]PlayerStats {
+	t.Helper()
+	players, err := store.Leaderboard(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]PlayerStats)
+	for _, p := range players {
+		result[p.ID] = p
+	}
+	return result
+}
+
+// Players are rated by ID, whatever the name they play under.
+func TestRecordGame(t *testing.T) {
+	store := openTestStore(t)
+	alice := Player{ID: "player:a", Name: "alice"}
+	bob := Player{ID: "player:b", Name: "bob"}
+	// A different player with the same name as alice.
+	impostor := Player{ID: "player:c", Name: "alice"}
+	result := farkle.GameResult{Scores: []int{10000, 5000}, Winners: []int{0}}
+	stats := []GameStats{{Moves: 10, Rolls: 12, Farkles: 2}, {Moves: 8, Rolls: 11, Farkles: 3}}
+
+	ratings, err := store.RecordGame([]Player{alice, bob}, result, stats)
+	if err != nil {
+		t.Fatal(err)
+	} else if ratings[0] != 1516 || ratings[1] != 1484 {
+		t.Errorf("ratings = %v, want [1516 1484]", ratings)
+	}
+	// The impostor's game does not change alice's rating.
+	carol := Player{ID: "player:d", Name: "carol"}
+	if _, err := store.RecordGame([]Player{impostor, carol}, result, stats); err != nil {
+		t.Fatal(err)
+	}
+	alice.Name = "Alice"
+	if _, err := store.RecordGame([]Player{bob, alice}, result, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	players := leaderboardByID(t, store)
+	if len(players) != 4 {
+		t.Fatalf("leaderboard has %d players, want 4: %v", len(players), players)
+	}
+	got := players[alice.ID]
+	if got.Name != "Alice" || got.Games != 2 || got.Wins != 1 || got.Moves != 18 || got.Farkles != 5 {
+		t.Errorf("alice's statistics = %+v", got)
+	}
+	if got := players[impostor.ID]; got.Games != 1 || got.Wins != 1 || got.Rating != 1516 {
+		t.Errorf("statistics of the other alice = %+v", got)
+	}
+
+	var numResults int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM results WHERE player = ?`, bob.ID).Scan(&numResults); err != nil {
+		t.Fatal(err)
+	} else if numResults != 2 {
+		t.Errorf("bob has %d results, want 2", numResults)
+	}
+}
+
+// A player cannot play against themselves, e.g. the same bot in two seats.
+func TestRecordGameDuplicatePlayer(t *testing.T) {
+	store := openTestStore(t)
+	bot := Player{ID: "bot:optimal", Name: "optimal"}
+	result := farkle.GameResult{Scores: []int{10000, 5000}, Winners: []int{0}}
+	if _, err := store.RecordGame([]Player{bot, bot}, result, make([]GameStats, 2)); err == nil {
+		t.Error("recorded a game between a player and themselves")
+	}
+	if _, err := store.RecordGame([]Player{bot, {Name: "anonymous"}}, result, make([]GameStats, 2)); err == nil {
+		t.Error("recorded a game with a player without an ID")
+	}
+	if players := leaderboardByID(t, store); len(players) != 0 {
+		t.Errorf("rejected games changed the leaderboard: %v", players)
+	}
+}
+
+// Players who forfeit lose to everyone else, whatever their score.
+func TestRecordGameForfeit(t *testing.T) {
+	store := openTestStore(t)
+	players := []Player{{ID: "a", Name: "a"}, {ID: "b", Name: "b"}}
+	result := farkle.GameResult{Scores: []int{5000, 1000}, Winners: []int{1}, Forfeited: []int{0}}
+	ratings, err := store.RecordGame(players, result, make([]GameStats, 2))
+	if err != nil {
+		t.Fatal(err)
+	} else if ratings[0] >= ratings[1] {
+		t.Errorf("ratings = %v, want the player who forfeited to lose", ratings)
+	}
+}
+
+// Statistics from before players had IDs are not read.
+func TestOpenStoreOldVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE players (name TEXT PRIMARY KEY, rating REAL NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if store, err := OpenStore(path); err == nil {
+		store.Close()
+		t.Error("opened statistics without player IDs")
+	}
+}
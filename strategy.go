@@ -0,0 +1,180 @@
+package farkle
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Strategy selects the action a player takes in response to a roll.
+type Strategy interface {
+	SelectAction(state GameState, roll Roll) (Action, error)
+}
+
+// Plays optimally according to the values in a solved database.
+// Fails if the database was solved with other rules (see CheckRules).
+type OptimalStrategy struct {
+	DB DB
+}
+
+func (s OptimalStrategy) SelectAction(state GameState, roll Roll) (Action, error) {
+	if err := CheckRules(s.DB); err != nil {
+		return Action{}, err
+	}
+	db, release := AcquireDB(s.DB)
+	defer release()
+	action, _ := SelectAction(state, GetRollID(roll), db)
+	return action, nil
+}
+
+func (s OptimalStrategy) String() string {
+	return "optimal"
+}
+
+// A simple heuristic strategy: hold the dice that score the most points,
+// and continue rolling until the score this turn reaches a threshold.
+type ThresholdStrategy struct {
+	// Bank once the score this turn is at least this many points.
+	BankAt int
+	// Keep rolling regardless of the score if at least this many dice
+	// remain to be rolled. Zero disables this.
+	MinDiceToContinue int
+}
+
+func (s ThresholdStrategy) SelectAction(state GameState, roll Roll) (Action, error) {
+	if IsFarkle(roll) {
+		return Action{}, nil
+	}
+
+	var bestHold Roll
+	bestScore := uint8(0)
+	for _, hold := range rollIDToPotentialHolds[GetRollID(roll)] {
+		score := scoreCache[rollToID[hold]]
+		if score > bestScore || (score == bestScore && hold.NumDice() < bestHold.NumDice()) {
+			bestHold = hold
+			bestScore = score
+		}
+	}
+
+	action := Action{HeldDiceID: rollToID[bestHold]}
+	newState := ApplyAction(state, Action{HeldDiceID: action.HeldDiceID, ContinueRolling: true})
+	turnScore := incr * int(newState.ScoreThisRound)
+	action.ContinueRolling = turnScore < s.BankAt ||
+		(s.MinDiceToContinue > 0 && int(newState.NumDiceToRoll) >= s.MinDiceToContinue) ||
+		ValidateAction(state, roll, action) != nil // Not yet on the board.
+	return action, nil
+}
+
+func (s ThresholdStrategy) String() string {
+	if s.MinDiceToContinue > 0 {
+		return fmt.Sprintf("threshold:%d:%d", s.BankAt, s.MinDiceToContinue)
+	}
+	return fmt.Sprintf("threshold:%d", s.BankAt)
+}
+
+// Parse a ThresholdStrategy in the format of its String method,
+// i.e. threshold:BANK_AT[:MIN_DICE].
+func ParseThresholdStrategy(spec string) (ThresholdStrategy, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "threshold" {
+		return ThresholdStrategy{}, fmt.Errorf("expected threshold:BANK_AT[:MIN_DICE], got %q", spec)
+	}
+
+	var s ThresholdStrategy
+	var err error
+	if s.BankAt, err = strconv.Atoi(parts[1]); err != nil {
+		return ThresholdStrategy{}, err
+	}
+	if len(parts) == 3 {
+		if s.MinDiceToContinue, err = strconv.Atoi(parts[2]); err != nil {
+			return ThresholdStrategy{}, err
+		}
+	}
+	return s, nil
+}
+
+// Check that the given action is legal in response to a roll.
+func ValidateAction(state GameState, roll Roll, action Action) error {
+	if roll.NumDice() != state.NumDiceToRoll {
+		return fmt.Errorf("rolled %d dice but should have rolled %d",
+			roll.NumDice(), state.NumDiceToRoll)
+	}
+
+	if IsFarkle(roll) {
+		if action != (Action{}) {
+			return fmt.Errorf("%v is a farkle, but got action %v", roll, action)
+		}
+		return nil
+	}
+
+	held := action.HeldDice()
+	if !IsValidHold(roll, held) {
+		return fmt.Errorf("can't hold %v from %v, not a valid trick", held, roll)
+	}
+
+	score := int(state.ScoreThisRound) + int(scoreCache[action.HeldDiceID])
+	if !action.ContinueRolling && state.CurrentPlayerScore() == 0 && score < int(openingScore) {
+		return fmt.Errorf("must continue rolling until getting at least %d (have %d)",
+			incr*int(openingScore), incr*score)
+	}
+
+	return nil
+}
+
+// All legal actions in response to the given roll. Empty if the roll is a farkle.
+func LegalActions(state GameState, roll Roll) []Action {
+	return legalActions(nil, state, GetRollID(roll))
+}
+
+// Append the legal actions in response to the given roll to dst, as in
+// LegalActions, and return the extended slice. Reusing dst from one call to
+// the next avoids allocating.
+func AppendLegalActions(dst []Action, state GameState, roll Roll) []Action {
+	return legalActions(dst, state, GetRollID(roll))
+}
+
+// Result of a completed game.
+type GameResult struct {
+	// Final score of each player, in points, ordered by seat.
+	Scores []int
+	// Seats of the players with the highest score (more than one if tied).
+	Winners []int
+	// Total number of turns taken by all players.
+	NumTurns int
+	// Seats of the players who forfeited the game, if any.
+	Forfeited []int
+}
+
+// Play a game between the given strategies, with strategies[i] in seat i.
+// Seat 0 goes first.
+func PlayGame(strategies []Strategy, rng *rand.Rand) (GameResult, error) {
+	return PlayGameObserved(strategies, rng, nil)
+}
+
+// Called with each roll in a game, and the action taken in response to it.
+type GameObserver func(seat int, state GameState, roll Roll, action Action)
+
+// As PlayGame, but calling observe (if not nil) with every action taken.
+func PlayGameObserved(strategies []Strategy, rng *rand.Rand, observe GameObserver) (GameResult, error) {
+	game := NewGame(len(strategies), rng)
+	if observe != nil {
+		game.AddObserver(ActionObserver(observe))
+	}
+	for !game.IsOver() {
+		seat := game.CurrentPlayer()
+		roll, err := game.Roll()
+		if err != nil {
+			return GameResult{}, err
+		}
+		action, err := strategies[seat].SelectAction(game.State(), roll)
+		if err != nil {
+			return GameResult{}, fmt.Errorf("player %d: %w", seat, err)
+		}
+		if err := game.Apply(action); err != nil {
+			return GameResult{}, fmt.Errorf("player %d: illegal action: %w", seat, err)
+		}
+	}
+
+	return game.Result(), nil
+}
@@ -0,0 +1,88 @@
+package farkle
+
+import (
+	"sync"
+)
+
+// DB that makes the underlying database safe for concurrent use, with a
+// read-write lock: any number of goroutines may Get values at once, and
+// each Put has the database to itself.
+type SyncDB struct {
+	db DB
+	mx sync.RWMutex
+}
+
+func NewSyncDB(db DB) *SyncDB {
+	return &SyncDB{db: db}
+}
+
+// The given database if it is safe for concurrent use, e.g. a FileDB opened
+// read-only, or else a SyncDB wrapping it. Servers that answer requests
+// concurrently from one database should use the result.
+func ConcurrentDB(db DB) DB {
+	if c, ok := db.(concurrentDB); ok && c.safeForConcurrentUse() {
+		return db
+	}
+	return NewSyncDB(db)
+}
+
+// Databases that may be safe for concurrent use without a SyncDB.
+type concurrentDB interface {
+	safeForConcurrentUse() bool
+}
+
+// Databases that may be written concurrently for different states, while
+// other states are read, without a SyncDB.
+type concurrentPutDB interface {
+	safeForConcurrentPuts() bool
+}
+
+// The given database if it may be written concurrently for different states,
+// or else a SyncDB wrapping it.
+func concurrentPutsDB(db DB) DB {
+	if c, ok := db.(concurrentPutDB); ok && c.safeForConcurrentPuts() {
+		return db
+	}
+	return ConcurrentDB(db)
+}
+
+func (db *SyncDB) NumPlayers() int {
+	return db.db.NumPlayers()
+}
+
+func (db *SyncDB) Metadata() Metadata {
+	return db.db.Metadata()
+}
+
+func (db *SyncDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+	db.db.Put(gsID, pWin)
+}
+
+func (db *SyncDB) Get(gsID int) [maxNumPlayers]float64 {
+	db.mx.RLock()
+	defer db.mx.RUnlock()
+	return db.db.Get(gsID)
+}
+
+func (db *SyncDB) Close() error {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+	return db.db.Close()
+}
+
+func (db *SyncDB) IsDecided(gsID int) bool {
+	db.mx.RLock()
+	defer db.mx.RUnlock()
+	decided, ok := db.db.(decidedDB)
+	return ok && decided.IsDecided(gsID)
+}
+
+func (db *SyncDB) safeForConcurrentUse() bool {
+	return true
+}
+
+func (db *SyncDB) safeForConcurrentPuts() bool {
+	return true
+}
@@ -0,0 +1,68 @@
+package farkle
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// ConcurrentDB wraps only the databases that are not safe for concurrent use.
+func TestConcurrentDB(t *testing.T) {
+	memDB := NewInMemoryDB(1)
+	if _, ok := ConcurrentDB(memDB).(*SyncDB); !ok {
+		t.Error("InMemoryDB is not wrapped in a SyncDB")
+	}
+	syncDB := NewSyncDB(memDB)
+	if db := ConcurrentDB(syncDB); db != DB(syncDB) {
+		t.Error("SyncDB is wrapped again")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	fileDB, err := NewFileDB(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ConcurrentDB(fileDB).(*SyncDB); !ok {
+		t.Error("FileDB open for writing is not wrapped in a SyncDB")
+	}
+	if db := concurrentPutsDB(fileDB); db != DB(fileDB) {
+		t.Error("FileDB open for writing is wrapped for concurrent puts")
+	}
+	if err := fileDB.Close(); err != nil {
+		t.Fatal(err)
+	}
+	readOnly, err := OpenFileDBReadOnly(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Close()
+	if db := ConcurrentDB(readOnly); db != DB(readOnly) {
+		t.Error("read-only FileDB is wrapped in a SyncDB")
+	}
+}
+
+// A SyncDB may be read and written from many goroutines at once (run with
+// -race to check).
+func TestSyncDBConcurrentUse(t *testing.T) {
+	states, rollIDs := sampleGameStates(2)
+	db := ConcurrentDB(NewInMemoryDB(2))
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(states); i += 4 {
+				db.Put(states[i].ID(), [maxNumPlayers]float64{0.75, 0.25})
+				SelectAction(states[i], rollIDs[i], db)
+				CalculateWinProb(states[(i+1)%len(states)], db)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, state := range states {
+		if got := db.Get(state.ID()); got[0] != 0.75 {
+			t.Fatalf("value of %v = %v, want 0.75", state, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package farkle
+
+import (
+	"iter"
+)
+
+// DB that keeps the endgame states of another database, in which the players'
+// scores add up to at least a given total, in memory. The value iteration
+// sweep updates states in order of depth, starting from the endgame, and
+// states throughout the game look up endgame values, so keeping them in
+// memory avoids most page faults when the database does not fit in RAM.
+//
+// The endgame values are loaded when the TieredDB is created, and written
+// back to the underlying database by Flush and Close. All other states are
+// read and written directly.
+type TieredDB struct {
+	DB
+	endgameScore int
+	endgame      *InMemoryDB
+}
+
+// Load the endgame states of db, in which the sum of all players' scores is
+// at least endgameScore (in the units of GameState), into memory.
+func NewTieredDB(db DB, endgameScore int) *TieredDB {
+	numPlayers := db.NumPlayers()
+	tiered := &TieredDB{
+		DB:           db,
+		endgameScore: endgameScore,
+		endgame:      NewInMemoryDBWithMetadata(numPlayers, db.Metadata()),
+	}
+
+	// Only values that differ from their initial value need to be stored.
+	for gsID := range tiered.endgameStates() {
+		if pWin := db.Get(gsID); pWin != InitialValue(numPlayers, gsID, db.Metadata()) {
+			tiered.endgame.Put(gsID, pWin)
+		}
+	}
+
+	Logger().Info("Loaded endgame states into memory", "states", tiered.endgame.Len())
+	return tiered
+}
+
+// The IDs of all endgame states.
+func (db *TieredDB) endgameStates() iter.Seq[int] {
+	numPlayers := db.NumPlayers()
+	return func(yield func(int) bool) {
+		gs := NewGameState(numPlayers)
+		var next func(player, total int) bool
+		next = func(player, total int) bool {
+			if player < numPlayers {
+				for score := 0; score <= 0xff; score++ {
+					gs.PlayerScores[player] = uint8(score)
+					if !next(player+1, total+score) {
+						return false
+					}
+				}
+				return true
+			}
+
+			if total < db.endgameScore {
+				return true
+			}
+			for numDice := 1; numDice <= MaxNumDice; numDice++ {
+				gs.NumDiceToRoll = uint8(numDice)
+				for turnScore := 0; turnScore <= 0xff; turnScore++ {
+					gs.ScoreThisRound = uint8(turnScore)
+					if !yield(gs.ID()) {
+						return false
+					}
+				}
+			}
+			return true
+		}
+
+		next(0, 0)
+	}
+}
+
+// Whether the given state is kept in memory.
+func (db *TieredDB) IsEndgame(gsID int) bool {
+	gs := GameStateFromID(db.NumPlayers(), gsID)
+	total := 0
+	for _, score := range gs.PlayerScores[:gs.NumPlayers] {
+		total += int(score)
+	}
+	return total >= db.endgameScore
+}
+
+// The number of endgame states whose values are stored in memory.
+func (db *TieredDB) EndgameLen() int {
+	return db.endgame.Len()
+}
+
+func (db *TieredDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	if db.IsEndgame(gsID) {
+		db.endgame.Put(gsID, pWin)
+	} else {
+		db.DB.Put(gsID, pWin)
+	}
+}
+
+func (db *TieredDB) Get(gsID int) [maxNumPlayers]float64 {
+	if db.IsEndgame(gsID) {
+		return db.endgame.Get(gsID)
+	}
+	return db.DB.Get(gsID)
+}
+
+func (db *TieredDB) IsDecided(gsID int) bool {
+	decided, ok := db.DB.(decidedDB)
+	return ok && decided.IsDecided(gsID)
+}
+
+func (db *TieredDB) prefetch(gsIDs []int) {
+	if p, ok := db.DB.(prefetchDB); ok {
+		p.prefetch(gsIDs)
+	}
+}
+
+// Write the endgame values in memory to the underlying database.
+func (db *TieredDB) Flush() {
+	for gsID, pWin := range db.endgame.values {
+		db.DB.Put(gsID, pWin)
+	}
+}
+
+func (db *TieredDB) Close() error {
+	db.Flush()
+	return db.DB.Close()
+}
@@ -0,0 +1,71 @@
+package farkle
+
+import (
+	"testing"
+)
+
+// Endgame values are kept in memory until they are flushed, and other
+// values are read and written directly.
+func TestTieredDB(t *testing.T) {
+	const endgameScore = 200
+	endgame, early := NewGameState(1), NewGameState(1)
+	endgame.PlayerScores[0] = endgameScore
+	early.PlayerScores[0] = endgameScore - 1
+	value := [maxNumPlayers]float64{0.25}
+
+	db := NewInMemoryDB(1)
+	db.Put(endgame.ID(), value)
+	tiered := NewTieredDB(db, endgameScore)
+	if n := tiered.EndgameLen(); n != 1 {
+		t.Errorf("loaded %d endgame states, want 1", n)
+	}
+
+	numStates := 0
+	for gsID := range tiered.endgameStates() {
+		if !tiered.IsEndgame(gsID) {
+			t.Fatalf("%v is enumerated as an endgame state", GameStateFromID(1, gsID))
+		}
+		numStates++
+	}
+	if want := (0x100 - endgameScore) * MaxNumDice * 0x100; numStates != want {
+		t.Errorf("enumerated %d endgame states, want %d", numStates, want)
+	}
+	if tiered.IsEndgame(early.ID()) {
+		t.Errorf("%v is an endgame state", early)
+	}
+
+	tiered.Put(endgame.ID(), [maxNumPlayers]float64{0.5})
+	tiered.Put(early.ID(), [maxNumPlayers]float64{0.75})
+	if got := tiered.Get(endgame.ID()); got[0] != 0.5 {
+		t.Errorf("endgame value = %v, want 0.5", got[0])
+	} else if got := db.Get(endgame.ID()); got != value {
+		t.Errorf("endgame value was written before Flush: %v", got[0])
+	}
+	if got := db.Get(early.ID()); got[0] != 0.75 {
+		t.Errorf("value of an early state = %v, want 0.75", got[0])
+	}
+
+	if err := tiered.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Get(endgame.ID()); got[0] != 0.5 {
+		t.Errorf("endgame value after Close = %v, want 0.5", got[0])
+	}
+}
+
+// Solving through a TieredDB gives the same values as solving directly.
+func TestTieredDBSolve(t *testing.T) {
+	setTestRules(t, "pocket-farkle,target=300,dice=3")
+	states := miniatureGameStates(t, 1)
+	want := NewInMemoryDB(1)
+	solveByValueIteration(t, want, states, UpdateOptions{}, 1e-12)
+
+	db := NewInMemoryDB(1)
+	tiered := NewTieredDB(db, 3)
+	solveByValueIteration(t, tiered, states, UpdateOptions{}, 1e-12)
+	if tiered.EndgameLen() == 0 {
+		t.Error("no endgame states were solved in memory")
+	}
+	tiered.Flush()
+	checkValues(t, "TieredDB", states, db, want, 1e-12)
+}
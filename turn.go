@@ -0,0 +1,133 @@
+package farkle
+
+import (
+	"fmt"
+	"math"
+)
+
+// The values of all states of one player's turn, solved exactly from the
+// values of the states after the turn ends. Every action either continues
+// the turn with a higher score this round, or ends it, so the states of the
+// turn are solved in a single pass backwards from the highest score this
+// round, and their values do not depend on how many value iteration cycles
+// solved the database.
+type TurnSolution struct {
+	turn GameState
+	// Indexed by score this round and number of dice to roll.
+	values  [math.MaxUint8 + 1][MaxNumDice + 1][maxNumPlayers]float64
+	pFarkle [math.MaxUint8 + 1][MaxNumDice + 1]float64
+}
+
+// Solve the turn of the current player in the given state, which must be
+// before the end of the game, looking up the values of the states after the
+// player banks, and of the next player's turn after they farkle, in db.
+func SolveTurn(state GameState, db DB) *TurnSolution {
+	meta := db.Metadata()
+	state.ScoreThisRound = 0
+	state.NumDiceToRoll = turnNumDice
+	farkle := turnEndValue(ApplyAction(state, Action{}), db, meta)
+
+	result := &TurnSolution{}
+	solveTurn(result, state, db, meta, farkle)
+	return result
+}
+
+// The value of the given state of the turn.
+func (t *TurnSolution) Get(state GameState) [maxNumPlayers]float64 {
+	t.check(state)
+	return t.values[state.ScoreThisRound][state.NumDiceToRoll]
+}
+
+// The probability that the current player farkles before the end of the turn
+// from the given state of the turn, playing optimally.
+func (t *TurnSolution) PFarkle(state GameState) float64 {
+	t.check(state)
+	return t.pFarkle[state.ScoreThisRound][state.NumDiceToRoll]
+}
+
+func (t *TurnSolution) check(state GameState) {
+	if state.PlayerScores != t.turn.PlayerScores || state.NumPlayers != t.turn.NumPlayers {
+		panic(fmt.Errorf("state %v is not in the turn of %v", state, t.turn))
+	}
+}
+
+// Solve the states of the turn with the first state turn into t, given the
+// value of farkling relative to the current player.
+func solveTurn(t *TurnSolution, turn GameState, db DB, meta Metadata, farkle [maxNumPlayers]float64) {
+	t.turn = turn
+	state := turn
+	for scoreThisRound := math.MaxUint8; scoreThisRound >= 0; scoreThisRound-- {
+		state.ScoreThisRound = uint8(scoreThisRound)
+		for numDice := 1; numDice <= int(turnNumDice); numDice++ {
+			state.NumDiceToRoll = uint8(numDice)
+			var pWin [maxNumPlayers]float64
+			pFarkle := 0.0
+			for _, wRoll := range allRolls[numDice] {
+				v, q := t.bestOutcome(state, wRoll.ID, db, meta, farkle)
+				mixInto(&pWin, wRoll.Prob, &v)
+				pFarkle += wRoll.Prob * q
+			}
+			t.values[scoreThisRound][numDice] = pWin
+			t.pFarkle[scoreThisRound][numDice] = pFarkle
+		}
+	}
+}
+
+// The value of the best action after the given roll, as in SelectAction, and
+// the probability of farkling before the end of the turn after it.
+func (t *TurnSolution) bestOutcome(state GameState, rollID uint16, db DB, meta Metadata, farkle [maxNumPlayers]float64) ([maxNumPlayers]float64, float64) {
+	potentialActions := rollIDToPotentialActions[rollID]
+	if len(potentialActions) == 0 {
+		return farkle, 1
+	}
+
+	solitaire := state.NumPlayers == 1
+	best := [maxNumPlayers]float64{math.Inf(-1)}
+	if solitaire {
+		best[0] = math.Inf(1)
+	}
+	bestFarkle := 0.0
+	effects := rollIDToActionEffects[rollID]
+	parts := newStateIDParts(state)
+	for i, action := range potentialActions {
+		action, _, allowed := parts.takeAction(action, effects[i])
+		if !allowed {
+			continue
+		}
+
+		newState := ApplyAction(state, action)
+		var v [maxNumPlayers]float64
+		q := 0.0
+		if action.ContinueRolling {
+			v = t.values[newState.ScoreThisRound][newState.NumDiceToRoll]
+			q = t.pFarkle[newState.ScoreThisRound][newState.NumDiceToRoll]
+		} else {
+			v = turnEndValue(newState, db, meta)
+		}
+
+		if (solitaire && v[0] < best[0]) || (!solitaire && v[0] > best[0]) {
+			best, bestFarkle = v, q
+		}
+	}
+	return best, bestFarkle
+}
+
+// The value of ending the turn in the given state of the next player's turn,
+// relative to the current player.
+func turnEndValue(next GameState, db DB, meta Metadata) [maxNumPlayers]float64 {
+	if next.IsGameOver() {
+		return fromNextTurn(calcEndGameValue(next, meta), int(next.NumPlayers))
+	}
+	return fromNextTurn(db.Get(next.ID()), int(next.NumPlayers))
+}
+
+// The value of ending the turn, relative to the current player, given the
+// value of the next player's turn. In single-player games, the turn that
+// ends counts towards the number of turns.
+func fromNextTurn(pWin [maxNumPlayers]float64, numPlayers int) [maxNumPlayers]float64 {
+	pWin = unrotate(pWin, uint8(numPlayers))
+	if numPlayers == 1 {
+		pWin[0]++
+	}
+	return pWin
+}
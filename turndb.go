@@ -0,0 +1,208 @@
+package farkle
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Files written by TurnDB.WriteTo are gzipped, and begin with a header of
+// turnDBHeaderSize bytes: turnDBMagic, the format version and the number of
+// players (uint32 each), the metadata and the RollTableVersion (uint32).
+const (
+	turnDBMagic         = "FARKLETD"
+	turnDBFormatVersion = 3
+	turnDBHeaderSize    = 20 + metadataSize
+)
+
+// DB that stores only the values of states at the start of each turn,
+// with no points scored yet and all dice to roll. The values of all other
+// states are calculated exactly when needed, by searching the rest of the
+// turn, since every turn ends at the start of the next player's turn.
+// This makes it small enough to embed a full 2-player solution in a program,
+// as play-farkle does (see NewTurnDBFrom and ReadTurnDB). It is not safe for
+// concurrent use.
+type TurnDB struct {
+	numPlayers int
+	meta       Metadata
+	// Values of the states at the start of each turn, indexed by turnIndex.
+	values []float32
+	// Memoized values of states within the current turn.
+	memo map[int][maxNumPlayers]float64
+}
+
+func newTurnDB(numPlayers int, meta Metadata) *TurnDB {
+	return &TurnDB{
+		numPlayers: numPlayers,
+		meta:       meta,
+		values:     make([]float32, numPlayers<<(numPlayers*numScoreBits)),
+		memo:       make(map[int][maxNumPlayers]float64),
+	}
+}
+
+// Copy the values of all states at the start of a turn from the given (solved) database.
+func NewTurnDBFrom(db DB) *TurnDB {
+	result := newTurnDB(db.NumPlayers(), db.Metadata())
+	for i := 0; i < len(result.values)/result.numPlayers; i++ {
+		pWin := db.Get(result.turnStartID(i))
+		for j := range pWin[:result.numPlayers] {
+			result.values[i*result.numPlayers+j] = float32(pWin[j])
+		}
+	}
+	return result
+}
+
+// The ID of the state at the start of a turn with the given index.
+func (db *TurnDB) turnStartID(i int) int {
+	return int(turnNumDice-1)<<((db.numPlayers+1)*numScoreBits) | i<<numScoreBits
+}
+
+// The index of the given state in values, if it is at the start of a turn.
+func (db *TurnDB) turnIndex(state GameState) (int, bool) {
+	if state.ScoreThisRound != 0 || state.NumDiceToRoll != turnNumDice {
+		return 0, false
+	}
+	return (state.ID() >> numScoreBits) & (1<<(db.numPlayers*numScoreBits) - 1), true
+}
+
+func (db *TurnDB) NumPlayers() int {
+	return db.numPlayers
+}
+
+func (db *TurnDB) Metadata() Metadata {
+	return db.meta
+}
+
+// Store the value of a state at the start of a turn. The values of all other
+// states are calculated from these, so storing them has no effect.
+func (db *TurnDB) Put(gsID int, pWin [maxNumPlayers]float64) {
+	i, ok := db.turnIndex(GameStateFromID(db.numPlayers, gsID))
+	if !ok {
+		return
+	}
+
+	for j := range pWin[:db.numPlayers] {
+		db.values[i*db.numPlayers+j] = float32(pWin[j])
+	}
+	clear(db.memo)
+}
+
+func (db *TurnDB) Get(gsID int) [maxNumPlayers]float64 {
+	state := GameStateFromID(db.numPlayers, gsID)
+	if state.IsGameOver() {
+		return calcEndGameValue(state, db.meta)
+	}
+
+	var pWin [maxNumPlayers]float64
+	if i, ok := db.turnIndex(state); ok {
+		for j := range pWin[:db.numPlayers] {
+			pWin[j] = float64(db.values[i*db.numPlayers+j])
+		}
+		return pWin
+	}
+
+	if pWin, ok := db.memo[gsID]; ok {
+		return pWin
+	}
+
+	// Every action either continues this turn, which has a higher score,
+	// or leads to the start of the next turn, so this terminates.
+	pWin = calcStateValue(state, db)
+	if len(db.memo) >= maxMemoSize() {
+		clear(db.memo)
+	}
+	db.memo[gsID] = pWin
+	return pWin
+}
+
+func (db *TurnDB) Close() error {
+	return nil
+}
+
+// Write the database in compressed form, to be read by ReadTurnDB.
+func (db *TurnDB) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	zw := gzip.NewWriter(cw)
+	bw := bufio.NewWriter(zw)
+
+	header := make([]byte, turnDBHeaderSize)
+	copy(header, turnDBMagic)
+	binary.LittleEndian.PutUint32(header[8:], turnDBFormatVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(db.numPlayers))
+	encodeMetadata(header[16:16+metadataSize], db.meta)
+	binary.LittleEndian.PutUint32(header[16+metadataSize:], RollTableVersion)
+	if _, err := bw.Write(header); err != nil {
+		return cw.n, err
+	}
+
+	buf := make([]byte, 4)
+	for _, v := range db.values {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+		if _, err := bw.Write(buf); err != nil {
+			return cw.n, err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	err := zw.Close()
+	return cw.n, err
+}
+
+// Read a database written by TurnDB.WriteTo.
+func ReadTurnDB(r io.Reader) (*TurnDB, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	header := make([]byte, turnDBHeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if string(header[:8]) != turnDBMagic {
+		return nil, fmt.Errorf("not a farkle turn database")
+	}
+	numPlayers := int(binary.LittleEndian.Uint32(header[12:]))
+	if numPlayers < 1 || numPlayers > maxNumPlayers {
+		return nil, fmt.Errorf("invalid number of players: %d", numPlayers)
+	}
+	if version := binary.LittleEndian.Uint32(header[8:]); version != turnDBFormatVersion {
+		return nil, fmt.Errorf("unsupported database version: %d", version)
+	}
+	meta, err := decodeMetadata(header[16 : 16+metadataSize])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRollTableVersion(binary.LittleEndian.Uint32(header[16+metadataSize:])); err != nil {
+		return nil, err
+	}
+
+	db := newTurnDB(numPlayers, meta)
+	buf := make([]byte, 4)
+	for i := range db.values {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		db.values[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf))
+	}
+
+	return db, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}